@@ -217,6 +217,7 @@ func New(requiredUserAgent string, requiredPassword string, cs modules.Consensus
 		router.GET("/host", api.hostHandlerGET)                                                   // Get the host status.
 		router.POST("/host", RequirePassword(api.hostHandlerPOST, requiredPassword))              // Change the settings of the host.
 		router.POST("/host/announce", RequirePassword(api.hostAnnounceHandler, requiredPassword)) // Announce the host to the network.
+		router.GET("/host/contracts", api.hostContractsHandlerGET)
 		router.GET("/host/estimatescore", api.hostEstimateScoreGET)
 
 		// Calls pertaining to the storage manager that the host uses.
@@ -241,26 +242,39 @@ func New(requiredUserAgent string, requiredPassword string, cs modules.Consensus
 		router.GET("/renter", api.renterHandlerGET)
 		router.POST("/renter", RequirePassword(api.renterHandlerPOST, requiredPassword))
 		router.GET("/renter/contracts", api.renterContractsHandler)
+		router.POST("/renter/contracts/:id", RequirePassword(api.renterContractsFormHandler, requiredPassword))
+		router.POST("/renter/contracts/:id/cancel", RequirePassword(api.renterContractsCancelHandler, requiredPassword))
+		router.POST("/renter/contracts/:id/renew", RequirePassword(api.renterContractsRenewHandler, requiredPassword))
 		router.GET("/renter/downloads", api.renterDownloadsHandler)
 		router.GET("/renter/files", api.renterFilesHandler)
 		router.GET("/renter/prices", api.renterPricesHandler)
-
-		// TODO: re-enable these routes once the new .sia format has been
-		// standardized and implemented.
-		// router.POST("/renter/load", RequirePassword(api.renterLoadHandler, requiredPassword))
-		// router.POST("/renter/loadascii", RequirePassword(api.renterLoadAsciiHandler, requiredPassword))
-		// router.GET("/renter/share", RequirePassword(api.renterShareHandler, requiredPassword))
-		// router.GET("/renter/shareascii", RequirePassword(api.renterShareAsciiHandler, requiredPassword))
+		router.GET("/renter/spending", api.renterSpendingHandler)
+		router.POST("/renter/backup", RequirePassword(api.renterBackupHandler, requiredPassword))
+		router.POST("/renter/restore", RequirePassword(api.renterRestoreHandler, requiredPassword))
+		router.POST("/renter/load", RequirePassword(api.renterLoadHandler, requiredPassword))
+		router.POST("/renter/loadascii", RequirePassword(api.renterLoadAsciiHandler, requiredPassword))
+		router.GET("/renter/share", RequirePassword(api.renterShareHandler, requiredPassword))
+		router.GET("/renter/shareascii", RequirePassword(api.renterShareAsciiHandler, requiredPassword))
 
 		router.POST("/renter/delete/*siapath", RequirePassword(api.renterDeleteHandler, requiredPassword))
+		router.GET("/renter/dir/*siapath", api.renterDirHandlerGET)
+		router.POST("/renter/dir/*siapath", RequirePassword(api.renterDirHandlerPOST, requiredPassword))
 		router.GET("/renter/download/*siapath", RequirePassword(api.renterDownloadHandler, requiredPassword))
+		router.POST("/renter/download/cancel/:id", RequirePassword(api.renterDownloadCancelHandler, requiredPassword))
+		router.POST("/renter/download/pause/:id", RequirePassword(api.renterDownloadPauseHandler, requiredPassword))
+		router.POST("/renter/download/resume/:id", RequirePassword(api.renterDownloadResumeHandler, requiredPassword))
 		router.GET("/renter/downloadasync/*siapath", RequirePassword(api.renterDownloadAsyncHandler, requiredPassword))
+		router.POST("/renter/redundancy/*siapath", RequirePassword(api.renterRedundancyHandler, requiredPassword))
 		router.POST("/renter/rename/*siapath", RequirePassword(api.renterRenameHandler, requiredPassword))
+		router.GET("/renter/stream/*siapath", RequirePassword(api.renterStreamHandler, requiredPassword))
 		router.POST("/renter/upload/*siapath", RequirePassword(api.renterUploadHandler, requiredPassword))
+		router.POST("/renter/uploadstream/*siapath", RequirePassword(api.renterUploadStreamHandler, requiredPassword))
 
 		// HostDB endpoints.
 		router.GET("/hostdb/active", api.hostdbActiveHandler)
 		router.GET("/hostdb/all", api.hostdbAllHandler)
+		router.GET("/hostdb/filtermode", api.hostdbFilterModeHandlerGET)
+		router.POST("/hostdb/filtermode", RequirePassword(api.hostdbFilterModeHandlerPOST, requiredPassword))
 		router.GET("/hostdb/hosts/:pubkey", api.hostdbHostsHandler)
 	}
 
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
@@ -33,6 +34,19 @@ type (
 		NetworkMetrics       modules.HostNetworkMetrics       `json:"networkmetrics"`
 		ConnectabilityStatus modules.HostConnectabilityStatus `json:"connectabilitystatus"`
 		WorkingStatus        modules.HostWorkingStatus        `json:"workingstatus"`
+		PriceHistory         []modules.HostPriceChange        `json:"pricehistory"`
+
+		// LastObligationExpiration is the height at which the proof window of
+		// the host's last unresolved storage obligation closes. Once the
+		// blockchain has passed this height, a host in maintenance mode can
+		// go offline without losing collateral.
+		LastObligationExpiration types.BlockHeight `json:"lastobligationexpiration"`
+	}
+
+	// HostContractsGET contains the information that is returned after a GET
+	// request to /host/contracts - the storage obligations of the host.
+	HostContractsGET struct {
+		Contracts []modules.StorageObligation `json:"contracts"`
 	}
 
 	// HostEstimateScoreGET contains the information that is returned from a
@@ -70,6 +84,13 @@ func (api *API) hostHandlerGET(w http.ResponseWriter, req *http.Request, _ httpr
 	nm := api.host.NetworkMetrics()
 	cs := api.host.ConnectabilityStatus()
 	ws := api.host.WorkingStatus()
+	ph := api.host.PriceHistory()
+	var loe types.BlockHeight
+	for _, so := range api.host.StorageObligations() {
+		if so.Status == modules.ObligationStatusUnresolved && so.ProofDeadline > loe {
+			loe = so.ProofDeadline
+		}
+	}
 	hg := HostGET{
 		ExternalSettings:     es,
 		FinancialMetrics:     fm,
@@ -77,6 +98,9 @@ func (api *API) hostHandlerGET(w http.ResponseWriter, req *http.Request, _ httpr
 		NetworkMetrics:       nm,
 		ConnectabilityStatus: cs,
 		WorkingStatus:        ws,
+		PriceHistory:         ph,
+
+		LastObligationExpiration: loe,
 	}
 	WriteJSON(w, hg)
 }
@@ -194,9 +218,163 @@ func (api *API) parseHostSettings(req *http.Request) (modules.HostInternalSettin
 		settings.MinUploadBandwidthPrice = x
 	}
 
+	if req.FormValue("autopricing") != "" {
+		x, err := scanBool(req.FormValue("autopricing"))
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.AutoPricing = x
+	}
+	if req.FormValue("autopricingtargetutilization") != "" {
+		var x float64
+		_, err := fmt.Sscan(req.FormValue("autopricingtargetutilization"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.AutoPricingTargetUtilization = x
+	}
+	if req.FormValue("contractpricefloor") != "" {
+		var x types.Currency
+		_, err := fmt.Sscan(req.FormValue("contractpricefloor"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.ContractPriceFloor = x
+	}
+	if req.FormValue("contractpriceceiling") != "" {
+		var x types.Currency
+		_, err := fmt.Sscan(req.FormValue("contractpriceceiling"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.ContractPriceCeiling = x
+	}
+	if req.FormValue("downloadbandwidthpricefloor") != "" {
+		var x types.Currency
+		_, err := fmt.Sscan(req.FormValue("downloadbandwidthpricefloor"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.DownloadBandwidthPriceFloor = x
+	}
+	if req.FormValue("downloadbandwidthpriceceiling") != "" {
+		var x types.Currency
+		_, err := fmt.Sscan(req.FormValue("downloadbandwidthpriceceiling"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.DownloadBandwidthPriceCeiling = x
+	}
+	if req.FormValue("storagepricefloor") != "" {
+		var x types.Currency
+		_, err := fmt.Sscan(req.FormValue("storagepricefloor"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.StoragePriceFloor = x
+	}
+	if req.FormValue("storagepriceceiling") != "" {
+		var x types.Currency
+		_, err := fmt.Sscan(req.FormValue("storagepriceceiling"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.StoragePriceCeiling = x
+	}
+	if req.FormValue("uploadbandwidthpricefloor") != "" {
+		var x types.Currency
+		_, err := fmt.Sscan(req.FormValue("uploadbandwidthpricefloor"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.UploadBandwidthPriceFloor = x
+	}
+	if req.FormValue("uploadbandwidthpriceceiling") != "" {
+		var x types.Currency
+		_, err := fmt.Sscan(req.FormValue("uploadbandwidthpriceceiling"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.UploadBandwidthPriceCeiling = x
+	}
+
+	if req.FormValue("maxdownloadspeed") != "" {
+		var x int64
+		_, err := fmt.Sscan(req.FormValue("maxdownloadspeed"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaxDownloadSpeed = x
+	}
+	if req.FormValue("maxuploadspeed") != "" {
+		var x int64
+		_, err := fmt.Sscan(req.FormValue("maxuploadspeed"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaxUploadSpeed = x
+	}
+	if req.FormValue("maxconnections") != "" {
+		var x uint64
+		_, err := fmt.Sscan(req.FormValue("maxconnections"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaxConnections = x
+	}
+	if req.FormValue("maxconnectionsperip") != "" {
+		var x uint64
+		_, err := fmt.Sscan(req.FormValue("maxconnectionsperip"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaxConnectionsPerIP = x
+	}
+	if req.FormValue("maintenancemode") != "" {
+		x, err := scanBool(req.FormValue("maintenancemode"))
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaintenanceMode = x
+	}
+	if req.FormValue("maintenancerefuseuploads") != "" {
+		x, err := scanBool(req.FormValue("maintenancerefuseuploads"))
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaintenanceRefuseUploads = x
+	}
+
 	return settings, nil
 }
 
+// hostContractsHandlerGET handles GET requests to the /host/contracts API
+// endpoint, returning the storage obligations of the host ordered by the
+// height at which their storage proofs are due. The obligations can be
+// filtered by status.
+func (api *API) hostContractsHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	status := req.FormValue("status")
+	switch status {
+	case "", modules.ObligationStatusUnresolved, modules.ObligationStatusRejected, modules.ObligationStatusSucceeded, modules.ObligationStatusFailed:
+	default:
+		WriteError(w, Error{"unrecognized obligation status: " + status}, http.StatusBadRequest)
+		return
+	}
+
+	contracts := []modules.StorageObligation{}
+	for _, so := range api.host.StorageObligations() {
+		if status == "" || so.Status == status {
+			contracts = append(contracts, so)
+		}
+	}
+	sort.Slice(contracts, func(i, j int) bool {
+		return contracts[i].ExpirationHeight < contracts[j].ExpirationHeight
+	})
+	WriteJSON(w, HostContractsGET{
+		Contracts: contracts,
+	})
+}
+
 // hostEstimateScoreGET handles the POST request to /host/estimatescore and
 // computes an estimated HostDB score for the provided settings.
 func (api *API) hostEstimateScoreGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
@@ -332,6 +332,34 @@ func TestStorageHandler(t *testing.T) {
 	}
 }
 
+// TestHostSettingsInvalid checks that host settings with invalid values are
+// rejected instead of being silently ignored.
+func TestHostSettingsInvalid(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	invalid := map[string]string{
+		"autopricing":                  "maybe",
+		"autopricingtargetutilization": "foo",
+		"storagepricefloor":            "-1",
+		"maxdownloadspeed":             "fast",
+		"maxconnectionsperip":          "-1",
+	}
+	for key, value := range invalid {
+		err = st.stdPostAPI("/host", url.Values{key: {value}})
+		if err == nil {
+			t.Errorf("invalid %v was accepted", key)
+		}
+	}
+}
+
 // TestAddFolderNoPath tests that an API call to add a storage folder fails if
 // no path was provided.
 func TestAddFolderNoPath(t *testing.T) {
@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
@@ -35,6 +36,15 @@ type (
 		Entry          ExtendedHostDBEntry        `json:"entry"`
 		ScoreBreakdown modules.HostScoreBreakdown `json:"scorebreakdown"`
 	}
+
+	// HostdbFilterModeGET contains the hostdb's filter.
+	HostdbFilterModeGET struct {
+		FilterMode    modules.HostDBFilterMode `json:"filtermode"`
+		Hosts         []string                 `json:"hosts"`
+		Subnets       []string                 `json:"subnets"`
+		Countries     []string                 `json:"countries"`
+		GeoIPDatabase string                   `json:"geoipdatabase"`
+	}
 )
 
 // hostdbActiveHandler handles the API call asking for the list of active
@@ -114,3 +124,65 @@ func (api *API) hostdbHostsHandler(w http.ResponseWriter, req *http.Request, ps
 		ScoreBreakdown: breakdown,
 	})
 }
+
+// hostdbFilterModeHandlerGET handles the API call asking for the hostdb's
+// filter.
+func (api *API) hostdbFilterModeHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	filter := api.renter.HostDBFilter()
+	hosts := make([]string, 0, len(filter.PublicKeys))
+	for _, spk := range filter.PublicKeys {
+		hosts = append(hosts, spk.String())
+	}
+	subnets := filter.Subnets
+	if subnets == nil {
+		subnets = []string{}
+	}
+	countries := filter.Countries
+	if countries == nil {
+		countries = []string{}
+	}
+	WriteJSON(w, HostdbFilterModeGET{
+		FilterMode:    filter.Mode,
+		Hosts:         hosts,
+		Subnets:       subnets,
+		Countries:     countries,
+		GeoIPDatabase: filter.GeoIPDatabase,
+	})
+}
+
+// hostdbFilterModeHandlerPOST handles the API call to replace the hostdb's
+// filter.
+func (api *API) hostdbFilterModeHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	filter := modules.HostDBFilter{
+		Mode: modules.HostDBFilterMode(req.FormValue("filtermode")),
+	}
+	if filter.Mode == "" {
+		WriteError(w, Error{"filtermode must be provided"}, http.StatusBadRequest)
+		return
+	}
+	if hosts := req.FormValue("hosts"); hosts != "" {
+		for _, host := range strings.Split(hosts, ",") {
+			var spk types.SiaPublicKey
+			spk.LoadString(host)
+			if len(spk.Key) == 0 {
+				WriteError(w, Error{"unable to parse host public key " + host}, http.StatusBadRequest)
+				return
+			}
+			filter.PublicKeys = append(filter.PublicKeys, spk)
+		}
+	}
+	if subnets := req.FormValue("subnets"); subnets != "" {
+		filter.Subnets = strings.Split(subnets, ",")
+	}
+	if countries := req.FormValue("countries"); countries != "" {
+		filter.Countries = strings.Split(countries, ",")
+	}
+	filter.GeoIPDatabase = req.FormValue("geoipdatabase")
+
+	err := api.renter.SetHostDBFilter(filter)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
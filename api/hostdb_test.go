@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net/url"
 	"path/filepath"
 	"testing"
@@ -315,6 +316,215 @@ func assembleHostPort(key crypto.TwofishKey, hostHostname string, testdir string
 	return st, nil
 }
 
+// TestHostDBFilterMode checks that the hostdb's filter can be changed through
+// /hostdb/filtermode, and that the renter does not form contracts with hosts
+// that the filter excludes.
+func TestHostDBFilterMode(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+	if err := st.announceHost(); err != nil {
+		t.Fatal(err)
+	}
+	var ah HostdbActiveGET
+	if err := st.getAPI("/hostdb/active", &ah); err != nil {
+		t.Fatal(err)
+	}
+	if len(ah.Hosts) != 1 {
+		t.Fatal("expected 1 host, got", len(ah.Hosts))
+	}
+	hostKey := ah.Hosts[0].PublicKeyString
+
+	// The filter is disabled by default.
+	var hfm HostdbFilterModeGET
+	if err := st.getAPI("/hostdb/filtermode", &hfm); err != nil {
+		t.Fatal(err)
+	}
+	if hfm.FilterMode != modules.HostDBFilterDisabled || len(hfm.Hosts) != 0 {
+		t.Fatal("unexpected default filter:", hfm)
+	}
+
+	// Create a GeoIP database that places the host, which listens on the
+	// loopback address, in the country XX.
+	geoIPPath := filepath.Join(st.dir, "geoip.csv")
+	geoIPData := "1.0.0.0,1.0.0.255,NL\n127.0.0.0,127.255.255.255,XX\n::1,::1,XX\n"
+	if err := ioutil.WriteFile(geoIPPath, []byte(geoIPData), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Invalid filters are rejected.
+	invalid := []url.Values{
+		{"filtermode": {"greylist"}},
+		{"filtermode": {"whitelist"}},
+		{"filtermode": {"blacklist"}, "hosts": {"notakey"}},
+		{"filtermode": {"blacklist"}, "subnets": {"10.0.0.1"}},
+		{"filtermode": {"blacklist"}, "countries": {"NL"}},
+		{"filtermode": {"blacklist"}, "countries": {"NLD"}, "geoipdatabase": {geoIPPath}},
+		{"filtermode": {"blacklist"}, "countries": {"NL"}, "geoipdatabase": {"geoip.csv"}},
+		{"filtermode": {"blacklist"}, "countries": {"NL"}, "geoipdatabase": {filepath.Join(st.dir, "dne.csv")}},
+	}
+	for _, values := range invalid {
+		if err := st.stdPostAPI("/hostdb/filtermode", values); err == nil {
+			t.Fatal("invalid filter was accepted:", values)
+		}
+	}
+
+	// Blacklist the only host. The renter should be unable to form
+	// contracts.
+	err = st.stdPostAPI("/hostdb/filtermode", url.Values{
+		"filtermode": {"blacklist"},
+		"hosts":      {hostKey},
+		"subnets":    {"203.0.113.0/24"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.getAPI("/hostdb/filtermode", &hfm); err != nil {
+		t.Fatal(err)
+	}
+	if hfm.FilterMode != modules.HostDBFilterBlacklist || len(hfm.Hosts) != 1 || hfm.Hosts[0] != hostKey || len(hfm.Subnets) != 1 {
+		t.Fatal("filter was not updated:", hfm)
+	}
+	allowanceValues := url.Values{}
+	allowanceValues.Set("funds", testFunds)
+	allowanceValues.Set("period", testPeriod)
+	allowanceValues.Set("hosts", "1")
+	if err := st.stdPostAPI("/renter", allowanceValues); err == nil {
+		t.Fatal("allowance was set although the only host is blacklisted")
+	}
+
+	// Blacklisting the country of the host has the same effect.
+	err = st.stdPostAPI("/hostdb/filtermode", url.Values{
+		"filtermode":    {"blacklist"},
+		"countries":     {"xx"},
+		"geoipdatabase": {geoIPPath},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.getAPI("/hostdb/filtermode", &hfm); err != nil {
+		t.Fatal(err)
+	}
+	if len(hfm.Countries) != 1 || hfm.Countries[0] != "XX" || hfm.GeoIPDatabase != geoIPPath {
+		t.Fatal("filter was not updated:", hfm)
+	}
+	if err := st.stdPostAPI("/renter", allowanceValues); err == nil {
+		t.Fatal("allowance was set although the country of the only host is blacklisted")
+	}
+
+	// Whitelisting the host allows contracts to be formed.
+	if err := st.stdPostAPI("/hostdb/filtermode", url.Values{"filtermode": {"whitelist"}, "hosts": {hostKey}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.stdPostAPI("/renter", allowanceValues); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestHostDBScoringProfile checks that the scoring profile can be changed
+// through the renter's settings, and that /hostdb/hosts reports the
+// adjustments under the new profile.
+func TestHostDBScoringProfile(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+	if err := st.announceHost(); err != nil {
+		t.Fatal(err)
+	}
+	var ah HostdbActiveGET
+	if err := st.getAPI("/hostdb/active", &ah); err != nil {
+		t.Fatal(err)
+	}
+	if len(ah.Hosts) != 1 {
+		t.Fatal("expected 1 host, got", len(ah.Hosts))
+	}
+	query := "/hostdb/hosts/" + ah.Hosts[0].PublicKeyString
+	var before HostdbHostsGET
+	if err := st.getAPI(query, &before); err != nil {
+		t.Fatal(err)
+	}
+
+	// The default profile weights every adjustment normally.
+	var rg RenterGET
+	if err := st.getAPI("/renter", &rg); err != nil {
+		t.Fatal(err)
+	}
+	if rg.Settings.ScoringProfile.PriceExponent != 1 || rg.Settings.ScoringProfile.MinVersion != "" {
+		t.Fatal("unexpected default scoring profile:", rg.Settings.ScoringProfile)
+	}
+
+	// Invalid profiles are rejected.
+	invalid := []url.Values{
+		{"priceexponent": {"-1"}},
+		{"minuptime": {"2"}},
+		{"minversion": {"latest"}},
+		{"maxstorageprice": {"cheap"}},
+		{"ageexponent": {"0"}, "collateralexponent": {"0"}, "interactionexponent": {"0"}, "priceexponent": {"0"}, "storageremainingexponent": {"0"}, "uptimeexponent": {"0"}, "versionexponent": {"0"}},
+	}
+	for _, values := range invalid {
+		if err := st.stdPostAPI("/renter", values); err == nil {
+			t.Fatal("invalid scoring profile was accepted:", values)
+		}
+	}
+
+	// Ignoring the collateral and squaring the price adjustment should be
+	// reflected in the host's score breakdown.
+	err = st.stdPostAPI("/renter", url.Values{
+		"collateralexponent": {"0"},
+		"priceexponent":      {"2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.getAPI("/renter", &rg); err != nil {
+		t.Fatal(err)
+	}
+	if rg.Settings.ScoringProfile.PriceExponent != 2 || rg.Settings.ScoringProfile.CollateralExponent != 0 || rg.Settings.ScoringProfile.AgeExponent != 1 {
+		t.Fatal("scoring profile was not updated:", rg.Settings.ScoringProfile)
+	}
+	var after HostdbHostsGET
+	if err := st.getAPI(query, &after); err != nil {
+		t.Fatal(err)
+	}
+	if after.ScoreBreakdown.CollateralAdjustment != 1 {
+		t.Fatal("collateral adjustment was not ignored:", after.ScoreBreakdown.CollateralAdjustment)
+	}
+	expected := before.ScoreBreakdown.PriceAdjustment * before.ScoreBreakdown.PriceAdjustment
+	if math.Abs(after.ScoreBreakdown.PriceAdjustment-expected) > 1e-9*expected {
+		t.Fatal("price adjustment was not squared:", after.ScoreBreakdown.PriceAdjustment, expected)
+	}
+
+	// A host below the minimum version cannot be used for contracts.
+	if err := st.stdPostAPI("/renter", url.Values{"minversion": {"1000.0"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.getAPI(query, &after); err != nil {
+		t.Fatal(err)
+	}
+	if after.ScoreBreakdown.VersionAdjustment != 0 {
+		t.Fatal("version adjustment of an outdated host is not zero")
+	}
+	allowanceValues := url.Values{}
+	allowanceValues.Set("funds", testFunds)
+	allowanceValues.Set("period", testPeriod)
+	allowanceValues.Set("hosts", "1")
+	if err := st.stdPostAPI("/renter", allowanceValues); err == nil {
+		t.Fatal("allowance was set although the only host is outdated")
+	}
+}
+
 // TestHostDBScanOnlineOffline checks that both online and offline hosts get
 // scanned in the hostdb.
 func TestHostDBScanOnlineOffline(t *testing.T) {
@@ -4,6 +4,7 @@ package api
 // zeroing them out.
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"path/filepath"
@@ -69,9 +70,10 @@ var (
 type (
 	// RenterGET contains various renter metrics.
 	RenterGET struct {
-		Settings         modules.RenterSettings `json:"settings"`
-		FinancialMetrics RenterFinancialMetrics `json:"financialmetrics"`
-		CurrentPeriod    types.BlockHeight      `json:"currentperiod"`
+		Settings         modules.RenterSettings          `json:"settings"`
+		FinancialMetrics RenterFinancialMetrics          `json:"financialmetrics"`
+		CurrentPeriod    types.BlockHeight               `json:"currentperiod"`
+		ChunkCache       modules.RenterChunkCacheMetrics `json:"chunkcache"`
 	}
 
 	// RenterFinancialMetrics contains metrics about how much the Renter has
@@ -89,6 +91,13 @@ type (
 		Unspent types.Currency `json:"unspent"`
 	}
 
+	// RenterSpendingGET contains a breakdown of the renter's spending in the
+	// current allowance period and in each past allowance period.
+	RenterSpendingGET struct {
+		Current modules.RenterSpending   `json:"current"`
+		Past    []modules.RenterSpending `json:"past"`
+	}
+
 	// RenterContract represents a contract formed by the renter.
 	RenterContract struct {
 		// Amount of contract funds that have been spent on downloads.
@@ -118,11 +127,33 @@ type (
 		TotalCost types.Currency `json:"totalcost"`
 		// Amount of contract funds that have been spent on uploads.
 		UploadSpending types.Currency `json:"uploadspending"`
+		// Whether the contract is used for uploading and will be renewed.
+		GoodForUpload bool `json:"goodforupload"`
+		GoodForRenew  bool `json:"goodforrenew"`
+		// Whether the contract was formed manually rather than by the
+		// allowance, and whether it has been canceled.
+		Manual   bool `json:"manual"`
+		Canceled bool `json:"canceled"`
+		// Whether the host submitted a valid storage proof for the contract
+		// or missed it, and the funds that were returned to the renter as a
+		// result. Only expired contracts have a resolved proof status.
+		ProofStatus  modules.ContractProofStatus `json:"proofstatus"`
+		RenterPayout types.Currency              `json:"renterpayout"`
+		// Rolling download statistics of the host: the average time to
+		// connect to the host in milliseconds, the average rate at which it
+		// sends piece data in bytes per second, and the number of piece
+		// downloads that stalled or failed.
+		DownloadLatency    uint64 `json:"downloadlatency"`
+		DownloadThroughput uint64 `json:"downloadthroughput"`
+		DownloadStalls     uint64 `json:"downloadstalls"`
+		DownloadFailures   uint64 `json:"downloadfailures"`
 	}
 
-	// RenterContracts contains the renter's contracts.
+	// RenterContracts contains the renter's contracts. ExpiredContracts is
+	// only filled in when requested.
 	RenterContracts struct {
-		Contracts []RenterContract `json:"contracts"`
+		Contracts        []RenterContract `json:"contracts"`
+		ExpiredContracts []RenterContract `json:"expiredcontracts"`
 	}
 
 	// DownloadQueue contains the renter's download queue.
@@ -130,14 +161,23 @@ type (
 		Downloads []DownloadInfo `json:"downloads"`
 	}
 
+	// RenterDirectory lists the contents of a renter directory. The first
+	// entry of Directories describes the requested directory itself.
+	RenterDirectory struct {
+		Directories []modules.DirectoryInfo `json:"directories"`
+		Files       []modules.FileInfo      `json:"files"`
+	}
+
 	// RenterFiles lists the files known to the renter.
 	RenterFiles struct {
 		Files []modules.FileInfo `json:"files"`
 	}
 
-	// RenterLoad lists files that were loaded into the renter.
+	// RenterLoad lists files that were loaded into the renter, along with
+	// their availability.
 	RenterLoad struct {
-		FilesAdded []string `json:"filesadded"`
+		FilesAdded []string                 `json:"filesadded"`
+		Files      []modules.SharedFileInfo `json:"files"`
 	}
 
 	// RenterPricesGET lists the data that is returned when a GET call is made
@@ -153,12 +193,14 @@ type (
 
 	// DownloadInfo contains all client-facing information of a file.
 	DownloadInfo struct {
-		SiaPath     string    `json:"siapath"`
-		Destination string    `json:"destination"`
-		Filesize    uint64    `json:"filesize"`
-		Received    uint64    `json:"received"`
-		StartTime   time.Time `json:"starttime"`
-		Error       string    `json:"error"`
+		ID          string                 `json:"id"`
+		SiaPath     string                 `json:"siapath"`
+		Destination string                 `json:"destination"`
+		Filesize    uint64                 `json:"filesize"`
+		Received    uint64                 `json:"received"`
+		StartTime   time.Time              `json:"starttime"`
+		Status      modules.DownloadStatus `json:"status"`
+		Error       string                 `json:"error"`
 	}
 )
 
@@ -192,11 +234,79 @@ func (api *API) renterHandlerGET(w http.ResponseWriter, req *http.Request, _ htt
 		Settings:         settings,
 		FinancialMetrics: fm,
 		CurrentPeriod:    periodStart,
+		ChunkCache:       api.renter.ChunkCacheMetrics(),
 	})
 }
 
 // renterHandlerPOST handles the API call to set the Renter's settings.
 func (api *API) renterHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	settings := api.renter.Settings()
+
+	// Scan the chunk cache size. (optional parameter)
+	if req.FormValue("chunkcachesize") != "" {
+		_, err := fmt.Sscan(req.FormValue("chunkcachesize"), &settings.ChunkCacheSize)
+		if err != nil {
+			WriteError(w, Error{"unable to parse chunkcachesize: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Scan the bandwidth limits. (optional parameters)
+	if req.FormValue("maxdownloadspeed") != "" {
+		_, err := fmt.Sscan(req.FormValue("maxdownloadspeed"), &settings.MaxDownloadSpeed)
+		if err != nil || settings.MaxDownloadSpeed < 0 {
+			WriteError(w, Error{"unable to parse maxdownloadspeed"}, http.StatusBadRequest)
+			return
+		}
+	}
+	if req.FormValue("maxuploadspeed") != "" {
+		_, err := fmt.Sscan(req.FormValue("maxuploadspeed"), &settings.MaxUploadSpeed)
+		if err != nil || settings.MaxUploadSpeed < 0 {
+			WriteError(w, Error{"unable to parse maxuploadspeed"}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Scan the upload memory limit. (optional parameter)
+	if req.FormValue("maxuploadmemory") != "" {
+		_, err := fmt.Sscan(req.FormValue("maxuploadmemory"), &settings.MaxUploadMemory)
+		if err != nil {
+			WriteError(w, Error{"unable to parse maxuploadmemory: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Scan the subnet ranges. (optional parameters)
+	if req.FormValue("ipv4subnetbits") != "" {
+		_, err := fmt.Sscan(req.FormValue("ipv4subnetbits"), &settings.IPv4SubnetBits)
+		if err != nil {
+			WriteError(w, Error{"unable to parse ipv4subnetbits: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if req.FormValue("ipv6subnetbits") != "" {
+		_, err := fmt.Sscan(req.FormValue("ipv6subnetbits"), &settings.IPv6SubnetBits)
+		if err != nil {
+			WriteError(w, Error{"unable to parse ipv6subnetbits: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Scan the scoring profile. (optional parameters)
+	profileSet, err := scanScoringProfile(req, &settings.ScoringProfile)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	// The allowance can be left out when only the other settings are
+	// changed.
+	otherSettings := req.FormValue("chunkcachesize") != "" || req.FormValue("maxdownloadspeed") != "" || req.FormValue("maxuploadspeed") != "" || req.FormValue("maxuploadmemory") != "" || req.FormValue("ipv4subnetbits") != "" || req.FormValue("ipv6subnetbits") != "" || profileSet
+	if otherSettings && req.FormValue("funds") == "" && req.FormValue("period") == "" {
+		api.renterSetSettings(w, settings)
+		return
+	}
+
 	// Scan the allowance amount.
 	funds, ok := scanAmount(req.FormValue("funds"))
 	if !ok {
@@ -222,7 +332,7 @@ func (api *API) renterHandlerPOST(w http.ResponseWriter, req *http.Request, _ ht
 
 	// Scan the period.
 	var period types.BlockHeight
-	_, err := fmt.Sscan(req.FormValue("period"), &period)
+	_, err = fmt.Sscan(req.FormValue("period"), &period)
 	if err != nil {
 		WriteError(w, Error{"unable to parse period: " + err.Error()}, http.StatusBadRequest)
 		return
@@ -244,15 +354,71 @@ func (api *API) renterHandlerPOST(w http.ResponseWriter, req *http.Request, _ ht
 		renewWindow = period / 2
 	}
 
-	// Set the settings in the renter.
-	err = api.renter.SetSettings(modules.RenterSettings{
-		Allowance: modules.Allowance{
-			Funds:       funds,
-			Hosts:       hosts,
-			Period:      period,
-			RenewWindow: renewWindow,
-		},
-	})
+	settings.Allowance = modules.Allowance{
+		Funds:       funds,
+		Hosts:       hosts,
+		Period:      period,
+		RenewWindow: renewWindow,
+	}
+	api.renterSetSettings(w, settings)
+}
+
+// scanScoringProfile scans the scoring profile parameters of req into p. It
+// returns whether any of the parameters were provided.
+func scanScoringProfile(req *http.Request, p *modules.HostScoringProfile) (bool, error) {
+	var set bool
+	floats := []struct {
+		name string
+		val  *float64
+	}{
+		{"ageexponent", &p.AgeExponent},
+		{"collateralexponent", &p.CollateralExponent},
+		{"interactionexponent", &p.InteractionExponent},
+		{"priceexponent", &p.PriceExponent},
+		{"storageremainingexponent", &p.StorageRemainingExponent},
+		{"uptimeexponent", &p.UptimeExponent},
+		{"versionexponent", &p.VersionExponent},
+		{"minuptime", &p.MinUptime},
+	}
+	for _, f := range floats {
+		if req.FormValue(f.name) == "" {
+			continue
+		}
+		if _, err := fmt.Sscan(req.FormValue(f.name), f.val); err != nil {
+			return false, errors.New("unable to parse " + f.name)
+		}
+		set = true
+	}
+	ceilings := []struct {
+		name    string
+		ceiling *types.Currency
+	}{
+		{"maxcontractprice", &p.MaxContractPrice},
+		{"maxdownloadbandwidthprice", &p.MaxDownloadBandwidthPrice},
+		{"maxstorageprice", &p.MaxStoragePrice},
+		{"maxuploadbandwidthprice", &p.MaxUploadBandwidthPrice},
+	}
+	for _, c := range ceilings {
+		if req.FormValue(c.name) == "" {
+			continue
+		}
+		ceiling, ok := scanAmount(req.FormValue(c.name))
+		if !ok {
+			return false, errors.New("unable to parse " + c.name)
+		}
+		*c.ceiling = ceiling
+		set = true
+	}
+	if _, ok := req.Form["minversion"]; ok {
+		p.MinVersion = req.FormValue("minversion")
+		set = true
+	}
+	return set, nil
+}
+
+// renterSetSettings sets the Renter's settings and writes the response.
+func (api *API) renterSetSettings(w http.ResponseWriter, settings modules.RenterSettings) {
+	err := api.renter.SetSettings(settings)
 	if err != nil {
 		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
 		return
@@ -260,28 +426,161 @@ func (api *API) renterHandlerPOST(w http.ResponseWriter, req *http.Request, _ ht
 	WriteSuccess(w)
 }
 
-// renterContractsHandler handles the API call to request the Renter's contracts.
-func (api *API) renterContractsHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
-	contracts := []RenterContract{}
+// newRenterContract converts a modules.RenterContract into the
+// RenterContract returned by the API.
+func newRenterContract(c modules.RenterContract) RenterContract {
+	return RenterContract{
+		DownloadSpending: c.DownloadSpending,
+		EndHeight:        c.EndHeight(),
+		Fees:             c.TxnFee.Add(c.SiafundFee).Add(c.ContractFee),
+		HostPublicKey:    c.HostPublicKey,
+		ID:               c.ID,
+		LastTransaction:  c.LastRevisionTxn,
+		NetAddress:       c.NetAddress,
+		RenterFunds:      c.RenterFunds(),
+		Size:             c.LastRevision.NewFileSize,
+		StartHeight:      c.StartHeight,
+		StorageSpending:  c.StorageSpending,
+		TotalCost:        c.TotalCost,
+		UploadSpending:   c.UploadSpending,
+		GoodForUpload:    c.GoodForUpload,
+		GoodForRenew:     c.GoodForRenew,
+		Manual:           c.Manual,
+		Canceled:         c.Canceled,
+		ProofStatus:      c.ProofStatus,
+		RenterPayout:     c.RenterPayout(),
+	}
+}
+
+// renterContractsHandler handles the API call to request the Renter's
+// contracts. The expired contracts are included if requested.
+func (api *API) renterContractsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var expired bool
+	if req.FormValue("expired") != "" {
+		var err error
+		expired, err = scanBool(req.FormValue("expired"))
+		if err != nil {
+			WriteError(w, Error{"unable to parse expired: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	rc := RenterContracts{
+		Contracts: []RenterContract{},
+	}
+	stats := api.renter.DownloadStats()
 	for _, c := range api.renter.Contracts() {
-		contracts = append(contracts, RenterContract{
-			DownloadSpending: c.DownloadSpending,
-			EndHeight:        c.EndHeight(),
-			Fees:             c.TxnFee.Add(c.SiafundFee).Add(c.ContractFee),
-			HostPublicKey:    c.HostPublicKey,
-			ID:               c.ID,
-			LastTransaction:  c.LastRevisionTxn,
-			NetAddress:       c.NetAddress,
-			RenterFunds:      c.RenterFunds(),
-			Size:             c.LastRevision.NewFileSize,
-			StartHeight:      c.StartHeight,
-			StorageSpending:  c.StorageSpending,
-			TotalCost:        c.TotalCost,
-			UploadSpending:   c.UploadSpending,
-		})
+		contract := newRenterContract(c)
+		if s, ok := stats[c.HostPublicKey.String()]; ok {
+			contract.DownloadLatency = uint64(s.Latency / time.Millisecond)
+			contract.DownloadThroughput = s.Throughput
+			contract.DownloadStalls = s.Stalls
+			contract.DownloadFailures = s.Failures
+		}
+		rc.Contracts = append(rc.Contracts, contract)
+	}
+	if expired {
+		rc.ExpiredContracts = []RenterContract{}
+		for _, c := range api.renter.ExpiredContracts() {
+			rc.ExpiredContracts = append(rc.ExpiredContracts, newRenterContract(c))
+		}
 	}
-	WriteJSON(w, RenterContracts{
-		Contracts: contracts,
+	WriteJSON(w, rc)
+}
+
+// scanContractParams scans the funds and end height of a manually formed or
+// renewed contract.
+func scanContractParams(req *http.Request) (types.Currency, types.BlockHeight, error) {
+	funds, ok := scanAmount(req.FormValue("funds"))
+	if !ok {
+		return types.Currency{}, 0, errors.New("unable to parse funds")
+	}
+	var endHeight types.BlockHeight
+	_, err := fmt.Sscan(req.FormValue("endheight"), &endHeight)
+	if err != nil {
+		return types.Currency{}, 0, errors.New("unable to parse endheight: " + err.Error())
+	}
+	return funds, endHeight, nil
+}
+
+// scanContractID scans the contract id of a contracts API call.
+func scanContractID(ps httprouter.Params) (types.FileContractID, error) {
+	h, err := scanHash(ps.ByName("id"))
+	if err != nil {
+		return types.FileContractID{}, errors.New("unable to parse contract id: " + err.Error())
+	}
+	return types.FileContractID(h), nil
+}
+
+// renterContractsFormHandler handles the API call to form a manual contract.
+// It is registered as /renter/contracts/:id because the router does not allow
+// /renter/contracts/form next to the routes of individual contracts, so any
+// id other than "form" is not found.
+func (api *API) renterContractsFormHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	if ps.ByName("id") != "form" {
+		UnrecognizedCallHandler(w, req)
+		return
+	}
+	var hostKey types.SiaPublicKey
+	hostKey.LoadString(req.FormValue("host"))
+	if len(hostKey.Key) == 0 {
+		WriteError(w, Error{"unable to parse host public key"}, http.StatusBadRequest)
+		return
+	}
+	funds, endHeight, err := scanContractParams(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	contract, err := api.renter.FormContract(hostKey, funds, endHeight)
+	if err != nil {
+		WriteError(w, Error{"unable to form contract: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, newRenterContract(contract))
+}
+
+// renterContractsRenewHandler handles the API call to renew a contract.
+func (api *API) renterContractsRenewHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	id, err := scanContractID(ps)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	funds, endHeight, err := scanContractParams(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	contract, err := api.renter.RenewContract(id, funds, endHeight)
+	if err != nil {
+		WriteError(w, Error{"unable to renew contract: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, newRenterContract(contract))
+}
+
+// renterContractsCancelHandler handles the API call to cancel a contract.
+func (api *API) renterContractsCancelHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	id, err := scanContractID(ps)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if err := api.renter.CancelContract(id); err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterSpendingHandler handles the API call to request the renter's spending
+// report.
+func (api *API) renterSpendingHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	current, past := api.renter.Spending()
+	WriteJSON(w, RenterSpendingGET{
+		Current: current,
+		Past:    past,
 	})
 }
 
@@ -290,11 +589,13 @@ func (api *API) renterDownloadsHandler(w http.ResponseWriter, _ *http.Request, _
 	var downloads []DownloadInfo
 	for _, d := range api.renter.DownloadQueue() {
 		downloads = append(downloads, DownloadInfo{
+			ID:          d.ID,
 			SiaPath:     d.SiaPath,
 			Destination: d.Destination.Destination(),
 			Filesize:    d.Filesize,
 			StartTime:   d.StartTime,
 			Received:    d.Received,
+			Status:      d.Status,
 			Error:       d.Error,
 		})
 	}
@@ -305,6 +606,37 @@ func (api *API) renterDownloadsHandler(w http.ResponseWriter, _ *http.Request, _
 	})
 }
 
+// renterDownloadCancelHandler handles the API call to cancel a download.
+func (api *API) renterDownloadCancelHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	err := api.renter.CancelDownload(ps.ByName("id"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterDownloadPauseHandler handles the API call to pause a download.
+func (api *API) renterDownloadPauseHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	err := api.renter.PauseDownload(ps.ByName("id"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterDownloadResumeHandler handles the API call to resume a paused
+// download.
+func (api *API) renterDownloadResumeHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	err := api.renter.ResumeDownload(ps.ByName("id"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // renterLoadHandler handles the API call to load a '.sia' file.
 func (api *API) renterLoadHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	source := req.FormValue("source")
@@ -319,19 +651,77 @@ func (api *API) renterLoadHandler(w http.ResponseWriter, req *http.Request, _ ht
 		return
 	}
 
-	WriteJSON(w, RenterLoad{FilesAdded: files})
+	WriteJSON(w, newRenterLoad(files))
 }
 
 // renterLoadAsciiHandler handles the API call to load a '.sia' file
 // in ASCII form.
 func (api *API) renterLoadAsciiHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
-	files, err := api.renter.LoadSharedFilesAscii(req.FormValue("asciisia"))
+	asciiSia := req.FormValue("asciisia")
+	if asciiSia == "" {
+		WriteError(w, Error{"asciisia must be provided"}, http.StatusBadRequest)
+		return
+	}
+
+	files, err := api.renter.LoadSharedFilesAscii(asciiSia)
 	if err != nil {
 		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
 		return
 	}
 
-	WriteJSON(w, RenterLoad{FilesAdded: files})
+	WriteJSON(w, newRenterLoad(files))
+}
+
+// newRenterLoad creates the response of the load calls from the files that
+// were loaded.
+func newRenterLoad(files []modules.SharedFileInfo) RenterLoad {
+	rl := RenterLoad{
+		FilesAdded: make([]string, len(files)),
+		Files:      files,
+	}
+	for i, f := range files {
+		rl.FilesAdded[i] = f.SiaPath
+	}
+	return rl
+}
+
+// renterBackupHandler handles the API call to create an encrypted backup of
+// the renter's files and contracts.
+func (api *API) renterBackupHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	destination := req.FormValue("destination")
+	if !filepath.IsAbs(destination) {
+		WriteError(w, Error{"destination must be an absolute path"}, http.StatusBadRequest)
+		return
+	}
+	password := req.FormValue("password")
+	if password == "" {
+		WriteError(w, Error{"a password must be provided"}, http.StatusBadRequest)
+		return
+	}
+
+	err := api.renter.CreateBackup(destination, password)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterRestoreHandler handles the API call to restore the renter's files and
+// contracts from a backup.
+func (api *API) renterRestoreHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	source := req.FormValue("source")
+	if !filepath.IsAbs(source) {
+		WriteError(w, Error{"source must be an absolute path"}, http.StatusBadRequest)
+		return
+	}
+
+	err := api.renter.RestoreBackup(source, req.FormValue("password"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
 }
 
 // renterRenameHandler handles the API call to rename a file entry in the
@@ -346,6 +736,22 @@ func (api *API) renterRenameHandler(w http.ResponseWriter, req *http.Request, ps
 	WriteSuccess(w)
 }
 
+// renterRedundancyHandler handles the API call to change the redundancy of a
+// file. The file keeps its type of erasure coder.
+func (api *API) renterRedundancyHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	dataPieces, parityPieces, err := parseErasureCodingPieces(req.FormValue)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = api.renter.SetFileRedundancy(strings.TrimPrefix(ps.ByName("siapath"), "/"), dataPieces, parityPieces)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // renterFilesHandler handles the API call to list all of the files.
 func (api *API) renterFilesHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	WriteJSON(w, RenterFiles{
@@ -361,6 +767,44 @@ func (api *API) renterPricesHandler(w http.ResponseWriter, req *http.Request, _
 	})
 }
 
+// renterDirHandlerGET handles the API call to list the contents of a renter
+// directory.
+func (api *API) renterDirHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	directories, files, err := api.renter.DirList(strings.TrimPrefix(ps.ByName("siapath"), "/"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteJSON(w, RenterDirectory{
+		Directories: directories,
+		Files:       files,
+	})
+}
+
+// renterDirHandlerPOST handles the API calls to create, delete and rename
+// renter directories.
+func (api *API) renterDirHandlerPOST(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	siapath := strings.TrimPrefix(ps.ByName("siapath"), "/")
+	var err error
+	switch action := req.FormValue("action"); action {
+	case "create":
+		err = api.renter.CreateDir(siapath)
+	case "delete":
+		err = api.renter.DeleteDir(siapath)
+	case "rename":
+		err = api.renter.RenameDir(siapath, req.FormValue("newsiapath"))
+	default:
+		err = fmt.Errorf("unknown action %q; expected create, delete or rename", action)
+	}
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	WriteSuccess(w)
+}
+
 // renterDeleteHandler handles the API call to delete a file entry from the
 // renter.
 func (api *API) renterDeleteHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
@@ -413,6 +857,19 @@ func (api *API) renterDownloadHandler(w http.ResponseWriter, req *http.Request,
 	}
 }
 
+// renterStreamHandler handles the API call to stream a file. Requests may
+// include a Range header, in which case only the chunks covering the
+// requested ranges are downloaded.
+func (api *API) renterStreamHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	siapath := strings.TrimPrefix(ps.ByName("siapath"), "/")
+	name, streamer, err := api.renter.Streamer(siapath)
+	if err != nil {
+		WriteError(w, Error{"failed to create download streamer: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	http.ServeContent(w, req, name, time.Time{}, streamer)
+}
+
 // renterDownloadAsyncHandler handles the API call to download a file asynchronously.
 func (api *API) renterDownloadAsyncHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 	req.ParseForm()
@@ -490,7 +947,13 @@ func (api *API) renterShareHandler(w http.ResponseWriter, req *http.Request, ps
 		return
 	}
 
-	err := api.renter.ShareFiles(strings.Split(req.FormValue("siapaths"), ","), destination)
+	siapaths, err := parseSiapaths(req.FormValue("siapaths"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	err = api.renter.ShareFiles(siapaths, destination)
 	if err != nil {
 		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
 		return
@@ -502,7 +965,13 @@ func (api *API) renterShareHandler(w http.ResponseWriter, req *http.Request, ps
 // renterShareAsciiHandler handles the API call to return a '.sia' file
 // in ascii form.
 func (api *API) renterShareAsciiHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
-	ascii, err := api.renter.ShareFilesAscii(strings.Split(req.FormValue("siapaths"), ","))
+	siapaths, err := parseSiapaths(req.FormValue("siapaths"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	ascii, err := api.renter.ShareFilesAscii(siapaths)
 	if err != nil {
 		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
 		return
@@ -512,6 +981,76 @@ func (api *API) renterShareAsciiHandler(w http.ResponseWriter, req *http.Request
 	})
 }
 
+// parseSiapaths parses the comma-separated siapaths parameter of the share
+// calls.
+func parseSiapaths(siapaths string) ([]string, error) {
+	if siapaths == "" {
+		return nil, errors.New("at least one siapath must be provided")
+	}
+	paths := strings.Split(siapaths, ",")
+	for _, path := range paths {
+		if path == "" {
+			return nil, errors.New("siapaths cannot contain an empty siapath")
+		}
+	}
+	return paths, nil
+}
+
+// parseErasureCodingParameters parses the optional erasurecoder, datapieces
+// and paritypieces parameters of an upload request, returning a nil coder if
+// none is supplied. If the erasurecoder parameter is omitted, a Reed-Solomon
+// coder is created. formValue is used to look up the parameters.
+func parseErasureCodingParameters(formValue func(string) string) (modules.ErasureCoder, error) {
+	if formValue("erasurecoder") == "" && formValue("datapieces") == "" && formValue("paritypieces") == "" {
+		return nil, nil
+	}
+	ecType := renter.ECReedSolomon
+	if formValue("erasurecoder") != "" {
+		ecType = formValue("erasurecoder")
+	}
+	dataPieces, parityPieces, err := parseErasureCodingPieces(formValue)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the erasure coder.
+	ec, err := renter.NewErasureCoder(ecType, dataPieces, parityPieces)
+	if err != nil {
+		return nil, errors.New("unable to encode file using the provided parameters: " + err.Error())
+	}
+	return ec, nil
+}
+
+// parseErasureCodingPieces parses the datapieces and paritypieces parameters
+// of a request, checking that they provide enough redundancy.
+func parseErasureCodingPieces(formValue func(string) string) (dataPieces, parityPieces int, err error) {
+	// Check that both values have been supplied.
+	if formValue("datapieces") == "" || formValue("paritypieces") == "" {
+		return 0, 0, errors.New("must provide both the datapieces paramaeter and the paritypieces parameter if specifying erasure coding parameters")
+	}
+
+	// Parse the erasure coding parameters.
+	_, err = fmt.Sscan(formValue("datapieces"), &dataPieces)
+	if err != nil {
+		return 0, 0, errors.New("unable to read parameter 'datapieces': " + err.Error())
+	}
+	_, err = fmt.Sscan(formValue("paritypieces"), &parityPieces)
+	if err != nil {
+		return 0, 0, errors.New("unable to read parameter 'paritypieces': " + err.Error())
+	}
+
+	// Verify that sane values for parityPieces and redundancy are being
+	// supplied.
+	if parityPieces < requiredParityPieces {
+		return 0, 0, fmt.Errorf("a minimum of %v parity pieces is required, but %v parity pieces requested", parityPieces, requiredParityPieces)
+	}
+	redundancy := float64(dataPieces+parityPieces) / float64(dataPieces)
+	if float64(dataPieces+parityPieces)/float64(dataPieces) < requiredRedundancy {
+		return 0, 0, fmt.Errorf("a redundancy of %.2f is required, but redundancy of %.2f supplied", redundancy, requiredRedundancy)
+	}
+	return dataPieces, parityPieces, nil
+}
+
 // renterUploadHandler handles the API call to upload a file.
 func (api *API) renterUploadHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 	source := req.FormValue("source")
@@ -521,49 +1060,14 @@ func (api *API) renterUploadHandler(w http.ResponseWriter, req *http.Request, ps
 	}
 
 	// Check whether the erasure coding parameters have been supplied.
-	var ec modules.ErasureCoder
-	if req.FormValue("datapieces") != "" || req.FormValue("paritypieces") != "" {
-		// Check that both values have been supplied.
-		if req.FormValue("datapieces") == "" || req.FormValue("paritypieces") == "" {
-			WriteError(w, Error{"must provide both the datapieces paramaeter and the paritypieces parameter if specifying erasure coding parameters"}, http.StatusBadRequest)
-			return
-		}
-
-		// Parse the erasure coding parameters.
-		var dataPieces, parityPieces int
-		_, err := fmt.Sscan(req.FormValue("datapieces"), &dataPieces)
-		if err != nil {
-			WriteError(w, Error{"unable to read parameter 'datapieces': " + err.Error()}, http.StatusBadRequest)
-			return
-		}
-		_, err = fmt.Sscan(req.FormValue("paritypieces"), &parityPieces)
-		if err != nil {
-			WriteError(w, Error{"unable to read parameter 'paritypieces': " + err.Error()}, http.StatusBadRequest)
-			return
-		}
-
-		// Verify that sane values for parityPieces and redundancy are being
-		// supplied.
-		if parityPieces < requiredParityPieces {
-			WriteError(w, Error{fmt.Sprintf("a minimum of %v parity pieces is required, but %v parity pieces requested", parityPieces, requiredParityPieces)}, http.StatusBadRequest)
-			return
-		}
-		redundancy := float64(dataPieces+parityPieces) / float64(dataPieces)
-		if float64(dataPieces+parityPieces)/float64(dataPieces) < requiredRedundancy {
-			WriteError(w, Error{fmt.Sprintf("a redundancy of %.2f is required, but redundancy of %.2f supplied", redundancy, requiredRedundancy)}, http.StatusBadRequest)
-			return
-		}
-
-		// Create the erasure coder.
-		ec, err = renter.NewRSCode(dataPieces, parityPieces)
-		if err != nil {
-			WriteError(w, Error{"unable to encode file using the provided parameters: " + err.Error()}, http.StatusBadRequest)
-			return
-		}
+	ec, err := parseErasureCodingParameters(req.FormValue)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
 	}
 
 	// Call the renter to upload the file.
-	err := api.renter.Upload(modules.FileUploadParams{
+	err = api.renter.Upload(modules.FileUploadParams{
 		Source:      source,
 		SiaPath:     strings.TrimPrefix(ps.ByName("siapath"), "/"),
 		ErasureCode: ec,
@@ -574,3 +1078,24 @@ func (api *API) renterUploadHandler(w http.ResponseWriter, req *http.Request, ps
 	}
 	WriteSuccess(w)
 }
+
+// renterUploadStreamHandler handles the API call to upload a file using the
+// data in the request body. Erasure coding parameters are read from the query
+// string only, as parsing a form could consume the body.
+func (api *API) renterUploadStreamHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	ec, err := parseErasureCodingParameters(req.URL.Query().Get)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	err = api.renter.UploadStreamFromReader(modules.FileUploadParams{
+		SiaPath:     strings.TrimPrefix(ps.ByName("siapath"), "/"),
+		ErasureCode: ec,
+	}, req.Body)
+	if err != nil {
+		WriteError(w, Error{"upload failed: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteSuccess(w)
+}
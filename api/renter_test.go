@@ -492,6 +492,208 @@ func TestRenterAsyncDownload(t *testing.T) {
 	}
 }
 
+// TestRenterDownloadCancelPauseResume tests that downloads are listed with an
+// ID and status, and that the cancel, pause and resume routes only act on
+// downloads in the right state.
+func TestRenterDownloadCancelPauseResume(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	st, _ := setupTestDownload(t, 1e4, "test.dat", true)
+	defer st.server.panicClose()
+
+	// Unknown downloads cannot be cancelled, paused or resumed.
+	for _, action := range []string{"cancel", "pause", "resume"} {
+		if err := st.stdPostAPI("/renter/download/"+action+"/foo", url.Values{}); err == nil {
+			t.Fatalf("%v of an unknown download succeeded", action)
+		}
+	}
+
+	// Download the file asynchronously and wait for it to complete.
+	downpath := filepath.Join(st.dir, "asyncdown.dat")
+	err := st.getAPI("/renter/downloadasync/test.dat?destination="+downpath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var download DownloadInfo
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		var rdq RenterDownloadQueue
+		if err := st.getAPI("/renter/downloads", &rdq); err != nil {
+			return err
+		}
+		if len(rdq.Downloads) != 1 {
+			return fmt.Errorf("expected 1 download, got %v", len(rdq.Downloads))
+		}
+		download = rdq.Downloads[0]
+		if download.Status != modules.DownloadStatusComplete {
+			return fmt.Errorf("download has status %v", download.Status)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if download.ID == "" {
+		t.Fatal("download has no ID")
+	}
+
+	// A completed download cannot be cancelled, paused or resumed.
+	for _, action := range []string{"cancel", "pause", "resume"} {
+		if err := st.stdPostAPI("/renter/download/"+action+"/"+download.ID, url.Values{}); err == nil {
+			t.Fatalf("%v of a completed download succeeded", action)
+		}
+	}
+}
+
+// TestRenterBackupRestore checks that a backup allows a fresh node to download
+// the files of the node that created it.
+func TestRenterBackupRestore(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	st, path := setupTestDownload(t, 1e4, "test.dat", true)
+	defer st.server.panicClose()
+
+	// Create the backup.
+	backup := filepath.Join(st.dir, "renter.backup")
+	if err := st.stdPostAPI("/renter/backup", url.Values{"destination": {"renter.backup"}, "password": {"foo"}}); err == nil {
+		t.Fatal("backup to a relative path succeeded")
+	}
+	if err := st.stdPostAPI("/renter/backup", url.Values{"destination": {backup}}); err == nil {
+		t.Fatal("backup without a password succeeded")
+	}
+	if err := st.stdPostAPI("/renter/backup", url.Values{"destination": {backup}, "password": {"foo"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restore the backup on a fresh node that shares the blockchain.
+	st2, err := blankServerTester(t.Name() + "-fresh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st2.server.panicClose()
+	if err := fullyConnectNodes([]*serverTester{st, st2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st2.stdPostAPI("/renter/restore", url.Values{"source": {backup}, "password": {"bar"}}); err == nil {
+		t.Fatal("restore with the wrong password succeeded")
+	}
+	if err := st2.stdPostAPI("/renter/restore", url.Values{"source": {backup}, "password": {"foo"}}); err != nil {
+		t.Fatal(err)
+	}
+	var rf RenterFiles
+	if err := st2.getAPI("/renter/files", &rf); err != nil {
+		t.Fatal(err)
+	}
+	if len(rf.Files) != 1 || rf.Files[0].SiaPath != "test.dat" {
+		t.Fatal("file was not restored:", rf.Files)
+	}
+
+	// The fresh node should be able to download the file once it has scanned
+	// the host.
+	downpath := filepath.Join(st2.dir, "restored.dat")
+	err = retry(60, time.Second, func() error {
+		return st2.stdGetAPI("/renter/download/test.dat?destination=" + downpath)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	download, err := ioutil.ReadFile(downpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(orig, download) {
+		t.Fatal("data downloaded by the fresh node does not match the original")
+	}
+}
+
+// TestRenterShareLoad tests the /renter/share and /renter/load routes and
+// their ASCII variants.
+func TestRenterShareLoad(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	st, _ := setupTestDownload(t, 1e4, "test.dat", true)
+	defer st.server.panicClose()
+
+	// Invalid share requests should be rejected.
+	sia := filepath.Join(st.dir, "test.sia")
+	if err := st.stdGetAPI("/renter/share?siapaths=test.dat&destination=test.sia"); err == nil {
+		t.Fatal("share to a relative path succeeded")
+	}
+	if err := st.stdGetAPI("/renter/share?destination=" + sia); err == nil {
+		t.Fatal("share without siapaths succeeded")
+	}
+	if err := st.stdGetAPI("/renter/share?siapaths=test.dat,&destination=" + sia); err == nil {
+		t.Fatal("share with an empty siapath succeeded")
+	}
+	if err := st.stdGetAPI("/renter/share?siapaths=unknown&destination=" + sia); err == nil {
+		t.Fatal("share of an unknown file succeeded")
+	}
+	if _, err := os.Stat(sia); !os.IsNotExist(err) {
+		t.Fatal("failed share left a .sia file behind:", err)
+	}
+
+	// Share the file and load it back. Since the file still exists, the
+	// loaded file should be renamed.
+	if err := st.stdGetAPI("/renter/share?siapaths=test.dat&destination=" + sia); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.stdPostAPI("/renter/load", url.Values{"source": {"test.sia"}}); err == nil {
+		t.Fatal("load from a relative path succeeded")
+	}
+	var rl RenterLoad
+	if err := st.postAPI("/renter/load", url.Values{"source": {sia}}, &rl); err != nil {
+		t.Fatal(err)
+	}
+	if len(rl.FilesAdded) != 1 || rl.FilesAdded[0] != "test.dat_1" {
+		t.Fatal("wrong files added:", rl.FilesAdded)
+	}
+	if fi := rl.Files[0]; fi.OriginalSiaPath != "test.dat" || !fi.Available || fi.ReachableHosts != 1 || fi.UnreachableHosts != 0 {
+		t.Fatal("wrong availability of the loaded file:", fi)
+	}
+
+	// Loaded files are read-only.
+	err := st.stdPostAPI("/renter/redundancy/test.dat_1", url.Values{"datapieces": {"1"}, "paritypieces": {"2"}})
+	if err == nil {
+		t.Fatal("changed the redundancy of a read-only file")
+	}
+	var rf RenterFiles
+	if err := st.getAPI("/renter/files", &rf); err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range rf.Files {
+		if fi.ReadOnly != (fi.SiaPath == "test.dat_1") {
+			t.Fatal("wrong read-only status of", fi.SiaPath)
+		}
+	}
+
+	// Share and load the file in ASCII form.
+	var rsa RenterShareASCII
+	if err := st.getAPI("/renter/shareascii?siapaths=test.dat", &rsa); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.stdPostAPI("/renter/loadascii", url.Values{}); err == nil {
+		t.Fatal("load of an empty asciisia succeeded")
+	}
+	if err := st.postAPI("/renter/loadascii", url.Values{"asciisia": {rsa.ASCIIsia}}, &rl); err != nil {
+		t.Fatal(err)
+	}
+	if len(rl.FilesAdded) != 1 || rl.FilesAdded[0] != "test.dat_2" || !rl.Files[0].Available {
+		t.Fatal("file was not loaded properly:", rl)
+	}
+}
+
 // TestRenterPaths tests that the /renter routes handle path parameters
 // properly.
 func TestRenterPaths(t *testing.T) {
@@ -675,6 +877,133 @@ func TestRenterHandlerContracts(t *testing.T) {
 	if got := get.FinancialMetrics.ContractSpending; got.Cmp(expectedContractSpending) != 0 {
 		t.Fatalf("expected contract spending to be %v; got %v", expectedContractSpending, got)
 	}
+
+	// Check the spending report of the current period.
+	var rsg RenterSpendingGET
+	if err = st.getAPI("/renter/spending", &rsg); err != nil {
+		t.Fatal(err)
+	}
+	if len(rsg.Past) != 0 {
+		t.Fatal("expected no past periods, got", len(rsg.Past))
+	}
+	expectedUnspent := get.Settings.Allowance.Funds
+	var expectedFees types.Currency
+	for _, contract := range contracts.Contracts {
+		expectedUnspent = expectedUnspent.Sub(contract.TotalCost)
+		expectedFees = expectedFees.Add(contract.Fees)
+	}
+	fees := rsg.Current.ContractFees.Add(rsg.Current.TransactionFees).Add(rsg.Current.SiafundFees)
+	if rsg.Current.Unspent.Cmp(expectedUnspent) != 0 || fees.Cmp(expectedFees) != 0 {
+		t.Fatal("spending report does not match the contracts:", rsg.Current)
+	}
+	if rsg.Current.Refundable.Cmp(contracts.Contracts[0].RenterFunds) != 0 {
+		t.Fatal("expected the remaining contract funds to be refundable, got", rsg.Current.Refundable)
+	}
+}
+
+// TestRenterManualContracts checks that contracts can be formed, renewed and
+// canceled manually through the API.
+func TestRenterManualContracts(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Anounce the host and start accepting contracts.
+	if err := st.announceHost(); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.acceptContracts(); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.setHostStorage(); err != nil {
+		t.Fatal(err)
+	}
+	var ah HostdbActiveGET
+	if err = st.getAPI("/hostdb/active", &ah); err != nil {
+		t.Fatal(err)
+	}
+	if len(ah.Hosts) != 1 {
+		t.Fatal("expected one active host, got", len(ah.Hosts))
+	}
+	var cg ConsensusGET
+	if err = st.getAPI("/consensus", &cg); err != nil {
+		t.Fatal(err)
+	}
+
+	// Form a contract with the host.
+	formValues := url.Values{}
+	formValues.Set("host", ah.Hosts[0].PublicKeyString)
+	formValues.Set("funds", testFunds)
+	formValues.Set("endheight", fmt.Sprint(cg.Height+20))
+	var formed RenterContract
+	if err = st.postAPI("/renter/contracts/form", formValues, &formed); err != nil {
+		t.Fatal(err)
+	}
+	if !formed.Manual || !formed.GoodForUpload || formed.EndHeight != cg.Height+20 {
+		t.Fatal("formed contract is not a manual contract:", formed)
+	}
+	var rc RenterContracts
+	if err = st.getAPI("/renter/contracts", &rc); err != nil {
+		t.Fatal(err)
+	}
+	if len(rc.Contracts) != 1 || rc.Contracts[0].ID != formed.ID || !rc.Contracts[0].Manual {
+		t.Fatal("formed contract is not listed:", rc.Contracts)
+	}
+
+	// A second contract with the same host cannot be formed.
+	if err = st.stdPostAPI("/renter/contracts/form", formValues); err == nil {
+		t.Fatal("formed a second contract with the same host")
+	}
+	// Only "form" is a valid contracts call without an id.
+	if err = st.stdPostAPI("/renter/contracts/foo", formValues); err == nil {
+		t.Fatal("expected an error for an unknown call")
+	}
+
+	// Renew the contract.
+	renewValues := url.Values{}
+	renewValues.Set("funds", testFunds)
+	renewValues.Set("endheight", fmt.Sprint(cg.Height+30))
+	var renewed RenterContract
+	if err = st.postAPI("/renter/contracts/"+formed.ID.String()+"/renew", renewValues, &renewed); err != nil {
+		t.Fatal(err)
+	}
+	if renewed.ID == formed.ID || !renewed.Manual || renewed.EndHeight != cg.Height+30 {
+		t.Fatal("contract was not renewed:", renewed)
+	}
+
+	// The renewed contract is archived, and its proof is still pending.
+	if err = st.getAPI("/renter/contracts", &rc); err != nil {
+		t.Fatal(err)
+	}
+	if rc.ExpiredContracts != nil {
+		t.Fatal("expired contracts were returned without being requested")
+	}
+	if err = st.getAPI("/renter/contracts?expired=true", &rc); err != nil {
+		t.Fatal(err)
+	}
+	if len(rc.ExpiredContracts) != 1 || rc.ExpiredContracts[0].ID != formed.ID || rc.ExpiredContracts[0].ProofStatus != modules.ContractProofPending {
+		t.Fatal("renewed contract was not archived:", rc.ExpiredContracts)
+	}
+
+	// Cancel the renewed contract.
+	if err = st.stdPostAPI("/renter/contracts/"+renewed.ID.String()+"/cancel", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.getAPI("/renter/contracts", &rc); err != nil {
+		t.Fatal(err)
+	}
+	if len(rc.Contracts) != 1 || !rc.Contracts[0].Canceled || rc.Contracts[0].GoodForUpload || rc.Contracts[0].GoodForRenew {
+		t.Fatal("contract was not canceled:", rc.Contracts)
+	}
+	if err = st.stdPostAPI("/renter/contracts/"+renewed.ID.String()+"/renew", renewValues); err == nil {
+		t.Fatal("renewed a canceled contract")
+	}
 }
 
 // TestRenterHandlerGetAndPost checks that valid /renter calls successfully set
@@ -738,6 +1067,66 @@ func TestRenterHandlerGetAndPost(t *testing.T) {
 		t.Fatalf("expected renew window to be %v; got %v", expectedRenewWindow, got)
 	}
 
+	// The chunk cache size can be set without resubmitting the allowance.
+	if err = st.stdPostAPI("/renter", url.Values{"chunkcachesize": {"1000000"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.getAPI("/renter", &get); err != nil {
+		t.Fatal(err)
+	}
+	if get.Settings.ChunkCacheSize != 1e6 {
+		t.Fatal("chunk cache size was not set:", get.Settings.ChunkCacheSize)
+	}
+	if got := get.Settings.Allowance.Funds; got.Cmp(expectedFunds) != 0 {
+		t.Fatalf("setting the chunk cache size changed the allowance funds to %v", got)
+	}
+
+	// The same goes for the bandwidth limits.
+	if err = st.stdPostAPI("/renter", url.Values{"maxdownloadspeed": {"2000000"}, "maxuploadspeed": {"500000"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.getAPI("/renter", &get); err != nil {
+		t.Fatal(err)
+	}
+	if get.Settings.MaxDownloadSpeed != 2e6 || get.Settings.MaxUploadSpeed != 5e5 {
+		t.Fatal("rate limits were not set:", get.Settings.MaxDownloadSpeed, get.Settings.MaxUploadSpeed)
+	}
+	if get.Settings.ChunkCacheSize != 1e6 {
+		t.Fatal("setting the rate limits changed the chunk cache size")
+	}
+	err = st.stdPostAPI("/renter", url.Values{"maxuploadspeed": {"-1"}})
+	if err == nil || err.Error() != "unable to parse maxuploadspeed" {
+		t.Errorf("expected error to be 'unable to parse maxuploadspeed'; got %v", err)
+	}
+
+	// And for the upload memory limit.
+	if err = st.stdPostAPI("/renter", url.Values{"maxuploadmemory": {"50000000"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.getAPI("/renter", &get); err != nil {
+		t.Fatal(err)
+	}
+	if get.Settings.MaxUploadMemory != 5e7 || get.Settings.MaxUploadSpeed != 5e5 {
+		t.Fatal("upload memory limit was not set:", get.Settings.MaxUploadMemory)
+	}
+
+	// And for the subnet ranges.
+	if get.Settings.IPv4SubnetBits != 24 || get.Settings.IPv6SubnetBits != 54 {
+		t.Fatal("wrong default subnet ranges:", get.Settings.IPv4SubnetBits, get.Settings.IPv6SubnetBits)
+	}
+	if err = st.stdPostAPI("/renter", url.Values{"ipv4subnetbits": {"16"}, "ipv6subnetbits": {"0"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.getAPI("/renter", &get); err != nil {
+		t.Fatal(err)
+	}
+	if get.Settings.IPv4SubnetBits != 16 || get.Settings.IPv6SubnetBits != 0 || get.Settings.MaxUploadMemory != 5e7 {
+		t.Fatal("subnet ranges were not set:", get.Settings.IPv4SubnetBits, get.Settings.IPv6SubnetBits)
+	}
+	if err = st.stdPostAPI("/renter", url.Values{"ipv4subnetbits": {"33"}}); err == nil {
+		t.Fatal("invalid subnet range was accepted")
+	}
+
 	// Try an empty funds string.
 	allowanceValues = url.Values{}
 	allowanceValues.Set("funds", "")
@@ -998,6 +1387,73 @@ func TestRenterHandlerDelete(t *testing.T) {
 	}
 }
 
+// TestRenterHandlerDir checks that the /renter/dir calls create, list, rename
+// and delete directories.
+func TestRenterHandlerDir(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Create a directory, along with its parent.
+	createValues := url.Values{}
+	createValues.Set("action", "create")
+	if err = st.stdPostAPI("/renter/dir/foo/bar", createValues); err != nil {
+		t.Fatal(err)
+	}
+	err = st.stdPostAPI("/renter/dir/foo/bar", createValues)
+	if err == nil || err.Error() != renter.ErrDirExists.Error() {
+		t.Errorf("expected error to be %v, got %v", renter.ErrDirExists, err)
+	}
+
+	// List the root directory.
+	var rd RenterDirectory
+	if err = st.getAPI("/renter/dir/", &rd); err != nil {
+		t.Fatal(err)
+	}
+	if len(rd.Directories) != 2 || rd.Directories[1].SiaPath != "foo" || rd.Directories[1].NumSubDirs != 1 {
+		t.Fatalf("unexpected root directory listing: %v", rd)
+	}
+
+	// Rename the directory.
+	renameValues := url.Values{}
+	renameValues.Set("action", "rename")
+	renameValues.Set("newsiapath", "baz")
+	if err = st.stdPostAPI("/renter/dir/foo", renameValues); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.getAPI("/renter/dir/baz/bar", &rd); err != nil {
+		t.Fatal(err)
+	}
+	err = st.getAPI("/renter/dir/foo", &rd)
+	if err == nil || err.Error() != renter.ErrUnknownDir.Error() {
+		t.Errorf("expected error to be %v, got %v", renter.ErrUnknownDir, err)
+	}
+
+	// Delete the directory.
+	deleteValues := url.Values{}
+	deleteValues.Set("action", "delete")
+	if err = st.stdPostAPI("/renter/dir/baz", deleteValues); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.getAPI("/renter/dir/", &rd); err != nil {
+		t.Fatal(err)
+	}
+	if len(rd.Directories) != 1 {
+		t.Fatalf("expected only the root directory; got %v", rd.Directories)
+	}
+
+	// Try an unknown action.
+	if err = st.stdPostAPI("/renter/dir/foo", url.Values{}); err == nil {
+		t.Error("expected an error when no action is given")
+	}
+}
+
 // Tests that the /renter/upload call checks for relative paths.
 func TestRenterRelativePathErrorUpload(t *testing.T) {
 	if testing.Short() {
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -19,7 +20,9 @@ import (
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter"
 	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
 )
 
 // TestRenterLocalRepair verifies that the renter will use the local file to
@@ -466,11 +469,12 @@ func TestHostAndRentVanilla(t *testing.T) {
 	}
 
 	// Check the host, who should now be reporting file contracts.
-	//
-	// TODO: Switch to using an API call.
-	obligations := st.host.StorageObligations()
-	if len(obligations) != 1 {
-		t.Error("Host has wrong number of obligations:", len(obligations))
+	var hc HostContractsGET
+	if err = st.getAPI("/host/contracts", &hc); err != nil {
+		t.Fatal(err)
+	}
+	if len(hc.Contracts) != 1 || hc.Contracts[0].Status != modules.ObligationStatusUnresolved {
+		t.Error("Host has wrong obligations:", hc.Contracts)
 	}
 
 	// Create a file.
@@ -586,13 +590,18 @@ func TestHostAndRentVanilla(t *testing.T) {
 
 	// Check that the host was able to get the file contract confirmed on the
 	// blockchain.
-	obligations = st.host.StorageObligations()
-	if len(obligations) != 1 {
-		t.Error("Host has wrong number of obligations:", len(obligations))
+	if err = st.getAPI("/host/contracts", &hc); err != nil {
+		t.Fatal(err)
+	}
+	if len(hc.Contracts) != 1 {
+		t.Error("Host has wrong number of obligations:", len(hc.Contracts))
 	}
-	if !obligations[0].OriginConfirmed {
+	if !hc.Contracts[0].OriginConfirmed {
 		t.Error("host has not seen the file contract on the blockchain")
 	}
+	if hc.Contracts[0].FileSize == 0 || hc.Contracts[0].SectorCount == 0 || hc.Contracts[0].PotentialStorageRevenue.IsZero() {
+		t.Error("host is not reporting the uploaded data:", hc.Contracts[0])
+	}
 
 	// Mine blocks until the host should have submitted a storage proof.
 	for i := 0; i <= testPeriodInt+5; i++ {
@@ -604,8 +613,10 @@ func TestHostAndRentVanilla(t *testing.T) {
 	}
 
 	success := false
-	obligations = st.host.StorageObligations()
-	for _, obligation := range obligations {
+	if err = st.getAPI("/host/contracts", &hc); err != nil {
+		t.Fatal(err)
+	}
+	for _, obligation := range hc.Contracts {
 		if obligation.ProofConfirmed {
 			success = true
 			break
@@ -614,6 +625,156 @@ func TestHostAndRentVanilla(t *testing.T) {
 	if !success {
 		t.Error("does not seem like the host has submitted a storage proof successfully to the network")
 	}
+
+	// No obligation has failed.
+	if err = st.getAPI("/host/contracts?status="+modules.ObligationStatusFailed, &hc); err != nil {
+		t.Fatal(err)
+	} else if len(hc.Contracts) != 0 {
+		t.Error("host is reporting failed obligations:", hc.Contracts)
+	}
+	if err = st.getAPI("/host/contracts?status=foo", &hc); err == nil {
+		t.Error("expected an error for an unknown obligation status")
+	}
+}
+
+// TestHostMaintenanceMode checks that a host in maintenance mode advertises
+// it, keeps serving downloads for its existing contracts, refuses uploads if
+// configured to, and reports when its last obligation expires.
+func TestHostMaintenanceMode(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Announce the host and start accepting contracts.
+	err = st.announceHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.setHostStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.acceptContracts()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Set an allowance for the renter, allowing a contract to be formed.
+	allowanceValues := url.Values{}
+	allowanceValues.Set("funds", "10000000000000000000000000000") // 10k SC
+	allowanceValues.Set("period", "20")
+	err = st.stdPostAPI("/renter", allowanceValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(50, time.Millisecond*250, func() error {
+		var rc RenterContracts
+		err = st.getAPI("/renter/contracts", &rc)
+		if err != nil {
+			return errors.New("couldn't get renter stats")
+		}
+		if len(rc.Contracts) != 1 {
+			return errors.New("no contracts")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal("allowance setting failed")
+	}
+
+	// Upload a file to the host.
+	path := filepath.Join(st.dir, "test.dat")
+	err = createRandFile(path, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploadValues := url.Values{}
+	uploadValues.Set("source", path)
+	err = st.stdPostAPI("/renter/upload/test", uploadValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only one piece will be uploaded (10% at current redundancy).
+	var rf RenterFiles
+	for i := 0; i < 200 && (len(rf.Files) != 1 || rf.Files[0].UploadProgress < 10); i++ {
+		st.getAPI("/renter/files", &rf)
+		time.Sleep(100 * time.Millisecond)
+	}
+	if len(rf.Files) != 1 || rf.Files[0].UploadProgress < 10 {
+		t.Fatal("the uploading is not succeeding for some reason:", rf.Files[0])
+	}
+
+	// Invalid maintenance settings should be rejected.
+	err = st.stdPostAPI("/host", url.Values{"maintenancemode": {"maybe"}})
+	if err == nil {
+		t.Fatal("invalid maintenance mode was accepted")
+	}
+
+	// Put the host into maintenance mode, refusing uploads.
+	maintenanceValues := url.Values{}
+	maintenanceValues.Set("maintenancemode", "true")
+	maintenanceValues.Set("maintenancerefuseuploads", "true")
+	err = st.stdPostAPI("/host", maintenanceValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The host should advertise that it is in maintenance mode, and report
+	// the expiration of its only obligation.
+	var hg HostGET
+	err = st.getAPI("/host", &hg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hg.ExternalSettings.MaintenanceMode || hg.ExternalSettings.AcceptingContracts {
+		t.Fatal("host is not advertising maintenance mode:", hg.ExternalSettings)
+	}
+	if !hg.InternalSettings.AcceptingContracts {
+		t.Fatal("maintenance mode changed the acceptingcontracts setting")
+	}
+	var hc HostContractsGET
+	if err = st.getAPI("/host/contracts", &hc); err != nil {
+		t.Fatal(err)
+	}
+	if len(hc.Contracts) != 1 || hg.LastObligationExpiration != hc.Contracts[0].ProofDeadline {
+		t.Fatal("host reported the wrong last obligation expiration:", hg.LastObligationExpiration, hc.Contracts)
+	}
+
+	// The file should still be downloadable.
+	downloadPath := filepath.Join(st.dir, "test-downloaded.dat")
+	err = st.stdGetAPI("/renter/download/test?destination=" + downloadPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second upload should be refused by the host.
+	path2 := filepath.Join(st.dir, "test2.dat")
+	err = createRandFile(path2, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploadValues = url.Values{}
+	uploadValues.Set("source", path2)
+	err = st.stdPostAPI("/renter/upload/test2", uploadValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(3 * time.Second)
+	err = st.getAPI("/renter/files", &rf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range rf.Files {
+		if f.SiaPath == "test2" && f.UploadProgress != 0 {
+			t.Fatal("host accepted an upload in maintenance mode:", f.UploadProgress)
+		}
+	}
 }
 
 // TestHostAndRentMultiHost sets up an integration test where three hosts and a
@@ -1061,6 +1222,16 @@ func TestRenterUploadDownload(t *testing.T) {
 		t.Fatal("data mismatch when downloading a file")
 	}
 
+	// The download should be reflected in the statistics of the host.
+	var rc RenterContracts
+	err = st.getAPI("/renter/contracts", &rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rc.Contracts) != 1 || rc.Contracts[0].DownloadThroughput == 0 {
+		t.Fatal("download statistics of the host were not recorded:", rc.Contracts)
+	}
+
 	// Wait for upload to complete.
 	for i := 0; i < 200 && (len(rf.Files) != 2 || rf.Files[0].UploadProgress < 10 || rf.Files[1].UploadProgress < 10); i++ {
 		st.getAPI("/renter/files", &rf)
@@ -1084,6 +1255,252 @@ func TestRenterUploadDownload(t *testing.T) {
 	}
 }
 
+// TestRenterUploadStream tests that a file can be uploaded from the body of a
+// request and downloaded again.
+func TestRenterUploadStream(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Announce the host and start accepting contracts.
+	err = st.announceHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.acceptContracts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.setHostStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Set an allowance for the renter, allowing a contract to be formed.
+	allowanceValues := url.Values{}
+	allowanceValues.Set("funds", testFunds)
+	allowanceValues.Set("period", testPeriod)
+	err = st.stdPostAPI("/renter", allowanceValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Block until the allowance has finished forming contracts.
+	err = build.Retry(50, time.Millisecond*250, func() error {
+		var rc RenterContracts
+		err = st.getAPI("/renter/contracts", &rc)
+		if err != nil {
+			return errors.New("couldn't get renter stats")
+		}
+		if len(rc.Contracts) != 1 {
+			return errors.New("no contracts")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal("allowance setting failed")
+	}
+
+	// Stream a file that spans several chunks to the renter. The call should
+	// only return once the file is recoverable.
+	data := fastrand.Bytes(int(modules.SectorSize*2 + 1))
+	err = st.stdPostStreamAPI("/renter/uploadstream/test?datapieces=1&paritypieces=1", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rf RenterFiles
+	if err = st.getAPI("/renter/files", &rf); err != nil {
+		t.Fatal(err)
+	}
+	if len(rf.Files) != 1 || !rf.Files[0].Available || rf.Files[0].Filesize != uint64(len(data)) {
+		t.Fatal("streamed file was not committed correctly:", rf.Files)
+	}
+
+	// Streaming to the same siapath should fail.
+	err = st.stdPostStreamAPI("/renter/uploadstream/test", bytes.NewReader(data))
+	if err == nil || !strings.Contains(err.Error(), renter.ErrPathOverload.Error()) {
+		t.Fatalf("expected error to contain %v; got %v", renter.ErrPathOverload, err)
+	}
+
+	// Streaming an empty body should fail without adding a file.
+	err = st.stdPostStreamAPI("/renter/uploadstream/empty", bytes.NewReader(nil))
+	if err == nil || !strings.Contains(err.Error(), "empty stream") {
+		t.Fatal("expected an empty stream to be rejected, got", err)
+	}
+	if err = st.getAPI("/renter/files", &rf); err != nil {
+		t.Fatal(err)
+	} else if len(rf.Files) != 1 {
+		t.Fatal("empty stream added a file:", rf.Files)
+	}
+
+	// Download the file and check its contents.
+	downpath := filepath.Join(st.dir, "testdown.dat")
+	err = st.stdGetAPI("/renter/download/test?destination=" + downpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	download, err := ioutil.ReadFile(downpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, download) {
+		t.Fatal("data mismatch when downloading a streamed file")
+	}
+
+	// Stream the file with the other types of erasure coder.
+	for _, ec := range []string{renter.ECReedSolomonSegmented, renter.ECReplication} {
+		siaPath := strings.ToLower(ec)
+		err = st.stdPostStreamAPI("/renter/uploadstream/"+siaPath+"?erasurecoder="+ec+"&datapieces=1&paritypieces=1", bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		downpath = filepath.Join(st.dir, siaPath+".dat")
+		if err = st.stdGetAPI("/renter/download/" + siaPath + "?destination=" + downpath); err != nil {
+			t.Fatal(err)
+		}
+		if download, err = ioutil.ReadFile(downpath); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(data, download) {
+			t.Fatal("data mismatch when downloading a file encoded with", ec)
+		}
+
+		// The redundancy of the file can be changed without naming its
+		// erasure coder again.
+		err = st.stdPostAPI("/renter/redundancy/"+siaPath, url.Values{"datapieces": {"1"}, "paritypieces": {"2"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = st.getAPI("/renter/files", &rf); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"test":                   renter.ECReedSolomon,
+		"reed-solomon-segmented": renter.ECReedSolomonSegmented,
+		"replication":            renter.ECReplication,
+	}
+	for _, fi := range rf.Files {
+		if fi.ErasureCoder != expected[fi.SiaPath] {
+			t.Fatal("wrong erasure coder of", fi.SiaPath, ":", fi.ErasureCoder)
+		}
+	}
+}
+
+// TestRenterStream tests that files can be streamed from the renter, and that
+// Range requests are served with partial content.
+func TestRenterStream(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Announce the host and start accepting contracts.
+	err = st.announceHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.acceptContracts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.setHostStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Set an allowance for the renter, allowing a contract to be formed.
+	allowanceValues := url.Values{}
+	allowanceValues.Set("funds", testFunds)
+	allowanceValues.Set("period", testPeriod)
+	err = st.stdPostAPI("/renter", allowanceValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(50, time.Millisecond*250, func() error {
+		var rc RenterContracts
+		err = st.getAPI("/renter/contracts", &rc)
+		if err != nil {
+			return errors.New("couldn't get renter stats")
+		}
+		if len(rc.Contracts) != 1 {
+			return errors.New("no contracts")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal("allowance setting failed")
+	}
+
+	// Upload a file that spans several chunks.
+	data := fastrand.Bytes(int(modules.SectorSize*2 + 1))
+	err = st.stdPostStreamAPI("/renter/uploadstream/test.dat?datapieces=1&paritypieces=1", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stream fetches the file, sending rangeHeader if it is not empty.
+	stream := func(rangeHeader string) (*http.Response, []byte) {
+		req, err := http.NewRequest("GET", "http://"+st.server.listener.Addr().String()+"/renter/stream/test.dat", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("User-Agent", "Sia-Agent")
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, body
+	}
+
+	// Stream the whole file.
+	resp, body := stream("")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal("unexpected status code:", resp.StatusCode)
+	}
+	if resp.ContentLength != int64(len(data)) || !bytes.Equal(body, data) {
+		t.Fatal("streamed data does not match the uploaded data")
+	}
+
+	// Stream a range that crosses a chunk boundary.
+	start, end := modules.SectorSize-100, modules.SectorSize+100
+	resp, body = stream(fmt.Sprintf("bytes=%d-%d", start, end))
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Fatal("unexpected status code:", resp.StatusCode)
+	}
+	if resp.ContentLength != int64(end-start+1) || !bytes.Equal(body, data[start:end+1]) {
+		t.Fatal("streamed range does not match the uploaded data")
+	}
+
+	// Stream the final byte of the file.
+	resp, body = stream("bytes=-1")
+	if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, data[len(data)-1:]) {
+		t.Fatal("streamed suffix does not match the uploaded data")
+	}
+
+	// Streaming a nonexistent file should fail.
+	err = st.stdGetAPI("/renter/stream/dne")
+	if err == nil || !strings.Contains(err.Error(), renter.ErrUnknownPath.Error()) {
+		t.Fatalf("expected error to contain %v; got %v", renter.ErrUnknownPath, err)
+	}
+}
+
 // TestRenterCancelAllowance tests that setting an empty allowance causes
 // uploads, downloads, and renewals to cease.
 func TestRenterCancelAllowance(t *testing.T) {
@@ -2325,3 +2742,145 @@ func TestRepairLoopBlocking(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// TestRenterChangeRedundancy checks that lowering the redundancy of a file
+// drops the pieces that are no longer needed from the hosts, and that raising
+// it again is reported in the target redundancy.
+func TestRenterChangeRedundancy(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.Close()
+	stH1, err := blankServerTester(t.Name() + " - Host 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stH1.server.Close()
+	stH2, err := blankServerTester(t.Name() + " - Host 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stH2.server.Close()
+	testGroup := []*serverTester{st, stH1, stH2}
+
+	// Connect the testers to eachother so that they are all on the same
+	// blockchain.
+	err = fullyConnectNodes(testGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Make sure that every wallet has money in it.
+	err = fundAllNodes(testGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Add storage to every host.
+	err = addStorageToAllHosts(testGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Announce every host.
+	err = announceAllHosts(testGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Set an allowance with three hosts.
+	allowanceValues := url.Values{}
+	allowanceValues.Set("funds", "50000000000000000000000000000") // 50k SC
+	allowanceValues.Set("hosts", "3")
+	allowanceValues.Set("period", "10")
+	err = st.stdPostAPI("/renter", allowanceValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Upload a file with a redundancy of 3.
+	path := filepath.Join(st.dir, "test.dat")
+	err = createRandFile(path, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploadValues := url.Values{}
+	uploadValues.Set("source", path)
+	uploadValues.Set("datapieces", "1")
+	uploadValues.Set("paritypieces", "2")
+	err = st.stdPostAPI("/renter/upload/test", uploadValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rf RenterFiles
+	err = retry(60, time.Second, func() error {
+		st.getAPI("/renter/files", &rf)
+		if len(rf.Files) == 1 && rf.Files[0].Redundancy == 3 {
+			return nil
+		}
+		return errors.New("file not uploaded")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rf.Files[0].TargetRedundancy != 3 {
+		t.Fatal("expected a target redundancy of 3, got", rf.Files[0].TargetRedundancy)
+	}
+	contractsSize := func() (size uint64) {
+		var rc RenterContracts
+		if err := st.getAPI("/renter/contracts", &rc); err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range rc.Contracts {
+			size += c.Size
+		}
+		return size
+	}
+	sizeBefore := contractsSize()
+
+	// The number of data pieces cannot be changed.
+	redundancyValues := url.Values{}
+	redundancyValues.Set("datapieces", "2")
+	redundancyValues.Set("paritypieces", "1")
+	if err = st.stdPostAPI("/renter/redundancy/test", redundancyValues); err == nil {
+		t.Fatal("changing the number of data pieces should fail")
+	}
+
+	// Lower the redundancy to 2. One sector should be deleted from the hosts.
+	redundancyValues.Set("datapieces", "1")
+	redundancyValues.Set("paritypieces", "1")
+	if err = st.stdPostAPI("/renter/redundancy/test", redundancyValues); err != nil {
+		t.Fatal(err)
+	}
+	st.getAPI("/renter/files", &rf)
+	if rf.Files[0].Redundancy != 2 || rf.Files[0].TargetRedundancy != 2 {
+		t.Fatal("unexpected redundancy after lowering it:", rf.Files[0].Redundancy, rf.Files[0].TargetRedundancy)
+	}
+	err = retry(60, time.Second, func() error {
+		if size := contractsSize(); size != sizeBefore-modules.SectorSize {
+			return fmt.Errorf("expected contracts to shrink from %v to %v bytes, got %v", sizeBefore, sizeBefore-modules.SectorSize, size)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Raise the redundancy again. The missing piece should be uploaded.
+	redundancyValues.Set("paritypieces", "2")
+	if err = st.stdPostAPI("/renter/redundancy/test", redundancyValues); err != nil {
+		t.Fatal(err)
+	}
+	err = retry(60, time.Second, func() error {
+		st.getAPI("/renter/files", &rf)
+		if rf.Files[0].TargetRedundancy != 3 || rf.Files[0].Redundancy != 3 {
+			return fmt.Errorf("file has redundancy %v of %v", rf.Files[0].Redundancy, rf.Files[0].TargetRedundancy)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -659,6 +659,27 @@ func (st *serverTester) stdGetAPIUA(call string, userAgent string) error {
 	return nil
 }
 
+// stdPostStreamAPI makes an API call with body as the request body, and
+// discards the response.
+func (st *serverTester) stdPostStreamAPI(call string, body io.Reader) error {
+	req, err := http.NewRequest("POST", "http://"+st.server.listener.Addr().String()+call, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "Sia-Agent")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if non2xx(resp.StatusCode) {
+		return decodeError(resp)
+	}
+	return nil
+}
+
 // stdPostAPI makes an API call and discards the response.
 func (st *serverTester) stdPostAPI(call string, values url.Values) error {
 	resp, err := HttpPOST("http://"+st.server.listener.Addr().String()+call, values.Encode())
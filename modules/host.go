@@ -46,6 +46,25 @@ var (
 	HostConnectabilityStatusNotConnectable = HostConnectabilityStatus("not connectable")
 )
 
+// The statuses of a storage obligation, as reported by StorageObligation.
+const (
+	// ObligationStatusUnresolved indicates that the obligation has not ended
+	// yet.
+	ObligationStatusUnresolved = "unresolved"
+
+	// ObligationStatusRejected indicates that the file contract never made it
+	// onto the blockchain. No revenue was gained or lost.
+	ObligationStatusRejected = "rejected"
+
+	// ObligationStatusSucceeded indicates that the storage proof was
+	// confirmed and the revenue was gained.
+	ObligationStatusSucceeded = "succeeded"
+
+	// ObligationStatusFailed indicates that the storage proof was missed, and
+	// the revenue and the risked collateral were lost.
+	ObligationStatusFailed = "failed"
+)
+
 type (
 	// HostFinancialMetrics provides financial statistics for the host,
 	// including money that is locked in contracts. Though verbose, these
@@ -94,6 +113,58 @@ type (
 		MinDownloadBandwidthPrice types.Currency `json:"mindownloadbandwidthprice"`
 		MinStoragePrice           types.Currency `json:"minstorageprice"`
 		MinUploadBandwidthPrice   types.Currency `json:"minuploadbandwidthprice"`
+
+		// When AutoPricing is enabled, the host periodically adjusts its
+		// prices toward the target utilization of its storage folders. Each
+		// price is kept between its floor and its ceiling, and prices without
+		// a ceiling are not adjusted.
+		AutoPricing                   bool           `json:"autopricing"`
+		AutoPricingTargetUtilization  float64        `json:"autopricingtargetutilization"`
+		ContractPriceFloor            types.Currency `json:"contractpricefloor"`
+		ContractPriceCeiling          types.Currency `json:"contractpriceceiling"`
+		DownloadBandwidthPriceFloor   types.Currency `json:"downloadbandwidthpricefloor"`
+		DownloadBandwidthPriceCeiling types.Currency `json:"downloadbandwidthpriceceiling"`
+		StoragePriceFloor             types.Currency `json:"storagepricefloor"`
+		StoragePriceCeiling           types.Currency `json:"storagepriceceiling"`
+		UploadBandwidthPriceFloor     types.Currency `json:"uploadbandwidthpricefloor"`
+		UploadBandwidthPriceCeiling   types.Currency `json:"uploadbandwidthpriceceiling"`
+
+		// The bandwidth limits, in bytes per second, that apply to all
+		// connections of the host combined. MaxDownloadSpeed limits the data
+		// that renters download from the host, and MaxUploadSpeed limits the
+		// data that renters upload to it. MaxConnections and
+		// MaxConnectionsPerIP cap the number of connections that the host
+		// serves at once, in total and from a single IP address. A value of 0
+		// means that there is no limit.
+		MaxDownloadSpeed    int64  `json:"maxdownloadspeed"`
+		MaxUploadSpeed      int64  `json:"maxuploadspeed"`
+		MaxConnections      uint64 `json:"maxconnections"`
+		MaxConnectionsPerIP uint64 `json:"maxconnectionsperip"`
+
+		// When MaintenanceMode is enabled, the host refuses new contracts and
+		// renewals and advertises that it is in maintenance, but keeps serving
+		// downloads and submitting storage proofs for its existing contracts.
+		// If MaintenanceRefuseUploads is also set, uploads to the existing
+		// contracts are refused as well.
+		MaintenanceMode          bool `json:"maintenancemode"`
+		MaintenanceRefuseUploads bool `json:"maintenancerefuseuploads"`
+	}
+
+	// HostPriceChange records an adjustment of the host's prices made by
+	// automatic pricing, along with the utilization of the host's storage
+	// that prompted it. Announced indicates that the change was large enough
+	// for the host to re-announce itself.
+	HostPriceChange struct {
+		BlockHeight types.BlockHeight `json:"blockheight"`
+		Timestamp   types.Timestamp   `json:"timestamp"`
+		Utilization float64           `json:"utilization"`
+
+		ContractPrice          types.Currency `json:"contractprice"`
+		DownloadBandwidthPrice types.Currency `json:"downloadbandwidthprice"`
+		StoragePrice           types.Currency `json:"storageprice"`
+		UploadBandwidthPrice   types.Currency `json:"uploadbandwidthprice"`
+
+		Announced bool `json:"announced"`
 	}
 
 	// HostNetworkMetrics reports the quantity of each type of RPC call that
@@ -106,12 +177,34 @@ type (
 		ReviseCalls       uint64 `json:"revisecalls"`
 		SettingsCalls     uint64 `json:"settingscalls"`
 		UnrecognizedCalls uint64 `json:"unrecognizedcalls"`
+
+		// ActiveConnections is the number of connections that the host is
+		// currently serving, and RejectedConnections is the number of
+		// connections that were refused because of the connection limits.
+		ActiveConnections   uint64 `json:"activeconnections"`
+		RejectedConnections uint64 `json:"rejectedconnections"`
 	}
 
 	// StorageObligation contains information about a storage obligation that
-	// the host has accepted.
+	// the host has accepted. The storage proof of the obligation must be
+	// submitted between the expiration height and the proof deadline. The
+	// sector count is zero once the obligation has been resolved.
 	StorageObligation struct {
-		NegotiationHeight types.BlockHeight `json:"negotiationheight"`
+		ObligationID      types.FileContractID `json:"obligationid"`
+		FileSize          uint64               `json:"filesize"`
+		SectorCount       uint64               `json:"sectorcount"`
+		NegotiationHeight types.BlockHeight    `json:"negotiationheight"`
+		ExpirationHeight  types.BlockHeight    `json:"expirationheight"`
+		ProofDeadline     types.BlockHeight    `json:"proofdeadline"`
+
+		// Revenue paid by the renter, and collateral put up by the host.
+		ContractCost             types.Currency `json:"contractcost"`
+		PotentialDownloadRevenue types.Currency `json:"potentialdownloadrevenue"`
+		PotentialStorageRevenue  types.Currency `json:"potentialstoragerevenue"`
+		PotentialUploadRevenue   types.Currency `json:"potentialuploadrevenue"`
+		LockedCollateral         types.Currency `json:"lockedcollateral"`
+		RiskedCollateral         types.Currency `json:"riskedcollateral"`
+		TransactionFeesAdded     types.Currency `json:"transactionfeesadded"`
 
 		OriginConfirmed     bool   `json:"originconfirmed"`
 		RevisionConstructed bool   `json:"revisionconstructed"`
@@ -119,6 +212,7 @@ type (
 		ProofConstructed    bool   `json:"proofconstructed"`
 		ProofConfirmed      bool   `json:"proofconfirmed"`
 		ObligationStatus    uint64 `json:"obligationstatus"`
+		Status              string `json:"status"`
 	}
 
 	// HostWorkingStatus reports the working state of a host. Can be one of
@@ -154,6 +248,10 @@ type (
 		// have been made to the host.
 		NetworkMetrics() HostNetworkMetrics
 
+		// PriceHistory returns the recent price changes made by automatic
+		// pricing, oldest first.
+		PriceHistory() []HostPriceChange
+
 		// PublicKey returns the public key of the host.
 		PublicKey() types.SiaPublicKey
 
@@ -26,6 +26,23 @@ const (
 	// connection.
 	iteratedConnectionTime = 1200 * time.Second
 
+	// autoPricingAnnounceThreshold is the relative change of any price since
+	// the last announcement that makes automatic pricing re-announce the
+	// host.
+	autoPricingAnnounceThreshold = 0.25
+
+	// autoPricingMaxStep is the largest relative change that automatic
+	// pricing makes to a price in a single adjustment.
+	autoPricingMaxStep = 0.1
+
+	// maxPriceHistory is the number of price changes that the host keeps in
+	// its price history.
+	maxPriceHistory = 100
+
+	// rejectConnTimeout is the amount of time that the host waits for the
+	// RPC specifier of a connection that it refuses.
+	rejectConnTimeout = 5 * time.Second
+
 	// resubmissionTimeout defines the number of blocks that a host will wait
 	// before attempting to resubmit a transaction to the blockchain.
 	// Typically, this transaction will contain either a file contract, a file
@@ -104,6 +121,14 @@ var (
 	// data.
 	defaultUploadBandwidthPrice = types.SiacoinPrecision.Mul64(1).Div(modules.BytesPerTerabyte) // 1 SC / TB
 
+	// autoPricingFrequency defines how often automatic pricing adjusts the
+	// prices of the host.
+	autoPricingFrequency = build.Select(build.Var{
+		Standard: time.Hour * 6,
+		Dev:      time.Minute * 5,
+		Testing:  time.Second * 5,
+	}).(time.Duration)
+
 	// workingStatusFirstCheck defines how frequently the Host's working status
 	// check runs
 	workingStatusFirstCheck = build.Select(build.Var{
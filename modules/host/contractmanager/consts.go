@@ -125,3 +125,22 @@ var (
 		Testing:  time.Second * 8,
 	}).(time.Duration)
 )
+
+var (
+	// scrubInterval specifies how long the contract manager waits between
+	// scrubs of its storage folders.
+	scrubInterval = build.Select(build.Var{
+		Dev:      time.Minute * 10,
+		Standard: time.Hour * 24 * 7,
+		Testing:  time.Second * 10,
+	}).(time.Duration)
+
+	// scrubRate limits the number of bytes per second that the scrubber reads
+	// from the storage folders, so that scrubbing does not compete with
+	// renters for disk bandwidth.
+	scrubRate = build.Select(build.Var{
+		Dev:      uint64(1 << 24), // 16 MiB/s
+		Standard: uint64(1 << 24), // 16 MiB/s
+		Testing:  uint64(1 << 30), // 1 GiB/s
+	}).(uint64)
+)
@@ -213,6 +213,10 @@ func newContractManager(dependencies dependencies, persistDir string) (*Contract
 	// and adds them if they are discovered.
 	go cm.threadedFolderRecheck()
 
+	// Spin up the thread that periodically verifies the sectors in the
+	// storage folders.
+	go cm.threadedScrubSectors()
+
 	// Simulate an error to make sure the cleanup code is triggered correctly.
 	if cm.dependencies.disrupt("erroredStartup") {
 		err = errors.New("startup disrupted")
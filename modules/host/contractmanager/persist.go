@@ -14,11 +14,14 @@ import (
 
 type (
 	// savedStorageFolder contains fields that are saved automatically to disk
-	// for each storage folder.
+	// for each storage folder. LastScrub is the unix timestamp of the most
+	// recent scrub of the storage folder, so that the scrub schedule survives
+	// restarts.
 	savedStorageFolder struct {
-		Index uint16
-		Path  string
-		Usage []uint64
+		Index     uint16
+		Path      string
+		Usage     []uint64
+		LastScrub uint64
 	}
 
 	// savedSettings contains fields that are saved atomically to disk inside
@@ -32,9 +35,10 @@ type (
 // savedStorageFolder returns the persistent version of the storage folder.
 func (sf *storageFolder) savedStorageFolder() savedStorageFolder {
 	ssf := savedStorageFolder{
-		Index: sf.index,
-		Path:  sf.path,
-		Usage: make([]uint64, len(sf.usage)),
+		Index:     sf.index,
+		Path:      sf.path,
+		Usage:     make([]uint64, len(sf.usage)),
+		LastScrub: atomic.LoadUint64(&sf.atomicLastScrub),
 	}
 	copy(ssf.Usage, sf.usage)
 	return ssf
@@ -76,6 +80,7 @@ func (cm *ContractManager) loadSettings() error {
 		sf.index = ss.StorageFolders[i].Index
 		sf.path = ss.StorageFolders[i].Path
 		sf.usage = ss.StorageFolders[i].Usage
+		atomic.StoreUint64(&sf.atomicLastScrub, ss.StorageFolders[i].LastScrub)
 		sf.metadataFile, err = cm.dependencies.openFile(filepath.Join(ss.StorageFolders[i].Path, metadataFile), os.O_RDWR, 0700)
 		if err != nil {
 			// Mark the folder as unavailable and log an error.
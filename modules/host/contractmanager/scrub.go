@@ -0,0 +1,130 @@
+package contractmanager
+
+// scrub.go implements a background scrubber that periodically reads every
+// physical sector and checks that it still matches the Merkle root that it was
+// stored under. Sector ids are salted hashes of the Merkle roots, so a sector
+// is verified by recomputing its id from the data on disk. Corrupt sectors are
+// logged and counted in the metadata of their storage folder, so that bit rot
+// is noticed before a storage proof fails and collateral is lost.
+//
+// Each storage folder is scrubbed once per scrubInterval. The time of the last
+// scrub is saved with the storage folder, so a folder that is due is scrubbed
+// right after startup rather than a full interval later.
+//
+// The scrubber reads at most scrubRate bytes per second, and holds the lock of
+// a sector only while the sector is being read and verified.
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// managedScrubSector reads the sector with the provided id from the storage
+// folder and checks that the data still matches the id. Sectors that have been
+// deleted or moved out of the storage folder since the scrub started are
+// skipped.
+func (cm *ContractManager) managedScrubSector(sf *storageFolder, id sectorID) {
+	cm.wal.managedLockSector(id)
+	defer cm.wal.managedUnlockSector(id)
+
+	cm.wal.mu.Lock()
+	sl, exists := cm.sectorLocations[id]
+	current := cm.storageFolders[sf.index] == sf
+	cm.wal.mu.Unlock()
+	if !exists || !current || sl.storageFolder != sf.index || atomic.LoadUint64(&sf.atomicUnavailable) == 1 {
+		return
+	}
+
+	// Scrub reads are not counted as successful reads, so that the read
+	// statistics keep reflecting the requests of renters. Failed reads are
+	// counted, because they indicate disk trouble.
+	sectorData, err := readSector(sf.sectorFile, sl.index)
+	if err != nil {
+		atomic.AddUint64(&sf.atomicFailedReads, 1)
+		cm.log.Printf("WARN: scrubber unable to read sector %x in storage folder %v: %v\n", id, sf.path, err)
+		return
+	}
+	if cm.managedSectorID(crypto.MerkleRoot(sectorData)) != id {
+		atomic.AddUint64(&sf.atomicCorruptSectors, 1)
+		cm.log.Printf("WARN: sector %x at index %v of storage folder %v is corrupt\n", id, sl.index, sf.path)
+	}
+}
+
+// managedScrubStorageFolder verifies every sector in the storage folder. False
+// is returned if the contract manager shut down before the scrub finished.
+func (cm *ContractManager) managedScrubStorageFolder(sf *storageFolder) bool {
+	// Collect the sectors that are stored in the storage folder. Sectors that
+	// are added during the scrub are checked by the next scrub.
+	var ids []sectorID
+	cm.wal.mu.Lock()
+	for id, sl := range cm.sectorLocations {
+		if sl.storageFolder == sf.index {
+			ids = append(ids, id)
+		}
+	}
+	cm.wal.mu.Unlock()
+	atomic.StoreUint64(&sf.atomicCorruptSectors, 0)
+	atomic.StoreUint64(&sf.atomicScrubProgress, 0)
+	atomic.StoreUint64(&sf.atomicScrubTotal, uint64(len(ids)))
+
+	sectorDelay := time.Duration(modules.SectorSize * uint64(time.Second) / scrubRate)
+	for _, id := range ids {
+		if err := cm.tg.Add(); err != nil {
+			return false
+		}
+		cm.managedScrubSector(sf, id)
+		cm.tg.Done()
+		atomic.AddUint64(&sf.atomicScrubProgress, 1)
+
+		select {
+		case <-cm.tg.StopChan():
+			return false
+		case <-time.After(sectorDelay):
+		}
+	}
+	atomic.StoreUint64(&sf.atomicLastScrub, uint64(time.Now().Unix()))
+	return true
+}
+
+// nextScrub returns the time at which the storage folder is due to be
+// scrubbed.
+func (sf *storageFolder) nextScrub() time.Time {
+	return time.Unix(int64(atomic.LoadUint64(&sf.atomicLastScrub)), 0).Add(scrubInterval)
+}
+
+// threadedScrubSectors periodically scrubs the available storage folders.
+func (cm *ContractManager) threadedScrubSectors() {
+	// Don't spawn the loop if 'noScrub' disruption is set.
+	if cm.dependencies.disrupt("noScrub") {
+		return
+	}
+
+	for {
+		// Scrub the storage folders that are due.
+		cm.wal.mu.Lock()
+		sfs := cm.availableStorageFolders()
+		cm.wal.mu.Unlock()
+		next := time.Now().Add(scrubInterval)
+		for _, sf := range sfs {
+			if time.Now().Before(sf.nextScrub()) {
+				if sf.nextScrub().Before(next) {
+					next = sf.nextScrub()
+				}
+				continue
+			}
+			if !cm.managedScrubStorageFolder(sf) {
+				return
+			}
+		}
+
+		// Wait until the next storage folder is due.
+		select {
+		case <-cm.tg.StopChan():
+			return
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
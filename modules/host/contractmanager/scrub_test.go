@@ -0,0 +1,189 @@
+package contractmanager
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// dependencyNoScrub prevents the scrub loop from running in the contract
+// manager.
+type dependencyNoScrub struct {
+	productionDependencies
+}
+
+// disrupt prevents the scrub loop from running in the contract manager.
+func (dependencyNoScrub) disrupt(s string) bool {
+	return s == "noScrub"
+}
+
+// TestScrubStorageFolder checks that scrubbing a storage folder finds the
+// sectors whose data no longer matches their Merkle root, and reports its
+// progress in the storage folder metadata.
+func TestScrubStorageFolder(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	d := new(dependencyNoScrub)
+	cmt, err := newMockedContractManagerTester(d, t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cmt.panicClose()
+
+	// Add a storage folder and fill it with a few sectors.
+	storageFolderDir := filepath.Join(cmt.persistDir, "storageFolderOne")
+	err = os.MkdirAll(storageFolderDir, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cmt.cm.AddStorageFolder(storageFolderDir, modules.SectorSize*storageFolderGranularity*2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		root, data := randSector()
+		err = cmt.cm.AddSector(root, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A scrub of the intact sectors should not find any corruption.
+	sf := cmt.cm.storageFolders[cmt.cm.StorageFolders()[0].Index]
+	if !cmt.cm.managedScrubStorageFolder(sf) {
+		t.Fatal("scrub did not finish")
+	}
+	sfm := cmt.cm.StorageFolders()[0]
+	if sfm.CorruptSectors != 0 {
+		t.Fatal("intact sectors were reported as corrupt:", sfm.CorruptSectors)
+	}
+	if sfm.ScrubProgress != 3 || sfm.ScrubTotal != 3 || sfm.LastScrub == 0 {
+		t.Fatal("scrub progress was not reported:", sfm.ScrubProgress, sfm.ScrubTotal, sfm.LastScrub)
+	}
+
+	// Corrupt one of the sectors on disk and scrub again.
+	root, data := randSector()
+	err = cmt.cm.AddSector(root, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sl := cmt.cm.sectorLocations[cmt.cm.managedSectorID(root)]
+	err = writeSector(sf.sectorFile, sl.index, fastrand.Bytes(int(modules.SectorSize)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmt.cm.managedScrubStorageFolder(sf) {
+		t.Fatal("scrub did not finish")
+	}
+	sfm = cmt.cm.StorageFolders()[0]
+	if sfm.CorruptSectors != 1 {
+		t.Fatal("expected one corrupt sector, got", sfm.CorruptSectors)
+	}
+	if sfm.ScrubProgress != 4 || sfm.ScrubTotal != 4 {
+		t.Fatal("scrub progress was not reported:", sfm.ScrubProgress, sfm.ScrubTotal)
+	}
+
+	// Scrubbing again should find the same corrupt sector, rather than count
+	// it twice.
+	if !cmt.cm.managedScrubStorageFolder(sf) {
+		t.Fatal("scrub did not finish")
+	}
+	sfm = cmt.cm.StorageFolders()[0]
+	if sfm.CorruptSectors != 1 {
+		t.Fatal("expected one corrupt sector after scrubbing twice, got", sfm.CorruptSectors)
+	}
+
+	// Resetting the health of the storage folder should clear the corruption
+	// statistics.
+	err = cmt.cm.ResetStorageFolderHealth(sfm.Index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmt.cm.StorageFolders()[0].CorruptSectors != 0 {
+		t.Fatal("corrupt sectors were not reset")
+	}
+
+	// The time of the last scrub should survive a restart, so that the
+	// storage folder is not scrubbed again until it is due. The settings are
+	// written by one iteration of the sync loop and moved into place by the
+	// next, so wait for two iterations before closing.
+	for i := 0; i < 2; i++ {
+		cmt.cm.wal.mu.Lock()
+		syncChan := cmt.cm.wal.syncChan
+		cmt.cm.wal.mu.Unlock()
+		<-syncChan
+	}
+	err = cmt.cm.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmt.cm, err = newContractManager(d, filepath.Join(cmt.persistDir, modules.ContractManagerDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastScrub := cmt.cm.StorageFolders()[0].LastScrub; lastScrub != sfm.LastScrub {
+		t.Fatal("last scrub was not persisted:", lastScrub, sfm.LastScrub)
+	}
+	sf = cmt.cm.storageFolders[sfm.Index]
+	if !time.Now().Before(sf.nextScrub()) {
+		t.Fatal("storage folder is due for a scrub right after being scrubbed")
+	}
+}
+
+// TestScrubAfterStartup checks that the scrubber scrubs storage folders that
+// have never been scrubbed right after startup.
+func TestScrubAfterStartup(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cmt, err := newMockedContractManagerTester(new(dependencyNoScrub), t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cmt.panicClose()
+
+	storageFolderDir := filepath.Join(cmt.persistDir, "storageFolderOne")
+	err = os.MkdirAll(storageFolderDir, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cmt.cm.AddStorageFolder(storageFolderDir, modules.SectorSize*storageFolderGranularity*2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, data := randSector()
+	err = cmt.cm.AddSector(root, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Restart the contract manager with the scrubber enabled. The storage
+	// folder has never been scrubbed, so it should be scrubbed well before
+	// scrubInterval has passed.
+	err = cmt.cm.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmt.cm, err = newContractManager(new(productionDependencies), filepath.Join(cmt.persistDir, modules.ContractManagerDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(50, scrubInterval/100, func() error {
+		if sfm := cmt.cm.StorageFolders()[0]; sfm.LastScrub == 0 || sfm.ScrubProgress != 1 {
+			return errors.New("storage folder was not scrubbed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/sync"
+	"github.com/NebulousLabs/Sia/types"
 	"github.com/NebulousLabs/fastrand"
 )
 
@@ -93,6 +94,14 @@ type storageFolder struct {
 	atomicSuccessfulReads  uint64
 	atomicSuccessfulWrites uint64
 
+	// Scrub statistics. atomicCorruptSectors counts the corrupt sectors
+	// found by the current or most recent scrub. atomicLastScrub is a unix
+	// timestamp, and is saved to disk.
+	atomicCorruptSectors uint64
+	atomicLastScrub      uint64
+	atomicScrubProgress  uint64
+	atomicScrubTotal     uint64
+
 	// Atomic bool indicating whether or not the storage folder is available. If
 	// the storage folder is not available, it will still be loaded but return
 	// an error if it is queried.
@@ -328,8 +337,8 @@ func (cm *ContractManager) threadedFolderRecheck() {
 	}
 }
 
-// ResetStorageFolderHealth will reset the read and write statistics for the
-// input storage folder.
+// ResetStorageFolderHealth will reset the read, write, and corruption
+// statistics for the input storage folder.
 func (cm *ContractManager) ResetStorageFolderHealth(index uint16) error {
 	err := cm.tg.Add()
 	if err != nil {
@@ -347,6 +356,7 @@ func (cm *ContractManager) ResetStorageFolderHealth(index uint16) error {
 	atomic.StoreUint64(&sf.atomicFailedWrites, 0)
 	atomic.StoreUint64(&sf.atomicSuccessfulReads, 0)
 	atomic.StoreUint64(&sf.atomicSuccessfulWrites, 0)
+	atomic.StoreUint64(&sf.atomicCorruptSectors, 0)
 	return nil
 }
 
@@ -412,6 +422,11 @@ func (cm *ContractManager) StorageFolders() []modules.StorageFolderMetadata {
 			SuccessfulReads:  atomic.LoadUint64(&sf.atomicSuccessfulReads),
 			SuccessfulWrites: atomic.LoadUint64(&sf.atomicSuccessfulWrites),
 
+			CorruptSectors: atomic.LoadUint64(&sf.atomicCorruptSectors),
+			LastScrub:      types.Timestamp(atomic.LoadUint64(&sf.atomicLastScrub)),
+			ScrubProgress:  atomic.LoadUint64(&sf.atomicScrubProgress),
+			ScrubTotal:     atomic.LoadUint64(&sf.atomicScrubTotal),
+
 			Capacity:          modules.SectorSize * 64 * uint64(len(sf.usage)),
 			CapacityRemaining: ((64 * uint64(len(sf.usage))) - sf.sectors) * modules.SectorSize,
 			Index:             sf.index,
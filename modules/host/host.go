@@ -124,6 +124,10 @@ type Host struct {
 	atomicSettingsCalls     uint64
 	atomicUnrecognizedCalls uint64
 
+	// atomicRejectedConnections counts the connections that were refused
+	// because of the connection limits.
+	atomicRejectedConnections uint64
+
 	// Error management. There are a few different types of errors returned by
 	// the host. These errors intentionally not persistent, so that the logging
 	// limits of each error type will be reset each time the host is reset.
@@ -156,10 +160,17 @@ type Host struct {
 	autoAddress          modules.NetAddress // Determined using automatic tooling in network.go
 	financialMetrics     modules.HostFinancialMetrics
 	settings             modules.HostInternalSettings
+	priceHistory         []modules.HostPriceChange
 	revisionNumber       uint64
 	workingStatus        modules.HostWorkingStatus
 	connectabilityStatus modules.HostConnectabilityStatus
 
+	// The connections that the host is currently serving, in total and per IP
+	// address. Connections beyond the limits in the internal settings are
+	// refused.
+	activeConnections uint64
+	connectionsPerIP  map[string]uint64
+
 	// A map of storage obligations that are currently being modified. Locks on
 	// storage obligations can be long-running, and each storage obligation can
 	// be locked separately.
@@ -172,6 +183,7 @@ type Host struct {
 	mu         sync.RWMutex
 	persistDir string
 	port       string
+	rl         *modules.RateLimit
 	tg         siasync.ThreadGroup
 }
 
@@ -222,9 +234,11 @@ func newHost(dependencies dependencies, cs modules.ConsensusSet, tpool modules.T
 		wallet:       wallet,
 		dependencies: dependencies,
 
+		connectionsPerIP:         make(map[string]uint64),
 		lockedStorageObligations: make(map[types.FileContractID]*siasync.TryMutex),
 
 		persistDir: persistDir,
+		rl:         modules.NewRateLimit(0, 0),
 	}
 
 	// Call stop in the event of a partial startup.
@@ -289,6 +303,9 @@ func newHost(dependencies dependencies, cs modules.ConsensusSet, tpool modules.T
 		h.log.Println("Could not initialize host networking:", err)
 		return nil, err
 	}
+
+	// Start adjusting the prices, if automatic pricing is enabled.
+	go h.threadedAdjustPrices()
 	return h, nil
 }
 
@@ -379,6 +396,12 @@ func (h *Host) SetInternalSettings(settings modules.HostInternalSettings) error
 			return errors.New("internal settings not updated, invalid NetAddress: " + err.Error())
 		}
 	}
+	if err := checkAutoPricingSettings(settings); err != nil {
+		return errors.New("internal settings not updated: " + err.Error())
+	}
+	if settings.MaxDownloadSpeed < 0 || settings.MaxUploadSpeed < 0 {
+		return errors.New("internal settings not updated, bandwidth limits cannot be negative")
+	}
 
 	// Check if the net address for the host has changed. If it has, and it's
 	// not equal to the auto address, then the host is going to need to make
@@ -389,6 +412,7 @@ func (h *Host) SetInternalSettings(settings modules.HostInternalSettings) error
 
 	h.settings = settings
 	h.revisionNumber++
+	h.setRateLimits()
 
 	err = h.saveSync()
 	if err != nil {
@@ -120,6 +120,10 @@ var (
 	// funds to the void output.
 	errLowVoidOutput = ErrorCommunication("rejected for low value void output")
 
+	// errMaintenanceUploads is returned if the renter tries to upload data
+	// while the host is in maintenance mode and refusing uploads.
+	errMaintenanceUploads = ErrorCommunication("host is in maintenance mode and is not accepting uploads")
+
 	// errMismatchedHostPayouts is returned if the renter incorrectly sets the
 	// host valid and missed payouts to different values during contract
 	// formation.
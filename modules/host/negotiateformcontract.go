@@ -80,6 +80,10 @@ func (h *Host) managedRPCFormContract(conn net.Conn) error {
 		h.log.Debugln("Turning down contract because the host is not accepting contracts.")
 		return nil
 	}
+	if settings.MaintenanceMode {
+		h.log.Debugln("Turning down contract because the host is in maintenance mode.")
+		return nil
+	}
 
 	// Extend the deadline to meet the rest of file contract negotiation.
 	conn.SetDeadline(time.Now().Add(modules.NegotiateFileContractTime))
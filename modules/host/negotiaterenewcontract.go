@@ -92,6 +92,16 @@ func (h *Host) managedRPCRenewContract(conn net.Conn) error {
 	if err != nil {
 		return extendErr("RPCSettings failed: ", err)
 	}
+	// Contracts are not renewed in maintenance mode. The renter has been given
+	// enough information in the host settings to understand that the
+	// connection is going to be closed.
+	h.mu.RLock()
+	maintenance := h.settings.MaintenanceMode
+	h.mu.RUnlock()
+	if maintenance {
+		h.log.Debugln("Turning down renewal because the host is in maintenance mode.")
+		return nil
+	}
 
 	// Set the renewal deadline.
 	conn.SetDeadline(time.Now().Add(modules.NegotiateRenewContractTime))
@@ -80,6 +80,10 @@ func (h *Host) managedRevisionIteration(conn net.Conn, so *storageObligation, fi
 			if uint64(len(modification.Data)) > modules.SectorSize {
 				return errLargeSector
 			}
+			// Uploads may be refused while the host is in maintenance mode.
+			if settings.MaintenanceMode && settings.MaintenanceRefuseUploads && modification.Type != modules.ActionDelete {
+				return errMaintenanceUploads
+			}
 
 			switch modification.Type {
 			case modules.ActionDelete:
@@ -32,8 +32,9 @@ func (h *Host) externalSettings() modules.HostExternalSettings {
 	} else {
 		netAddr = h.autoAddress
 	}
+	// A host in maintenance mode does not form or renew any contracts.
 	return modules.HostExternalSettings{
-		AcceptingContracts:   h.settings.AcceptingContracts,
+		AcceptingContracts:   h.settings.AcceptingContracts && !h.settings.MaintenanceMode,
 		MaxDownloadBatchSize: h.settings.MaxDownloadBatchSize,
 		MaxDuration:          h.settings.MaxDuration,
 		MaxReviseBatchSize:   h.settings.MaxReviseBatchSize,
@@ -54,6 +55,8 @@ func (h *Host) externalSettings() modules.HostExternalSettings {
 
 		RevisionNumber: h.revisionNumber,
 		Version:        build.Version,
+
+		MaintenanceMode: h.settings.MaintenanceMode,
 	}
 }
 
@@ -15,6 +15,7 @@ package host
 // have to keep all the files following a renew in order to get the money.
 
 import (
+	"errors"
 	"net"
 	"sync/atomic"
 	"time"
@@ -28,6 +29,81 @@ import (
 // rpcSettingsDeprecated is a specifier for a deprecated settings request.
 var rpcSettingsDeprecated = types.Specifier{'S', 'e', 't', 't', 'i', 'n', 'g', 's'}
 
+var (
+	// errTooManyConnections is returned to a renter when the host is already
+	// serving the maximum number of connections.
+	errTooManyConnections = errors.New("host is serving too many connections, try again later")
+
+	// errTooManyConnectionsFromIP is returned to a renter when the host is
+	// already serving the maximum number of connections from its IP address.
+	errTooManyConnectionsFromIP = errors.New("host is serving too many connections from your IP address, try again later")
+)
+
+// connectionIP returns the IP address of the remote end of conn, which is
+// used to count the connections per IP address.
+func connectionIP(conn net.Conn) string {
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return conn.RemoteAddr().String()
+	}
+	return host
+}
+
+// managedAddConnection counts a new connection from ip, returning an error if
+// the connection would exceed the connection limits of the host.
+func (h *Host) managedAddConnection(ip string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.settings.MaxConnections != 0 && h.activeConnections >= h.settings.MaxConnections {
+		return errTooManyConnections
+	}
+	if h.settings.MaxConnectionsPerIP != 0 && h.connectionsPerIP[ip] >= h.settings.MaxConnectionsPerIP {
+		return errTooManyConnectionsFromIP
+	}
+	h.activeConnections++
+	h.connectionsPerIP[ip]++
+	return nil
+}
+
+// managedRemoveConnection stops counting a connection from ip that was added
+// by managedAddConnection.
+func (h *Host) managedRemoveConnection(ip string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.activeConnections == 0 || h.connectionsPerIP[ip] == 0 {
+		build.Critical("host removed a connection that was not counted")
+		return
+	}
+	h.activeConnections--
+	h.connectionsPerIP[ip]--
+	if h.connectionsPerIP[ip] == 0 {
+		delete(h.connectionsPerIP, ip)
+	}
+}
+
+// managedRejectConn refuses a connection that exceeds the connection limits.
+// The RPC specifier is read first so that the renter receives the rejection
+// as the response to its request.
+func (h *Host) managedRejectConn(conn net.Conn, err error) {
+	atomic.AddUint64(&h.atomicRejectedConnections, 1)
+	if deadlineErr := conn.SetDeadline(time.Now().Add(rejectConnTimeout)); deadlineErr != nil {
+		return
+	}
+	var id types.Specifier
+	if encoding.ReadObject(conn, &id, 16) != nil {
+		return
+	}
+	modules.WriteNegotiationRejection(conn, err)
+	h.log.Debugf("Refused connection from %v: %v", conn.RemoteAddr(), err)
+}
+
+// setRateLimits applies the bandwidth limits of the internal settings to the
+// connections of the host. Renter downloads are written by the host and
+// renter uploads are read by it. The caller must hold the lock.
+func (h *Host) setRateLimits() {
+	h.rl.SetLimits(h.settings.MaxUploadSpeed, h.settings.MaxDownloadSpeed)
+}
+
 // threadedUpdateHostname periodically runs 'managedLearnHostname', which
 // checks if the host's hostname has changed, and makes an updated host
 // announcement if so.
@@ -249,6 +325,10 @@ func (h *Host) threadedHandleConn(conn net.Conn) {
 	}
 	defer h.tg.Done()
 
+	// Limit the bandwidth of the connection.
+	ip := connectionIP(conn)
+	conn = h.rl.Conn(conn)
+
 	// Close the conn on host.Close or when the method terminates, whichever comes
 	// first.
 	connCloseChan := make(chan struct{})
@@ -269,6 +349,13 @@ func (h *Host) threadedHandleConn(conn net.Conn) {
 		return
 	}
 
+	// Refuse the connection if it exceeds the connection limits.
+	if err := h.managedAddConnection(ip); err != nil {
+		h.managedRejectConn(conn, err)
+		return
+	}
+	defer h.managedRemoveConnection(ip)
+
 	// Read a specifier indicating which action is being called.
 	var id types.Specifier
 	if err := encoding.ReadObject(conn, &id, 16); err != nil {
@@ -353,5 +440,8 @@ func (h *Host) NetworkMetrics() modules.HostNetworkMetrics {
 		ReviseCalls:       atomic.LoadUint64(&h.atomicReviseCalls),
 		SettingsCalls:     atomic.LoadUint64(&h.atomicSettingsCalls),
 		UnrecognizedCalls: atomic.LoadUint64(&h.atomicUnrecognizedCalls),
+
+		ActiveConnections:   h.activeConnections,
+		RejectedConnections: atomic.LoadUint64(&h.atomicRejectedConnections),
 	}
 }
@@ -1,11 +1,13 @@
 package host
 
 import (
+	"net"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 )
 
@@ -143,3 +145,81 @@ func TestHostConnectabilityStatus(t *testing.T) {
 		t.Fatal("expected connectability state to flip to HostConnectabilityStatusConnectable")
 	}
 }
+
+// TestHostConnectionLimits checks that the host refuses connections beyond
+// its connection limits with a negotiation error, and counts them in its
+// network metrics.
+func TestHostConnectionLimits(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	ht, err := newHostTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+
+	// Negative bandwidth limits should be rejected.
+	settings := ht.host.InternalSettings()
+	settings.MaxDownloadSpeed = -1
+	if ht.host.SetInternalSettings(settings) == nil {
+		t.Fatal("negative bandwidth limit was accepted")
+	}
+
+	// rpcSettings dials the host, requests its settings and returns the
+	// response of the host.
+	addr := ht.host.listener.Addr().String()
+	rpcSettings := func() error {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if err := encoding.WriteObject(conn, modules.RPCSettings); err != nil {
+			return err
+		}
+		return modules.ReadNegotiationAcceptance(conn)
+	}
+
+	// Occupy the only connection slot of the host.
+	settings.MaxDownloadSpeed = 0
+	settings.MaxConnections = 1
+	err = ht.host.SetInternalSettings(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ht.host.managedAddConnection("1.2.3.4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ht.host.managedAddConnection("5.6.7.8"); err != errTooManyConnections {
+		t.Fatal("expected errTooManyConnections, got", err)
+	}
+	if err := rpcSettings(); err == nil || err.Error() != errTooManyConnections.Error() {
+		t.Fatal("expected the host to refuse the connection, got", err)
+	}
+	nm := ht.host.NetworkMetrics()
+	if nm.ActiveConnections != 1 || nm.RejectedConnections == 0 {
+		t.Fatal("connections were not counted:", nm.ActiveConnections, nm.RejectedConnections)
+	}
+	ht.host.managedRemoveConnection("1.2.3.4")
+
+	// Occupy the only connection slot of the local IP address.
+	settings.MaxConnections = 0
+	settings.MaxConnectionsPerIP = 1
+	err = ht.host.SetInternalSettings(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ht.host.managedAddConnection("127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ht.host.managedAddConnection("1.2.3.4"); err != nil {
+		t.Fatal("connection from another IP address was refused:", err)
+	}
+	if err := rpcSettings(); err == nil || err.Error() != errTooManyConnectionsFromIP.Error() {
+		t.Fatal("expected the host to refuse the connection, got", err)
+	}
+	ht.host.managedRemoveConnection("127.0.0.1")
+	ht.host.managedRemoveConnection("1.2.3.4")
+}
@@ -24,6 +24,7 @@ type persistence struct {
 	Announced        bool                         `json:"announced"`
 	AutoAddress      modules.NetAddress           `json:"autoaddress"`
 	FinancialMetrics modules.HostFinancialMetrics `json:"financialmetrics"`
+	PriceHistory     []modules.HostPriceChange    `json:"pricehistory"`
 	PublicKey        types.SiaPublicKey           `json:"publickey"`
 	RevisionNumber   uint64                       `json:"revisionnumber"`
 	SecretKey        crypto.SecretKey             `json:"secretkey"`
@@ -42,6 +43,7 @@ func (h *Host) persistData() persistence {
 		Announced:        h.announced,
 		AutoAddress:      h.autoAddress,
 		FinancialMetrics: h.financialMetrics,
+		PriceHistory:     h.priceHistory,
 		PublicKey:        h.publicKey,
 		RevisionNumber:   h.revisionNumber,
 		SecretKey:        h.secretKey,
@@ -98,6 +100,7 @@ func (h *Host) loadPersistObject(p *persistence) {
 		h.autoAddress = ""
 	}
 	h.financialMetrics = p.FinancialMetrics
+	h.priceHistory = p.PriceHistory
 	h.publicKey = p.PublicKey
 	h.revisionNumber = p.RevisionNumber
 	h.secretKey = p.SecretKey
@@ -106,6 +109,7 @@ func (h *Host) loadPersistObject(p *persistence) {
 		h.log.Printf("WARN: NetAddress '%v' loaded from persist is invalid: %v", p.Settings.NetAddress, err)
 		h.settings.NetAddress = ""
 	}
+	h.setRateLimits()
 	h.unlockHash = p.UnlockHash
 }
 
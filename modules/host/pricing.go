@@ -0,0 +1,216 @@
+package host
+
+// pricing.go implements automatic pricing. When it is enabled, the host
+// periodically compares the utilization of its storage folders to a target
+// utilization. Prices are lowered while the host is emptier than the target
+// and raised while it is fuller, by at most autoPricingMaxStep per
+// adjustment. Every adjustment is logged and kept in the price history. The
+// host re-announces itself when its prices have moved materially since its
+// last announcement, so that renters rescan it.
+
+import (
+	"errors"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// errAutoPricingTarget is returned when automatic pricing is enabled
+	// without a valid target utilization.
+	errAutoPricingTarget = errors.New("auto-pricing target utilization must be greater than 0 and at most 1")
+
+	// errPriceFloorAboveCeiling is returned when the floor of a price is
+	// higher than its ceiling.
+	errPriceFloorAboveCeiling = errors.New("auto-pricing price floor is higher than the price ceiling")
+)
+
+// checkAutoPricingSettings checks that the automatic pricing parameters of
+// settings are sane.
+func checkAutoPricingSettings(settings modules.HostInternalSettings) error {
+	if !settings.AutoPricing {
+		return nil
+	}
+	if settings.AutoPricingTargetUtilization <= 0 || settings.AutoPricingTargetUtilization > 1 {
+		return errAutoPricingTarget
+	}
+	bounds := [][2]types.Currency{
+		{settings.ContractPriceFloor, settings.ContractPriceCeiling},
+		{settings.DownloadBandwidthPriceFloor, settings.DownloadBandwidthPriceCeiling},
+		{settings.StoragePriceFloor, settings.StoragePriceCeiling},
+		{settings.UploadBandwidthPriceFloor, settings.UploadBandwidthPriceCeiling},
+	}
+	for _, b := range bounds {
+		if !b[1].IsZero() && b[0].Cmp(b[1]) > 0 {
+			return errPriceFloorAboveCeiling
+		}
+	}
+	return nil
+}
+
+// pricingFactor returns the factor that the prices are multiplied by to move
+// the host toward the target utilization.
+func pricingFactor(utilization, target float64) float64 {
+	if utilization < target {
+		return 1 - autoPricingMaxStep*(target-utilization)/target
+	}
+	if target >= 1 {
+		return 1
+	}
+	return 1 + autoPricingMaxStep*(utilization-target)/(1-target)
+}
+
+// adjustPrice multiplies price by factor, keeping it between floor and
+// ceiling. Prices without a ceiling are left unchanged.
+func adjustPrice(price, floor, ceiling types.Currency, factor float64) types.Currency {
+	if ceiling.IsZero() {
+		return price
+	}
+	price = price.MulFloat(factor)
+	if price.Cmp(floor) < 0 {
+		return floor
+	} else if price.Cmp(ceiling) > 0 {
+		return ceiling
+	}
+	return price
+}
+
+// materialChange reports whether a price moved by more than
+// autoPricingAnnounceThreshold relative to its previous value.
+func materialChange(prev, cur types.Currency) bool {
+	if prev.IsZero() {
+		return !cur.IsZero()
+	}
+	var diff types.Currency
+	if cur.Cmp(prev) > 0 {
+		diff = cur.Sub(prev)
+	} else {
+		diff = prev.Sub(cur)
+	}
+	return diff.Cmp(prev.MulFloat(autoPricingAnnounceThreshold)) > 0
+}
+
+// pricesChangedMaterially reports whether any of the prices of change moved
+// materially since the last announced price change. If no change was
+// announced, the oldest recorded prices are used instead, or prev if there
+// are none.
+func (h *Host) pricesChangedMaterially(prev, change modules.HostPriceChange) bool {
+	if len(h.priceHistory) > 0 {
+		prev = h.priceHistory[0]
+	}
+	for i := len(h.priceHistory) - 1; i >= 0; i-- {
+		if h.priceHistory[i].Announced {
+			prev = h.priceHistory[i]
+			break
+		}
+	}
+	return materialChange(prev.ContractPrice, change.ContractPrice) ||
+		materialChange(prev.DownloadBandwidthPrice, change.DownloadBandwidthPrice) ||
+		materialChange(prev.StoragePrice, change.StoragePrice) ||
+		materialChange(prev.UploadBandwidthPrice, change.UploadBandwidthPrice)
+}
+
+// managedAdjustPrices moves the prices of the host toward the target
+// utilization of its storage folders, if automatic pricing is enabled.
+func (h *Host) managedAdjustPrices() {
+	var totalStorage, remainingStorage uint64
+	for _, sf := range h.StorageFolders() {
+		totalStorage += sf.Capacity
+		remainingStorage += sf.CapacityRemaining
+	}
+	if totalStorage == 0 {
+		return
+	}
+	utilization := float64(totalStorage-remainingStorage) / float64(totalStorage)
+
+	h.mu.Lock()
+	s := h.settings
+	if !s.AutoPricing {
+		h.mu.Unlock()
+		return
+	}
+	factor := pricingFactor(utilization, s.AutoPricingTargetUtilization)
+	prev := modules.HostPriceChange{
+		ContractPrice:          s.MinContractPrice,
+		DownloadBandwidthPrice: s.MinDownloadBandwidthPrice,
+		StoragePrice:           s.MinStoragePrice,
+		UploadBandwidthPrice:   s.MinUploadBandwidthPrice,
+	}
+	change := modules.HostPriceChange{
+		BlockHeight: h.blockHeight,
+		Timestamp:   types.CurrentTimestamp(),
+		Utilization: utilization,
+
+		ContractPrice:          adjustPrice(s.MinContractPrice, s.ContractPriceFloor, s.ContractPriceCeiling, factor),
+		DownloadBandwidthPrice: adjustPrice(s.MinDownloadBandwidthPrice, s.DownloadBandwidthPriceFloor, s.DownloadBandwidthPriceCeiling, factor),
+		StoragePrice:           adjustPrice(s.MinStoragePrice, s.StoragePriceFloor, s.StoragePriceCeiling, factor),
+		UploadBandwidthPrice:   adjustPrice(s.MinUploadBandwidthPrice, s.UploadBandwidthPriceFloor, s.UploadBandwidthPriceCeiling, factor),
+	}
+	if change.ContractPrice.Equals(prev.ContractPrice) && change.DownloadBandwidthPrice.Equals(prev.DownloadBandwidthPrice) &&
+		change.StoragePrice.Equals(prev.StoragePrice) && change.UploadBandwidthPrice.Equals(prev.UploadBandwidthPrice) {
+		h.mu.Unlock()
+		return
+	}
+	h.settings.MinContractPrice = change.ContractPrice
+	h.settings.MinDownloadBandwidthPrice = change.DownloadBandwidthPrice
+	h.settings.MinStoragePrice = change.StoragePrice
+	h.settings.MinUploadBandwidthPrice = change.UploadBandwidthPrice
+	h.revisionNumber++
+	reannounce := h.announced && h.pricesChangedMaterially(prev, change)
+	err := h.saveSync()
+	h.mu.Unlock()
+	if err != nil {
+		h.log.Println("Could not save host after adjusting prices:", err)
+	}
+
+	h.log.Printf("INFO: auto-pricing adjusted prices at %.2f%% utilization: storage %v -> %v, contract %v -> %v, download %v -> %v, upload %v -> %v\n",
+		utilization*100, prev.StoragePrice, change.StoragePrice, prev.ContractPrice, change.ContractPrice,
+		prev.DownloadBandwidthPrice, change.DownloadBandwidthPrice, prev.UploadBandwidthPrice, change.UploadBandwidthPrice)
+	if reannounce {
+		if err := h.Announce(); err != nil {
+			h.log.Println("Could not re-announce the host after a material price change:", err)
+		} else {
+			change.Announced = true
+		}
+	}
+
+	h.mu.Lock()
+	h.priceHistory = append(h.priceHistory, change)
+	if len(h.priceHistory) > maxPriceHistory {
+		h.priceHistory = h.priceHistory[len(h.priceHistory)-maxPriceHistory:]
+	}
+	err = h.saveSync()
+	h.mu.Unlock()
+	if err != nil {
+		h.log.Println("Could not save host price history:", err)
+	}
+}
+
+// threadedAdjustPrices periodically adjusts the prices of the host. The
+// thread group is only held during an adjustment so that the thread does not
+// block calls to Flush.
+func (h *Host) threadedAdjustPrices() {
+	for {
+		select {
+		case <-h.tg.StopChan():
+			return
+		case <-time.After(autoPricingFrequency):
+		}
+		if err := h.tg.Add(); err != nil {
+			return
+		}
+		h.managedAdjustPrices()
+		h.tg.Done()
+	}
+}
+
+// PriceHistory returns the recent price changes made by automatic pricing,
+// oldest first.
+func (h *Host) PriceHistory() []modules.HostPriceChange {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	history := make([]modules.HostPriceChange, len(h.priceHistory))
+	copy(history, h.priceHistory)
+	return history
+}
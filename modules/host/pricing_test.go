@@ -0,0 +1,168 @@
+package host
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestPricingFactor checks that prices are lowered below the target
+// utilization, raised above it, and never moved by more than
+// autoPricingMaxStep.
+func TestPricingFactor(t *testing.T) {
+	tests := []struct {
+		utilization, target, factor float64
+	}{
+		{0, 0.5, 1 - autoPricingMaxStep},
+		{0.25, 0.5, 1 - autoPricingMaxStep/2},
+		{0.5, 0.5, 1},
+		{0.75, 0.5, 1 + autoPricingMaxStep/2},
+		{1, 0.5, 1 + autoPricingMaxStep},
+		{1, 1, 1},
+	}
+	for _, test := range tests {
+		if f := pricingFactor(test.utilization, test.target); f != test.factor {
+			t.Errorf("pricingFactor(%v, %v): expected %v, got %v", test.utilization, test.target, test.factor, f)
+		}
+	}
+}
+
+// TestAdjustPrice checks that adjusted prices are kept between their floor
+// and ceiling, and that prices without a ceiling are not adjusted.
+func TestAdjustPrice(t *testing.T) {
+	price := types.NewCurrency64(100)
+	floor := types.NewCurrency64(95)
+	ceiling := types.NewCurrency64(105)
+	if p := adjustPrice(price, floor, ceiling, 1.02); !p.Equals(types.NewCurrency64(102)) {
+		t.Error("expected 102, got", p)
+	}
+	if p := adjustPrice(price, floor, ceiling, 0.5); !p.Equals(floor) {
+		t.Error("price was not kept above the floor:", p)
+	}
+	if p := adjustPrice(price, floor, ceiling, 2); !p.Equals(ceiling) {
+		t.Error("price was not kept below the ceiling:", p)
+	}
+	if p := adjustPrice(price, floor, types.ZeroCurrency, 2); !p.Equals(price) {
+		t.Error("price without a ceiling was adjusted:", p)
+	}
+}
+
+// TestMaterialChange checks that only changes larger than
+// autoPricingAnnounceThreshold are considered material.
+func TestMaterialChange(t *testing.T) {
+	prev := types.NewCurrency64(1000)
+	if materialChange(prev, types.NewCurrency64(1100)) || materialChange(prev, types.NewCurrency64(900)) {
+		t.Error("small change was considered material")
+	}
+	if !materialChange(prev, types.NewCurrency64(1300)) || !materialChange(prev, types.NewCurrency64(700)) {
+		t.Error("large change was not considered material")
+	}
+	if !materialChange(types.ZeroCurrency, prev) || materialChange(types.ZeroCurrency, types.ZeroCurrency) {
+		t.Error("changes from a zero price were not handled correctly")
+	}
+}
+
+// TestCheckAutoPricingSettings probes the validation of the automatic pricing
+// settings.
+func TestCheckAutoPricingSettings(t *testing.T) {
+	var settings modules.HostInternalSettings
+	if err := checkAutoPricingSettings(settings); err != nil {
+		t.Error("disabled auto-pricing was rejected:", err)
+	}
+	settings.AutoPricing = true
+	if err := checkAutoPricingSettings(settings); err != errAutoPricingTarget {
+		t.Error("expected errAutoPricingTarget, got", err)
+	}
+	settings.AutoPricingTargetUtilization = 1.5
+	if err := checkAutoPricingSettings(settings); err != errAutoPricingTarget {
+		t.Error("expected errAutoPricingTarget, got", err)
+	}
+	settings.AutoPricingTargetUtilization = 0.8
+	settings.StoragePriceFloor = types.NewCurrency64(10)
+	if err := checkAutoPricingSettings(settings); err != nil {
+		t.Error("floor without a ceiling was rejected:", err)
+	}
+	settings.StoragePriceCeiling = types.NewCurrency64(5)
+	if err := checkAutoPricingSettings(settings); err != errPriceFloorAboveCeiling {
+		t.Error("expected errPriceFloorAboveCeiling, got", err)
+	}
+}
+
+// TestHostAutoPricing checks that an empty host lowers its prices toward
+// their floors, records the changes, and persists the price history.
+func TestHostAutoPricing(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	ht, err := newHostTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+
+	// Invalid auto-pricing settings should be rejected.
+	settings := ht.host.InternalSettings()
+	settings.AutoPricing = true
+	err = ht.host.SetInternalSettings(settings)
+	if err == nil {
+		t.Fatal("auto-pricing was enabled without a target utilization")
+	}
+
+	// Enable auto-pricing for the storage price only.
+	startPrice := settings.MinStoragePrice
+	floor := startPrice.Div64(2)
+	settings.AutoPricingTargetUtilization = 0.5
+	settings.StoragePriceFloor = floor
+	settings.StoragePriceCeiling = startPrice.Mul64(2)
+	err = ht.host.SetInternalSettings(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The host is empty, so the storage price should drop with every
+	// adjustment until it reaches the floor.
+	ht.host.managedAdjustPrices()
+	is := ht.host.InternalSettings()
+	if is.MinStoragePrice.Cmp(startPrice) >= 0 {
+		t.Fatal("storage price was not lowered:", startPrice, is.MinStoragePrice)
+	}
+	if !is.MinContractPrice.Equals(settings.MinContractPrice) {
+		t.Fatal("contract price without a ceiling was adjusted")
+	}
+	for i := 0; i < 20; i++ {
+		ht.host.managedAdjustPrices()
+	}
+	is = ht.host.InternalSettings()
+	if !is.MinStoragePrice.Equals(floor) {
+		t.Fatal("storage price did not reach the floor:", floor, is.MinStoragePrice)
+	}
+
+	// The price history should end with the current prices.
+	history := ht.host.PriceHistory()
+	if len(history) == 0 {
+		t.Fatal("price changes were not recorded")
+	}
+	last := history[len(history)-1]
+	if !last.StoragePrice.Equals(floor) || last.Utilization != 0 {
+		t.Fatal("price history does not match the adjustment:", last)
+	}
+
+	// Reload the host and check that the history and prices persisted.
+	err = ht.host.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ht.host, err = New(ht.cs, ht.tpool, ht.wallet, "localhost:0", filepath.Join(ht.persistDir, modules.HostDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ht.host.PriceHistory()) != len(history) {
+		t.Fatal("price history was not persisted")
+	}
+	if !ht.host.InternalSettings().MinStoragePrice.Equals(floor) {
+		t.Fatal("adjusted storage price was not persisted")
+	}
+}
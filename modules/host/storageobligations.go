@@ -105,6 +105,22 @@ var (
 
 type storageObligationStatus uint64
 
+// String returns the name of the status, as reported by the API.
+func (sos storageObligationStatus) String() string {
+	switch sos {
+	case obligationUnresolved:
+		return modules.ObligationStatusUnresolved
+	case obligationRejected:
+		return modules.ObligationStatusRejected
+	case obligationSucceeded:
+		return modules.ObligationStatusSucceeded
+	case obligationFailed:
+		return modules.ObligationStatusFailed
+	default:
+		return "unknown"
+	}
+}
+
 // storageObligation contains all of the metadata related to a file contract
 // and the storage contained by the file contract.
 type storageObligation struct {
@@ -870,7 +886,20 @@ func (h *Host) StorageObligations() (sos []modules.StorageObligation) {
 				return build.ExtendErr("unable to unmarshal storage obligation:", err)
 			}
 			mso := modules.StorageObligation{
+				ObligationID:      so.id(),
+				FileSize:          so.fileSize(),
+				SectorCount:       uint64(len(so.SectorRoots)),
 				NegotiationHeight: so.NegotiationHeight,
+				ExpirationHeight:  so.expiration(),
+				ProofDeadline:     so.proofDeadline(),
+
+				ContractCost:             so.ContractCost,
+				PotentialDownloadRevenue: so.PotentialDownloadRevenue,
+				PotentialStorageRevenue:  so.PotentialStorageRevenue,
+				PotentialUploadRevenue:   so.PotentialUploadRevenue,
+				LockedCollateral:         so.LockedCollateral,
+				RiskedCollateral:         so.RiskedCollateral,
+				TransactionFeesAdded:     so.TransactionFeesAdded,
 
 				OriginConfirmed:     so.OriginConfirmed,
 				RevisionConstructed: so.RevisionConstructed,
@@ -878,6 +907,7 @@ func (h *Host) StorageObligations() (sos []modules.StorageObligation) {
 				ProofConstructed:    so.ProofConstructed,
 				ProofConfirmed:      so.ProofConfirmed,
 				ObligationStatus:    uint64(so.ObligationStatus),
+				Status:              so.ObligationStatus.String(),
 			}
 			sos = append(sos, mso)
 			return nil
@@ -275,6 +275,18 @@ func TestSingleSectorStorageObligationStack(t *testing.T) {
 		t.Fatal("revision transaction for storage obligation was not confirmed after a block was mined")
 	}
 
+	// The obligation should be reported with the details of the revision.
+	sos := ht.host.StorageObligations()
+	if len(sos) != 1 || sos[0].ObligationID != so.id() || sos[0].Status != modules.ObligationStatusUnresolved {
+		t.Fatal("storage obligation was not reported correctly:", sos)
+	}
+	if sos[0].FileSize != so.fileSize() || sos[0].SectorCount != 1 || sos[0].ExpirationHeight != so.expiration() || sos[0].ProofDeadline != so.proofDeadline() {
+		t.Fatal("storage obligation reported with the wrong size or deadlines:", sos[0])
+	}
+	if !sos[0].PotentialStorageRevenue.Equals(sectorCost) || !sos[0].LockedCollateral.Equals(so.LockedCollateral) {
+		t.Fatal("storage obligation reported with the wrong revenue or collateral:", sos[0])
+	}
+
 	// Mine until the host submits a storage proof.
 	for i := ht.host.blockHeight; i <= so.expiration()+resubmissionTimeout; i++ {
 		_, err := ht.miner.AddBlock()
@@ -343,6 +355,9 @@ func TestSingleSectorStorageObligationStack(t *testing.T) {
 	if !ht.host.financialMetrics.StorageRevenue.Equals(sectorCost) {
 		t.Fatal("the host should be reporting revenue after a successful storage proof")
 	}
+	if sos := ht.host.StorageObligations(); sos[0].Status != modules.ObligationStatusSucceeded || sos[0].SectorCount != 0 {
+		t.Fatal("finalized storage obligation was not reported correctly:", sos[0])
+	}
 }
 
 // TestMultiSectorObligationStack checks that the host correctly manages a
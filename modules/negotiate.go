@@ -252,6 +252,11 @@ type (
 		// which is the most recent.
 		RevisionNumber uint64 `json:"revisionnumber"`
 		Version        string `json:"version"`
+
+		// MaintenanceMode indicates that the host is draining its existing
+		// contracts, and will not form or renew any contracts. Older hosts do
+		// not send this field.
+		MaintenanceMode bool `json:"maintenancemode"`
 	}
 
 	// A RevisionAction is a description of an edit to be performed on a file
@@ -274,6 +279,55 @@ type (
 	}
 )
 
+// CompatHostExternalSettings is used to decode the HostExternalSettings that a
+// host sends in response to RPCSettings. The settings of older hosts end after
+// the Version field, in which case the fields that follow it are left at their
+// zero values. It is a separate type so that its UnmarshalSia method is not
+// promoted to the types that embed HostExternalSettings.
+type CompatHostExternalSettings HostExternalSettings
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (hes *CompatHostExternalSettings) UnmarshalSia(r io.Reader) error {
+	err := encoding.NewDecoder(r).DecodeAll(
+		&hes.AcceptingContracts,
+		&hes.MaxDownloadBatchSize,
+		&hes.MaxDuration,
+		&hes.MaxReviseBatchSize,
+		&hes.NetAddress,
+		&hes.RemainingStorage,
+		&hes.SectorSize,
+		&hes.TotalStorage,
+		&hes.UnlockHash,
+		&hes.WindowSize,
+		&hes.Collateral,
+		&hes.MaxCollateral,
+		&hes.ContractPrice,
+		&hes.DownloadBandwidthPrice,
+		&hes.StoragePrice,
+		&hes.UploadBandwidthPrice,
+		&hes.RevisionNumber,
+		&hes.Version,
+	)
+	if err != nil {
+		return err
+	}
+
+	// COMPATv1.3.0 - hosts that do not support maintenance mode do not send
+	// the MaintenanceMode field.
+	var maintenance [1]byte
+	_, err = io.ReadFull(r, maintenance[:])
+	if err == io.EOF {
+		hes.MaintenanceMode = false
+		return nil
+	} else if err != nil {
+		return err
+	} else if maintenance[0] > 1 {
+		return errors.New("boolean value was not 0 or 1")
+	}
+	hes.MaintenanceMode = maintenance[0] == 1
+	return nil
+}
+
 // ReadNegotiationAcceptance reads an accept/reject response from r (usually a
 // net.Conn). If the response is not AcceptResponse, ReadNegotiationAcceptance
 // returns the response as an error. If the response is StopResponse,
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -125,3 +126,56 @@ func TestNegotiationResponses(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// TestHostExternalSettingsCompat checks that host settings are decoded
+// correctly both with and without the fields that older hosts do not send,
+// and that the compat decoding does not leak into the types that embed
+// HostExternalSettings.
+func TestHostExternalSettingsCompat(t *testing.T) {
+	t.Parallel()
+
+	settings := HostExternalSettings{
+		AcceptingContracts: true,
+		NetAddress:         "foo.com:1234",
+		StoragePrice:       types.NewCurrency64(100),
+		RevisionNumber:     5,
+		Version:            "1.3.0",
+		MaintenanceMode:    true,
+	}
+	b := encoding.Marshal(settings)
+
+	// Decode the full settings.
+	var decoded HostExternalSettings
+	err := encoding.Unmarshal(b, (*CompatHostExternalSettings)(&decoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoding.Marshal(decoded), b) {
+		t.Fatal("settings do not match after decoding:", decoded, settings)
+	}
+
+	// Decode the settings of an older host by dropping the MaintenanceMode
+	// field.
+	decoded = HostExternalSettings{}
+	err = encoding.Unmarshal(b[:len(b)-1], (*CompatHostExternalSettings)(&decoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.MaintenanceMode || decoded.RevisionNumber != settings.RevisionNumber || !decoded.StoragePrice.Equals(settings.StoragePrice) || decoded.Version != settings.Version {
+		t.Fatal("settings of an older host were not decoded correctly:", decoded, settings)
+	}
+
+	// Truncated settings should still be rejected.
+	err = encoding.Unmarshal(b[:len(b)-2], (*CompatHostExternalSettings)(&decoded))
+	if err == nil {
+		t.Fatal("truncated settings were decoded")
+	}
+
+	// Types that embed the settings must not inherit the compat decoding.
+	if _, ok := interface{}(new(HostDBEntry)).(encoding.SiaUnmarshaler); ok {
+		t.Fatal("HostDBEntry has a custom decoder")
+	}
+	if _, ok := interface{}(new(HostExternalSettings)).(encoding.SiaUnmarshaler); ok {
+		t.Fatal("HostExternalSettings has a custom decoder")
+	}
+}
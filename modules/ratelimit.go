@@ -0,0 +1,161 @@
+package modules
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// rateLimitPacketSize is the largest number of bytes that a rate limited
+// connection reads or writes at once. Splitting large transfers into packets
+// allows the bandwidth to be shared fairly between connections, and allows
+// changes to the limits to take effect in the middle of a transfer.
+const rateLimitPacketSize = 1 << 14
+
+var errRateLimitedConnClosed = errors.New("rate limited connection was closed")
+
+type (
+	// A bandwidthLimit schedules the transfers in one direction. next is the
+	// time at which the next transfer may start; every transfer pushes it
+	// back by the time that the transfer takes at the limit.
+	bandwidthLimit struct {
+		bps  int64
+		next time.Time
+	}
+
+	// A RateLimit limits the combined bandwidth of every connection that is
+	// wrapped by it. Reads count towards the download limit and writes count
+	// towards the upload limit. A limit of 0 means that the bandwidth in
+	// that direction is not limited.
+	RateLimit struct {
+		download bandwidthLimit
+		upload   bandwidthLimit
+		mu       sync.Mutex
+	}
+
+	// A rateLimitedConn is a net.Conn whose reads and writes are delayed to
+	// stay within the limits of a RateLimit.
+	rateLimitedConn struct {
+		net.Conn
+		closed    chan struct{}
+		closeOnce sync.Once
+		rl        *RateLimit
+	}
+)
+
+// reserve schedules a transfer of n bytes and returns how long the caller has
+// to wait before the transfer may start.
+func (bl *bandwidthLimit) reserve(n int) time.Duration {
+	if bl.bps <= 0 || n <= 0 {
+		return 0
+	}
+	now := time.Now()
+	if bl.next.Before(now) {
+		bl.next = now
+	}
+	delay := bl.next.Sub(now)
+	bl.next = bl.next.Add(time.Duration(int64(n) * int64(time.Second) / bl.bps))
+	return delay
+}
+
+// NewRateLimit returns a RateLimit with the provided limits, in bytes per
+// second.
+func NewRateLimit(downloadBPS, uploadBPS int64) *RateLimit {
+	rl := new(RateLimit)
+	rl.SetLimits(downloadBPS, uploadBPS)
+	return rl
+}
+
+// Limits returns the current download and upload limits, in bytes per
+// second.
+func (rl *RateLimit) Limits() (downloadBPS, uploadBPS int64) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.download.bps, rl.upload.bps
+}
+
+// SetLimits changes the download and upload limits, in bytes per second. The
+// new limits apply to connections that are already open.
+func (rl *RateLimit) SetLimits(downloadBPS, uploadBPS int64) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.download = bandwidthLimit{bps: downloadBPS}
+	rl.upload = bandwidthLimit{bps: uploadBPS}
+}
+
+// Conn wraps c so that its bandwidth counts towards the limits of rl. A nil
+// RateLimit does not limit c.
+func (rl *RateLimit) Conn(c net.Conn) net.Conn {
+	if rl == nil {
+		return c
+	}
+	return &rateLimitedConn{
+		Conn:   c,
+		closed: make(chan struct{}),
+		rl:     rl,
+	}
+}
+
+// wait blocks for the provided duration, or until the connection is closed.
+func (rlc *rateLimitedConn) wait(d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-rlc.closed:
+		return errRateLimitedConnClosed
+	}
+}
+
+// Read implements the io.Reader interface. The bytes that were read are paid
+// for after the read, since their number is not known in advance.
+func (rlc *rateLimitedConn) Read(b []byte) (int, error) {
+	if len(b) > rateLimitPacketSize {
+		b = b[:rateLimitPacketSize]
+	}
+	n, err := rlc.Conn.Read(b)
+	rlc.rl.mu.Lock()
+	delay := rlc.rl.download.reserve(n)
+	rlc.rl.mu.Unlock()
+	if waitErr := rlc.wait(delay); waitErr != nil && err == nil {
+		err = waitErr
+	}
+	return n, err
+}
+
+// Write implements the io.Writer interface. Large writes are split into
+// packets that are each delayed according to the upload limit.
+func (rlc *rateLimitedConn) Write(b []byte) (int, error) {
+	var written int
+	for len(b) > 0 {
+		packet := b
+		if len(packet) > rateLimitPacketSize {
+			packet = packet[:rateLimitPacketSize]
+		}
+		rlc.rl.mu.Lock()
+		delay := rlc.rl.upload.reserve(len(packet))
+		rlc.rl.mu.Unlock()
+		if err := rlc.wait(delay); err != nil {
+			return written, err
+		}
+		n, err := rlc.Conn.Write(packet)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
+
+// Close closes the underlying connection and interrupts any reads or writes
+// that are waiting on the rate limit.
+func (rlc *rateLimitedConn) Close() error {
+	rlc.closeOnce.Do(func() { close(rlc.closed) })
+	return rlc.Conn.Close()
+}
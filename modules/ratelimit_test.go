@@ -0,0 +1,71 @@
+package modules
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestRateLimit checks that a RateLimit delays the writes and reads of the
+// connections that it wraps, and that its limits can be changed.
+func TestRateLimit(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rl := NewRateLimit(0, 0)
+	c1, c2 := net.Pipe()
+	conn := rl.Conn(c1)
+	defer conn.Close()
+	go io.Copy(ioutil.Discard, c2)
+
+	// Without limits, writes are not delayed.
+	data := make([]byte, 4*rateLimitPacketSize)
+	start := time.Now()
+	if _, err := conn.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatal("unlimited write took", elapsed)
+	}
+
+	// At 8 packets per second, the last of 4 packets may start after 375ms.
+	rl.SetLimits(0, 8*rateLimitPacketSize)
+	if down, up := rl.Limits(); down != 0 || up != 8*rateLimitPacketSize {
+		t.Fatal("wrong limits:", down, up)
+	}
+	start = time.Now()
+	if _, err := conn.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 375*time.Millisecond {
+		t.Fatal("limited write took only", elapsed)
+	}
+
+	// Reads count towards the download limit.
+	rl.SetLimits(8*rateLimitPacketSize, 0)
+	go c2.Write(data)
+	start = time.Now()
+	if _, err := io.ReadFull(conn, make([]byte, len(data))); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 375*time.Millisecond {
+		t.Fatal("limited read took only", elapsed)
+	}
+
+	// Closing the connection interrupts a write that is waiting on the
+	// limit.
+	rl.SetLimits(0, 1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		conn.Close()
+	}()
+	start = time.Now()
+	if _, err := conn.Write(data); err == nil {
+		t.Fatal("expected write to a closed connection to fail")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatal("closing the connection did not interrupt the write")
+	}
+}
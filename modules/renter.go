@@ -47,14 +47,86 @@ type Allowance struct {
 	RenewWindow types.BlockHeight `json:"renewwindow"`
 }
 
+// HostDownloadStats contains the rolling download statistics that the renter
+// keeps for a host. Latency is the average time it takes to
+// connect to the host before a piece can be requested, and Throughput is the
+// average rate, in bytes per second, at which the host sends piece data.
+// Stalls counts the piece downloads that took so long that an extra piece was
+// requested from another host, and Failures counts the piece downloads that
+// failed.
+type HostDownloadStats struct {
+	Latency    time.Duration `json:"latency"`
+	Throughput uint64        `json:"throughput"`
+	Samples    uint64        `json:"samples"`
+	Stalls     uint64        `json:"stalls"`
+	Failures   uint64        `json:"failures"`
+}
+
+// RenterSpending is a breakdown of the funds that the renter spent on the
+// contracts formed in one allowance period. A contract belongs to the period
+// in which it was formed or renewed.
+type RenterSpending struct {
+	// StartHeight is the height at which the period began, and EndHeight is
+	// the height at which the period ends. The end height of the current
+	// period is zero if no allowance is set.
+	StartHeight types.BlockHeight `json:"startheight"`
+	EndHeight   types.BlockHeight `json:"endheight"`
+
+	// Allowance is the allowance funds of the period.
+	Allowance types.Currency `json:"allowance"`
+
+	// Fees paid to the hosts, to the miners and to the siafund pool.
+	ContractFees    types.Currency `json:"contractfees"`
+	TransactionFees types.Currency `json:"transactionfees"`
+	SiafundFees     types.Currency `json:"siafundfees"`
+
+	// Contract funds that were spent on storage, uploads and downloads.
+	DownloadSpending types.Currency `json:"downloadspending"`
+	StorageSpending  types.Currency `json:"storagespending"`
+	UploadSpending   types.Currency `json:"uploadspending"`
+
+	// Unspent is the part of the allowance that was not put into contracts.
+	// Refundable is the part of the contract funds that is returned to the
+	// renter when the contracts are resolved, including the funds of
+	// contracts that have been resolved already.
+	Unspent    types.Currency `json:"unspent"`
+	Refundable types.Currency `json:"refundable"`
+}
+
+// DownloadStatus describes the state of a download.
+type DownloadStatus string
+
+var (
+	// DownloadStatusActive is the status of a download that is in progress.
+	DownloadStatusActive = DownloadStatus("active")
+
+	// DownloadStatusPaused is the status of a download that has been paused,
+	// or that was interrupted by a shutdown and has not been resumed yet.
+	DownloadStatusPaused = DownloadStatus("paused")
+
+	// DownloadStatusCancelled is the status of a download that has been
+	// cancelled.
+	DownloadStatusCancelled = DownloadStatus("cancelled")
+
+	// DownloadStatusFailed is the status of a download that stopped because
+	// of an error.
+	DownloadStatusFailed = DownloadStatus("failed")
+
+	// DownloadStatusComplete is the status of a download that has finished
+	// successfully.
+	DownloadStatusComplete = DownloadStatus("complete")
+)
+
 // DownloadInfo provides information about a file that has been requested for
 // download.
 type DownloadInfo struct {
+	ID          string         `json:"id"`
 	SiaPath     string         `json:"siapath"`
 	Destination DownloadWriter `json:"destination"`
 	Filesize    uint64         `json:"filesize"`
 	Received    uint64         `json:"received"`
 	StartTime   time.Time      `json:"starttime"`
+	Status      DownloadStatus `json:"status"`
 	Error       string         `json:"error"`
 }
 
@@ -73,15 +145,50 @@ type FileUploadParams struct {
 	ErasureCode ErasureCoder
 }
 
+// DirectoryInfo provides information about a directory in the renter's
+// siapath tree. Sizes and file counts are aggregated recursively over every
+// file beneath the directory.
+type DirectoryInfo struct {
+	SiaPath       string `json:"siapath"`
+	AggregateSize uint64 `json:"aggregatesize"`
+	NumFiles      uint64 `json:"numfiles"`
+	NumSubDirs    uint64 `json:"numsubdirs"`
+
+	// Health is the health of the least healthy file beneath the directory,
+	// where the health of a file is the number of additional pieces its least
+	// healthy chunk can lose before the file becomes unrecoverable.
+	// MinRedundancy is the redundancy of the least redundant file beneath the
+	// directory. Directories that contain no files report a Health of 0 and a
+	// MinRedundancy of -1.
+	Health        int     `json:"health"`
+	MinRedundancy float64 `json:"minredundancy"`
+}
+
 // FileInfo provides information about a file.
 type FileInfo struct {
-	SiaPath        string            `json:"siapath"`
-	Filesize       uint64            `json:"filesize"`
-	Available      bool              `json:"available"`
-	Renewing       bool              `json:"renewing"`
-	Redundancy     float64           `json:"redundancy"`
-	UploadProgress float64           `json:"uploadprogress"`
-	Expiration     types.BlockHeight `json:"expiration"`
+	SiaPath          string            `json:"siapath"`
+	Filesize         uint64            `json:"filesize"`
+	Available        bool              `json:"available"`
+	Renewing         bool              `json:"renewing"`
+	Health           int               `json:"health"`
+	Redundancy       float64           `json:"redundancy"`
+	TargetRedundancy float64           `json:"targetredundancy"`
+	ErasureCoder     string            `json:"erasurecoder"`
+	UploadProgress   float64           `json:"uploadprogress"`
+	Expiration       types.BlockHeight `json:"expiration"`
+	ReadOnly         bool              `json:"readonly"`
+}
+
+// SharedFileInfo describes a file that was loaded from a '.sia' file. The
+// availability and redundancy of the file are computed from the hosts that
+// the hostdb considered reachable when the file was loaded.
+type SharedFileInfo struct {
+	SiaPath          string  `json:"siapath"`
+	OriginalSiaPath  string  `json:"originalsiapath"`
+	Available        bool    `json:"available"`
+	Redundancy       float64 `json:"redundancy"`
+	ReachableHosts   int     `json:"reachablehosts"`
+	UnreachableHosts int     `json:"unreachablehosts"`
 }
 
 // A HostDBEntry represents one host entry in the Renter's host DB. It
@@ -111,6 +218,38 @@ type HostDBEntry struct {
 	PublicKey types.SiaPublicKey `json:"publickey"`
 }
 
+// HostDBFilterMode determines how the hostdb's filter is applied when hosts
+// are selected for contracts.
+type HostDBFilterMode string
+
+var (
+	// HostDBFilterDisabled is the filter mode in which every host may be
+	// selected.
+	HostDBFilterDisabled = HostDBFilterMode("disable")
+
+	// HostDBFilterBlacklist is the filter mode in which the hosts that match
+	// the filter are never selected.
+	HostDBFilterBlacklist = HostDBFilterMode("blacklist")
+
+	// HostDBFilterWhitelist is the filter mode in which only the hosts that
+	// match the filter are selected.
+	HostDBFilterWhitelist = HostDBFilterMode("whitelist")
+)
+
+// HostDBFilter describes the hosts that the hostdb's filter matches. A host
+// matches if its public key is listed, or if one of the IP addresses that its
+// NetAddress resolved to when it was last scanned lies within one of the
+// subnets, given in CIDR notation, or within one of the countries, given as
+// two-letter ISO 3166 codes. Countries are looked up in the GeoIP database
+// at the absolute path GeoIPDatabase, which must be provided by the operator.
+type HostDBFilter struct {
+	Mode          HostDBFilterMode     `json:"filtermode"`
+	PublicKeys    []types.SiaPublicKey `json:"hosts"`
+	Subnets       []string             `json:"subnets"`
+	Countries     []string             `json:"countries"`
+	GeoIPDatabase string               `json:"geoipdatabase"`
+}
+
 // HostDBScan represents a single scan event.
 type HostDBScan struct {
 	Timestamp time.Time `json:"timestamp"`
@@ -138,6 +277,49 @@ type HostScoreBreakdown struct {
 	VersionAdjustment          float64 `json:"versionadjustment"`
 }
 
+// HostScoringProfile adjusts how the renter scores hosts. Each adjustment of
+// the HostScoreBreakdown is raised to the power of its exponent: an exponent
+// of 1 keeps the default weighting, a larger exponent makes the adjustment
+// more important, and an exponent of 0 ignores it. At least one exponent must
+// be nonzero.
+//
+// Hosts that exceed one of the price ceilings, or that fall short of the
+// minimum uptime or version, get the lowest possible score and are not
+// selected for new contracts. Ceilings and minimums that are zero are not
+// enforced. Prices are in hastings, per byte per block for storage and per
+// byte for bandwidth.
+type HostScoringProfile struct {
+	AgeExponent              float64 `json:"ageexponent"`
+	CollateralExponent       float64 `json:"collateralexponent"`
+	InteractionExponent      float64 `json:"interactionexponent"`
+	PriceExponent            float64 `json:"priceexponent"`
+	StorageRemainingExponent float64 `json:"storageremainingexponent"`
+	UptimeExponent           float64 `json:"uptimeexponent"`
+	VersionExponent          float64 `json:"versionexponent"`
+
+	MaxContractPrice          types.Currency `json:"maxcontractprice"`
+	MaxDownloadBandwidthPrice types.Currency `json:"maxdownloadbandwidthprice"`
+	MaxStoragePrice           types.Currency `json:"maxstorageprice"`
+	MaxUploadBandwidthPrice   types.Currency `json:"maxuploadbandwidthprice"`
+
+	// MinUptime is the minimum fraction of time, between 0 and 1, that the
+	// host has been online since it was first scanned.
+	MinUptime  float64 `json:"minuptime"`
+	MinVersion string  `json:"minversion"`
+}
+
+// DefaultHostScoringProfile is the HostScoringProfile that weights every
+// adjustment normally and enforces no ceilings or minimums.
+var DefaultHostScoringProfile = HostScoringProfile{
+	AgeExponent:              1,
+	CollateralExponent:       1,
+	InteractionExponent:      1,
+	PriceExponent:            1,
+	StorageRemainingExponent: 1,
+	UptimeExponent:           1,
+	VersionExponent:          1,
+}
+
 // RenterPriceEstimation contains a bunch of files estimating the costs of
 // various operations on the network.
 type RenterPriceEstimation struct {
@@ -158,6 +340,42 @@ type RenterPriceEstimation struct {
 // RenterSettings control the behavior of the Renter.
 type RenterSettings struct {
 	Allowance Allowance `json:"allowance"`
+
+	// ChunkCacheSize is the maximum number of bytes of disk space used to
+	// cache recently downloaded chunks. A ChunkCacheSize of 0 disables the
+	// cache.
+	ChunkCacheSize uint64 `json:"chunkcachesize"`
+
+	// MaxDownloadSpeed and MaxUploadSpeed limit the combined bandwidth, in
+	// bytes per second, of all the renter's connections to hosts. A limit of
+	// 0 means that the bandwidth is not limited.
+	MaxDownloadSpeed int64 `json:"maxdownloadspeed"`
+	MaxUploadSpeed   int64 `json:"maxuploadspeed"`
+
+	// MaxUploadMemory is the maximum number of bytes of memory used to
+	// erasure code, encrypt and upload chunks. New uploads wait once the
+	// limit is reached. A MaxUploadMemory of 0 selects the default limit.
+	MaxUploadMemory uint64 `json:"maxuploadmemory"`
+
+	// ScoringProfile adjusts how hosts are scored when selecting hosts for
+	// new contracts.
+	ScoringProfile HostScoringProfile `json:"scoringprofile"`
+
+	// IPv4SubnetBits and IPv6SubnetBits are the prefix lengths of the IP
+	// subnets within which at most one host is used for contracts. A prefix
+	// length of 0 disables the check for that type of address.
+	IPv4SubnetBits int `json:"ipv4subnetbits"`
+	IPv6SubnetBits int `json:"ipv6subnetbits"`
+}
+
+// RenterChunkCacheMetrics reports on the effectiveness of the renter's cache
+// of downloaded chunks. Hits and Misses count the chunk lookups made by
+// downloads since the renter was started.
+type RenterChunkCacheMetrics struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	Chunks uint64 `json:"chunks"`
+	Size   uint64 `json:"size"`
 }
 
 // HostDBScans represents a sortable slice of scans.
@@ -205,6 +423,26 @@ func (mrs *MerkleRootSet) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// ContractProofStatus describes how a file contract was resolved on the
+// blockchain.
+type ContractProofStatus string
+
+var (
+	// ContractProofPending is the proof status of a contract whose proof
+	// window has not closed yet.
+	ContractProofPending = ContractProofStatus("pending")
+
+	// ContractProofValid is the proof status of a contract for which the
+	// host submitted a valid storage proof, paying out the valid proof
+	// outputs.
+	ContractProofValid = ContractProofStatus("valid")
+
+	// ContractProofMissed is the proof status of a contract whose proof
+	// window closed without a storage proof, paying out the missed proof
+	// outputs.
+	ContractProofMissed = ContractProofStatus("missed")
+)
+
 // A RenterContract contains all the metadata necessary to revise or renew a
 // file contract. See `api.RenterContract` for field information.
 type RenterContract struct {
@@ -249,6 +487,19 @@ type RenterContract struct {
 	GoodForRenew  bool
 	GoodForUpload bool
 
+	// Manual indicates that the contract was formed or renewed by the user
+	// rather than by the allowance. Contract maintenance does not renew,
+	// replace, or count the funds of manual contracts. Canceled indicates
+	// that the user has canceled the contract; it will not be used for
+	// uploading or renewed, but its data can be downloaded until it expires.
+	Manual   bool
+	Canceled bool
+
+	// ProofStatus indicates whether the host submitted a valid storage proof
+	// for the contract, or whether the missed proof outputs were paid out
+	// instead. It is only resolved after the contract has expired.
+	ProofStatus ContractProofStatus
+
 	// PreviousContracts contains the list of contracts which were previously
 	// rewned **for the same billing cylce**. This is not a full history of the
 	// contract line, but only a history within the billing cycle. The primary
@@ -273,6 +524,22 @@ func (rc *RenterContract) RenterFunds() types.Currency {
 	return rc.LastRevision.NewValidProofOutputs[0].Value
 }
 
+// RenterPayout returns the funds that were returned to the renter when the
+// contract was resolved. It is zero while the proof status is pending.
+func (rc *RenterContract) RenterPayout() types.Currency {
+	switch rc.ProofStatus {
+	case ContractProofValid:
+		if len(rc.LastRevision.NewValidProofOutputs) > 0 {
+			return rc.LastRevision.NewValidProofOutputs[0].Value
+		}
+	case ContractProofMissed:
+		if len(rc.LastRevision.NewMissedProofOutputs) > 0 {
+			return rc.LastRevision.NewMissedProofOutputs[0].Value
+		}
+	}
+	return types.ZeroCurrency
+}
+
 // A Renter uploads, tracks, repairs, and downloads a set of files for the
 // user.
 type Renter interface {
@@ -283,19 +550,50 @@ type Renter interface {
 	// AllHosts returns the full list of hosts known to the renter.
 	AllHosts() []HostDBEntry
 
+	// CancelContract cancels the contract with the given ID. The contract
+	// is no longer used for uploading or renewed, but its data can be
+	// downloaded until it expires.
+	CancelContract(id types.FileContractID) error
+
+	// CancelDownload cancels the download with the given ID. Paused
+	// downloads can be cancelled as well.
+	CancelDownload(id string) error
+
+	// ChunkCacheMetrics reports the hits, misses and size of the cache of
+	// downloaded chunks.
+	ChunkCacheMetrics() RenterChunkCacheMetrics
+
 	// Close closes the Renter.
 	Close() error
 
 	// Contracts returns the contracts formed by the renter.
 	Contracts() []RenterContract
 
+	// CreateBackup writes an archive of the renter's files and contracts to
+	// dst, encrypted with the provided password.
+	CreateBackup(dst string, password string) error
+
+	// CreateDir creates an empty directory at the provided siapath, along
+	// with any missing parent directories.
+	CreateDir(siaPath string) error
+
 	// CurrentPeriod returns the height at which the current allowance period
 	// began.
 	CurrentPeriod() types.BlockHeight
 
+	// DeleteDir deletes a directory and, recursively, every file and
+	// directory beneath it.
+	DeleteDir(siaPath string) error
+
 	// DeleteFile deletes a file entry from the renter.
 	DeleteFile(path string) error
 
+	// DirList returns information on the directory at the provided siapath
+	// and its direct children. The first DirectoryInfo returned describes the
+	// requested directory itself. The empty siapath refers to the root
+	// directory.
+	DirList(siaPath string) ([]DirectoryInfo, []FileInfo, error)
+
 	// Download performs a download according to the parameters passed, including
 	// downloads of `offset` and `length` type.
 	Download(params RenterDownloadParameters) error
@@ -303,27 +601,66 @@ type Renter interface {
 	// DownloadQueue lists all the files that have been scheduled for download.
 	DownloadQueue() []DownloadInfo
 
+	// DownloadStats returns the download statistics of the hosts that the
+	// renter has downloaded from, keyed by host public key.
+	DownloadStats() map[string]HostDownloadStats
+
+	// ExpiredContracts returns the contracts that have expired or that have
+	// been renewed, along with the outcome of their storage proofs.
+	ExpiredContracts() []RenterContract
+
 	// FileList returns information on all of the files stored by the renter.
 	FileList() []FileInfo
 
+	// FormContract forms a manual contract with the host that has the given
+	// public key. Manual contracts are not renewed or replaced by contract
+	// maintenance, and are not paid for by the allowance. The hostdb's filter
+	// and subnet rules do not apply to them.
+	FormContract(hostKey types.SiaPublicKey, funds types.Currency, endHeight types.BlockHeight) (RenterContract, error)
+
 	// Host provides the DB entry and score breakdown for the requested host.
 	Host(pk types.SiaPublicKey) (HostDBEntry, bool)
 
+	// HostDBFilter returns the hostdb's filter.
+	HostDBFilter() HostDBFilter
+
 	// LoadSharedFiles loads a '.sia' file into the renter. A .sia file may
-	// contain multiple files. The paths of the added files are returned.
-	LoadSharedFiles(source string) ([]string, error)
+	// contain multiple files, which are loaded read-only. Files whose path is
+	// already in use are renamed. The availability of each added file is
+	// returned.
+	LoadSharedFiles(source string) ([]SharedFileInfo, error)
 
 	// LoadSharedFilesAscii loads an ASCII-encoded '.sia' file into the
 	// renter.
-	LoadSharedFilesAscii(asciiSia string) ([]string, error)
+	LoadSharedFilesAscii(asciiSia string) ([]SharedFileInfo, error)
+
+	// PauseDownload pauses the download with the given ID. Only downloads to
+	// a file on disk can be paused.
+	PauseDownload(id string) error
 
 	// PriceEstimation estimates the cost in siacoins of performing various
 	// storage and data operations.
 	PriceEstimation() RenterPriceEstimation
 
+	// RenameDir changes the path of a directory, moving every file and
+	// directory beneath it.
+	RenameDir(siaPath, newSiaPath string) error
+
 	// RenameFile changes the path of a file.
 	RenameFile(path, newPath string) error
 
+	// RenewContract renews the contract with the given ID, adding funds and
+	// extending it to the given end height.
+	RenewContract(id types.FileContractID, funds types.Currency, endHeight types.BlockHeight) (RenterContract, error)
+
+	// RestoreBackup restores the files and contracts in an archive created
+	// by CreateBackup.
+	RestoreBackup(src string, password string) error
+
+	// ResumeDownload resumes a paused download from its last completed
+	// chunk.
+	ResumeDownload(id string) error
+
 	// EstimateHostScore will return the score for a host with the provided
 	// settings, assuming perfect age and uptime adjustments
 	EstimateHostScore(entry HostDBEntry) HostScoreBreakdown
@@ -332,6 +669,15 @@ type Renter interface {
 	// hostdb's weighting algorithm.
 	ScoreBreakdown(entry HostDBEntry) HostScoreBreakdown
 
+	// SetFileRedundancy changes the target redundancy of a file by replacing
+	// its erasure code with a code of the same type that uses the provided
+	// number of pieces. The number of data pieces cannot be changed.
+	SetFileRedundancy(siaPath string, dataPieces, parityPieces int) error
+
+	// SetHostDBFilter replaces the hostdb's filter. Contracts with hosts that
+	// the new filter excludes are no longer renewed or used for uploads.
+	SetHostDBFilter(HostDBFilter) error
+
 	// Settings returns the Renter's current settings.
 	Settings() RenterSettings
 
@@ -344,8 +690,29 @@ type Renter interface {
 	// ShareFilesAscii creates an ASCII-encoded '.sia' file.
 	ShareFilesAscii(paths []string) (asciiSia string, err error)
 
+	// Spending returns a breakdown of the renter's spending in the current
+	// allowance period, and in each past allowance period in chronological
+	// order.
+	Spending() (current RenterSpending, past []RenterSpending)
+
+	// Streamer returns the name of the file at siaPath along with a Streamer
+	// over its contents.
+	Streamer(siaPath string) (string, Streamer, error)
+
 	// Upload uploads a file using the input parameters.
 	Upload(FileUploadParams) error
+
+	// UploadStreamFromReader uploads a file using data read from reader
+	// instead of a file on disk. The Source of the parameters is ignored. The
+	// call blocks until every chunk of the file is recoverable from the
+	// network.
+	UploadStreamFromReader(up FileUploadParams, reader io.Reader) error
+}
+
+// Streamer is an io.ReadSeeker over the contents of a file stored by the
+// renter. Only the parts of the file that are read are downloaded.
+type Streamer interface {
+	io.ReadSeeker
 }
 
 // RenterDownloadParameters defines the parameters passed to the Renter's
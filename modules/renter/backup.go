@@ -0,0 +1,186 @@
+package renter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/persist"
+	"github.com/NebulousLabs/fastrand"
+)
+
+var (
+	// ErrBadBackup is returned when a file is not a renter backup.
+	ErrBadBackup = errors.New("not a renter backup")
+
+	// errBackupPassword is returned when a backup cannot be decrypted, which
+	// means that the password is wrong or that the backup was corrupted.
+	errBackupPassword = errors.New("wrong password, or the backup is corrupted")
+
+	backupHeader  = [15]byte{'S', 'i', 'a', ' ', 'R', 'e', 'n', 't', 'e', 'r', ' ', 'B', 'k', 'u', 'p'}
+	backupVersion = "1.0"
+)
+
+// backupData is the content of a renter backup. Files holds every file of
+// the renter in the .sia format, and Contractor holds the contractor's
+// contracts, which include the keys needed to revise them. ReadOnly lists the
+// files that were loaded from a .sia file shared by another renter.
+type backupData struct {
+	Files       []byte                 `json:"files"`
+	Directories []string               `json:"directories"`
+	Tracking    map[string]trackedFile `json:"tracking"`
+	ReadOnly    []string               `json:"readonly"`
+	Contractor  []byte                 `json:"contractor"`
+}
+
+// backupKey derives the key that encrypts a backup from its password.
+func backupKey(salt crypto.Hash, password string) crypto.TwofishKey {
+	return crypto.TwofishKey(crypto.HashAll(salt, password))
+}
+
+// CreateBackup writes an encrypted archive of the renter's files and
+// contracts to dst. Restoring the archive with RestoreBackup, even on a
+// different node, allows the files to be downloaded again.
+func (r *Renter) CreateBackup(dst string, password string) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+
+	// Collect the files and tracking data.
+	var data backupData
+	files := new(bytes.Buffer)
+	lockID := r.mu.RLock()
+	fs := make([]*file, 0, len(r.files))
+	for _, f := range r.files {
+		fs = append(fs, f)
+	}
+	err := shareFiles(fs, files)
+	data.Directories = r.dirNames()
+	data.Tracking = make(map[string]trackedFile, len(r.tracking))
+	for name, tf := range r.tracking {
+		data.Tracking[name] = tf
+	}
+	data.ReadOnly = r.readOnlyNames()
+	r.mu.RUnlock(lockID)
+	if err != nil {
+		return err
+	}
+	data.Files = files.Bytes()
+
+	// Collect the contracts.
+	contracts := new(bytes.Buffer)
+	if err := r.hostContractor.Backup(contracts); err != nil {
+		return err
+	}
+	data.Contractor = contracts.Bytes()
+
+	// Compress and encrypt the archive.
+	plaintext := new(bytes.Buffer)
+	zip, _ := gzip.NewWriterLevel(plaintext, gzip.BestCompression)
+	if err := json.NewEncoder(zip).Encode(data); err != nil {
+		return err
+	}
+	if err := zip.Close(); err != nil {
+		return err
+	}
+	var salt crypto.Hash
+	fastrand.Read(salt[:])
+	ciphertext := backupKey(salt, password).EncryptBytes(plaintext.Bytes())
+
+	// Write the archive.
+	handle, err := persist.NewSafeFile(dst)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+	err = encoding.NewEncoder(handle).EncodeAll(backupHeader, backupVersion, salt)
+	if err != nil {
+		return err
+	}
+	if _, err := handle.Write(ciphertext); err != nil {
+		return err
+	}
+	return handle.CommitSync()
+}
+
+// RestoreBackup restores the files and contracts in a backup created by
+// CreateBackup. Files and contracts that the renter already has are kept as
+// they are.
+func (r *Renter) RestoreBackup(src string, password string) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+
+	// Read and decrypt the archive.
+	handle, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+	var header [15]byte
+	var version string
+	var salt crypto.Hash
+	err = encoding.NewDecoder(handle).DecodeAll(&header, &version, &salt)
+	if err != nil || header != backupHeader {
+		return ErrBadBackup
+	} else if version != backupVersion {
+		return ErrIncompatible
+	}
+	ciphertext, err := ioutil.ReadAll(handle)
+	if err != nil {
+		return err
+	}
+	plaintext, err := backupKey(salt, password).DecryptBytes(ciphertext)
+	if err != nil {
+		return errBackupPassword
+	}
+	unzip, err := gzip.NewReader(bytes.NewReader(plaintext))
+	if err != nil {
+		return err
+	}
+	var data backupData
+	if err := json.NewDecoder(unzip).Decode(&data); err != nil {
+		return err
+	}
+	files, err := readSharedFiles(bytes.NewReader(data.Files))
+	if err != nil {
+		return err
+	}
+
+	// Restore the contracts before the files that refer to them.
+	if err := r.hostContractor.Restore(bytes.NewReader(data.Contractor)); err != nil {
+		return err
+	}
+
+	readOnly := make(map[string]bool, len(data.ReadOnly))
+	for _, name := range data.ReadOnly {
+		readOnly[name] = true
+	}
+
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	for _, f := range files {
+		if _, exists := r.files[f.name]; exists {
+			continue
+		}
+		f.readOnly = readOnly[f.name]
+		r.addFile(f)
+		if tf, tracked := data.Tracking[f.name]; tracked {
+			r.tracking[f.name] = tf
+		}
+		if err := r.saveFile(f); err != nil {
+			return err
+		}
+	}
+	for _, name := range data.Directories {
+		r.createDir(name)
+	}
+	return r.saveSync()
+}
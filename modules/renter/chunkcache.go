@@ -0,0 +1,190 @@
+package renter
+
+import (
+	"container/list"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+const (
+	// chunkCacheDir is the directory within the renter's persist directory
+	// that holds the chunk cache. It is a reserved siapath, so no .sia files
+	// can be stored in it.
+	chunkCacheDir = "chunkcache"
+)
+
+type (
+	// chunkCacheKey identifies a chunk of a file. The master key of a file
+	// does not change when the file is renamed.
+	chunkCacheKey struct {
+		masterKey crypto.TwofishKey
+		index     uint64
+	}
+
+	// chunkCacheEntry is an element of the chunk cache's LRU list.
+	chunkCacheEntry struct {
+		key  chunkCacheKey
+		size uint64
+	}
+
+	// A chunkCache is an on-disk LRU cache of the decrypted, recovered
+	// chunks of downloaded files. It allows chunks that were downloaded
+	// recently to be served without fetching them from the hosts again. The
+	// cache is cleared when the renter starts.
+	chunkCache struct {
+		// budget is the maximum number of bytes that the cached chunks may
+		// occupy on disk, and size is the number of bytes that they occupy
+		// now. A budget of 0 disables the cache.
+		budget uint64
+		size   uint64
+
+		// entries maps the cached chunks to their elements of lru, which is
+		// ordered from most to least recently used.
+		entries map[chunkCacheKey]*list.Element
+		lru     *list.List
+
+		hits   uint64
+		misses uint64
+
+		dir string
+		mu  sync.Mutex
+	}
+)
+
+// newChunkCache returns an empty chunk cache that stores its chunks in dir.
+// Any chunks left in dir by a previous run are removed. Other files in dir are
+// left untouched.
+func newChunkCache(dir string, budget uint64) (*chunkCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() || !isChunkCacheName(fi.Name()) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, fi.Name())); err != nil {
+			return nil, err
+		}
+	}
+	return &chunkCache{
+		budget:  budget,
+		entries: make(map[chunkCacheKey]*list.Element),
+		lru:     list.New(),
+		dir:     dir,
+	}, nil
+}
+
+// path returns the location of the cached chunk on disk. The name is derived
+// from the key so that the master key of the file is not exposed.
+func (cc *chunkCache) path(key chunkCacheKey) string {
+	return filepath.Join(cc.dir, crypto.HashAll(key.masterKey, key.index).String())
+}
+
+// isChunkCacheName returns whether name is the name of a cached chunk, which
+// is the hex encoding of a hash.
+func isChunkCacheName(name string) bool {
+	var h crypto.Hash
+	return h.LoadString(name) == nil
+}
+
+// remove removes a chunk from the cache. The cache's lock must be held.
+func (cc *chunkCache) remove(e *list.Element) {
+	entry := cc.lru.Remove(e).(chunkCacheEntry)
+	delete(cc.entries, entry.key)
+	cc.size -= entry.size
+	os.Remove(cc.path(entry.key))
+}
+
+// evict removes the least recently used chunks until the cache fits within
+// its budget. The cache's lock must be held.
+func (cc *chunkCache) evict() {
+	for cc.size > cc.budget {
+		cc.remove(cc.lru.Back())
+	}
+}
+
+// add adds the data of a recovered chunk to the cache. Chunks that do not fit
+// in the cache are ignored.
+func (cc *chunkCache) add(key chunkCacheKey, data []byte) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	size := uint64(len(data))
+	if _, exists := cc.entries[key]; exists || size > cc.budget {
+		return
+	}
+	if err := ioutil.WriteFile(cc.path(key), data, 0600); err != nil {
+		os.Remove(cc.path(key))
+		return
+	}
+	cc.entries[key] = cc.lru.PushFront(chunkCacheEntry{key: key, size: size})
+	cc.size += size
+	cc.evict()
+}
+
+// get returns the data of a cached chunk, or false if the chunk is not in
+// the cache.
+func (cc *chunkCache) get(key chunkCacheKey) ([]byte, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	// Lookups are not counted while the cache is disabled.
+	if cc.budget == 0 {
+		return nil, false
+	}
+	e, exists := cc.entries[key]
+	if !exists {
+		cc.misses++
+		return nil, false
+	}
+	data, err := ioutil.ReadFile(cc.path(key))
+	if err != nil || uint64(len(data)) != e.Value.(chunkCacheEntry).size {
+		cc.remove(e)
+		cc.misses++
+		return nil, false
+	}
+	cc.lru.MoveToFront(e)
+	cc.hits++
+	return data, true
+}
+
+// purge removes every cached chunk of the file with the given master key.
+func (cc *chunkCache) purge(masterKey crypto.TwofishKey) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	for key, e := range cc.entries {
+		if key.masterKey == masterKey {
+			cc.remove(e)
+		}
+	}
+}
+
+// setBudget changes the budget of the cache, evicting chunks if the cache no
+// longer fits.
+func (cc *chunkCache) setBudget(budget uint64) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.budget = budget
+	cc.evict()
+}
+
+// metrics returns the hit and miss counts and the size of the cache.
+func (cc *chunkCache) metrics() modules.RenterChunkCacheMetrics {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return modules.RenterChunkCacheMetrics{
+		Hits:   cc.hits,
+		Misses: cc.misses,
+		Chunks: uint64(len(cc.entries)),
+		Size:   cc.size,
+	}
+}
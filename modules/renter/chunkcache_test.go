@@ -0,0 +1,159 @@
+package renter
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestChunkCache probes the eviction and accounting of the chunk cache.
+func TestChunkCache(t *testing.T) {
+	cc, err := newChunkCache(build.TempDir("renter", t.Name()), 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := func(i uint64) chunkCacheKey { return chunkCacheKey{index: i} }
+
+	// Add three chunks of 100 bytes. The first one should be evicted.
+	chunks := make([][]byte, 3)
+	for i := range chunks {
+		chunks[i] = fastrand.Bytes(100)
+		cc.add(key(uint64(i)), chunks[i])
+	}
+	if _, ok := cc.get(key(0)); ok {
+		t.Fatal("least recently used chunk was not evicted")
+	}
+	data, ok := cc.get(key(1))
+	if !ok || !bytes.Equal(data, chunks[1]) {
+		t.Fatal("cached chunk was not returned")
+	}
+
+	// Chunk 2 is now the least recently used chunk.
+	cc.add(key(3), fastrand.Bytes(100))
+	if _, ok := cc.get(key(2)); ok {
+		t.Fatal("least recently used chunk was not evicted")
+	}
+	if m := cc.metrics(); m.Hits != 1 || m.Misses != 2 || m.Chunks != 2 || m.Size != 200 {
+		t.Fatal("wrong metrics:", m)
+	}
+
+	// Chunks that are larger than the budget are not cached.
+	cc.add(key(4), fastrand.Bytes(300))
+	if m := cc.metrics(); m.Chunks != 2 {
+		t.Fatal("oversized chunk was cached")
+	}
+
+	// Purging a file removes its chunks from disk.
+	cc.purge(crypto.TwofishKey{})
+	if m := cc.metrics(); m.Chunks != 0 || m.Size != 0 {
+		t.Fatal("purge did not empty the cache:", m)
+	}
+	if fis, err := ioutil.ReadDir(cc.dir); err != nil || len(fis) != 0 {
+		t.Fatal("purge did not remove the cached chunks from disk", err)
+	}
+
+	// A disabled cache neither stores chunks nor counts lookups.
+	cc.setBudget(0)
+	cc.add(key(5), fastrand.Bytes(10))
+	if _, ok := cc.get(key(5)); ok {
+		t.Fatal("disabled cache returned a chunk")
+	}
+	if m := cc.metrics(); m.Misses != 2 || m.Chunks != 0 {
+		t.Fatal("disabled cache was used:", m)
+	}
+}
+
+// TestChunkCacheStartup checks that a new chunk cache only removes the chunks
+// left behind by a previous run, and leaves other files in its directory.
+func TestChunkCacheStartup(t *testing.T) {
+	dir := build.TempDir("renter", t.Name())
+	cc, err := newChunkCache(dir, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := chunkCacheKey{index: 1}
+	cc.add(key, fastrand.Bytes(100))
+	foreign := []string{"foo.sia", "notes.txt", "sub"}
+	for _, name := range foreign[:2] {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("data"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restart the cache. The stale chunk should be gone, the other files
+	// should still be there.
+	cc, err = newChunkCache(dir, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cc.path(key)); !os.IsNotExist(err) {
+		t.Fatal("stale chunk was not removed:", err)
+	}
+	for _, name := range foreign {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatal("file that does not belong to the cache was removed:", err)
+		}
+	}
+}
+
+// TestDownloadFromChunkCache checks that downloads are served from the chunk
+// cache without contacting any hosts.
+func TestDownloadFromChunkCache(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+
+	// The file has no contracts, so it can only be downloaded from the
+	// cache.
+	f := newTestingFile()
+	f.pieceSize = 100
+	f.size = 2*f.chunkSize() - 50
+	id := r.mu.Lock()
+	r.addFile(f)
+	r.mu.Unlock(id)
+	r.chunkCache.setBudget(2 * f.chunkSize())
+	data := fastrand.Bytes(int(2 * f.chunkSize()))
+	r.chunkCache.add(chunkCacheKey{f.masterKey, 0}, data[:f.chunkSize()])
+	r.chunkCache.add(chunkCacheKey{f.masterKey, 1}, data[f.chunkSize():])
+
+	dir := build.TempDir("renter", t.Name()+"-downloads")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(dir, "download")
+	err = r.Download(modules.RenterDownloadParameters{
+		Siapath:     f.name,
+		Destination: dest,
+		Offset:      10,
+		Length:      f.size - 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloaded, err := ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, data[10:f.size]) {
+		t.Fatal("downloaded data does not match the cached data")
+	}
+	if m := r.ChunkCacheMetrics(); m.Hits != 2 {
+		t.Fatal("expected 2 cache hits, got", m.Hits)
+	}
+}
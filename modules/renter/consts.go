@@ -6,6 +6,21 @@ import (
 	"github.com/NebulousLabs/Sia/build"
 )
 
+const (
+	// downloadOverdrive is the maximum number of extra pieces that are
+	// requested for a chunk when the hosts downloading its pieces stall.
+	downloadOverdrive = 2
+
+	// downloadStallFactor is the factor by which a piece download must exceed
+	// the estimated download time of its host before it is considered stalled.
+	downloadStallFactor = 3
+
+	// downloadStatsDecay is the weight that the rolling download statistics
+	// of a host give to the previous statistics when a new piece download is
+	// recorded.
+	downloadStatsDecay = 0.9
+)
+
 var (
 	// Prime to avoid intersecting with regular events.
 	uploadFailureCooldown = build.Select(build.Var{
@@ -29,6 +44,15 @@ var (
 		Testing:  3,
 	}).(int)
 
+	// dirMetricsCacheTime is how long the aggregated metrics of a directory
+	// are cached. The cache is discarded when a file beneath the directory
+	// changes, but not when a host goes offline.
+	dirMetricsCacheTime = build.Select(build.Var{
+		Dev:      10 * time.Second,
+		Standard: time.Minute,
+		Testing:  time.Minute,
+	}).(time.Duration)
+
 	repairQueueInterval = build.Select(build.Var{
 		Dev:      30 * time.Second,
 		Standard: time.Minute * 15,
@@ -43,6 +67,23 @@ var (
 		Testing:  60,
 	}).(int)
 
+	// maxStreamChunks is the maximum number of chunks of a streamed upload
+	// that are held in memory while waiting for them to reach the minimum
+	// redundancy.
+	maxStreamChunks = build.Select(build.Var{
+		Dev:      3,
+		Standard: 3,
+		Testing:  2,
+	}).(int)
+
+	// defaultUploadMemory is the amount of memory that the upload pipeline
+	// uses if the renter settings do not specify a limit.
+	defaultUploadMemory = build.Select(build.Var{
+		Dev:      uint64(1 << 28), // 256 MiB
+		Standard: uint64(1 << 30), // 1 GiB
+		Testing:  uint64(1 << 20), // 1 MiB
+	}).(uint64)
+
 	// chunkDownloadTimeout defines the maximum amount of time to wait for a
 	// chunk download to finish before returning in the download-to-upload repair
 	// loop
@@ -51,4 +92,29 @@ var (
 		Standard: 15 * time.Minute,
 		Testing:  40 * time.Second,
 	}).(time.Duration)
+
+	// downloadStallTimeout is the minimum amount of time a piece download may
+	// take before it is considered stalled.
+	downloadStallTimeout = build.Select(build.Var{
+		Dev:      10 * time.Second,
+		Standard: 30 * time.Second,
+		Testing:  5 * time.Second,
+	}).(time.Duration)
+
+	// downloadSaveInterval is the minimum amount of time between two saves of
+	// the download history while the download loop is recovering chunks.
+	downloadSaveInterval = build.Select(build.Var{
+		Dev:      10 * time.Second,
+		Standard: 30 * time.Second,
+		Testing:  time.Second,
+	}).(time.Duration)
+
+	// maxDownloadHistory is the maximum number of downloads that are kept in
+	// the download history. The oldest downloads that have stopped are pruned
+	// first; active and paused downloads are never pruned.
+	maxDownloadHistory = build.Select(build.Var{
+		Dev:      100,
+		Standard: 1000,
+		Testing:  5,
+	}).(int)
 )
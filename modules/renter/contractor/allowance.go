@@ -31,7 +31,7 @@ var (
 // allocated funds will eventually be returned.
 //
 // If a is the empty allowance, SetAllowance will archive the current contract
-// set. The contracts cannot be used to create Editors or Downloads, and will
+// set, except for manual contracts. The contracts cannot be used to create Editors or Downloads, and will
 // not be renewed.
 //
 // TODO: can an Editor or Downloader be used across renewals?
@@ -91,7 +91,10 @@ func (c *Contractor) managedCancelAllowance(a modules.Allowance) error {
 	// NOTE: this code is the same as in managedRenewContracts
 	var ids []types.FileContractID
 	c.mu.Lock()
-	for id := range c.contracts {
+	for id, contract := range c.contracts {
+		if contract.Manual {
+			continue
+		}
 		ids = append(ids, id)
 		// we aren't renewing, but we don't want new editors or downloaders to
 		// be created
@@ -118,14 +121,18 @@ func (c *Contractor) managedCancelAllowance(a modules.Allowance) error {
 		}
 	}
 
-	// reset currentPeriod and archive all contracts
+	// reset currentPeriod and archive all contracts except the manual ones,
+	// which are not funded by the allowance
 	c.mu.Lock()
+	c.endPeriod(c.blockHeight + 1)
 	c.allowance = a
 	c.currentPeriod = 0
-	for id, contract := range c.contracts {
-		c.oldContracts[id] = contract
+	for _, id := range ids {
+		if contract, ok := c.contracts[id]; ok {
+			c.oldContracts[id] = contract
+			delete(c.contracts, id)
+		}
 	}
-	c.contracts = make(map[types.FileContractID]modules.RenterContract)
 	err := c.saveSync()
 	c.mu.Unlock()
 	return err
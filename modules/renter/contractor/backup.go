@@ -0,0 +1,76 @@
+package contractor
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// Backup writes the contractor's persisted data, including the secret keys of
+// its contracts, to w. The data is written in the journal format, with all
+// updates applied to the initial object.
+func (c *Contractor) Backup(w io.Writer) error {
+	c.mu.RLock()
+	data := c.persistData()
+	c.mu.RUnlock()
+
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(journalMeta); err != nil {
+		return err
+	}
+	return enc.Encode(data)
+}
+
+// Restore adds the contracts in a backup created by Backup to the contractor.
+// Contracts that the contractor already knows about are left untouched. If no
+// allowance is set, the allowance of the backup is restored as well, so that
+// the restored contracts are renewed.
+func (c *Contractor) Restore(r io.Reader) error {
+	var data contractorPersist
+	if err := decodeJournal(r, &data); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	known := func(id types.FileContractID) bool {
+		_, active := c.contracts[id]
+		_, old := c.oldContracts[id]
+		return active || old
+	}
+	for _, rev := range data.CachedRevisions {
+		if !known(rev.Revision.ParentID) {
+			c.cachedRevisions[rev.Revision.ParentID] = rev
+		}
+	}
+	for _, contract := range data.Contracts {
+		if !known(contract.ID) {
+			c.contracts[contract.ID] = contract
+		}
+	}
+	for _, contract := range data.OldContracts {
+		if !known(contract.ID) {
+			c.oldContracts[contract.ID] = contract
+		}
+	}
+	for oldString, newString := range data.RenewedIDs {
+		var oldHash, newHash crypto.Hash
+		if err := oldHash.LoadString(oldString); err != nil {
+			return err
+		}
+		if err := newHash.LoadString(newString); err != nil {
+			return err
+		}
+		if _, exists := c.renewedIDs[types.FileContractID(oldHash)]; !exists {
+			c.renewedIDs[types.FileContractID(oldHash)] = types.FileContractID(newHash)
+		}
+	}
+	if c.allowance.Funds.IsZero() && c.allowance.Period == 0 {
+		c.allowance = data.Allowance
+		c.currentPeriod = data.CurrentPeriod
+		c.pastPeriods = data.PastPeriods
+	}
+	return c.saveSync()
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 
 	"github.com/NebulousLabs/Sia/modules"
@@ -42,6 +43,7 @@ type Contractor struct {
 	log     *persist.Logger
 	persist persister
 	mu      sync.RWMutex
+	rl      *modules.RateLimit
 	tg      siasync.ThreadGroup
 	tpool   transactionPool
 	wallet  wallet
@@ -53,6 +55,7 @@ type Contractor struct {
 	blockHeight   types.BlockHeight
 	currentPeriod types.BlockHeight
 	lastChange    modules.ConsensusChangeID
+	pastPeriods   []pastPeriod
 
 	downloaders map[types.FileContractID]*hostDownloader
 	editors     map[types.FileContractID]*hostEditor
@@ -118,6 +121,30 @@ func (c *Contractor) AllContracts() (cs []modules.RenterContract) {
 	return
 }
 
+// ExpiredContracts returns the archived contracts of the contractor, which
+// are the contracts that have expired or that have been renewed, sorted by
+// end height.
+func (c *Contractor) ExpiredContracts() []modules.RenterContract {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cs := make([]modules.RenterContract, 0, len(c.oldContracts))
+	for id, contract := range c.oldContracts {
+		// COMPATv1.0.4-lts
+		// the special metrics contract is not a real contract.
+		if id == metricsContractID {
+			continue
+		}
+		cs = append(cs, contract)
+	}
+	sort.Slice(cs, func(i, j int) bool {
+		if cs[i].EndHeight() != cs[j].EndHeight() {
+			return cs[i].EndHeight() < cs[j].EndHeight()
+		}
+		return cs[i].ID.String() < cs[j].ID.String()
+	})
+	return cs
+}
+
 // CurrentPeriod returns the height at which the current allowance period
 // began.
 func (c *Contractor) CurrentPeriod() types.BlockHeight {
@@ -139,6 +166,13 @@ func (c *Contractor) ResolveID(id types.FileContractID) types.FileContractID {
 	return id
 }
 
+// SetRateLimits sets the maximum bandwidth, in bytes per second, that all of
+// the contractor's editors and downloaders may use together. A limit of 0
+// removes the limit.
+func (c *Contractor) SetRateLimits(downloadSpeed, uploadSpeed int64) {
+	c.rl.SetLimits(downloadSpeed, uploadSpeed)
+}
+
 // Close closes the Contractor.
 func (c *Contractor) Close() error {
 	return c.tg.Stop()
@@ -192,6 +226,7 @@ func newContractor(cs consensusSet, w wallet, tp transactionPool, hdb hostDB, p
 		hdb:     hdb,
 		log:     l,
 		persist: p,
+		rl:      modules.NewRateLimit(0, 0),
 		tpool:   tp,
 		wallet:  w,
 
@@ -36,10 +36,12 @@ func (newStub) ActiveHosts() []modules.HostDBEntry
 func (newStub) Host(types.SiaPublicKey) (settings modules.HostDBEntry, ok bool) { return }
 func (newStub) IncrementSuccessfulInteractions(key types.SiaPublicKey)          { return }
 func (newStub) IncrementFailedInteractions(key types.SiaPublicKey)              { return }
+func (newStub) IsFiltered(types.SiaPublicKey) bool                              { return false }
 func (newStub) RandomHosts(int, []types.SiaPublicKey) []modules.HostDBEntry     { return nil }
 func (newStub) ScoreBreakdown(modules.HostDBEntry) modules.HostScoreBreakdown {
 	return modules.HostScoreBreakdown{}
 }
+func (newStub) SubnetViolations([]types.SiaPublicKey) []types.SiaPublicKey { return nil }
 
 // TestNew tests the New function.
 func TestNew(t *testing.T) {
@@ -192,11 +194,13 @@ func (stubHostDB) ActiveHosts() (hs []modules.HostDBEntry)
 func (stubHostDB) Host(types.SiaPublicKey) (h modules.HostDBEntry, ok bool)         { return }
 func (stubHostDB) IncrementSuccessfulInteractions(key types.SiaPublicKey)           { return }
 func (stubHostDB) IncrementFailedInteractions(key types.SiaPublicKey)               { return }
+func (stubHostDB) IsFiltered(types.SiaPublicKey) bool                               { return false }
 func (stubHostDB) PublicKey() (spk types.SiaPublicKey)                              { return }
 func (stubHostDB) RandomHosts(int, []types.SiaPublicKey) (hs []modules.HostDBEntry) { return }
 func (stubHostDB) ScoreBreakdown(modules.HostDBEntry) modules.HostScoreBreakdown {
 	return modules.HostScoreBreakdown{}
 }
+func (stubHostDB) SubnetViolations([]types.SiaPublicKey) (spks []types.SiaPublicKey) { return }
 
 // TestAllowanceOverspend verifies that the contractor will not spend more
 // than the allowance if contracts need to be renewed early.
@@ -499,3 +503,96 @@ func TestWalletBridge(t *testing.T) {
 		t.Error("StartTransaction was not called on the shim")
 	}
 }
+
+// filterHostDB is a stubHostDB whose filter excludes the host with the key
+// "foo".
+type filterHostDB struct {
+	stubHostDB
+}
+
+func (filterHostDB) IsFiltered(spk types.SiaPublicKey) bool { return string(spk.Key) == "foo" }
+
+// TestMarkFilteredContracts checks that contracts with hosts that are
+// excluded by the hostdb's filter are no longer used or renewed.
+func TestMarkFilteredContracts(t *testing.T) {
+	c := &Contractor{
+		allowance: modules.Allowance{Hosts: 2},
+		contracts: map[types.FileContractID]modules.RenterContract{
+			{1}: {ID: types.FileContractID{1}, HostPublicKey: types.SiaPublicKey{Key: []byte("foo")}, GoodForUpload: true, GoodForRenew: true},
+			{2}: {ID: types.FileContractID{2}, HostPublicKey: types.SiaPublicKey{Key: []byte("bar")}, GoodForUpload: true, GoodForRenew: true},
+		},
+		hdb: filterHostDB{},
+	}
+	c.managedMarkContractsUtility()
+	if contract := c.contracts[types.FileContractID{1}]; contract.GoodForUpload || contract.GoodForRenew {
+		t.Fatal("contract with a filtered host is still marked as useful")
+	}
+	if contract := c.contracts[types.FileContractID{2}]; !contract.GoodForUpload || !contract.GoodForRenew {
+		t.Fatal("contract with an unfiltered host was marked as not useful")
+	}
+}
+
+// subnetHostDB is a stubHostDB that knows every host, and that places the
+// hosts with the keys "foo" and "bar" in the same subnet.
+type subnetHostDB struct {
+	stubHostDB
+}
+
+func (subnetHostDB) Host(spk types.SiaPublicKey) (modules.HostDBEntry, bool) {
+	return modules.HostDBEntry{PublicKey: spk}, true
+}
+func (subnetHostDB) RandomHosts(int, []types.SiaPublicKey) []modules.HostDBEntry {
+	return []modules.HostDBEntry{{}}
+}
+func (subnetHostDB) SubnetViolations(spks []types.SiaPublicKey) (violations []types.SiaPublicKey) {
+	seen := false
+	for _, spk := range spks {
+		if key := string(spk.Key); key == "foo" || key == "bar" {
+			if seen {
+				violations = append(violations, spk)
+			}
+			seen = true
+		}
+	}
+	return violations
+}
+
+// subnetFilterHostDB is a subnetHostDB that filters the host with the key
+// "foo".
+type subnetFilterHostDB struct {
+	subnetHostDB
+}
+
+func (subnetFilterHostDB) IsFiltered(spk types.SiaPublicKey) bool { return string(spk.Key) == "foo" }
+
+// TestMarkSubnetViolations checks that only the oldest of several contracts
+// with hosts in the same subnet is renewed, ignoring contracts that are not
+// renewed for other reasons.
+func TestMarkSubnetViolations(t *testing.T) {
+	c := &Contractor{
+		allowance: modules.Allowance{Hosts: 3},
+		contracts: map[types.FileContractID]modules.RenterContract{
+			{1}: {ID: types.FileContractID{1}, HostPublicKey: types.SiaPublicKey{Key: []byte("bar")}, StartHeight: 2},
+			{2}: {ID: types.FileContractID{2}, HostPublicKey: types.SiaPublicKey{Key: []byte("foo")}, StartHeight: 1},
+			{3}: {ID: types.FileContractID{3}, HostPublicKey: types.SiaPublicKey{Key: []byte("baz")}, StartHeight: 3},
+		},
+		hdb: subnetHostDB{},
+	}
+	c.managedMarkContractsUtility()
+	if c.contracts[types.FileContractID{1}].GoodForRenew {
+		t.Fatal("contract with a host in a used subnet is still marked for renewal")
+	}
+	if !c.contracts[types.FileContractID{2}].GoodForRenew || !c.contracts[types.FileContractID{3}].GoodForRenew {
+		t.Fatal("contract with a host in an unused subnet was not marked for renewal")
+	}
+	// If the host of the older contract is filtered, the older contract does
+	// not claim the subnet and the newer contract is renewed instead.
+	c.hdb = subnetFilterHostDB{}
+	c.managedMarkContractsUtility()
+	if !c.contracts[types.FileContractID{1}].GoodForRenew {
+		t.Fatal("contract was not renewed although the other host in its subnet is filtered")
+	}
+	if c.contracts[types.FileContractID{2}].GoodForRenew {
+		t.Fatal("contract with a filtered host is marked for renewal")
+	}
+}
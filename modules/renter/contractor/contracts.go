@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
@@ -101,6 +102,9 @@ func (c *Contractor) managedMarkContractsUtility() {
 	}
 	hosts := c.hdb.RandomHosts(hostCount+minScoreHostBuffer, nil)
 	if len(hosts) <= 0 {
+		// Without a baseline score, only the hostdb's filter can be
+		// applied.
+		c.managedMarkFilteredContracts()
 		return
 	}
 
@@ -123,8 +127,23 @@ func (c *Contractor) managedMarkContractsUtility() {
 	}
 	c.mu.RUnlock()
 
+	// Sort the contracts from oldest to newest, so that the oldest contract in
+	// each IP subnet is the one that is kept for renewal.
+	sort.Slice(contracts, func(i, j int) bool {
+		if contracts[i].StartHeight != contracts[j].StartHeight {
+			return contracts[i].StartHeight < contracts[j].StartHeight
+		}
+		return contracts[i].ID.String() < contracts[j].ID.String()
+	})
+
 	// Go through and figure out if the utility fields need to be changed.
 	for i := 0; i < len(contracts); i++ {
+		// The utility of manual and canceled contracts is decided by the
+		// user.
+		if contracts[i].Manual || contracts[i].Canceled {
+			continue
+		}
+
 		// Start the contract in good standing.
 		contracts[i].GoodForUpload = true
 		contracts[i].GoodForRenew = true
@@ -136,6 +155,13 @@ func (c *Contractor) managedMarkContractsUtility() {
 			contracts[i].GoodForRenew = false
 			continue
 		}
+		// Contract has no utility if the host is excluded by the hostdb's
+		// filter.
+		if c.hdb.IsFiltered(host.PublicKey) {
+			contracts[i].GoodForUpload = false
+			contracts[i].GoodForRenew = false
+			continue
+		}
 		// Contract has no utility if the score is poor.
 		if c.hdb.ScoreBreakdown(host).Score.Cmp(minScore) < 0 {
 			contracts[i].GoodForUpload = false
@@ -183,6 +209,33 @@ func (c *Contractor) managedMarkContractsUtility() {
 		}
 	}
 
+	// A contract should not be renewed if the host of an older contract that
+	// will be renewed is in the same subnet, since hosts in one subnet are
+	// likely to fail together. Contracts that will not be renewed anyway do
+	// not claim their subnet. The contract can still be used for uploading
+	// until it expires.
+	var renewKeys []types.SiaPublicKey
+	seen := make(map[string]struct{})
+	for _, contract := range contracts {
+		if _, exists := seen[contract.HostPublicKey.String()]; exists || !contract.GoodForRenew {
+			continue
+		}
+		seen[contract.HostPublicKey.String()] = struct{}{}
+		renewKeys = append(renewKeys, contract.HostPublicKey)
+	}
+	subnetViolations := make(map[string]struct{})
+	for _, spk := range c.hdb.SubnetViolations(renewKeys) {
+		subnetViolations[spk.String()] = struct{}{}
+	}
+	for i := range contracts {
+		if contracts[i].Manual || contracts[i].Canceled || !contracts[i].GoodForRenew {
+			continue
+		}
+		if _, violation := subnetViolations[contracts[i].HostPublicKey.String()]; violation {
+			contracts[i].GoodForRenew = false
+		}
+	}
+
 	// Update the contractor to reflect the new state for each of the contracts.
 	c.mu.Lock()
 	for i := 0; i < len(contracts); i++ {
@@ -197,6 +250,85 @@ func (c *Contractor) managedMarkContractsUtility() {
 	c.mu.Unlock()
 }
 
+// managedMarkFilteredContracts marks the contracts with hosts that are
+// excluded by the hostdb's filter as not useful for uploading or renewing.
+// Manual contracts are left as they are.
+func (c *Contractor) managedMarkFilteredContracts() {
+	c.mu.RLock()
+	var filtered []types.FileContractID
+	for id, contract := range c.contracts {
+		if contract.Manual {
+			continue
+		}
+		if c.hdb.IsFiltered(contract.HostPublicKey) {
+			filtered = append(filtered, id)
+		}
+	}
+	c.mu.RUnlock()
+
+	c.mu.Lock()
+	for _, id := range filtered {
+		contract, exists := c.contracts[id]
+		if !exists {
+			continue
+		}
+		contract.GoodForUpload = false
+		contract.GoodForRenew = false
+		c.contracts[id] = contract
+	}
+	c.mu.Unlock()
+}
+
+// MarkContractsUtility updates the utility of the active contracts, and then
+// starts contract maintenance in the background to replace the contracts that
+// are no longer useful. It is called when the hostdb's filter changes, so that
+// contracts with newly excluded hosts are dropped.
+func (c *Contractor) MarkContractsUtility() {
+	if err := c.tg.Add(); err != nil {
+		return
+	}
+	defer c.tg.Done()
+	c.managedMarkContractsUtility()
+	go c.threadedContractMaintenance()
+}
+
+// reclaimedFunds returns the funds that were allocated to the previous
+// contracts of a contract line but never spent, and that have been returned
+// to the renter because the proof window of their contract has closed. The
+// unused funds of contracts that have not expired yet are still locked.
+func reclaimedFunds(contract modules.RenterContract, blockHeight types.BlockHeight) types.Currency {
+	var reclaimed types.Currency
+	for _, pre := range contract.PreviousContracts {
+		if pre.LastRevision.NewWindowEnd <= blockHeight {
+			reclaimed = reclaimed.Add(pre.RenterFunds())
+		}
+	}
+	return reclaimed
+}
+
+// refreshReason reports whether a contract that is not yet expiring should be
+// refreshed, along with the reason. A contract is refreshed when the renter
+// can no longer pay for uploading and storing a few more sectors, or when the
+// host has run out of collateral to back them.
+func refreshReason(contract modules.RenterContract, host modules.HostDBEntry, blockHeight types.BlockHeight) (string, bool) {
+	blockBytes := types.NewCurrency64(modules.SectorSize * uint64(contract.EndHeight()-blockHeight))
+	sectorStoragePrice := host.StoragePrice.Mul(blockBytes)
+	sectorBandwidthPrice := host.UploadBandwidthPrice.Mul64(modules.SectorSize)
+	sectorPrice := sectorStoragePrice.Add(sectorBandwidthPrice)
+	percentRemaining, _ := big.NewRat(0, 1).SetFrac(contract.RenterFunds().Big(), contract.TotalCost.Big()).Float64()
+	if contract.RenterFunds().Cmp(sectorPrice.Mul64(3)) < 0 || percentRemaining < minContractFundRenewalThreshold {
+		return "storage budget exhausted", true
+	}
+
+	// The host moves collateral into the void for every sector that is
+	// uploaded, and rejects uploads it can no longer back.
+	sectorCollateral := host.Collateral.Mul(blockBytes)
+	if len(contract.LastRevision.NewMissedProofOutputs) > 1 && contract.LastRevision.NewMissedProofOutputs[1].Value.Cmp(sectorCollateral.Mul64(3)) < 0 {
+		return "upload budget exhausted", true
+	}
+	return "", false
+}
+
 // managedNewContract negotiates an initial file contract with the specified
 // host, saves it, and returns it.
 func (c *Contractor) managedNewContract(host modules.HostDBEntry, contractFunding types.Currency, endHeight types.BlockHeight) (modules.RenterContract, error) {
@@ -235,6 +367,8 @@ func (c *Contractor) managedNewContract(host modules.HostDBEntry, contractFundin
 		return modules.RenterContract{}, err
 	}
 
+	contract.ProofStatus = modules.ContractProofPending
+
 	contractValue := contract.RenterFunds()
 	c.log.Printf("Formed contract with %v for %v", host.NetAddress, contractValue.HumanString())
 	return contract, nil
@@ -300,10 +434,104 @@ func (c *Contractor) managedRenew(contract modules.RenterContract, contractFundi
 		txnBuilder.Drop() // return unused outputs to wallet
 		return modules.RenterContract{}, err
 	}
+	newContract.ProofStatus = modules.ContractProofPending
 
 	return newContract, nil
 }
 
+// managedRenewContract renews the active contract with the provided id,
+// replacing it with the renewed contract. If refresh is set, the contract is
+// being renewed because it ran out of funds, and the old contract is added to
+// the contract line of the new contract. Only one renewal of a contract can
+// run at a time.
+func (c *Contractor) managedRenewContract(id types.FileContractID, amount types.Currency, endHeight types.BlockHeight, refresh bool) (modules.RenterContract, error) {
+	// Mark the contract as being renewed, and defer logic to unmark it once
+	// renewing is complete.
+	c.mu.Lock()
+	if c.renewing[id] {
+		c.mu.Unlock()
+		return modules.RenterContract{}, errContractRenewing
+	}
+	c.renewing[id] = true
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.renewing, id)
+		c.mu.Unlock()
+	}()
+
+	// Wait for any active editors and downloaders to finish for this
+	// contract, and then grab the latest revision.
+	c.mu.RLock()
+	e, eok := c.editors[id]
+	d, dok := c.downloaders[id]
+	c.mu.RUnlock()
+	if eok {
+		e.invalidate()
+	}
+	if dok {
+		d.invalidate()
+	}
+
+	c.mu.RLock()
+	oldContract, ok := c.contracts[id]
+	c.mu.RUnlock()
+	if !ok {
+		return modules.RenterContract{}, errContractNotFound
+	}
+
+	// Create the new contract.
+	newContract, err := c.managedRenew(oldContract, amount, endHeight)
+	if err != nil {
+		return modules.RenterContract{}, fmt.Errorf("renewal with %v failed: %v", oldContract.NetAddress, err)
+	}
+	c.log.Printf("Renewed contract %v with %v\n", id, oldContract.NetAddress)
+	newFees := newContract.ContractFee.Add(newContract.TxnFee).Add(newContract.SiafundFee)
+	c.log.Printf("Renewal of %v moved %v into contract %v: %v in fees, %v available to the renter. The %v left unused in the old contract are returned to the renter at height %v\n",
+		id, newContract.TotalCost.HumanString(), newContract.ID, newFees.HumanString(), newContract.RenterFunds().HumanString(), oldContract.RenterFunds().HumanString(), oldContract.LastRevision.NewWindowEnd)
+	// Update the utility values for the new contract, and for the old
+	// contract. A renewed manual contract remains manual.
+	newContract.GoodForUpload = true
+	newContract.GoodForRenew = true
+	newContract.Manual = oldContract.Manual
+	oldContract.GoodForRenew = false
+	oldContract.GoodForUpload = false
+	// If the contract is a mid-cycle renew, add the contract line to the new
+	// contract. The contract line is not included/extended if we are just
+	// renewing because the contract is expiring.
+	if refresh {
+		newContract.PreviousContracts = oldContract.PreviousContracts
+		oldContract.PreviousContracts = nil
+		newContract.PreviousContracts = append(newContract.PreviousContracts, oldContract)
+	}
+
+	// Lock the contractor as we update it to use the new contract instead of
+	// the old contract.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Store the contract in the record of historic contracts.
+	_, exists := c.contracts[oldContract.ID]
+	if exists {
+		c.oldContracts[oldContract.ID] = oldContract
+		delete(c.contracts, oldContract.ID)
+	}
+
+	// Add the new contract, including a mapping from the old contract to the
+	// new contract.
+	c.contracts[newContract.ID] = newContract
+	c.renewedIDs[oldContract.ID] = newContract.ID
+	c.cachedRevisions[newContract.ID] = c.cachedRevisions[oldContract.ID]
+	delete(c.cachedRevisions, oldContract.ID)
+
+	// Save the contractor.
+	err = c.saveSync()
+	if err != nil {
+		c.log.Println("Failed to save the contractor after creating a new contract.")
+	}
+	return newContract, nil
+}
+
 // threadedContractMaintenance checks the set of contracts that the contractor
 // has against the allownace, renewing any contracts that need to be renewed,
 // dropping contracts which are no longer worthwhile, and adding contracts if
@@ -375,6 +603,11 @@ func (c *Contractor) threadedContractMaintenance() {
 		// get the full picture for how many funds are available.
 		var fundsUsed types.Currency
 		for _, contract := range c.contracts {
+			// Manual contracts are paid for outside of the allowance.
+			if contract.Manual {
+				continue
+			}
+
 			// Calculate the cost of the contract line.
 			contractLineCost := contract.TotalCost
 			for _, pre := range contract.PreviousContracts {
@@ -394,6 +627,10 @@ func (c *Contractor) threadedContractMaintenance() {
 				// contract are available to renew or form contracts.
 				fundsUsed = fundsUsed.Add(contractLineCost)
 			}
+
+			// The unused funds of the contracts that this contract replaced
+			// have been returned to the renter once those contracts expired.
+			fundsUsed = fundsUsed.Sub(reclaimedFunds(contract, c.blockHeight))
 		}
 
 		// Add any unspent funds from the allowance to the available funds. If
@@ -418,7 +655,8 @@ func (c *Contractor) threadedContractMaintenance() {
 		// Iterate through the contracts again, figuring out which contracts to
 		// renew and how much extra funds to renew them with.
 		for _, contract := range c.contracts {
-			if !contract.GoodForRenew {
+			// Manual contracts are only renewed by the user.
+			if !contract.GoodForRenew || contract.Manual {
 				continue
 			}
 			if c.blockHeight+c.allowance.RenewWindow >= contract.EndHeight() {
@@ -435,7 +673,7 @@ func (c *Contractor) threadedContractMaintenance() {
 				// money that was allocated (the RenterFunds()).
 				renewAmount := contract.TotalCost.Sub(contract.ContractFee).Sub(contract.TxnFee).Sub(contract.SiafundFee).Sub(contract.RenterFunds())
 				for _, pre := range contract.PreviousContracts {
-					renewAmount = renewAmount.Add(pre.TotalCost).Sub(pre.ContractFee).Sub(pre.TxnFee).Sub(pre.SiafundFee).Sub(contract.RenterFunds())
+					renewAmount = renewAmount.Add(pre.TotalCost).Sub(pre.ContractFee).Sub(pre.TxnFee).Sub(pre.SiafundFee).Sub(pre.RenterFunds())
 				}
 
 				// Get an estimate for how much the fees will cost.
@@ -475,25 +713,26 @@ func (c *Contractor) threadedContractMaintenance() {
 					continue
 				}
 
-				blockBytes := types.NewCurrency64(modules.SectorSize * uint64(contract.EndHeight()-c.blockHeight))
-				sectorStoragePrice := host.StoragePrice.Mul(blockBytes)
-				sectorBandwidthPrice := host.UploadBandwidthPrice.Mul64(modules.SectorSize)
-				sectorPrice := sectorStoragePrice.Add(sectorBandwidthPrice)
-				percentRemaining, _ := big.NewRat(0, 1).SetFrac(contract.RenterFunds().Big(), contract.TotalCost.Big()).Float64()
-				if contract.RenterFunds().Cmp(sectorPrice.Mul64(3)) < 0 || percentRemaining < minContractFundRenewalThreshold {
-					// This contract does need to be refreshed. Make sure there
-					// are enough funds available to perform the refresh, and
-					// then execute.
-					refreshAmount := contract.TotalCost.Mul64(2)
-					if refreshAmount.Cmp(fundsAvailable) < 0 {
-						refreshSet[contract.ID] = struct{}{}
-						renewSet = append(renewSet, renewal{
-							id:     contract.ID,
-							amount: refreshAmount,
-						})
-					} else {
-						c.log.Println("WARN: cannot refresh empty contract due to low allowance.")
-					}
+				reason, refresh := refreshReason(contract, host, c.blockHeight)
+				if !refresh {
+					continue
+				}
+				// This contract does need to be refreshed. The unused funds of
+				// the contract stay locked until the contract expires, so the
+				// refresh is paid for from the allowance. Make sure there are
+				// enough funds available to perform the refresh, and then
+				// execute.
+				refreshAmount := contract.TotalCost.Mul64(2)
+				if refreshAmount.Cmp(fundsAvailable) < 0 {
+					c.log.Printf("contract %v with %v needs to be refreshed: %v", contract.ID, contract.NetAddress, reason)
+					fundsAvailable = fundsAvailable.Sub(refreshAmount)
+					refreshSet[contract.ID] = struct{}{}
+					renewSet = append(renewSet, renewal{
+						id:     contract.ID,
+						amount: refreshAmount,
+					})
+				} else {
+					c.log.Println("WARN: cannot refresh empty contract due to low allowance.")
 				}
 			}
 		}
@@ -507,88 +746,13 @@ func (c *Contractor) threadedContractMaintenance() {
 		// Pull the variables out of the renewal.
 		id := renewal.id
 		amount := renewal.amount
+		_, refresh := refreshSet[id]
 
 		// Renew one contract.
-		func() {
-			// Mark the contract as being renewed, and defer logic to unmark it
-			// once renewing is complete.
-			c.mu.Lock()
-			c.renewing[id] = true
-			c.mu.Unlock()
-			defer func() {
-				c.mu.Lock()
-				delete(c.renewing, id)
-				c.mu.Unlock()
-			}()
-
-			// Wait for any active editors and downloaders to finish for this
-			// contract, and then grab the latest revision.
-			c.mu.RLock()
-			e, eok := c.editors[id]
-			d, dok := c.downloaders[id]
-			c.mu.RUnlock()
-			if eok {
-				e.invalidate()
-			}
-			if dok {
-				d.invalidate()
-			}
-
-			c.mu.RLock()
-			oldContract, ok := c.contracts[id]
-			c.mu.RUnlock()
-			if !ok {
-				c.log.Println("WARN: no record of contract previously added to the renew set:", id)
-				return
-			}
-
-			// Create the new contract.
-			newContract, err := c.managedRenew(oldContract, amount, endHeight)
-			if err != nil {
-				c.log.Printf("WARN: failed to renew contract %v with %v: %v\n", id, oldContract.NetAddress, err)
-				return
-			}
-			c.log.Printf("Renewed contract %v with %v\n", id, oldContract.NetAddress)
-			// Update the utility values for the new contract, and for the old
-			// contract.
-			newContract.GoodForUpload = true
-			newContract.GoodForRenew = true
-			oldContract.GoodForRenew = false
-			oldContract.GoodForUpload = false
-			// If the contract is a mid-cycle renew, add the contract line to
-			// the new contract. The contract line is not included/extended if
-			// we are just renewing because the contract is expiring.
-			if _, exists := refreshSet[id]; exists {
-				newContract.PreviousContracts = oldContract.PreviousContracts
-				oldContract.PreviousContracts = nil
-				newContract.PreviousContracts = append(newContract.PreviousContracts, oldContract)
-			}
-
-			// Lock the contractor as we update it to use the new contract
-			// instead of the old contract.
-			c.mu.Lock()
-			defer c.mu.Unlock()
-
-			// Store the contract in the record of historic contracts.
-			_, exists := c.contracts[oldContract.ID]
-			if exists {
-				c.oldContracts[oldContract.ID] = oldContract
-				delete(c.contracts, oldContract.ID)
-			}
-
-			// Add the new contract, including a mapping from the old
-			// contract to the new contract.
-			c.contracts[newContract.ID] = newContract
-			c.renewedIDs[oldContract.ID] = newContract.ID
-			c.cachedRevisions[newContract.ID] = c.cachedRevisions[oldContract.ID]
-			delete(c.cachedRevisions, oldContract.ID)
-
-			// Save the contractor.
-			err = c.saveSync()
-			if err != nil {
-				c.log.Println("Failed to save the contractor after creating a new contract.")
-			}
-		}()
+		_, err := c.managedRenewContract(id, amount, endHeight, refresh)
+		if err != nil {
+			c.log.Printf("WARN: failed to renew contract %v: %v\n", id, err)
+		}
 
 		// Soft sleep for a minute to allow all of the transactions to propagate
 		// the network.
@@ -612,6 +776,10 @@ func (c *Contractor) threadedContractMaintenance() {
 	c.mu.RLock()
 	uploadContracts := 0
 	for _, contract := range c.contracts {
+		// Manual contracts do not count towards the allowance's hosts.
+		if contract.Manual {
+			continue
+		}
 		if contract.GoodForUpload || (contract.GoodForRenew && c.blockHeight+c.allowance.RenewWindow >= contract.EndHeight()) {
 			uploadContracts++
 		}
@@ -623,8 +791,8 @@ func (c *Contractor) threadedContractMaintenance() {
 	}
 
 	// Assemble an exclusion list that includes all of the hosts that we already
-	// have contracts with, then select a new batch of hosts to attempt contract
-	// formation with.
+	// have contracts with, including manual contracts, then select a new batch
+	// of hosts to attempt contract formation with.
 	c.mu.RLock()
 	var exclude []types.SiaPublicKey
 	for _, contract := range c.contracts {
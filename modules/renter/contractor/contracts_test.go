@@ -0,0 +1,67 @@
+package contractor
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestRefreshReason checks that contracts are refreshed when either the
+// renter funds or the host collateral of the contract run out.
+func TestRefreshReason(t *testing.T) {
+	newContract := func(renterFunds, hostCollateral uint64) modules.RenterContract {
+		rc := modules.RenterContract{TotalCost: types.NewCurrency64(1e6)}
+		rc.LastRevision.NewWindowStart = 20
+		rc.LastRevision.NewValidProofOutputs = []types.SiacoinOutput{{Value: types.NewCurrency64(renterFunds)}, {}}
+		rc.LastRevision.NewMissedProofOutputs = []types.SiacoinOutput{{Value: types.NewCurrency64(renterFunds)}, {Value: types.NewCurrency64(hostCollateral)}, {}}
+		return rc
+	}
+	host := modules.HostDBEntry{}
+	host.Collateral = types.NewCurrency64(1)
+
+	// 10 blocks remain, so three more sectors need 30 sectors' worth of
+	// collateral.
+	sectorCollateral := modules.SectorSize * 10
+	tests := []struct {
+		contract modules.RenterContract
+		refresh  bool
+	}{
+		{newContract(5e5, 3*sectorCollateral), false},
+		{newContract(1e4, 3*sectorCollateral), true},
+		{newContract(5e5, 3*sectorCollateral-1), true},
+		{newContract(5e5, 0), true},
+	}
+	for i, test := range tests {
+		if reason, refresh := refreshReason(test.contract, host, 10); refresh != test.refresh {
+			t.Errorf("%v: expected refresh to be %v, got %v (%q)", i, test.refresh, refresh, reason)
+		}
+	}
+}
+
+// TestReclaimedFunds checks that only the unused funds of previous contracts
+// that have expired are counted as reclaimed.
+func TestReclaimedFunds(t *testing.T) {
+	newContract := func(renterFunds uint64, windowEnd types.BlockHeight) modules.RenterContract {
+		var rc modules.RenterContract
+		rc.LastRevision.NewWindowEnd = windowEnd
+		rc.LastRevision.NewValidProofOutputs = []types.SiacoinOutput{{Value: types.NewCurrency64(renterFunds)}, {}}
+		return rc
+	}
+	contract := newContract(10, 100)
+	contract.PreviousContracts = []modules.RenterContract{newContract(20, 40), newContract(30, 60)}
+	tests := []struct {
+		height    types.BlockHeight
+		reclaimed uint64
+	}{
+		{30, 0},
+		{40, 20},
+		{60, 50},
+		{100, 50},
+	}
+	for _, test := range tests {
+		if reclaimed := reclaimedFunds(contract, test.height); reclaimed.Cmp64(test.reclaimed) != 0 {
+			t.Errorf("at height %v: expected %v reclaimed funds, got %v", test.height, test.reclaimed, reclaimed)
+		}
+	}
+}
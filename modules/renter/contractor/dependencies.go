@@ -51,8 +51,10 @@ type (
 		Host(types.SiaPublicKey) (modules.HostDBEntry, bool)
 		IncrementSuccessfulInteractions(key types.SiaPublicKey)
 		IncrementFailedInteractions(key types.SiaPublicKey)
+		IsFiltered(types.SiaPublicKey) bool
 		RandomHosts(n int, exclude []types.SiaPublicKey) []modules.HostDBEntry
 		ScoreBreakdown(modules.HostDBEntry) modules.HostScoreBreakdown
+		SubnetViolations([]types.SiaPublicKey) []types.SiaPublicKey
 	}
 
 	persister interface {
@@ -176,7 +176,7 @@ func (c *Contractor) Downloader(id types.FileContractID, cancel <-chan struct{})
 	}
 
 	// create downloader
-	d, err := proto.NewDownloader(host, contract, c.hdb, cancel)
+	d, err := proto.NewDownloader(host, contract, c.hdb, c.rl, cancel)
 	if proto.IsRevisionMismatch(err) {
 		// try again with the cached revision
 		c.mu.RLock()
@@ -189,7 +189,7 @@ func (c *Contractor) Downloader(id types.FileContractID, cancel <-chan struct{})
 		}
 		c.log.Printf("host %v has different revision for %v; retrying with cached revision", contract.NetAddress, contract.ID)
 		contract.LastRevision = cached.Revision
-		d, err = proto.NewDownloader(host, contract, c.hdb, cancel)
+		d, err = proto.NewDownloader(host, contract, c.hdb, c.rl, cancel)
 		// needs to be handled separately since a revision mismatch is not automatically a failed interaction
 		if proto.IsRevisionMismatch(err) {
 			c.hdb.IncrementFailedInteractions(host.PublicKey)
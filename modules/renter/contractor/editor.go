@@ -136,6 +136,13 @@ func (he *hostEditor) Delete(root crypto.Hash) (err error) {
 	if he.invalid {
 		return errInvalidEditor
 	}
+	index := -1
+	for i, h := range he.contract.MerkleRoots {
+		if h == root {
+			index = i
+			break
+		}
+	}
 	contract, err := he.editor.Delete(root)
 	if err != nil {
 		return err
@@ -143,7 +150,10 @@ func (he *hostEditor) Delete(root crypto.Hash) (err error) {
 
 	he.contractor.mu.Lock()
 	he.contractor.contracts[contract.ID] = contract
-	he.contractor.saveSync()
+	he.contractor.persist.update(updateDeleteRevision{
+		NewRevisionTxn: contract.LastRevisionTxn,
+		SectorIndex:    index,
+	})
 	he.contractor.mu.Unlock()
 	he.contract = contract
 
@@ -243,7 +253,7 @@ func (c *Contractor) Editor(id types.FileContractID, cancel <-chan struct{}) (_
 	}
 
 	// create editor
-	e, err := proto.NewEditor(host, contract, height, c.hdb, cancel)
+	e, err := proto.NewEditor(host, contract, height, c.hdb, c.rl, cancel)
 	if proto.IsRevisionMismatch(err) {
 		// try again with the cached revision
 		c.mu.RLock()
@@ -257,7 +267,7 @@ func (c *Contractor) Editor(id types.FileContractID, cancel <-chan struct{}) (_
 		c.log.Printf("host %v has different revision for %v; retrying with cached revision", contract.NetAddress, contract.ID)
 		contract.LastRevision = cached.Revision
 		contract.MerkleRoots = cached.MerkleRoots
-		e, err = proto.NewEditor(host, contract, height, c.hdb, cancel)
+		e, err = proto.NewEditor(host, contract, height, c.hdb, c.rl, cancel)
 		// needs to be handled separately since a revision mismatch is not automatically a failed interaction
 		if proto.IsRevisionMismatch(err) {
 			c.hdb.IncrementFailedInteractions(host.PublicKey)
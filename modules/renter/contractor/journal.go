@@ -151,21 +151,34 @@ func openJournal(filename string, data *contractorPersist) (*journal, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := decodeJournal(f, data); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &journal{
+		f:        f,
+		filename: filename,
+	}, nil
+}
 
+// decodeJournal reads a journal from r and decodes the reconstructed
+// contractorPersist into data.
+func decodeJournal(r io.Reader, data *contractorPersist) error {
 	// Decode the metadata.
-	dec := json.NewDecoder(f)
+	dec := json.NewDecoder(r)
 	var meta persist.Metadata
-	if err = dec.Decode(&meta); err != nil {
-		return nil, err
+	if err := dec.Decode(&meta); err != nil {
+		return err
 	} else if meta.Header != journalMeta.Header {
-		return nil, fmt.Errorf("expected header %q, got %q", journalMeta.Header, meta.Header)
+		return fmt.Errorf("expected header %q, got %q", journalMeta.Header, meta.Header)
 	} else if meta.Version != journalMeta.Version {
-		return nil, fmt.Errorf("journal version (%s) is incompatible with the current version (%s)", meta.Version, journalMeta.Version)
+		return fmt.Errorf("journal version (%s) is incompatible with the current version (%s)", meta.Version, journalMeta.Version)
 	}
 
 	// Decode the initial object.
-	if err = dec.Decode(data); err != nil {
-		return nil, err
+	if err := dec.Decode(data); err != nil {
+		return err
 	}
 
 	// Make sure all maps are properly initialized.
@@ -182,7 +195,7 @@ func openJournal(filename string, data *contractorPersist) (*journal, error) {
 	// Decode each set of updates and apply them to data.
 	for {
 		var set updateSet
-		if err = dec.Decode(&set); err == io.EOF || err == io.ErrUnexpectedEOF {
+		if err := dec.Decode(&set); err == io.EOF || err == io.ErrUnexpectedEOF {
 			// unexpected EOF means the last update was corrupted; skip it
 			break
 		} else if err != nil {
@@ -193,11 +206,7 @@ func openJournal(filename string, data *contractorPersist) (*journal, error) {
 			u.apply(data)
 		}
 	}
-
-	return &journal{
-		f:        f,
-		filename: filename,
-	}, nil
+	return nil
 }
 
 type journalUpdate interface {
@@ -228,10 +237,14 @@ func (set updateSet) MarshalJSON() ([]byte, error) {
 			marshaledSet[i].Type = "uploadRevision"
 		case updateDownloadRevision:
 			marshaledSet[i].Type = "downloadRevision"
+		case updateDeleteRevision:
+			marshaledSet[i].Type = "deleteRevision"
 		case updateCachedUploadRevision:
 			marshaledSet[i].Type = "cachedUploadRevision"
 		case updateCachedDownloadRevision:
 			marshaledSet[i].Type = "cachedDownloadRevision"
+		case updateCachedDeleteRevision:
+			marshaledSet[i].Type = "cachedDeleteRevision"
 		}
 	}
 	return json.Marshal(marshaledSet)
@@ -258,6 +271,10 @@ func (set *updateSet) UnmarshalJSON(b []byte) error {
 			var dr updateDownloadRevision
 			err = json.Unmarshal(u.Data, &dr)
 			*set = append(*set, dr)
+		case "deleteRevision":
+			var dr updateDeleteRevision
+			err = json.Unmarshal(u.Data, &dr)
+			*set = append(*set, dr)
 		case "cachedUploadRevision":
 			var cur updateCachedUploadRevision
 			err = json.Unmarshal(u.Data, &cur)
@@ -266,6 +283,10 @@ func (set *updateSet) UnmarshalJSON(b []byte) error {
 			var cdr updateCachedDownloadRevision
 			err = json.Unmarshal(u.Data, &cdr)
 			*set = append(*set, cdr)
+		case "cachedDeleteRevision":
+			var cdr updateCachedDeleteRevision
+			err = json.Unmarshal(u.Data, &cdr)
+			*set = append(*set, cdr)
 		}
 		if err != nil {
 			return err
@@ -333,6 +354,32 @@ func (u updateDownloadRevision) apply(data *contractorPersist) {
 	data.Contracts[rev.ParentID.String()] = c
 }
 
+// updateDeleteRevision is a journalUpdate that records the new data
+// associated with deleting a sector from a host.
+type updateDeleteRevision struct {
+	NewRevisionTxn types.Transaction `json:"newrevisiontxn"`
+	SectorIndex    int               `json:"sectorindex"`
+}
+
+// apply sets the LastRevision and LastRevisionTxn fields of the contract
+// being revised. It also removes the deleted sector's Merkle root from the
+// contract's Merkle root set.
+func (u updateDeleteRevision) apply(data *contractorPersist) {
+	if len(u.NewRevisionTxn.FileContractRevisions) == 0 {
+		build.Critical("updateDeleteRevision is missing its FileContractRevision")
+		return
+	}
+
+	rev := u.NewRevisionTxn.FileContractRevisions[0]
+	c := data.Contracts[rev.ParentID.String()]
+	c.LastRevisionTxn = u.NewRevisionTxn
+	c.LastRevision = rev
+	if u.SectorIndex < len(c.MerkleRoots) {
+		c.MerkleRoots = append(c.MerkleRoots[:u.SectorIndex], c.MerkleRoots[u.SectorIndex+1:]...)
+	}
+	data.Contracts[rev.ParentID.String()] = c
+}
+
 // updateCachedUploadRevision is a journalUpdate that records the unsigned
 // revision sent to the host during a sector upload, along with the Merkle
 // root of the new sector.
@@ -370,3 +417,22 @@ func (u updateCachedDownloadRevision) apply(data *contractorPersist) {
 	c.Revision = u.Revision
 	data.CachedRevisions[u.Revision.ParentID.String()] = c
 }
+
+// updateCachedDeleteRevision is a journalUpdate that records the unsigned
+// revision sent to the host during a sector deletion, along with the index of
+// the deleted sector.
+type updateCachedDeleteRevision struct {
+	Revision    types.FileContractRevision `json:"revision"`
+	SectorIndex int                        `json:"sectorindex"`
+}
+
+// apply sets the Revision field of the cachedRevision associated with the
+// contract being revised, and removes the Merkle root of the deleted sector.
+func (u updateCachedDeleteRevision) apply(data *contractorPersist) {
+	c := data.CachedRevisions[u.Revision.ParentID.String()]
+	c.Revision = u.Revision
+	if u.SectorIndex < len(c.MerkleRoots) {
+		c.MerkleRoots = append(c.MerkleRoots[:u.SectorIndex], c.MerkleRoots[u.SectorIndex+1:]...)
+	}
+	data.CachedRevisions[u.Revision.ParentID.String()] = c
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -91,6 +92,54 @@ func TestJournalCheckpoint(t *testing.T) {
 	}
 }
 
+// TestJournalDeleteRevision checks that deleting a sector removes the right
+// Merkle root from both the contract and its cached revision.
+func TestJournalDeleteRevision(t *testing.T) {
+	roots := []crypto.Hash{{1}, {2}, {3}}
+	var id types.FileContractID
+	initial := contractorPersist{
+		CachedRevisions: map[string]cachedRevision{
+			id.String(): {MerkleRoots: append([]crypto.Hash(nil), roots...)},
+		},
+	}
+	contract := modules.RenterContract{ID: id, MerkleRoots: append([]crypto.Hash(nil), roots...)}
+	initial.Contracts = map[string]modules.RenterContract{id.String(): contract}
+
+	j, err := newJournal(build.TempDir("contractor", t.Name()), initial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(j.filename)
+	rev := types.FileContractRevision{ParentID: id, NewRevisionNumber: 2}
+	err = j.update(updateSet{
+		updateCachedDeleteRevision{Revision: rev, SectorIndex: 1},
+		updateDeleteRevision{
+			NewRevisionTxn: types.Transaction{FileContractRevisions: []types.FileContractRevision{rev}},
+			SectorIndex:    1,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var data contractorPersist
+	j2, err := openJournal(j.filename, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j2.Close()
+	exp := []crypto.Hash{{1}, {3}}
+	if cached := data.CachedRevisions[id.String()]; !reflect.DeepEqual([]crypto.Hash(cached.MerkleRoots), exp) || cached.Revision.NewRevisionNumber != 2 {
+		t.Fatal("cached revision was not updated correctly:", cached)
+	}
+	if c := data.Contracts[id.String()]; !reflect.DeepEqual([]crypto.Hash(c.MerkleRoots), exp) || c.LastRevision.NewRevisionNumber != 2 {
+		t.Fatal("contract was not updated correctly:", c.MerkleRoots)
+	}
+}
+
 func TestJournalMalformedJSON(t *testing.T) {
 	j, cleanup := tempJournal(t)
 	defer cleanup()
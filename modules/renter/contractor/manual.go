@@ -0,0 +1,127 @@
+package contractor
+
+// manual.go lets the user form, renew and cancel individual contracts, outside
+// of the allowance. Contract maintenance leaves manual contracts alone: they
+// are not renewed automatically, their funds are not counted against the
+// allowance, and their hosts do not count towards the allowance's hosts.
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errContractCanceled = errors.New("contract has been canceled")
+	errContractNotFound = errors.New("no record of that contract")
+	errContractRenewing = errors.New("currently renewing that contract")
+	errEndHeightPassed  = errors.New("end height must be in the future")
+	errHostHasContract  = errors.New("a contract with that host already exists")
+	errHostNotFound     = errors.New("no record of that host")
+	errZeroFunds        = errors.New("contract funds must be non-zero")
+)
+
+// checkManualParams checks the funds and end height of a manual contract.
+func (c *Contractor) checkManualParams(funds types.Currency, endHeight types.BlockHeight) error {
+	if funds.IsZero() {
+		return errZeroFunds
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if endHeight <= c.blockHeight {
+		return errEndHeightPassed
+	}
+	return nil
+}
+
+// FormContract forms a manual contract with the host that has the provided
+// public key. The contract is used for uploading, but it is never renewed or
+// replaced by contract maintenance. The hostdb's filter and the one host per
+// subnet rule are not applied, so the user can form a contract with any known
+// host, and maintenance does not drop the contract if its host is filtered
+// later.
+func (c *Contractor) FormContract(hostKey types.SiaPublicKey, funds types.Currency, endHeight types.BlockHeight) (modules.RenterContract, error) {
+	if err := c.tg.Add(); err != nil {
+		return modules.RenterContract{}, err
+	}
+	defer c.tg.Done()
+
+	if err := c.checkManualParams(funds, endHeight); err != nil {
+		return modules.RenterContract{}, err
+	}
+	host, ok := c.hdb.Host(hostKey)
+	if !ok {
+		return modules.RenterContract{}, errHostNotFound
+	}
+	c.mu.RLock()
+	for _, contract := range c.contracts {
+		if contract.HostPublicKey.String() == hostKey.String() {
+			c.mu.RUnlock()
+			return modules.RenterContract{}, errHostHasContract
+		}
+	}
+	c.mu.RUnlock()
+
+	contract, err := c.managedNewContract(host, funds, endHeight)
+	if err != nil {
+		return modules.RenterContract{}, err
+	}
+	contract.GoodForUpload = true
+	contract.GoodForRenew = true
+	contract.Manual = true
+
+	c.mu.Lock()
+	c.contracts[contract.ID] = contract
+	err = c.saveSync()
+	c.mu.Unlock()
+	if err != nil {
+		c.log.Println("Unable to save the contractor:", err)
+	}
+	return contract, nil
+}
+
+// RenewContract renews the active contract with the provided id, adding the
+// provided funds and extending it to the provided end height. Renewing a
+// contract that was formed by the allowance does not make it manual.
+func (c *Contractor) RenewContract(id types.FileContractID, funds types.Currency, endHeight types.BlockHeight) (modules.RenterContract, error) {
+	if err := c.tg.Add(); err != nil {
+		return modules.RenterContract{}, err
+	}
+	defer c.tg.Done()
+
+	if err := c.checkManualParams(funds, endHeight); err != nil {
+		return modules.RenterContract{}, err
+	}
+	c.mu.RLock()
+	contract, ok := c.contracts[id]
+	c.mu.RUnlock()
+	if !ok {
+		return modules.RenterContract{}, errContractNotFound
+	} else if contract.Canceled {
+		return modules.RenterContract{}, errContractCanceled
+	}
+	return c.managedRenewContract(id, funds, endHeight, false)
+}
+
+// CancelContract cancels the active contract with the provided id. The
+// contract is no longer used for uploading and is not renewed, but the data
+// stored with the host can be downloaded until the contract expires.
+func (c *Contractor) CancelContract(id types.FileContractID) error {
+	if err := c.tg.Add(); err != nil {
+		return err
+	}
+	defer c.tg.Done()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	contract, ok := c.contracts[id]
+	if !ok {
+		return errContractNotFound
+	}
+	contract.Canceled = true
+	contract.GoodForUpload = false
+	contract.GoodForRenew = false
+	c.contracts[id] = contract
+	return c.saveSync()
+}
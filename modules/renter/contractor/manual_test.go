@@ -0,0 +1,122 @@
+package contractor
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestManualContractUtility checks that contract maintenance does not change
+// the utility of manual and canceled contracts.
+func TestManualContractUtility(t *testing.T) {
+	c := &Contractor{
+		allowance: modules.Allowance{Hosts: 3},
+		contracts: map[types.FileContractID]modules.RenterContract{
+			{1}: {ID: types.FileContractID{1}, HostPublicKey: types.SiaPublicKey{Key: []byte("foo")}, StartHeight: 1},
+			{2}: {ID: types.FileContractID{2}, HostPublicKey: types.SiaPublicKey{Key: []byte("bar")}, StartHeight: 2, Manual: true, GoodForUpload: true, GoodForRenew: true},
+			{3}: {ID: types.FileContractID{3}, HostPublicKey: types.SiaPublicKey{Key: []byte("baz")}, StartHeight: 3, Canceled: true},
+		},
+		hdb: subnetHostDB{},
+	}
+	c.managedMarkContractsUtility()
+	if contract := c.contracts[types.FileContractID{2}]; !contract.GoodForUpload || !contract.GoodForRenew {
+		t.Fatal("utility of a manual contract was changed by maintenance")
+	}
+	if contract := c.contracts[types.FileContractID{3}]; contract.GoodForUpload || contract.GoodForRenew {
+		t.Fatal("canceled contract was marked as useful")
+	}
+}
+
+// TestManualContractFiltered checks that contract maintenance leaves a manual
+// contract alone even if its host is excluded by the hostdb's filter.
+func TestManualContractFiltered(t *testing.T) {
+	for _, hdb := range []hostDB{filterHostDB{}, subnetFilterHostDB{}} {
+		c := &Contractor{
+			allowance: modules.Allowance{Hosts: 3},
+			contracts: map[types.FileContractID]modules.RenterContract{
+				{1}: {ID: types.FileContractID{1}, HostPublicKey: types.SiaPublicKey{Key: []byte("foo")}, Manual: true, GoodForUpload: true, GoodForRenew: true},
+			},
+			hdb: hdb,
+		}
+		c.managedMarkContractsUtility()
+		if contract := c.contracts[types.FileContractID{1}]; !contract.GoodForUpload || !contract.GoodForRenew {
+			t.Fatalf("maintenance with %T changed the utility of a filtered manual contract", hdb)
+		}
+	}
+}
+
+// TestManualContractErrors checks the errors returned when forming, renewing
+// and canceling manual contracts.
+func TestManualContractErrors(t *testing.T) {
+	c := &Contractor{
+		blockHeight: 10,
+		contracts: map[types.FileContractID]modules.RenterContract{
+			{1}: {ID: types.FileContractID{1}, HostPublicKey: types.SiaPublicKey{Key: []byte("foo")}},
+		},
+		hdb:     subnetHostDB{},
+		persist: new(memPersist),
+	}
+	funds := types.SiacoinPrecision
+
+	if _, err := c.FormContract(types.SiaPublicKey{Key: []byte("bar")}, types.ZeroCurrency, 20); err != errZeroFunds {
+		t.Fatal("expected errZeroFunds, got", err)
+	}
+	if _, err := c.FormContract(types.SiaPublicKey{Key: []byte("bar")}, funds, 10); err != errEndHeightPassed {
+		t.Fatal("expected errEndHeightPassed, got", err)
+	}
+	if _, err := c.FormContract(types.SiaPublicKey{Key: []byte("foo")}, funds, 20); err != errHostHasContract {
+		t.Fatal("expected errHostHasContract, got", err)
+	}
+	c.hdb = newStub{}
+	if _, err := c.FormContract(types.SiaPublicKey{Key: []byte("bar")}, funds, 20); err != errHostNotFound {
+		t.Fatal("expected errHostNotFound, got", err)
+	}
+	if _, err := c.RenewContract(types.FileContractID{2}, funds, 20); err != errContractNotFound {
+		t.Fatal("expected errContractNotFound, got", err)
+	}
+
+	// Cancel the contract, and check that the cancellation is persisted.
+	if err := c.CancelContract(types.FileContractID{2}); err != errContractNotFound {
+		t.Fatal("expected errContractNotFound, got", err)
+	}
+	if err := c.CancelContract(types.FileContractID{1}); err != nil {
+		t.Fatal(err)
+	}
+	contract := c.contracts[types.FileContractID{1}]
+	if !contract.Canceled || contract.GoodForUpload || contract.GoodForRenew {
+		t.Fatal("contract was not canceled")
+	}
+	if persisted := c.persist.(*memPersist).Contracts[contract.ID.String()]; !persisted.Canceled {
+		t.Fatal("cancellation was not persisted")
+	}
+	if _, err := c.RenewContract(types.FileContractID{1}, funds, 20); err != errContractCanceled {
+		t.Fatal("expected errContractCanceled, got", err)
+	}
+}
+
+// TestCancelAllowanceManual checks that canceling the allowance does not
+// archive manual contracts.
+func TestCancelAllowanceManual(t *testing.T) {
+	c := &Contractor{
+		contracts: map[types.FileContractID]modules.RenterContract{
+			{1}: {ID: types.FileContractID{1}},
+			{2}: {ID: types.FileContractID{2}, Manual: true},
+		},
+		log:          persist.NewLogger(ioutil.Discard),
+		oldContracts: make(map[types.FileContractID]modules.RenterContract),
+		renewing:     make(map[types.FileContractID]bool),
+		persist:      new(memPersist),
+	}
+	if err := c.SetAllowance(modules.Allowance{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.contracts[types.FileContractID{1}]; ok {
+		t.Fatal("allowance contract was not archived")
+	}
+	if _, ok := c.contracts[types.FileContractID{2}]; !ok {
+		t.Fatal("manual contract was archived")
+	}
+}
@@ -18,6 +18,7 @@ type contractorPersist struct {
 	CurrentPeriod   types.BlockHeight                 `json:"currentperiod"`
 	LastChange      modules.ConsensusChangeID         `json:"lastchange"`
 	OldContracts    []modules.RenterContract          `json:"oldcontracts"`
+	PastPeriods     []pastPeriod                      `json:"pastperiods"`
 	RenewedIDs      map[string]string                 `json:"renewedids"`
 }
 
@@ -30,6 +31,7 @@ func (c *Contractor) persistData() contractorPersist {
 		Contracts:       make(map[string]modules.RenterContract),
 		CurrentPeriod:   c.currentPeriod,
 		LastChange:      c.lastChange,
+		PastPeriods:     c.pastPeriods,
 		RenewedIDs:      make(map[string]string),
 	}
 	for _, rev := range c.cachedRevisions {
@@ -102,11 +104,20 @@ func (c *Contractor) load() error {
 			contract.TotalCost = contract.FileContract.ValidProofOutputs[0].Value.
 				Add(contract.TxnFee).Add(contract.SiafundFee).Add(contract.ContractFee)
 		}
+		// COMPATv1.3.0
+		// Old versions did not track the proof status of contracts.
+		if contract.ProofStatus == "" {
+			contract.ProofStatus = modules.ContractProofPending
+		}
 		c.contracts[contract.ID] = contract
 	}
 
 	c.lastChange = data.LastChange
+	c.pastPeriods = data.PastPeriods
 	for _, contract := range data.OldContracts {
+		if contract.ProofStatus == "" {
+			contract.ProofStatus = modules.ContractProofPending
+		}
 		c.oldContracts[contract.ID] = contract
 	}
 	for oldString, newString := range data.RenewedIDs {
@@ -135,7 +146,22 @@ func (c *Contractor) saveUploadRevision(id types.FileContractID) func(types.File
 	return func(rev types.FileContractRevision, newRoots []crypto.Hash) error {
 		c.mu.Lock()
 		defer c.mu.Unlock()
+		oldRoots := c.cachedRevisions[id].MerkleRoots
 		c.cachedRevisions[id] = cachedRevision{rev, newRoots}
+		if len(newRoots) == len(oldRoots)-1 {
+			// a sector was deleted; find the index of its root
+			index := len(newRoots)
+			for i := range newRoots {
+				if newRoots[i] != oldRoots[i] {
+					index = i
+					break
+				}
+			}
+			return c.persist.update(updateCachedDeleteRevision{
+				Revision:    rev,
+				SectorIndex: index,
+			})
+		}
 		return c.persist.update(updateCachedUploadRevision{
 			Revision: rev,
 			// only the last root is new
@@ -133,6 +133,69 @@ func TestSaveLoad(t *testing.T) {
 	}
 }
 
+// TestBackupRestore tests that contracts can be restored from a backup into
+// another contractor.
+func TestBackupRestore(t *testing.T) {
+	newTestContractor := func() *Contractor {
+		return &Contractor{
+			persist:         new(memPersist),
+			cachedRevisions: make(map[types.FileContractID]cachedRevision),
+			contracts:       make(map[types.FileContractID]modules.RenterContract),
+			oldContracts:    make(map[types.FileContractID]modules.RenterContract),
+			renewedIDs:      make(map[types.FileContractID]types.FileContractID),
+		}
+	}
+	c := newTestContractor()
+	c.allowance = modules.Allowance{Funds: types.SiacoinPrecision, Hosts: 2, Period: 100, RenewWindow: 50}
+	c.currentPeriod = 20
+	c.contracts[types.FileContractID{1}] = modules.RenterContract{ID: types.FileContractID{1}, SecretKey: crypto.SecretKey{1}}
+	c.contracts[types.FileContractID{2}] = modules.RenterContract{ID: types.FileContractID{2}, SecretKey: crypto.SecretKey{2}}
+	c.oldContracts[types.FileContractID{0}] = modules.RenterContract{ID: types.FileContractID{0}}
+	c.renewedIDs[types.FileContractID{0}] = types.FileContractID{1}
+	c.cachedRevisions[types.FileContractID{1}] = cachedRevision{Revision: types.FileContractRevision{ParentID: types.FileContractID{1}}}
+
+	buf := new(bytes.Buffer)
+	if err := c.Backup(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restore into a contractor that already has contract 2, which should
+	// not be overwritten.
+	c2 := newTestContractor()
+	c2.contracts[types.FileContractID{2}] = modules.RenterContract{ID: types.FileContractID{2}, SecretKey: crypto.SecretKey{3}}
+	if err := c2.Restore(buf); err != nil {
+		t.Fatal(err)
+	}
+	if c2.contracts[types.FileContractID{1}].SecretKey != (crypto.SecretKey{1}) {
+		t.Fatal("contract was not restored:", c2.contracts)
+	}
+	if c2.contracts[types.FileContractID{2}].SecretKey != (crypto.SecretKey{3}) {
+		t.Fatal("existing contract was overwritten")
+	}
+	if _, ok := c2.oldContracts[types.FileContractID{0}]; !ok {
+		t.Fatal("old contract was not restored")
+	}
+	if c2.ResolveID(types.FileContractID{0}) != (types.FileContractID{1}) {
+		t.Fatal("renewed IDs were not restored")
+	}
+	if _, ok := c2.cachedRevisions[types.FileContractID{1}]; !ok {
+		t.Fatal("cached revision was not restored")
+	}
+	if c2.allowance.Period != 100 || c2.currentPeriod != 20 {
+		t.Fatal("allowance was not restored:", c2.allowance, c2.currentPeriod)
+	}
+
+	// The restored contracts should have been saved.
+	if len(c2.persist.(*memPersist).Contracts) != 2 {
+		t.Fatal("restored contracts were not saved")
+	}
+
+	// A corrupt backup is rejected.
+	if err := c2.Restore(bytes.NewReader([]byte("foo"))); err == nil {
+		t.Fatal("expected corrupt backup to be rejected")
+	}
+}
+
 // blockCS is a consensusSet that calls ProcessConsensusChange on its blocks.
 type blockCS struct {
 	blocks []types.Block
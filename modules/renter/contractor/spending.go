@@ -0,0 +1,103 @@
+package contractor
+
+import (
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// A pastPeriod is an allowance period that has ended. The contracts formed
+// between Start and End belong to the period.
+type pastPeriod struct {
+	Start types.BlockHeight `json:"start"`
+	End   types.BlockHeight `json:"end"`
+	Funds types.Currency    `json:"funds"`
+}
+
+// endPeriod records the current period as a past period that ends at end.
+// Periods in which no contracts were formed are not recorded, and the
+// recorded periods never overlap.
+func (c *Contractor) endPeriod(end types.BlockHeight) {
+	p := pastPeriod{
+		Start: c.currentPeriod,
+		End:   end,
+		Funds: c.allowance.Funds,
+	}
+	if n := len(c.pastPeriods); n > 0 && c.pastPeriods[n-1].End > p.Start {
+		p.Start = c.pastPeriods[n-1].End
+	}
+	if p.Start >= p.End {
+		return
+	}
+	for _, contracts := range []map[types.FileContractID]modules.RenterContract{c.contracts, c.oldContracts} {
+		for id, contract := range contracts {
+			if id != metricsContractID && contract.StartHeight >= p.Start && contract.StartHeight < p.End {
+				c.pastPeriods = append(c.pastPeriods, p)
+				return
+			}
+		}
+	}
+}
+
+// periodSpending returns the spending of the contracts formed at or after
+// start and before end.
+func (c *Contractor) periodSpending(start, end types.BlockHeight, funds types.Currency) modules.RenterSpending {
+	rs := modules.RenterSpending{
+		StartHeight: start,
+		EndHeight:   end,
+		Allowance:   funds,
+		Unspent:     funds,
+	}
+	for _, contracts := range []map[types.FileContractID]modules.RenterContract{c.contracts, c.oldContracts} {
+		for id, contract := range contracts {
+			// COMPATv1.0.4-lts
+			// the special metrics contract is not a real contract.
+			if id == metricsContractID || contract.StartHeight < start || (end != 0 && contract.StartHeight >= end) {
+				continue
+			}
+			rs.ContractFees = rs.ContractFees.Add(contract.ContractFee)
+			rs.TransactionFees = rs.TransactionFees.Add(contract.TxnFee)
+			rs.SiafundFees = rs.SiafundFees.Add(contract.SiafundFee)
+			rs.DownloadSpending = rs.DownloadSpending.Add(contract.DownloadSpending)
+			rs.StorageSpending = rs.StorageSpending.Add(contract.StorageSpending)
+			rs.UploadSpending = rs.UploadSpending.Add(contract.UploadSpending)
+			if contract.ProofStatus == modules.ContractProofValid || contract.ProofStatus == modules.ContractProofMissed {
+				rs.Refundable = rs.Refundable.Add(contract.RenterPayout())
+			} else {
+				rs.Refundable = rs.Refundable.Add(contract.RenterFunds())
+			}
+			// Manual contracts are not paid for by the allowance.
+			if !contract.Manual {
+				if rs.Unspent.Cmp(contract.TotalCost) > 0 {
+					rs.Unspent = rs.Unspent.Sub(contract.TotalCost)
+				} else {
+					rs.Unspent = types.ZeroCurrency
+				}
+			}
+		}
+	}
+	return rs
+}
+
+// Spending returns the spending of the current allowance period, and of each
+// past allowance period in chronological order.
+func (c *Contractor) Spending() (modules.RenterSpending, []modules.RenterSpending) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// The current period is reset to zero when the allowance is canceled,
+	// but it never overlaps with the past periods.
+	start := c.currentPeriod
+	if n := len(c.pastPeriods); n > 0 && c.pastPeriods[n-1].End > start {
+		start = c.pastPeriods[n-1].End
+	}
+	current := c.periodSpending(start, 0, c.allowance.Funds)
+	if c.allowance.Period > 0 {
+		current.EndHeight = c.currentPeriod + c.allowance.Period - c.allowance.RenewWindow
+	}
+
+	past := make([]modules.RenterSpending, 0, len(c.pastPeriods))
+	for _, p := range c.pastPeriods {
+		past = append(past, c.periodSpending(p.Start, p.End, p.Funds))
+	}
+	return current, past
+}
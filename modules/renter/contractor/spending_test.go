@@ -0,0 +1,76 @@
+package contractor
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestSpending checks that the spending of the contracts is split into
+// allowance periods.
+func TestSpending(t *testing.T) {
+	newContract := func(id byte, start types.BlockHeight, remaining uint64) modules.RenterContract {
+		rc := modules.RenterContract{
+			ID:              types.FileContractID{id},
+			StartHeight:     start,
+			TotalCost:       types.NewCurrency64(100),
+			ContractFee:     types.NewCurrency64(1),
+			TxnFee:          types.NewCurrency64(2),
+			SiafundFee:      types.NewCurrency64(3),
+			StorageSpending: types.NewCurrency64(4),
+			UploadSpending:  types.NewCurrency64(5),
+			ProofStatus:     modules.ContractProofPending,
+		}
+		rc.LastRevision.NewValidProofOutputs = []types.SiacoinOutput{{Value: types.NewCurrency64(remaining)}, {}}
+		rc.LastRevision.NewMissedProofOutputs = []types.SiacoinOutput{{Value: types.NewCurrency64(remaining / 2)}, {}}
+		return rc
+	}
+	c := &Contractor{
+		allowance:     modules.Allowance{Funds: types.NewCurrency64(1000), Period: 20, RenewWindow: 10},
+		currentPeriod: 10,
+		contracts: map[types.FileContractID]modules.RenterContract{
+			{1}: newContract(1, 5, 50),
+			{2}: newContract(2, 12, 60),
+		},
+		oldContracts: map[types.FileContractID]modules.RenterContract{
+			{3}: newContract(3, 2, 40),
+			// COMPATv1.0.4-lts
+			metricsContractID: {ID: metricsContractID, StartHeight: 12},
+		},
+	}
+	old := c.oldContracts[types.FileContractID{3}]
+	old.ProofStatus = modules.ContractProofMissed
+	c.oldContracts[old.ID] = old
+
+	// The first period, which had no contracts, is not recorded.
+	c.currentPeriod = 0
+	c.endPeriod(1)
+	c.currentPeriod = 1
+	c.allowance.Funds = types.NewCurrency64(150)
+	c.endPeriod(10)
+	c.currentPeriod = 10
+	c.allowance.Funds = types.NewCurrency64(1000)
+	if len(c.pastPeriods) != 1 {
+		t.Fatal("expected one past period, got", len(c.pastPeriods))
+	}
+
+	current, past := c.Spending()
+	if current.StartHeight != 10 || current.EndHeight != 20 || current.Allowance.Cmp64(1000) != 0 {
+		t.Fatal("wrong current period:", current)
+	}
+	if current.ContractFees.Cmp64(1) != 0 || current.StorageSpending.Cmp64(4) != 0 || current.Unspent.Cmp64(900) != 0 || current.Refundable.Cmp64(60) != 0 {
+		t.Fatal("wrong current spending:", current)
+	}
+	if len(past) != 1 {
+		t.Fatal("expected one past period, got", len(past))
+	}
+	// The past period contains one pending and one missed contract, whose
+	// cost exceeds the allowance.
+	if past[0].StartHeight != 1 || past[0].EndHeight != 10 || past[0].TransactionFees.Cmp64(4) != 0 || past[0].SiafundFees.Cmp64(6) != 0 {
+		t.Fatal("wrong past spending:", past[0])
+	}
+	if !past[0].Unspent.IsZero() || past[0].Refundable.Cmp64(70) != 0 {
+		t.Fatal("wrong past unspent and refundable funds:", past[0])
+	}
+}
@@ -38,12 +38,15 @@ func (c *Contractor) ProcessConsensusChange(cc modules.ConsensusChange) {
 		c.log.Println("INFO: archived expired contract", id)
 	}
 
+	c.updateProofStatuses(cc)
+
 	// If we have entered the next period, update currentPeriod
 	// NOTE: "period" refers to the duration of contracts, whereas "cycle"
 	// refers to how frequently the period metrics are reset.
 	// TODO: How to make this more explicit.
 	cycleLen := c.allowance.Period - c.allowance.RenewWindow
 	if c.blockHeight > c.currentPeriod+cycleLen {
+		c.endPeriod(c.currentPeriod + cycleLen)
 		c.currentPeriod += cycleLen
 		// COMPATv1.0.4-lts
 		// if we were storing a special metrics contract, it will be invalid
@@ -66,3 +69,52 @@ func (c *Contractor) ProcessConsensusChange(cc modules.ConsensusChange) {
 		go c.threadedContractMaintenance()
 	}
 }
+
+// updateProofStatuses updates the proof status of the contractor's contracts
+// according to the file contract diffs of cc. A file contract is removed from
+// the consensus set either by a storage proof, in which case the valid proof
+// outputs are paid out, or at the end of its proof window, in which case the
+// missed proof outputs are paid out. Revisions also remove a file contract,
+// but they add it back in the same change, and contract removals before the
+// proof window are caused by reorgs.
+func (c *Contractor) updateProofStatuses(cc modules.ConsensusChange) {
+	// Find the contracts that were proven by the applied blocks.
+	proven := make(map[types.FileContractID]struct{})
+	for _, block := range cc.AppliedBlocks {
+		for _, txn := range block.Transactions {
+			for _, sp := range txn.StorageProofs {
+				proven[sp.ParentID] = struct{}{}
+			}
+		}
+	}
+
+	// Find whether each contract is in the consensus set after the change.
+	inConsensus := make(map[types.FileContractID]bool)
+	for _, fcd := range cc.FileContractDiffs {
+		inConsensus[fcd.ID] = fcd.Direction == modules.DiffApply
+	}
+
+	for id, present := range inConsensus {
+		contracts := c.contracts
+		contract, exists := contracts[id]
+		if !exists {
+			contracts = c.oldContracts
+			contract, exists = contracts[id]
+		}
+		if !exists {
+			continue
+		}
+		status := modules.ContractProofPending
+		if !present && c.blockHeight >= contract.EndHeight() {
+			status = modules.ContractProofMissed
+			if _, ok := proven[id]; ok {
+				status = modules.ContractProofValid
+			}
+		}
+		if status != contract.ProofStatus {
+			contract.ProofStatus = status
+			contracts[id] = contract
+			c.log.Printf("INFO: proof status of contract %v is now %v", id, status)
+		}
+	}
+}
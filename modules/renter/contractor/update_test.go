@@ -52,6 +52,75 @@ func TestProcessConsensusUpdate(t *testing.T) {
 	}
 }
 
+// TestProcessConsensusProofStatus tests that the proof status of archived
+// contracts follows the file contract diffs of consensus changes.
+func TestProcessConsensusProofStatus(t *testing.T) {
+	var stub newStub
+	contracts := make([]modules.RenterContract, 3)
+	for i := range contracts {
+		contracts[i].ID = types.FileContractID{byte(i)}
+		contracts[i].LastRevision.NewWindowStart = 5
+		contracts[i].LastRevision.NewValidProofOutputs = []types.SiacoinOutput{{Value: types.NewCurrency64(10)}, {}}
+		contracts[i].LastRevision.NewMissedProofOutputs = []types.SiacoinOutput{{Value: types.NewCurrency64(3)}, {}}
+		contracts[i].ProofStatus = modules.ContractProofPending
+	}
+	c := &Contractor{
+		cs:          stub,
+		hdb:         stub,
+		blockHeight: 10,
+		contracts:   make(map[types.FileContractID]modules.RenterContract),
+		oldContracts: map[types.FileContractID]modules.RenterContract{
+			contracts[0].ID: contracts[0],
+			contracts[1].ID: contracts[1],
+			contracts[2].ID: contracts[2],
+		},
+		persist: new(memPersist),
+		log:     persist.NewLogger(ioutil.Discard),
+	}
+
+	// The first contract is proven, the second one misses its proof, and the
+	// third one is revised.
+	cc := modules.ConsensusChange{
+		AppliedBlocks: []types.Block{{
+			Transactions: []types.Transaction{{
+				StorageProofs: []types.StorageProof{{ParentID: contracts[0].ID}},
+			}},
+		}},
+		FileContractDiffs: []modules.FileContractDiff{
+			{Direction: modules.DiffRevert, ID: contracts[0].ID},
+			{Direction: modules.DiffRevert, ID: contracts[1].ID},
+			{Direction: modules.DiffRevert, ID: contracts[2].ID},
+			{Direction: modules.DiffApply, ID: contracts[2].ID},
+		},
+	}
+	c.ProcessConsensusChange(cc)
+	expected := []modules.ContractProofStatus{modules.ContractProofValid, modules.ContractProofMissed, modules.ContractProofPending}
+	payouts := []uint64{10, 3, 0}
+	for i, contract := range c.ExpiredContracts() {
+		if contract.ProofStatus != expected[i] {
+			t.Errorf("expected proof status %v for contract %v, got %v", expected[i], i, contract.ProofStatus)
+		}
+		if contract.RenterPayout().Cmp64(payouts[i]) != 0 {
+			t.Errorf("expected payout %v for contract %v, got %v", payouts[i], i, contract.RenterPayout())
+		}
+	}
+
+	// Reverting the block restores the contracts to the consensus set.
+	cc = modules.ConsensusChange{
+		RevertedBlocks: cc.AppliedBlocks,
+		FileContractDiffs: []modules.FileContractDiff{
+			{Direction: modules.DiffApply, ID: contracts[0].ID},
+			{Direction: modules.DiffApply, ID: contracts[1].ID},
+		},
+	}
+	c.ProcessConsensusChange(cc)
+	for i, contract := range c.ExpiredContracts() {
+		if contract.ProofStatus != modules.ContractProofPending {
+			t.Errorf("expected contract %v to be pending after the revert, got %v", i, contract.ProofStatus)
+		}
+	}
+}
+
 // TestIntegrationAutoRenew tests that contracts are automatically renwed at
 // the expected block height.
 func TestIntegrationAutoRenew(t *testing.T) {
@@ -0,0 +1,475 @@
+package renter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+var (
+	// ErrDirExists is returned when a directory is created or renamed to a
+	// siapath that already holds a directory.
+	ErrDirExists = errors.New("a directory already exists at that location")
+
+	// ErrUnknownDir is returned when a requested directory does not exist.
+	ErrUnknownDir = errors.New("no directory known with that path")
+
+	// errDirIntoSelf is returned when a directory is renamed to a location
+	// beneath itself.
+	errDirIntoSelf = errors.New("cannot move a directory into itself")
+)
+
+// A dir is a node in the renter's siapath tree. Every file is a member of the
+// dir named by the siapath of its parent. Dirs are created implicitly when a
+// file is added beneath them, or explicitly through CreateDir, and persist
+// until they are deleted, even if they become empty.
+//
+// Both maps are keyed by the full siapath of the child.
+//
+// metrics caches the aggregated size, health and redundancy of every file
+// beneath the dir until metricsExpiry, so that listing a dir does not visit
+// every file beneath it. The cache is discarded whenever a file beneath the
+// dir is added, removed or gains or loses pieces. metricsGen counts the
+// discards, so that metrics computed from files that changed in the meantime
+// are not cached. The metrics fields are protected by metricsMu, and
+// metricsGen is only changed while also holding the renter's lock.
+type dir struct {
+	name    string
+	files   map[string]*file
+	subDirs map[string]*dir
+
+	metrics       modules.DirectoryInfo
+	metricsExpiry time.Time
+	metricsGen    uint64
+	metricsMu     sync.Mutex
+}
+
+// newDir returns an empty dir with the provided siapath.
+func newDir(name string) *dir {
+	return &dir{
+		name:    name,
+		files:   make(map[string]*file),
+		subDirs: make(map[string]*dir),
+	}
+}
+
+// walk calls fn on d and on every dir beneath d.
+func (d *dir) walk(fn func(*dir)) {
+	fn(d)
+	for _, sd := range d.subDirs {
+		sd.walk(fn)
+	}
+}
+
+// collectMetrics folds the cached metrics of d into di if they have not
+// expired by now, and otherwise does the same for each dir beneath d. The
+// files of the dirs whose metrics are not cached are returned. The caller
+// must hold the renter's lock.
+func (d *dir) collectMetrics(di *modules.DirectoryInfo, now time.Time) (files []*file) {
+	d.metricsMu.Lock()
+	m, cached := d.metrics, now.Before(d.metricsExpiry)
+	d.metricsMu.Unlock()
+	if cached {
+		aggregateDirInfo(di, m.AggregateSize, m.NumFiles, m.Health, m.MinRedundancy)
+		return nil
+	}
+	for _, f := range d.files {
+		files = append(files, f)
+	}
+	for _, sd := range d.subDirs {
+		files = append(files, sd.collectMetrics(di, now)...)
+	}
+	return files
+}
+
+// cacheMetrics caches the metrics in di until expiry, unless the cache was
+// discarded since gen was read.
+func (d *dir) cacheMetrics(di modules.DirectoryInfo, gen uint64, expiry time.Time) {
+	d.metricsMu.Lock()
+	defer d.metricsMu.Unlock()
+	if d.metricsGen == gen {
+		d.metrics = di
+		d.metricsExpiry = expiry
+	}
+}
+
+// parentSiapath returns the siapath of the directory containing siapath. The
+// root directory is represented by the empty siapath.
+func parentSiapath(siapath string) string {
+	i := strings.LastIndex(siapath, "/")
+	if i == -1 {
+		return ""
+	}
+	return siapath[:i]
+}
+
+// isBeneath reports whether siapath is located somewhere beneath the
+// directory dirPath.
+func isBeneath(siapath, dirPath string) bool {
+	if dirPath == "" {
+		return siapath != ""
+	}
+	return strings.HasPrefix(siapath, dirPath+"/")
+}
+
+// aggregateDirInfo folds the metrics of numFiles files into di. Health and
+// redundancy are only taken into account if numFiles is non-zero.
+func aggregateDirInfo(di *modules.DirectoryInfo, size, numFiles uint64, health int, redundancy float64) {
+	if numFiles == 0 {
+		return
+	}
+	if di.NumFiles == 0 || health < di.Health {
+		di.Health = health
+	}
+	if di.NumFiles == 0 || redundancy < di.MinRedundancy {
+		di.MinRedundancy = redundancy
+	}
+	di.AggregateSize += size
+	di.NumFiles += numFiles
+}
+
+// createDir returns the dir at siapath, creating it and any missing parent
+// dirs.
+func (r *Renter) createDir(siapath string) *dir {
+	if d, exists := r.dirs[siapath]; exists {
+		return d
+	}
+	d := newDir(siapath)
+	r.dirs[siapath] = d
+	r.createDir(parentSiapath(siapath)).subDirs[siapath] = d
+	return d
+}
+
+// dirChanged discards the cached metrics of the dir at siapath and of every
+// dir above it. It must be called whenever a file beneath the dir is added,
+// removed or has its pieces changed.
+func (r *Renter) dirChanged(siapath string) {
+	for name := siapath; ; name = parentSiapath(name) {
+		if d, exists := r.dirs[name]; exists {
+			d.metricsMu.Lock()
+			d.metricsGen++
+			d.metricsExpiry = time.Time{}
+			d.metricsMu.Unlock()
+		}
+		if name == "" {
+			return
+		}
+	}
+}
+
+// addFile adds f to the renter's set of files and to the dir containing it.
+func (r *Renter) addFile(f *file) {
+	r.files[f.name] = f
+	r.createDir(parentSiapath(f.name)).files[f.name] = f
+	r.dirChanged(parentSiapath(f.name))
+}
+
+// removeFile removes the file at siapath from the renter's set of files and
+// from the dir containing it.
+func (r *Renter) removeFile(siapath string) {
+	delete(r.files, siapath)
+	if d, exists := r.dirs[parentSiapath(siapath)]; exists {
+		delete(d.files, siapath)
+	}
+	r.dirChanged(parentSiapath(siapath))
+}
+
+// detachDir removes d and every dir beneath it from the siapath tree,
+// returning the names of the removed dirs and the files they contained. The
+// files remain in the renter's set of files.
+func (r *Renter) detachDir(d *dir) (dirNames []string, files []*file) {
+	d.walk(func(sd *dir) {
+		dirNames = append(dirNames, sd.name)
+		for _, f := range sd.files {
+			files = append(files, f)
+		}
+	})
+	if parent, exists := r.dirs[parentSiapath(d.name)]; exists {
+		delete(parent.subDirs, d.name)
+	}
+	r.dirChanged(parentSiapath(d.name))
+	for _, name := range dirNames {
+		delete(r.dirs, name)
+	}
+	return dirNames, files
+}
+
+// removeSiaFiles removes the .sia files of the provided siapaths from disk,
+// followed by the directories named by dirNames that are left empty. Nothing
+// else in the persist directory is removed, because it also holds the
+// metadata of the renter, the contractor and the hostdb.
+func (r *Renter) removeSiaFiles(siapaths, dirNames []string) error {
+	var errs []error
+	for _, siapath := range siapaths {
+		err := os.Remove(filepath.Join(r.persistDir, siapath+ShareExtension))
+		if err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	// Remove the deepest directories first, so that their parents are empty
+	// by the time they are visited.
+	sort.Slice(dirNames, func(i, j int) bool {
+		return strings.Count(dirNames[i], "/") > strings.Count(dirNames[j], "/")
+	})
+	for _, name := range dirNames {
+		removeEmptyDir(filepath.Join(r.persistDir, name))
+	}
+	return build.JoinErrors(errs, "; ")
+}
+
+// removeEmptyDir removes the directory at path if it exists and is empty.
+func removeEmptyDir(path string) {
+	d, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	names, _ := d.Readdirnames(1)
+	d.Close()
+	if len(names) == 0 {
+		os.Remove(path)
+	}
+}
+
+// dirNames returns the siapaths of every dir known to the renter, excluding
+// the root dir.
+func (r *Renter) dirNames() []string {
+	names := make([]string, 0, len(r.dirs))
+	for name := range r.dirs {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CreateDir creates an empty directory at siaPath, along with any missing
+// parent directories.
+func (r *Renter) CreateDir(siaPath string) error {
+	if err := validateSiapath(siaPath); err != nil {
+		return err
+	}
+
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	if _, exists := r.dirs[siaPath]; exists {
+		return ErrDirExists
+	}
+	r.createDir(siaPath)
+	return r.saveSync()
+}
+
+// DeleteDir removes a directory from the renter, along with every file and
+// directory beneath it. As with DeleteFile, the data of the removed files is
+// not cleared from the hosts.
+func (r *Renter) DeleteDir(siaPath string) error {
+	if err := validateSiapath(siaPath); err != nil {
+		return err
+	}
+
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	d, exists := r.dirs[siaPath]
+	if !exists {
+		return ErrUnknownDir
+	}
+	dirNames, files := r.detachDir(d)
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		f.mu.RLock()
+		name := f.name
+		f.mu.RUnlock()
+		r.removeFile(name)
+		delete(r.tracking, name)
+		r.chunkCache.purge(f.masterKey)
+		names = append(names, name)
+	}
+	err := r.removeSiaFiles(names, dirNames)
+	if err != nil {
+		r.log.Println("WARN: couldn't remove .sia files during directory delete:", err)
+	}
+	return r.saveSync()
+}
+
+// DirList returns information on the directory at siaPath, followed by
+// information on each of its direct subdirectories, and information on each
+// of the files it directly contains. The empty siapath refers to the root
+// directory. The metrics of the subdirectories are cached, so listing a
+// directory only visits the files beneath it that changed since it was last
+// listed, until the cache expires after dirMetricsCacheTime.
+func (r *Renter) DirList(siaPath string) ([]modules.DirectoryInfo, []modules.FileInfo, error) {
+	if siaPath != "" {
+		if err := validateSiapath(siaPath); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Collect the cached metrics of each subdirectory and the files whose
+	// metrics are not cached while holding the lock, so that the remaining
+	// metrics can be computed without blocking the renter.
+	now := time.Now()
+	lockID := r.mu.RLock()
+	d, exists := r.dirs[siaPath]
+	if !exists {
+		r.mu.RUnlock(lockID)
+		return nil, nil, ErrUnknownDir
+	}
+	var subDirs []*dir
+	for _, sd := range d.subDirs {
+		subDirs = append(subDirs, sd)
+	}
+	sort.Slice(subDirs, func(i, j int) bool { return subDirs[i].name < subDirs[j].name })
+	subDirInfos := make([]modules.DirectoryInfo, len(subDirs))
+	subDirFiles := make([][]*file, len(subDirs))
+	subDirGens := make([]uint64, len(subDirs))
+	for i, sd := range subDirs {
+		subDirInfos[i] = modules.DirectoryInfo{
+			SiaPath:       sd.name,
+			NumSubDirs:    uint64(len(sd.subDirs)),
+			MinRedundancy: -1,
+		}
+		subDirGens[i] = sd.metricsGen
+		subDirFiles[i] = sd.collectMetrics(&subDirInfos[i], now)
+	}
+	var files []*file
+	for _, f := range d.files {
+		files = append(files, f)
+	}
+	r.mu.RUnlock(lockID)
+
+	// Compute the metrics of each subdirectory. The metrics of the requested
+	// directory are the combination of the metrics of its subdirectories and
+	// its files.
+	isOffline := r.managedIsOfflineFunc()
+	dirInfos := make([]modules.DirectoryInfo, 0, len(subDirs)+1)
+	dirInfos = append(dirInfos, modules.DirectoryInfo{
+		SiaPath:       siaPath,
+		NumSubDirs:    uint64(len(subDirs)),
+		MinRedundancy: -1,
+	})
+	for i, sd := range subDirs {
+		sdi := subDirInfos[i]
+		for _, f := range subDirFiles[i] {
+			f.mu.RLock()
+			aggregateDirInfo(&sdi, f.size, 1, f.health(isOffline), f.redundancy(isOffline))
+			f.mu.RUnlock()
+		}
+		if len(subDirFiles[i]) > 0 {
+			sd.cacheMetrics(sdi, subDirGens[i], now.Add(dirMetricsCacheTime))
+		}
+		aggregateDirInfo(&dirInfos[0], sdi.AggregateSize, sdi.NumFiles, sdi.Health, sdi.MinRedundancy)
+		dirInfos = append(dirInfos, sdi)
+	}
+	fileInfos := make([]modules.FileInfo, 0, len(files))
+	for _, f := range files {
+		f.mu.RLock()
+		aggregateDirInfo(&dirInfos[0], f.size, 1, f.health(isOffline), f.redundancy(isOffline))
+		fileInfos = append(fileInfos, f.info(isOffline))
+		f.mu.RUnlock()
+	}
+	sort.Slice(fileInfos, func(i, j int) bool { return fileInfos[i].SiaPath < fileInfos[j].SiaPath })
+	return dirInfos, fileInfos, nil
+}
+
+// RenameDir moves a directory, along with every file and directory beneath
+// it, to newSiaPath. The new location must not already hold a directory.
+func (r *Renter) RenameDir(siaPath, newSiaPath string) error {
+	if err := validateSiapath(siaPath); err != nil {
+		return err
+	}
+	if err := validateSiapath(newSiaPath); err != nil {
+		return err
+	}
+	if isBeneath(newSiaPath, siaPath) {
+		return errDirIntoSelf
+	}
+
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	d, exists := r.dirs[siaPath]
+	if !exists {
+		return ErrUnknownDir
+	}
+	if _, exists := r.dirs[newSiaPath]; exists {
+		return ErrDirExists
+	}
+	renamed := func(name string) string {
+		return newSiaPath + strings.TrimPrefix(name, siaPath)
+	}
+
+	// Check that none of the renamed files would overwrite an existing file
+	// before modifying anything.
+	var conflict bool
+	d.walk(func(sd *dir) {
+		for name := range sd.files {
+			if _, exists := r.files[renamed(name)]; exists {
+				conflict = true
+			}
+		}
+	})
+	if conflict {
+		return ErrPathOverload
+	}
+
+	// Save every file under its new name before modifying anything, so that
+	// a failed save leaves the directory where it was.
+	var files []*file
+	d.walk(func(sd *dir) {
+		for _, f := range sd.files {
+			files = append(files, f)
+		}
+	})
+	saved := make([]string, 0, len(files))
+	for _, f := range files {
+		f.mu.Lock()
+		oldName := f.name
+		f.name = renamed(oldName)
+		err := r.saveFile(f)
+		f.name = oldName
+		f.mu.Unlock()
+		if err != nil {
+			var newDirNames []string
+			d.walk(func(sd *dir) {
+				newDirNames = append(newDirNames, renamed(sd.name))
+			})
+			if rmErr := r.removeSiaFiles(saved, newDirNames); rmErr != nil {
+				r.log.Println("WARN: couldn't remove .sia files after failed directory rename:", rmErr)
+			}
+			return err
+		}
+		saved = append(saved, renamed(oldName))
+	}
+
+	// Move the dirs and the files.
+	dirNames, _ := r.detachDir(d)
+	for _, name := range dirNames {
+		r.createDir(renamed(name))
+	}
+	oldNames := make([]string, 0, len(files))
+	for _, f := range files {
+		f.mu.Lock()
+		oldName := f.name
+		f.name = renamed(oldName)
+		f.mu.Unlock()
+		oldNames = append(oldNames, oldName)
+		delete(r.files, oldName)
+		r.addFile(f)
+		if t, ok := r.tracking[oldName]; ok {
+			delete(r.tracking, oldName)
+			r.tracking[f.name] = t
+		}
+	}
+	if err := r.saveSync(); err != nil {
+		return err
+	}
+
+	// Delete the old .sia files.
+	return r.removeSiaFiles(oldNames, dirNames)
+}
@@ -0,0 +1,300 @@
+package renter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestParentSiapath probes the parentSiapath and isBeneath helpers.
+func TestParentSiapath(t *testing.T) {
+	parentTests := []struct {
+		in, parent string
+	}{
+		{"foo", ""},
+		{"foo/bar", "foo"},
+		{"foo/bar/baz", "foo/bar"},
+	}
+	for _, pt := range parentTests {
+		if p := parentSiapath(pt.in); p != pt.parent {
+			t.Errorf("parentSiapath(%q): expected %q, got %q", pt.in, pt.parent, p)
+		}
+	}
+
+	beneathTests := []struct {
+		siapath, dir string
+		beneath      bool
+	}{
+		{"foo", "", true},
+		{"", "", false},
+		{"foo/bar", "foo", true},
+		{"foo/bar/baz", "foo", true},
+		{"foo", "foo", false},
+		{"foobar/baz", "foo", false},
+	}
+	for _, bt := range beneathTests {
+		if b := isBeneath(bt.siapath, bt.dir); b != bt.beneath {
+			t.Errorf("isBeneath(%q, %q): expected %v, got %v", bt.siapath, bt.dir, bt.beneath, b)
+		}
+	}
+}
+
+// TestRenterDirs probes the creation, listing, renaming and deletion of
+// renter directories.
+func TestRenterDirs(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	// Add files at several depths of the tree.
+	names := []string{"top", "a/one", "a/two", "a/b/three", "c/four"}
+	var totalSize uint64
+	id := rt.renter.mu.Lock()
+	for _, name := range names {
+		f := newTestingFile()
+		f.name = name
+		totalSize += f.size
+		rt.renter.addFile(f)
+		if err := rt.renter.saveFile(f); err != nil {
+			rt.renter.mu.Unlock(id)
+			t.Fatal(err)
+		}
+	}
+	rt.renter.mu.Unlock(id)
+
+	// Create an empty directory, then try to create it again.
+	if err := rt.renter.CreateDir("empty/dir"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.renter.CreateDir("empty/dir"); err != ErrDirExists {
+		t.Fatal("expected ErrDirExists, got", err)
+	}
+
+	// List the root directory.
+	dirs, files, err := rt.renter.DirList("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 4 || dirs[0].SiaPath != "" || dirs[1].SiaPath != "a" || dirs[2].SiaPath != "c" || dirs[3].SiaPath != "empty" {
+		t.Fatal("root directory listing has wrong directories:", dirs)
+	}
+	if len(files) != 1 || files[0].SiaPath != "top" {
+		t.Fatal("root directory listing has wrong files:", files)
+	}
+	if dirs[0].NumFiles != uint64(len(names)) || dirs[0].AggregateSize != totalSize || dirs[0].NumSubDirs != 3 {
+		t.Fatal("root directory has wrong metrics:", dirs[0])
+	}
+	if dirs[1].NumFiles != 3 || dirs[1].NumSubDirs != 1 {
+		t.Fatal("directory a has wrong metrics:", dirs[1])
+	}
+	if dirs[3].NumFiles != 0 || dirs[3].NumSubDirs != 1 || dirs[3].MinRedundancy != -1 {
+		t.Fatal("empty directory has wrong metrics:", dirs[3])
+	}
+
+	// The metrics of the subdirectories are cached, but the cache is
+	// discarded when a file is added beneath them.
+	id = rt.renter.mu.Lock()
+	aSize := rt.renter.files["a/one"].size + rt.renter.files["a/two"].size + rt.renter.files["a/b/three"].size
+	rt.renter.files["a/one"].size++
+	rt.renter.mu.Unlock(id)
+	dirs, _, err = rt.renter.DirList("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dirs[0].AggregateSize != totalSize || dirs[1].AggregateSize != aSize {
+		t.Fatal("directory metrics were not cached:", dirs[0], dirs[1])
+	}
+	f := newTestingFile()
+	f.name = "a/b/five"
+	id = rt.renter.mu.Lock()
+	rt.renter.addFile(f)
+	rt.renter.mu.Unlock(id)
+	dirs, _, err = rt.renter.DirList("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dirs[0].NumFiles != uint64(len(names))+1 || dirs[1].NumFiles != 4 || dirs[1].AggregateSize != aSize+1+f.size {
+		t.Fatal("directory metrics were not updated:", dirs[0], dirs[1])
+	}
+	id = rt.renter.mu.Lock()
+	rt.renter.files["a/one"].size--
+	rt.renter.removeFile(f.name)
+	rt.renter.mu.Unlock(id)
+
+	// List a directory that does not exist.
+	if _, _, err := rt.renter.DirList("dne"); err != ErrUnknownDir {
+		t.Fatal("expected ErrUnknownDir, got", err)
+	}
+
+	// Try to move a directory into itself, and onto an existing directory.
+	if err := rt.renter.RenameDir("a", "a/b/a"); err != errDirIntoSelf {
+		t.Fatal("expected errDirIntoSelf, got", err)
+	}
+	if err := rt.renter.RenameDir("a", "c"); err != ErrDirExists {
+		t.Fatal("expected ErrDirExists, got", err)
+	}
+
+	// A rename that fails to save a file leaves the directory in place.
+	if err := ioutil.WriteFile(filepath.Join(rt.renter.persistDir, "blocked"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.renter.RenameDir("a", "blocked"); err == nil {
+		t.Fatal("expected rename onto a file on disk to fail")
+	}
+	if _, _, err := rt.renter.DirList("a/b"); err != nil {
+		t.Fatal("directory was moved by a failed rename:", err)
+	}
+	if _, _, err := rt.renter.DirList("blocked"); err != ErrUnknownDir {
+		t.Fatal("expected ErrUnknownDir, got", err)
+	}
+	id = rt.renter.mu.RLock()
+	for _, name := range names {
+		if f, exists := rt.renter.files[name]; !exists || f.name != name {
+			t.Error("file was renamed by a failed rename:", name)
+		}
+	}
+	rt.renter.mu.RUnlock(id)
+
+	// Move a directory beneath another directory.
+	rt.renter.tracking["a/b/three"] = trackedFile{"foo"}
+	if err := rt.renter.RenameDir("a", "c/a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := rt.renter.DirList("a"); err != ErrUnknownDir {
+		t.Fatal("expected ErrUnknownDir, got", err)
+	}
+	dirs, files, err = rt.renter.DirList("c/a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 1 || len(files) != 1 || files[0].SiaPath != "c/a/b/three" {
+		t.Fatal("renamed directory has wrong contents:", dirs, files)
+	}
+	if _, exists := rt.renter.tracking["c/a/b/three"]; !exists {
+		t.Error("renaming should have updated the entry in the tracking set")
+	}
+	if _, err := os.Stat(rt.renter.persistDir + "/c/a/b/three" + ShareExtension); err != nil {
+		t.Error("renamed file was not saved:", err)
+	}
+	if _, err := os.Stat(rt.renter.persistDir + "/a"); !os.IsNotExist(err) {
+		t.Error("old directory was not removed:", err)
+	}
+
+	// Reload the renter; the directories, including the empty ones, should
+	// be restored.
+	id = rt.renter.mu.Lock()
+	rt.renter.files = make(map[string]*file)
+	rt.renter.dirs = map[string]*dir{"": newDir("")}
+	err = rt.renter.load()
+	rt.renter.mu.Unlock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs, _, err = rt.renter.DirList("empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 2 || dirs[1].SiaPath != "empty/dir" {
+		t.Fatal("empty directories were not restored:", dirs)
+	}
+	dirs, _, err = rt.renter.DirList("c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dirs[0].NumFiles != 4 {
+		t.Fatal("directory c has wrong number of files after reload:", dirs[0])
+	}
+
+	// Delete a directory.
+	if err := rt.renter.DeleteDir("c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.renter.DeleteDir("c"); err != ErrUnknownDir {
+		t.Fatal("expected ErrUnknownDir, got", err)
+	}
+	if files := rt.renter.FileList(); len(files) != 1 || files[0].SiaPath != "top" {
+		t.Fatal("DeleteDir did not remove the files beneath the directory:", files)
+	}
+	if _, exists := rt.renter.tracking["c/a/b/three"]; exists {
+		t.Error("deleting should have removed the entry from the tracking set")
+	}
+}
+
+// TestRenterDirsMetadataCollision checks that directories cannot be created
+// on top of the metadata in the renter's persist directory, and that deleting
+// or renaming a directory only removes the .sia files of the renter.
+func TestRenterDirsMetadataCollision(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	// Directories may not be named after persisted metadata.
+	for _, name := range []string{"contractor.journal", PersistFilename, "hostdb.json/foo"} {
+		if err := rt.renter.CreateDir(name); err != errReservedSiapath {
+			t.Fatalf("expected errReservedSiapath for %q, got %v", name, err)
+		}
+	}
+
+	// Add a file to a directory that also holds a file on disk that does
+	// not belong to the renter.
+	f := newTestingFile()
+	f.name = "meta/file"
+	id := rt.renter.mu.Lock()
+	rt.renter.addFile(f)
+	err = rt.renter.saveFile(f)
+	rt.renter.mu.Unlock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign := filepath.Join(rt.renter.persistDir, "meta", "contractor.journal")
+	if err := ioutil.WriteFile(foreign, []byte("journal"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Renaming the directory should move the .sia file, but leave the
+	// foreign file and its directory in place.
+	if err := rt.renter.RenameDir("meta", "moved"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(rt.renter.persistDir, "meta", "file"+ShareExtension)); !os.IsNotExist(err) {
+		t.Fatal("old .sia file was not removed:", err)
+	}
+	if _, err := os.Stat(foreign); err != nil {
+		t.Fatal("renaming a directory removed a file that does not belong to the renter:", err)
+	}
+
+	// Deleting the directory should only remove the .sia file.
+	if err := rt.renter.CreateDir("meta"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.renter.DeleteDir("meta"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(foreign); err != nil {
+		t.Fatal("deleting a directory removed a file that does not belong to the renter:", err)
+	}
+	if err := rt.renter.DeleteDir("moved"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(rt.renter.persistDir, "moved")); !os.IsNotExist(err) {
+		t.Fatal("empty directory was not removed:", err)
+	}
+
+	// The metadata of the renter should be untouched.
+	for _, name := range []string{PersistFilename, logFile, "contractor.journal", "hostdb.log"} {
+		if _, err := os.Stat(filepath.Join(rt.renter.persistDir, name)); err != nil {
+			t.Fatalf("%v was removed: %v", name, err)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"io"
 	"os"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -56,6 +57,21 @@ type (
 		// have tried to fetch a piece of the chunk.
 		completedPieces map[uint64][]byte
 		workerAttempts  map[types.FileContractID]bool
+
+		// overdrive is the number of extra pieces that were requested because
+		// piece downloads of the chunk stalled. finished indicates that the
+		// chunk has been recovered, and that any pieces that are still being
+		// downloaded for it are no longer needed.
+		overdrive int
+		finished  bool
+	}
+
+	// activeDownload is a piece download that a worker is performing. The
+	// download is considered stalled once its deadline has passed.
+	activeDownload struct {
+		chunkDownload *chunkDownload
+		deadline      time.Time
+		stalled       bool
 	}
 
 	// A download is a file download that has been queued by the renter.
@@ -73,6 +89,7 @@ type (
 		startTime    time.Time
 
 		// Static information about the file - can be read without a lock.
+		chunkCache  *chunkCache
 		chunkSize   uint64
 		destination modules.DownloadWriter
 		erasureCode modules.ErasureCoder
@@ -80,6 +97,11 @@ type (
 		masterKey   crypto.TwofishKey
 		numChunks   uint64
 
+		// id identifies downloads that were requested by the user, and is
+		// empty for downloads that the renter performs internally. Downloads
+		// keep their id when they are resumed.
+		id string
+
 		// pieceSet contains a sparse map of the chunk indices to be downloaded to
 		// their piece data.
 		pieceSet          map[uint64]map[types.FileContractID]pieceData
@@ -104,7 +126,8 @@ type (
 		//
 		// activeWorkers indicates the list of workers which are actively
 		// download a piece, and can be utilized again later but are currently
-		// unavailable.
+		// unavailable. Each active worker is mapped to the piece download it
+		// is performing.
 		//
 		// incompleteChunks is a list of chunks (by index) which have had a
 		// download fail. Repeat entries means that multiple downloads failed.
@@ -112,12 +135,22 @@ type (
 		// unless no more workers exist who can download pieces for that chunk,
 		// in which case the download has failed.
 		//
+		// overdriveChunks is a list of chunks for which an extra piece was
+		// requested because a piece download stalled. Unlike the incomplete
+		// chunks, an overdrive request that no worker can serve is dropped
+		// without failing the download.
+		//
 		// resultChan is the channel that is used to receive completed worker
 		// downloads.
+		//
+		// lastSave is the time the download history was last saved after a
+		// chunk was recovered.
 		activePieces     int
-		activeWorkers    map[types.FileContractID]struct{}
+		activeWorkers    map[types.FileContractID]*activeDownload
 		availableWorkers []*worker
 		incompleteChunks []*chunkDownload
+		lastSave         time.Time
+		overdriveChunks  []*chunkDownload
 		resultChan       chan finishedDownload
 	}
 )
@@ -125,6 +158,7 @@ type (
 // newSectionDownload initialises and returns a download object for the specified chunk.
 func (r *Renter) newSectionDownload(f *file, destination modules.DownloadWriter, offset, length uint64) *download {
 	d := newDownload(f, destination)
+	d.chunkCache = r.chunkCache
 
 	if length == 0 {
 		build.Critical("download length should not be zero")
@@ -151,11 +185,14 @@ func (r *Renter) newSectionDownload(f *file, destination modules.DownloadWriter,
 
 // newDownload creates a newly initialized download.
 func newDownload(f *file, destination modules.DownloadWriter) *download {
+	f.mu.RLock()
+	ec := f.erasureCode
+	f.mu.RUnlock()
 	return &download{
 		startTime:        time.Now(),
 		chunkSize:        f.chunkSize(),
 		destination:      destination,
-		erasureCode:      f.erasureCode,
+		erasureCode:      ec,
 		fileSize:         f.size,
 		masterKey:        f.masterKey,
 		numChunks:        f.numChunks(),
@@ -189,6 +226,11 @@ func (d *download) initPieceSet(f *file, r *Renter) {
 	for _, contract := range f.contracts {
 		id := r.hostContractor.ResolveID(contract.ID)
 		for i := range contract.Pieces {
+			// Pieces that are not part of the download's erasure code cannot
+			// be used to recover a chunk.
+			if contract.Pieces[i].Piece >= uint64(d.erasureCode.NumPieces()) {
+				continue
+			}
 			// Only add pieceSet entries for chunks that are going to be downloaded.
 			m, exists := d.pieceSet[contract.Pieces[i].Chunk]
 			if exists {
@@ -267,49 +309,63 @@ func (cd *chunkDownload) recoverChunk() error {
 	}
 
 	result := recoverWriter.Bytes()
+	if cd.download.chunkCache != nil {
+		cd.download.chunkCache.add(chunkCacheKey{cd.download.masterKey, cd.index}, result)
+	}
+	return cd.download.writeChunk(cd.index, result)
+}
 
+// writeChunk writes the part of a recovered chunk that was requested by the
+// download to the download's destination, and marks the chunk as finished.
+func (d *download) writeChunk(index uint64, result []byte) error {
 	// Calculate the offset. If the offset is within the chunk, the
 	// requested offset is passed, otherwise the offset of the chunk
 	// within the overall file is passed.
-	chunkBaseAddress := cd.index * cd.download.chunkSize
-	chunkTopAddress := chunkBaseAddress + cd.download.chunkSize - 1
+	chunkBaseAddress := index * d.chunkSize
+	chunkTopAddress := chunkBaseAddress + d.chunkSize - 1
 	off := chunkBaseAddress
 	lowerBound := 0
-	if cd.download.offset >= chunkBaseAddress && cd.download.offset <= chunkTopAddress {
-		off = cd.download.offset
+	if d.offset >= chunkBaseAddress && d.offset <= chunkTopAddress {
+		off = d.offset
 		offsetInBlock := off - chunkBaseAddress
 		lowerBound = int(offsetInBlock) // If the offset is within the block, part of the block will be ignored
 	}
 
 	// Truncate b if writing the whole buffer at the specified offset would
-	// exceed the maximum file size.
-	upperBound := cd.download.chunkSize
-	if chunkTopAddress > cd.download.length+cd.download.offset {
-		diff := chunkTopAddress - (cd.download.length + cd.download.offset)
-		upperBound -= diff + 1
+	// exceed the end of the requested section.
+	upperBound := d.chunkSize
+	if lastByte := d.offset + d.length - 1; chunkTopAddress > lastByte {
+		upperBound -= chunkTopAddress - lastByte
 	}
 
 	result = result[lowerBound:upperBound]
 
 	// Write the bytes to the requested output.
-	_, err = cd.download.destination.WriteAt(result, int64(off))
+	_, err := d.destination.WriteAt(result, int64(off))
 	if err != nil {
 		return build.ExtendErr("unable to write to download destination", err)
 	}
 
-	cd.download.mu.Lock()
-	defer cd.download.mu.Unlock()
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
 	// Update the download to signal that this chunk has completed. Only update
 	// after the sync, so that durability is maintained.
-	if cd.download.finishedChunks[cd.index] {
+	if d.finishedChunks[index] {
 		build.Critical("recovering chunk when the chunk has already finished downloading")
 	}
-	cd.download.finishedChunks[cd.index] = true
+	d.finishedChunks[index] = true
+
+	// The download may have been paused or cancelled while the chunk was
+	// being written. The chunk still counts towards the progress of the
+	// download, but the download must not be completed a second time.
+	if d.downloadComplete {
+		return nil
+	}
 
 	// Determine whether the download is complete.
 	nowComplete := true
-	for _, chunkComplete := range cd.download.finishedChunks {
+	for _, chunkComplete := range d.finishedChunks {
 		if !chunkComplete {
 			nowComplete = false
 			break
@@ -317,9 +373,9 @@ func (cd *chunkDownload) recoverChunk() error {
 	}
 	if nowComplete {
 		// Signal that the download is complete.
-		cd.download.downloadComplete = true
-		close(cd.download.downloadFinished)
-		err = cd.download.destination.Close()
+		d.downloadComplete = true
+		close(d.downloadFinished)
+		err = d.destination.Close()
 		if err != nil {
 			return err
 		}
@@ -331,18 +387,34 @@ func (cd *chunkDownload) recoverChunk() error {
 // to the renter's chunk queue.
 func (r *Renter) addDownloadToChunkQueue(d *download) {
 	d.mu.Lock()
-	defer d.mu.Unlock()
-
 	// Skip this file if it has already errored out or has already finished
 	// downloading.
 	if d.downloadComplete {
+		d.mu.Unlock()
 		return
 	}
-
-	// Add the unfinished chunks one at a time.
+	var unfinished []uint64
 	for i, isChunkFinished := range d.finishedChunks {
-		// Skip chunks that have already finished downloading.
-		if isChunkFinished {
+		if !isChunkFinished {
+			unfinished = append(unfinished, i)
+		}
+	}
+	d.mu.Unlock()
+
+	// Add the unfinished chunks one at a time. Chunks that are in the chunk
+	// cache are written to the destination immediately instead.
+	var cacheHits bool
+	for _, i := range unfinished {
+		if data, ok := r.chunkCache.get(chunkCacheKey{d.masterKey, i}); ok {
+			if err := d.writeChunk(i, data); err != nil {
+				r.log.Println("Download failed - could not write a cached chunk:", err)
+				d.mu.Lock()
+				d.fail(err)
+				d.mu.Unlock()
+				return
+			}
+			atomic.AddUint64(&d.atomicDataReceived, d.reportedPieceSize*uint64(d.erasureCode.MinPieces()))
+			cacheHits = true
 			continue
 		}
 
@@ -359,12 +431,17 @@ func (r *Renter) addDownloadToChunkQueue(d *download) {
 		}
 		r.chunkQueue = append(r.chunkQueue, cd)
 	}
+
+	// Save the progress of the download if any chunks came from the cache.
+	if cacheHits && d.id != "" {
+		go r.threadedSaveDownloads()
+	}
 }
 
 // downloadIteration performs one iteration of the download loop.
 func (r *Renter) managedDownloadIteration(ds *downloadState) {
 	// Check for sleep and break conditions.
-	if len(ds.incompleteChunks) == 0 && len(ds.activeWorkers) == 0 && len(r.chunkQueue) == 0 {
+	if len(ds.incompleteChunks) == 0 && len(ds.overdriveChunks) == 0 && len(ds.activeWorkers) == 0 && len(r.chunkQueue) == 0 {
 		// If the above conditions are true, it should also be the case that
 		// the number of active pieces is zero.
 		if ds.activePieces != 0 {
@@ -407,6 +484,23 @@ func (r *Renter) managedDownloadIteration(ds *downloadState) {
 	}
 	r.mu.Unlock(id)
 
+	// Prefer the workers whose hosts are expected to deliver pieces the
+	// fastest. Workers that have not downloaded anything yet are tried first,
+	// so that their hosts get measured, unless their hosts have only ever
+	// failed or stalled, in which case they are tried last.
+	estimates := make(map[types.FileContractID]time.Duration, len(ds.availableWorkers))
+	unreliable := make(map[types.FileContractID]bool, len(ds.availableWorkers))
+	for _, worker := range ds.availableWorkers {
+		estimates[worker.contractID], unreliable[worker.contractID] = worker.estimatedDownloadTime()
+	}
+	sort.SliceStable(ds.availableWorkers, func(i, j int) bool {
+		wi, wj := ds.availableWorkers[i].contractID, ds.availableWorkers[j].contractID
+		if unreliable[wi] != unreliable[wj] {
+			return unreliable[wj]
+		}
+		return estimates[wi] < estimates[wj]
+	})
+
 	// Add new chunks to the extent that resources allow.
 	r.managedScheduleNewChunks(ds)
 
@@ -422,14 +516,20 @@ func (r *Renter) managedDownloadIteration(ds *downloadState) {
 // managedScheduleIncompleteChunks also checks wheter a chunk is unable to be
 // completed.
 func (r *Renter) managedScheduleIncompleteChunks(ds *downloadState) {
-	var newIncompleteChunks []*chunkDownload
+	// The overdrive requests are scheduled after the failed piece downloads.
+	numIncomplete := len(ds.incompleteChunks)
+	chunks := append(ds.incompleteChunks[:numIncomplete:numIncomplete], ds.overdriveChunks...)
+	var newIncompleteChunks, newOverdriveChunks []*chunkDownload
 loop:
-	for _, incompleteChunk := range ds.incompleteChunks {
-		// Drop this chunk if the file download has failed in any way.
+	for i, incompleteChunk := range chunks {
+		overdrive := i >= numIncomplete
+
+		// Drop this chunk if the file download has failed in any way, or if
+		// the chunk was already recovered using pieces from other workers.
 		incompleteChunk.download.mu.Lock()
 		downloadComplete := incompleteChunk.download.downloadComplete
 		incompleteChunk.download.mu.Unlock()
-		if downloadComplete {
+		if downloadComplete || incompleteChunk.finished {
 			// The download has most likely failed. No need to complete this
 			// chunk.
 			ds.activePieces--                                       // For the current incomplete chunk.
@@ -465,7 +565,10 @@ loop:
 			}
 			incompleteChunk.workerAttempts[worker.contractID] = true
 			ds.availableWorkers = append(ds.availableWorkers[:i], ds.availableWorkers[i+1:]...)
-			ds.activeWorkers[worker.contractID] = struct{}{}
+			ds.activeWorkers[worker.contractID] = &activeDownload{
+				chunkDownload: incompleteChunk,
+				deadline:      time.Now().Add(stallTimeout(worker)),
+			}
 			select {
 			case worker.priorityDownloadChan <- dw:
 			default:
@@ -485,7 +588,11 @@ loop:
 				// This worker is able to complete the download for this chunk,
 				// but is busy. Keep this chunk until the next iteration of the
 				// download loop.
-				newIncompleteChunks = append(newIncompleteChunks, incompleteChunk)
+				if overdrive {
+					newOverdriveChunks = append(newOverdriveChunks, incompleteChunk)
+				} else {
+					newIncompleteChunks = append(newIncompleteChunks, incompleteChunk)
+				}
 				continue loop
 			}
 		}
@@ -494,6 +601,15 @@ loop:
 		// or the active set is able to pick up the slack. Verify that they are
 		// safe to be scheduled, and then schedule them if so.
 
+		// An extra piece that was requested because of a stall is not needed
+		// to recover the chunk, so it is dropped instead of failing the
+		// download.
+		if overdrive {
+			incompleteChunk.overdrive--
+			ds.activePieces--
+			continue
+		}
+
 		// Cannot find workers to complete this download, fail the download
 		// connected to this chunk.
 		r.log.Println("Not enough workers to finish download:", errInsufficientHosts)
@@ -507,6 +623,7 @@ loop:
 		incompleteChunk.completedPieces = make(map[uint64][]byte)
 	}
 	ds.incompleteChunks = newIncompleteChunks
+	ds.overdriveChunks = newOverdriveChunks
 }
 
 // managedScheduleNewChunks uses the set of available workers to schedule new
@@ -559,6 +676,14 @@ func (r *Renter) managedWaitOnDownloadWork(ds *downloadState) {
 		return
 	}
 
+	// Wake up when the next piece download stalls.
+	var stallChan <-chan time.Time
+	if deadline, ok := ds.nextStall(); ok {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		stallChan = timer.C
+	}
+
 	// Wait for a piece to return. If a new download arrives while waiting, add
 	// it to the download queue immediately.
 	var finishedDownload finishedDownload
@@ -568,6 +693,9 @@ func (r *Renter) managedWaitOnDownloadWork(ds *downloadState) {
 	case d := <-r.newDownloads:
 		r.addDownloadToChunkQueue(d)
 		return
+	case <-stallChan:
+		r.managedOverdriveStalledChunks(ds)
+		return
 	case finishedDownload = <-ds.resultChan:
 	}
 
@@ -593,6 +721,13 @@ func (r *Renter) managedWaitOnDownloadWork(ds *downloadState) {
 		return
 	}
 
+	// Pieces that arrive after their chunk was recovered were requested by
+	// overdrive, and are no longer needed.
+	if cd.finished {
+		ds.activePieces--
+		return
+	}
+
 	// Add this returned piece to the appropriate chunk.
 	if _, ok := cd.completedPieces[finishedDownload.pieceIndex]; ok {
 		r.log.Debugln("Piece", finishedDownload.pieceIndex, "already added")
@@ -604,6 +739,7 @@ func (r *Renter) managedWaitOnDownloadWork(ds *downloadState) {
 
 	// If the chunk has completed, perform chunk recovery.
 	if len(cd.completedPieces) == cd.download.erasureCode.MinPieces() {
+		cd.finished = true
 		err := cd.recoverChunk()
 		ds.activePieces -= len(cd.completedPieces)
 		cd.completedPieces = make(map[uint64][]byte)
@@ -612,7 +748,66 @@ func (r *Renter) managedWaitOnDownloadWork(ds *downloadState) {
 			cd.download.mu.Lock()
 			cd.download.fail(err)
 			cd.download.mu.Unlock()
+		} else if cd.download.id != "" && time.Since(ds.lastSave) > downloadSaveInterval {
+			// Save the progress of the download so that it can be resumed
+			// from this chunk after a restart. The history is saved at most
+			// once per downloadSaveInterval; the final state is saved when
+			// the download stops.
+			ds.lastSave = time.Now()
+			go r.threadedSaveDownloads()
+		}
+	}
+}
+
+// stallTimeout returns the amount of time after which a piece download of the
+// worker is considered stalled.
+func stallTimeout(w *worker) time.Duration {
+	estimate, _ := w.estimatedDownloadTime()
+	timeout := estimate * downloadStallFactor
+	if timeout < downloadStallTimeout {
+		timeout = downloadStallTimeout
+	}
+	return timeout
+}
+
+// nextStall returns the earliest deadline of the active piece downloads that
+// have not stalled yet.
+func (ds *downloadState) nextStall() (time.Time, bool) {
+	var next time.Time
+	for _, ad := range ds.activeWorkers {
+		if !ad.stalled && (next.IsZero() || ad.deadline.Before(next)) {
+			next = ad.deadline
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// managedOverdriveStalledChunks requests an extra piece for the chunks of the
+// piece downloads that have stalled, so that the chunks can be recovered
+// without waiting for the slow hosts. At most downloadOverdrive extra pieces
+// are requested per chunk.
+func (r *Renter) managedOverdriveStalledChunks(ds *downloadState) {
+	now := time.Now()
+	for workerID, ad := range ds.activeWorkers {
+		if ad.stalled || now.Before(ad.deadline) {
+			continue
+		}
+		ad.stalled = true
+
+		id := r.mu.RLock()
+		worker, exists := r.workerPool[workerID]
+		r.mu.RUnlock(id)
+		if exists {
+			worker.recordStall()
+		}
+
+		cd := ad.chunkDownload
+		if cd.finished || cd.overdrive >= downloadOverdrive {
+			continue
 		}
+		cd.overdrive++
+		ds.overdriveChunks = append(ds.overdriveChunks, cd)
+		ds.activePieces++
 	}
 }
 
@@ -629,7 +824,7 @@ func (r *Renter) threadedDownloadLoop() {
 
 	// Create the download state.
 	ds := &downloadState{
-		activeWorkers:    make(map[types.FileContractID]struct{}),
+		activeWorkers:    make(map[types.FileContractID]*activeDownload),
 		availableWorkers: availableWorkers,
 		incompleteChunks: make([]*chunkDownload, 0),
 		resultChan:       make(chan finishedDownload),
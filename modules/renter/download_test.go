@@ -1,11 +1,20 @@
 package renter
 
 import (
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+	"github.com/NebulousLabs/Sia/sync"
+	"github.com/NebulousLabs/Sia/types"
 )
 
 // TestRenterDownloadFileWriter verifies that the renter's DownloadFileWriter
@@ -50,3 +59,318 @@ func TestRenterDownloadFileWriter(t *testing.T) {
 		t.Fatal("expected read to return file already closed, got", err, "instead.")
 	}
 }
+
+// TestStreamerSeek probes the Seek method of the streamer, which should never
+// need to fetch any data.
+func TestStreamerSeek(t *testing.T) {
+	f := newTestingFile()
+	f.size = 1000
+	s := &streamer{file: f}
+
+	seekTests := []struct {
+		offset int64
+		whence int
+		want   int64
+	}{
+		{10, io.SeekStart, 10},
+		{15, io.SeekCurrent, 25},
+		{-5, io.SeekCurrent, 20},
+		{0, io.SeekEnd, 1000},
+		{-100, io.SeekEnd, 900},
+		{2000, io.SeekStart, 2000},
+	}
+	for _, st := range seekTests {
+		off, err := s.Seek(st.offset, st.whence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if off != st.want || s.offset != st.want {
+			t.Fatalf("Seek(%v, %v): expected offset %v, got %v", st.offset, st.whence, st.want, off)
+		}
+	}
+
+	// Seeking before the start of the file should fail without changing the
+	// offset.
+	if _, err := s.Seek(-1, io.SeekStart); err != errNegativeSeek {
+		t.Fatal("expected errNegativeSeek, got", err)
+	}
+	if _, err := s.Seek(-1001, io.SeekEnd); err != errNegativeSeek {
+		t.Fatal("expected errNegativeSeek, got", err)
+	}
+	if s.offset != 2000 {
+		t.Fatal("failed seek changed the offset:", s.offset)
+	}
+	if _, err := s.Seek(0, 3); err == nil {
+		t.Fatal("expected an invalid whence to fail")
+	}
+}
+
+// TestDownloadHistory checks that the download history survives a restart,
+// and that paused downloads resume from their last completed chunk.
+func TestDownloadHistory(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+
+	f := newTestingFile()
+	f.pieceSize = 100
+	f.size = 10 * f.chunkSize()
+	id := r.mu.Lock()
+	r.addFile(f)
+	r.mu.Unlock(id)
+
+	// Queue a download of the whole file, and one of the first chunk.
+	newQueuedDownload := func(dest string, length uint64) *download {
+		dfw, err := NewDownloadFileWriter(dest, 0, length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := r.newSectionDownload(f, dfw, 0, length)
+		d.id = dest
+		id := r.mu.Lock()
+		r.downloadQueue = append(r.downloadQueue, d)
+		r.mu.Unlock(id)
+		return d
+	}
+	dir := build.TempDir("renter", t.Name()+"-downloads")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	paused := newQueuedDownload(filepath.Join(dir, "paused"), f.size)
+	active := newQueuedDownload(filepath.Join(dir, "active"), f.chunkSize())
+
+	// Pretend that the first three chunks of the first download have been
+	// written, then pause it.
+	for i := uint64(0); i < 3; i++ {
+		paused.finishedChunks[i] = true
+	}
+	if err := r.PauseDownload(paused.id); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.PauseDownload(paused.id); err != errDownloadNotActive {
+		t.Fatal("expected errDownloadNotActive, got", err)
+	}
+	if err := r.ResumeDownload(active.id); err != errDownloadNotPaused {
+		t.Fatal("expected errDownloadNotPaused, got", err)
+	}
+	if err := r.CancelDownload("foo"); err != errUnknownDownload {
+		t.Fatal("expected errUnknownDownload, got", err)
+	}
+
+	// Reload the download history, as if the renter had restarted.
+	id = r.mu.Lock()
+	r.downloadQueue = nil
+	err = r.loadDownloads()
+	r.mu.Unlock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := make(map[string]modules.DownloadStatus)
+	for _, di := range r.DownloadQueue() {
+		statuses[di.ID] = di.Status
+		if di.Destination.Destination() != di.ID {
+			t.Fatal("destination was not restored:", di.Destination.Destination())
+		}
+	}
+	if len(statuses) != 2 || statuses[paused.id] != modules.DownloadStatusPaused || statuses[active.id] != modules.DownloadStatusPaused {
+		t.Fatal("download statuses were not restored correctly:", statuses)
+	}
+	id = r.mu.RLock()
+	interrupted := r.downloadByID(active.id)
+	r.mu.RUnlock(id)
+	if interrupted.Err() != errDownloadInterrupted {
+		t.Fatal("in-progress download should be marked as interrupted, got", interrupted.Err())
+	}
+
+	// Resuming the paused download should only leave the last seven chunks
+	// to be downloaded.
+	resumed, err := r.managedResumeDownload(paused.id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.id != paused.id || !resumed.startTime.Equal(paused.startTime) {
+		t.Fatal("resumed download does not match the original download")
+	}
+	for i := uint64(0); i < 10; i++ {
+		if resumed.finishedChunks[i] != (i < 3) {
+			t.Fatalf("chunk %v has the wrong finished state after resuming", i)
+		}
+	}
+	id = r.mu.RLock()
+	replaced := r.downloadByID(paused.id) == resumed
+	r.mu.RUnlock(id)
+	if !replaced {
+		t.Fatal("resumed download did not replace the paused download")
+	}
+
+	// A cancelled download cannot be resumed.
+	if err := r.CancelDownload(active.id); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ResumeDownload(active.id); err != errDownloadNotPaused {
+		t.Fatal("expected errDownloadNotPaused, got", err)
+	}
+}
+
+// TestPruneDownloadHistory checks that the download history is capped at
+// maxDownloadHistory downloads, and that only the oldest downloads that have
+// stopped are pruned.
+func TestPruneDownloadHistory(t *testing.T) {
+	r := &Renter{}
+	for i := 0; i < maxDownloadHistory+3; i++ {
+		d := &download{id: strconv.Itoa(i), downloadComplete: true}
+		switch i {
+		case 0:
+			d.downloadErr = errDownloadPaused
+		case 1:
+			d.downloadComplete = false
+		case 2:
+			d.downloadErr = errDownloadCancelled
+		}
+		r.downloadQueue = append(r.downloadQueue, d)
+	}
+	r.pruneDownloadHistory()
+
+	if len(r.downloadQueue) != maxDownloadHistory {
+		t.Fatal("history was not pruned to the cap:", len(r.downloadQueue))
+	}
+	var ids []string
+	for _, d := range r.downloadQueue {
+		ids = append(ids, d.id)
+	}
+	if ids[0] != "0" || ids[1] != "1" || ids[2] != "5" || ids[len(ids)-1] != strconv.Itoa(maxDownloadHistory+2) {
+		t.Fatal("wrong downloads were pruned:", ids)
+	}
+
+	// A history below the cap is left alone.
+	r.pruneDownloadHistory()
+	if len(r.downloadQueue) != maxDownloadHistory {
+		t.Fatal("history below the cap was pruned:", len(r.downloadQueue))
+	}
+}
+
+// TestWorkerDownloadStats checks that workers keep rolling download
+// statistics for their hosts, and estimate their download times from them.
+func TestWorkerDownloadStats(t *testing.T) {
+	w := &worker{downloadStats: new(hostDownloadStats)}
+	if estimate, unreliable := w.estimatedDownloadTime(); estimate != 0 || unreliable {
+		t.Fatal("worker without statistics should have no estimate")
+	}
+	w.recordFailure()
+	if _, unreliable := w.estimatedDownloadTime(); !unreliable {
+		t.Fatal("worker that only failed should be unreliable")
+	}
+	w.recordDownload(time.Second, time.Second, int(modules.SectorSize))
+	if estimate, unreliable := w.estimatedDownloadTime(); estimate != 2*time.Second || unreliable {
+		t.Fatal("wrong estimate after the first download:", estimate, unreliable)
+	}
+	w.recordDownload(11*time.Second, time.Second, int(modules.SectorSize))
+	stats := w.downloadStats.stats
+	if stats.Latency != 2*time.Second || stats.Throughput != modules.SectorSize || stats.Samples != 2 || stats.Failures != 1 {
+		t.Fatal("wrong rolling statistics:", stats)
+	}
+	if stallTimeout(w) != 9*time.Second {
+		t.Fatal("wrong stall timeout:", stallTimeout(w))
+	}
+}
+
+// TestHostDownloadStats checks that the download statistics are kept per
+// host, so that a renewed contract keeps the statistics of its host.
+func TestHostDownloadStats(t *testing.T) {
+	r := &Renter{
+		mu:                sync.New(modules.SafeMutexDelay, 1),
+		workerPool:        make(map[types.FileContractID]*worker),
+		hostDownloadStats: make(map[string]*hostDownloadStats),
+	}
+	host := types.SiaPublicKey{Key: []byte{1}}
+	old := modules.RenterContract{ID: types.FileContractID{1}, HostPublicKey: host}
+	r.updateWorkerPool([]modules.RenterContract{old})
+	r.workerPool[old.ID].recordStall()
+
+	renewed := modules.RenterContract{ID: types.FileContractID{2}, HostPublicKey: host}
+	r.updateWorkerPool([]modules.RenterContract{renewed})
+	if r.workerPool[renewed.ID].downloadStats.stats.Stalls != 1 {
+		t.Fatal("renewed contract did not keep the statistics of its host")
+	}
+	if stats := r.DownloadStats(); len(stats) != 1 || stats[host.String()].Stalls != 1 {
+		t.Fatal("wrong download stats:", stats)
+	}
+	close(r.workerPool[renewed.ID].killChan)
+}
+
+// TestOverdriveStalledChunks checks that extra pieces are requested for the
+// chunks of stalled piece downloads, up to the overdrive limit, and that
+// extra pieces that cannot be served are dropped without failing the
+// download.
+func TestOverdriveStalledChunks(t *testing.T) {
+	r := &Renter{
+		log:        persist.NewLogger(ioutil.Discard),
+		mu:         sync.New(modules.SafeMutexDelay, 1),
+		workerPool: make(map[types.FileContractID]*worker),
+	}
+	dir := build.TempDir("renter", t.Name())
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	dfw, err := NewDownloadFileWriter(filepath.Join(dir, "download"), 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cd := &chunkDownload{download: &download{
+		destination:      dfw,
+		downloadFinished: make(chan struct{}),
+	}}
+	ds := &downloadState{
+		activeWorkers: make(map[types.FileContractID]*activeDownload),
+	}
+	for i := byte(0); i < downloadOverdrive+1; i++ {
+		id := types.FileContractID{i}
+		r.workerPool[id] = &worker{contractID: id, downloadStats: new(hostDownloadStats)}
+		ds.activeWorkers[id] = &activeDownload{
+			chunkDownload: cd,
+			deadline:      time.Now().Add(-time.Second),
+		}
+	}
+	ds.activeWorkers[types.FileContractID{100}] = &activeDownload{
+		chunkDownload: cd,
+		deadline:      time.Now().Add(time.Hour),
+	}
+
+	r.managedOverdriveStalledChunks(ds)
+	if cd.overdrive != downloadOverdrive || len(ds.overdriveChunks) != downloadOverdrive || len(ds.incompleteChunks) != 0 || ds.activePieces != downloadOverdrive {
+		t.Fatal("wrong number of extra pieces requested:", cd.overdrive, len(ds.overdriveChunks), ds.activePieces)
+	}
+	if r.workerPool[types.FileContractID{0}].downloadStats.stats.Stalls != 1 {
+		t.Fatal("stall was not recorded")
+	}
+	if next, ok := ds.nextStall(); !ok || time.Until(next) < time.Minute {
+		t.Fatal("wrong next stall:", next, ok)
+	}
+
+	// No worker can serve the extra pieces, so they are dropped without
+	// failing the download.
+	ds.activeWorkers = make(map[types.FileContractID]*activeDownload)
+	r.managedScheduleIncompleteChunks(ds)
+	if len(ds.overdriveChunks) != 0 || cd.overdrive != 0 || ds.activePieces != 0 {
+		t.Fatal("extra pieces were not dropped:", len(ds.overdriveChunks), cd.overdrive, ds.activePieces)
+	}
+	if err := cd.download.Err(); err != nil {
+		t.Fatal("dropping the extra pieces failed the download:", err)
+	}
+
+	// A failed piece download that cannot be retried fails the download,
+	// even if extra pieces were requested for the chunk.
+	cd.overdrive = 1
+	ds.incompleteChunks = []*chunkDownload{cd}
+	ds.activePieces++
+	r.managedScheduleIncompleteChunks(ds)
+	if cd.download.Err() != errInsufficientHosts {
+		t.Fatal("expected the failed piece download to fail the download, got", cd.download.Err())
+	}
+}
@@ -1,14 +1,251 @@
 package renter
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"path/filepath"
 	"sync/atomic"
 
+	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/fastrand"
 )
 
+var (
+	errDownloadCancelled   = errors.New("download was cancelled")
+	errDownloadInterrupted = errors.New("download was interrupted by shutdown")
+	errDownloadNotActive   = errors.New("download is not in progress")
+	errDownloadNotPausable = errors.New("only downloads to a file on disk can be paused")
+	errDownloadNotPaused   = errors.New("download is not paused")
+	errDownloadPaused      = errors.New("download was paused")
+	errUnknownDownload     = errors.New("no download with that id")
+)
+
+// historicDestination is the DownloadWriter of a download that was loaded
+// from the download history. It only records where the download was written
+// to; the download has to be resumed before it can write any data.
+type historicDestination string
+
+// Destination implements the Destination method of the DownloadWriter
+// interface.
+func (hd historicDestination) Destination() string {
+	return string(hd)
+}
+
+// WriteAt implements the WriteAt method of the DownloadWriter interface. It
+// always returns an error.
+func (hd historicDestination) WriteAt(b []byte, off int64) (int, error) {
+	return 0, errors.New("cannot write to the destination of an inactive download")
+}
+
+// Close implements the Close method of the DownloadWriter interface.
+func (hd historicDestination) Close() error {
+	return nil
+}
+
+// status reports the status of the download. The download's lock must be
+// held.
+func (d *download) status() modules.DownloadStatus {
+	switch {
+	case !d.downloadComplete:
+		return modules.DownloadStatusActive
+	case d.downloadErr == nil:
+		return modules.DownloadStatusComplete
+	case d.downloadErr == errDownloadPaused || d.downloadErr == errDownloadInterrupted:
+		return modules.DownloadStatusPaused
+	case d.downloadErr == errDownloadCancelled:
+		return modules.DownloadStatusCancelled
+	default:
+		return modules.DownloadStatusFailed
+	}
+}
+
+// resumable indicates whether the download can be paused and resumed, which
+// is only the case for downloads to a file on disk.
+func (d *download) resumable() bool {
+	return filepath.IsAbs(d.destination.Destination())
+}
+
+// downloadByID returns the download in the download queue with the given id,
+// or nil if there is no such download.
+func (r *Renter) downloadByID(id string) *download {
+	for _, d := range r.downloadQueue {
+		if d.id == id {
+			return d
+		}
+	}
+	return nil
+}
+
+// pruneDownloadHistory removes the oldest downloads that have stopped from
+// the download queue until at most maxDownloadHistory downloads remain.
+// Active and paused downloads are kept, as they can still make progress.
+func (r *Renter) pruneDownloadHistory() {
+	excess := len(r.downloadQueue) - maxDownloadHistory
+	if excess <= 0 {
+		return
+	}
+	kept := r.downloadQueue[:0]
+	for _, d := range r.downloadQueue {
+		d.mu.Lock()
+		status := d.status()
+		d.mu.Unlock()
+		if excess > 0 && status != modules.DownloadStatusActive && status != modules.DownloadStatusPaused {
+			excess--
+			continue
+		}
+		kept = append(kept, d)
+	}
+	for i := len(kept); i < len(r.downloadQueue); i++ {
+		r.downloadQueue[i] = nil
+	}
+	r.downloadQueue = kept
+}
+
+// managedRunDownload hands a download to the download loop and blocks until
+// the download has stopped. The download history is saved once the download
+// stops.
+func (r *Renter) managedRunDownload(d *download) error {
+	select {
+	case r.newDownloads <- d:
+	case <-r.tg.StopChan():
+		return errors.New("download interrupted by shutdown")
+	}
+
+	// Block until the download has completed.
+	//
+	// TODO: Eventually just return the channel to the error instead of the
+	// error itself.
+	select {
+	case <-d.downloadFinished:
+	case <-r.tg.StopChan():
+		return errors.New("download interrupted by shutdown")
+	}
+	if err := r.managedSaveDownloads(); err != nil {
+		r.log.Println("ERROR: unable to save download history:", err)
+	}
+	return d.Err()
+}
+
+// threadedRunDownload runs a download in the background.
+func (r *Renter) threadedRunDownload(d *download) {
+	if err := r.tg.Add(); err != nil {
+		return
+	}
+	defer r.tg.Done()
+	r.managedRunDownload(d)
+}
+
+// managedResumeDownload replaces the paused download with the given id with a
+// new download that continues from the last completed chunk. The new download
+// is not handed to the download loop.
+func (r *Renter) managedResumeDownload(id string) (*download, error) {
+	lockID := r.mu.Lock()
+	d, err := r.resumeDownload(id)
+	r.mu.Unlock(lockID)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.managedSaveDownloads(); err != nil {
+		r.log.Println("ERROR: unable to save download history:", err)
+	}
+	return d, nil
+}
+
+// resumeDownload creates the download that replaces the paused download with
+// the given id in the download queue.
+func (r *Renter) resumeDownload(id string) (*download, error) {
+	old := r.downloadByID(id)
+	if old == nil {
+		return nil, errUnknownDownload
+	}
+	old.mu.Lock()
+	status := old.status()
+	finishedChunks := make(map[uint64]bool, len(old.finishedChunks))
+	for i, finished := range old.finishedChunks {
+		finishedChunks[i] = finished
+	}
+	old.mu.Unlock()
+	if status != modules.DownloadStatusPaused {
+		return nil, errDownloadNotPaused
+	}
+
+	f, exists := r.files[old.siapath]
+	if !exists {
+		return nil, ErrUnknownPath
+	}
+	if old.offset+old.length > f.size {
+		return nil, errors.New("file is smaller than the requested download")
+	}
+
+	// The file writer does not truncate the destination, so the data of the
+	// completed chunks is kept.
+	dfw, err := NewDownloadFileWriter(old.destination.Destination(), old.offset, old.length)
+	if err != nil {
+		return nil, err
+	}
+	d := r.newSectionDownload(f, dfw, old.offset, old.length)
+	d.id = old.id
+	d.startTime = old.startTime
+	minPieces := uint64(d.erasureCode.MinPieces())
+	for i := range d.finishedChunks {
+		if finishedChunks[i] {
+			d.finishedChunks[i] = true
+			d.atomicDataReceived += d.reportedPieceSize * minPieces
+		}
+	}
+
+	// If every chunk was written before the download was paused, there is
+	// nothing left for the download loop to do.
+	complete := true
+	for _, finished := range d.finishedChunks {
+		complete = complete && finished
+	}
+	if complete {
+		d.downloadComplete = true
+		close(d.downloadFinished)
+		dfw.Close()
+	}
+
+	for i := range r.downloadQueue {
+		if r.downloadQueue[i] == old {
+			r.downloadQueue[i] = d
+		}
+	}
+	return d, nil
+}
+
+// threadedResumeInterruptedDownloads resumes the downloads that were still in
+// progress when the renter was last shut down.
+func (r *Renter) threadedResumeInterruptedDownloads() {
+	if err := r.tg.Add(); err != nil {
+		return
+	}
+	defer r.tg.Done()
+
+	var interrupted []*download
+	lockID := r.mu.RLock()
+	for _, d := range r.downloadQueue {
+		if d.Err() == errDownloadInterrupted {
+			interrupted = append(interrupted, d)
+		}
+	}
+	r.mu.RUnlock(lockID)
+
+	for _, old := range interrupted {
+		d, err := r.managedResumeDownload(old.id)
+		if err != nil {
+			r.log.Println("WARN: could not resume interrupted download of", old.siapath, ":", err)
+			old.mu.Lock()
+			old.downloadErr = build.ComposeErrors(errDownloadInterrupted, err)
+			old.mu.Unlock()
+			continue
+		}
+		go r.threadedRunDownload(d)
+	}
+}
+
 // Download performs a file download using the passed parameters.
 func (r *Renter) Download(p modules.RenterDownloadParameters) error {
 	// lookup the file associated with the nickname.
@@ -61,22 +298,17 @@ func (r *Renter) Download(p modules.RenterDownloadParameters) error {
 
 	// Create the download object and add it to the queue.
 	d := r.newSectionDownload(file, dw, p.Offset, p.Length)
+	d.id = hex.EncodeToString(fastrand.Bytes(8))
 
 	lockID = r.mu.Lock()
 	r.downloadQueue = append(r.downloadQueue, d)
+	r.pruneDownloadHistory()
 	r.mu.Unlock(lockID)
-	r.newDownloads <- d
-
-	// Block until the download has completed.
-	//
-	// TODO: Eventually just return the channel to the error instead of the
-	// error itself.
-	select {
-	case <-d.downloadFinished:
-		return d.Err()
-	case <-r.tg.StopChan():
-		return errors.New("download interrupted by shutdown")
+	if err := r.managedSaveDownloads(); err != nil {
+		r.log.Println("ERROR: unable to save download history:", err)
 	}
+
+	return r.managedRunDownload(d)
 }
 
 // DownloadQueue returns the list of downloads in the queue.
@@ -90,6 +322,7 @@ func (r *Renter) DownloadQueue() []modules.DownloadInfo {
 		d := r.downloadQueue[len(r.downloadQueue)-i-1]
 
 		downloads[i] = modules.DownloadInfo{
+			ID:          d.id,
 			SiaPath:     d.siapath,
 			Destination: d.destination,
 			Filesize:    d.length,
@@ -97,9 +330,91 @@ func (r *Renter) DownloadQueue() []modules.DownloadInfo {
 		}
 		downloads[i].Received = atomic.LoadUint64(&d.atomicDataReceived)
 
-		if err := d.Err(); err != nil {
-			downloads[i].Error = err.Error()
+		d.mu.Lock()
+		downloads[i].Status = d.status()
+		if d.downloadErr != nil && downloads[i].Status != modules.DownloadStatusPaused {
+			downloads[i].Error = d.downloadErr.Error()
 		}
+		d.mu.Unlock()
 	}
 	return downloads
 }
+
+// CancelDownload cancels the download with the given id. Any data that has
+// already been written to the destination is left in place.
+func (r *Renter) CancelDownload(id string) error {
+	lockID := r.mu.Lock()
+	err := r.cancelDownload(id)
+	r.mu.Unlock(lockID)
+	if err != nil {
+		return err
+	}
+	return r.managedSaveDownloads()
+}
+
+// cancelDownload marks the download with the given id as cancelled.
+func (r *Renter) cancelDownload(id string) error {
+	d := r.downloadByID(id)
+	if d == nil {
+		return errUnknownDownload
+	}
+	d.mu.Lock()
+	switch d.status() {
+	case modules.DownloadStatusActive:
+		d.fail(errDownloadCancelled)
+	case modules.DownloadStatusPaused:
+		d.downloadErr = errDownloadCancelled
+	default:
+		d.mu.Unlock()
+		return errDownloadNotActive
+	}
+	d.mu.Unlock()
+	return nil
+}
+
+// PauseDownload pauses the download with the given id. The download can be
+// continued from its last completed chunk with ResumeDownload.
+func (r *Renter) PauseDownload(id string) error {
+	lockID := r.mu.Lock()
+	err := r.pauseDownload(id)
+	r.mu.Unlock(lockID)
+	if err != nil {
+		return err
+	}
+	return r.managedSaveDownloads()
+}
+
+// pauseDownload marks the active download with the given id as paused.
+func (r *Renter) pauseDownload(id string) error {
+	d := r.downloadByID(id)
+	if d == nil {
+		return errUnknownDownload
+	}
+	if !d.resumable() {
+		return errDownloadNotPausable
+	}
+	d.mu.Lock()
+	if d.status() != modules.DownloadStatusActive {
+		d.mu.Unlock()
+		return errDownloadNotActive
+	}
+	d.fail(errDownloadPaused)
+	d.mu.Unlock()
+	return nil
+}
+
+// ResumeDownload resumes the paused download with the given id from its last
+// completed chunk. The download continues in the background.
+func (r *Renter) ResumeDownload(id string) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+
+	d, err := r.managedResumeDownload(id)
+	if err != nil {
+		return err
+	}
+	go r.threadedRunDownload(d)
+	return nil
+}
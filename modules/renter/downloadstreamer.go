@@ -0,0 +1,132 @@
+package renter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+var (
+	// errNegativeSeek is returned when a streamer is asked to seek to a
+	// position before the start of the file.
+	errNegativeSeek = errors.New("cannot seek to a negative offset")
+)
+
+// A streamer is an io.ReadSeeker over the contents of a file stored on the
+// network. Chunks are downloaded as they are read, so that only the chunks
+// covering the requested bytes are ever fetched. The most recently downloaded
+// chunk is kept in memory so that sequential reads do not refetch it.
+//
+// A streamer is not safe for concurrent use.
+type streamer struct {
+	file   *file
+	offset int64
+	r      *Renter
+
+	// chunk holds the data of the chunk at index chunkIndex, or is nil if no
+	// chunk has been downloaded yet.
+	chunk      []byte
+	chunkIndex uint64
+}
+
+// managedFetchChunk downloads the chunk at index into the streamer's buffer.
+func (s *streamer) managedFetchChunk(index uint64) error {
+	chunkSize := s.file.chunkSize()
+	offset := index * chunkSize
+	length := chunkSize
+	if offset+length > s.file.size {
+		length = s.file.size - offset
+	}
+
+	buf := NewDownloadBufferWriter(length, int64(offset))
+	d := s.r.newSectionDownload(s.file, buf, offset, length)
+	select {
+	case s.r.newDownloads <- d:
+	case <-s.r.tg.StopChan():
+		return errors.New("download interrupted by shutdown")
+	}
+	select {
+	case <-d.downloadFinished:
+	case <-s.r.tg.StopChan():
+		return errors.New("download interrupted by shutdown")
+	}
+	if err := d.Err(); err != nil {
+		return err
+	}
+
+	s.chunk = buf.Bytes()
+	s.chunkIndex = index
+	return nil
+}
+
+// Read implements the io.Reader interface. Read returns at most the remainder
+// of the chunk containing the current offset.
+func (s *streamer) Read(p []byte) (int, error) {
+	if err := s.r.tg.Add(); err != nil {
+		return 0, err
+	}
+	defer s.r.tg.Done()
+
+	if uint64(s.offset) >= s.file.size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	// Download the chunk containing the offset if it is not already in
+	// memory.
+	chunkSize := s.file.chunkSize()
+	index := uint64(s.offset) / chunkSize
+	if s.chunk == nil || s.chunkIndex != index {
+		if err := s.managedFetchChunk(index); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, s.chunk[uint64(s.offset)-index*chunkSize:])
+	s.offset += int64(n)
+	return n, nil
+}
+
+// Seek implements the io.Seeker interface. Seeking does not fetch any data.
+func (s *streamer) Seek(offset int64, whence int) (int64, error) {
+	var newOffset int64
+	switch whence {
+	case io.SeekStart:
+		newOffset = offset
+	case io.SeekCurrent:
+		newOffset = s.offset + offset
+	case io.SeekEnd:
+		newOffset = int64(s.file.size) + offset
+	default:
+		return 0, fmt.Errorf("invalid whence %v", whence)
+	}
+	if newOffset < 0 {
+		return 0, errNegativeSeek
+	}
+	s.offset = newOffset
+	return newOffset, nil
+}
+
+// Streamer returns the name of the file at siaPath along with an
+// io.ReadSeeker over its contents. Only the chunks containing the bytes that
+// are read are downloaded.
+func (r *Renter) Streamer(siaPath string) (string, modules.Streamer, error) {
+	lockID := r.mu.RLock()
+	file, exists := r.files[siaPath]
+	r.mu.RUnlock(lockID)
+	if !exists {
+		return "", nil, ErrUnknownPath
+	}
+
+	file.mu.RLock()
+	name := file.name
+	file.mu.RUnlock()
+	return name, &streamer{
+		file: file,
+		r:    r,
+	}, nil
+}
@@ -0,0 +1,127 @@
+package renter
+
+import (
+	"sync"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// An encodingJob erasure codes and encrypts a chunk, and hands the pieces to
+// the workers that the repair loop reserved for them. Each piece in pieces is
+// uploaded by the worker at the same index of workers.
+type encodingJob struct {
+	chunkID chunkID
+	ec      modules.ErasureCoder
+	file    *file
+	pieces  []uint64
+	workers []*worker
+
+	// data is the data of the chunk, if it is already held in memory.
+	// Otherwise the chunk is read from sourcePath.
+	data       []byte
+	sourcePath string
+
+	resultChan chan finishedUpload
+}
+
+// memory returns the number of bytes that the job needs to hold the chunk,
+// the parity pieces and the encrypted pieces. Except for the segmented
+// Reed-Solomon code, the data pieces returned by the erasure coder share
+// memory with the chunk.
+func (job *encodingJob) memory() uint64 {
+	memory := uint64(job.ec.NumPieces()-job.ec.MinPieces())*job.file.pieceSize + uint64(len(job.pieces))*modules.SectorSize
+	if job.data == nil {
+		memory += uint64(job.ec.MinPieces()) * job.file.pieceSize
+	}
+	if _, ok := job.ec.(*rsSegmentedCode); ok {
+		memory += uint64(job.ec.MinPieces()) * job.file.pieceSize
+	}
+	return memory
+}
+
+// encode reads the chunk if necessary, erasure codes it, and returns the
+// encrypted pieces of the job. The pieces are encrypted in parallel.
+func (job *encodingJob) encode() ([][]byte, error) {
+	data := job.data
+	if data == nil {
+		data = make([]byte, uint64(job.ec.MinPieces())*job.file.pieceSize)
+		if err := readChunk(job.sourcePath, job.file, job.chunkID.index, data); err != nil {
+			return nil, build.ExtendErr("unable to read chunk", err)
+		}
+	}
+	pieces, err := job.ec.Encode(data)
+	if err != nil {
+		return nil, build.ExtendErr("unable to erasure code chunk data", err)
+	}
+
+	encrypted := make([][]byte, len(job.pieces))
+	var wg sync.WaitGroup
+	for i, pieceIndex := range job.pieces {
+		wg.Add(1)
+		go func(i int, pieceIndex uint64) {
+			defer wg.Done()
+			key := deriveKey(job.file.masterKey, job.chunkID.index, pieceIndex)
+			encrypted[i] = key.EncryptBytes(pieces[pieceIndex])
+		}(i, pieceIndex)
+	}
+	wg.Wait()
+	return encrypted, nil
+}
+
+// threadedEncodeChunk performs an encoding job once the memory it needs is
+// available. Jobs run in parallel, limited by the renter's upload memory. The
+// memory of each encrypted piece is released by its worker once the piece has
+// been uploaded. If the chunk cannot be encoded, the failure is reported to
+// the repair loop for every reserved worker.
+func (r *Renter) threadedEncodeChunk(job *encodingJob) {
+	if err := r.tg.Add(); err != nil {
+		return
+	}
+	defer r.tg.Done()
+
+	memory := job.memory()
+	if !r.memoryManager.request(memory) {
+		return
+	}
+	pieces, err := job.encode()
+	if err != nil {
+		r.memoryManager.release(memory)
+		for i, worker := range job.workers {
+			select {
+			case job.resultChan <- finishedUpload{job.chunkID, crypto.Hash{}, err, job.pieces[i], worker.contractID}:
+			case <-r.tg.StopChan():
+				return
+			}
+		}
+		return
+	}
+
+	// Give each piece to its worker. Only the memory of the encrypted pieces
+	// is held until they are uploaded.
+	for i, worker := range job.workers {
+		pieceMemory := uint64(len(pieces[i]))
+		if pieceMemory > memory {
+			pieceMemory = memory
+		}
+		memory -= pieceMemory
+
+		uw := uploadWork{
+			chunkID:    job.chunkID,
+			data:       pieces[i],
+			file:       job.file,
+			memory:     pieceMemory,
+			pieceIndex: job.pieces[i],
+
+			resultChan: job.resultChan,
+		}
+		select {
+		case worker.uploadChan <- uw:
+		default:
+			r.log.Critical("Worker is supposed to be available, but upload work channel is full")
+			worker.uploadChan <- uw
+		}
+	}
+	r.memoryManager.release(memory)
+}
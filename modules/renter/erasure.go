@@ -1,6 +1,7 @@
 package renter
 
 import (
+	"errors"
 	"io"
 
 	"github.com/klauspost/reedsolomon"
@@ -8,6 +9,141 @@ import (
 	"github.com/NebulousLabs/Sia/modules"
 )
 
+// The specifiers of the erasure coder types that files can be encoded with.
+// The specifier of a file's erasure coder is stored in its .sia file.
+const (
+	// ECReedSolomon encodes each chunk as a single Reed-Solomon stripe.
+	ECReedSolomon = "Reed-Solomon"
+
+	// ECReedSolomonSegmented encodes each chunk as a sequence of
+	// Reed-Solomon stripes, each covering a segment of every piece.
+	ECReedSolomonSegmented = "Reed-Solomon-Segmented"
+
+	// ECReplication stores a full copy of each chunk in every piece. It is
+	// intended for small files, which fit in a single piece.
+	ECReplication = "Replication"
+)
+
+// defaultSegmentSize is the number of bytes of each piece that are covered by
+// a single stripe of a segmented Reed-Solomon code.
+const defaultSegmentSize = 1 << 16
+
+var (
+	errBadSegmentSize         = errors.New("segment size must be positive")
+	errReplicationDataPieces  = errors.New("replication requires exactly one data piece")
+	errUnknownErasureCoder    = errors.New("unknown erasure coder type")
+	errMismatchedPieceLengths = errors.New("pieces have different lengths")
+)
+
+type (
+	// An erasureCoderType is a type of erasure coder that can be stored in a
+	// .sia file. A stored coder consists of its specifier, its number of data
+	// and parity pieces, and numParams additional parameters.
+	erasureCoderType struct {
+		numParams int
+
+		// newCoder creates a coder from its stored parameters. If params is
+		// nil, the default parameters of the type are used.
+		newCoder func(dataPieces, parityPieces int, params []uint64) (modules.ErasureCoder, error)
+	}
+
+	// A persistedCoder is an erasure coder whose type is registered in
+	// erasureCoderTypes.
+	persistedCoder interface {
+		modules.ErasureCoder
+
+		// specifier returns the specifier of the coder's type.
+		specifier() string
+
+		// params returns the additional parameters of the coder.
+		params() []uint64
+	}
+)
+
+// erasureCoderTypes contains the erasure coder types that files can be
+// encoded with, indexed by their specifier.
+var erasureCoderTypes = map[string]erasureCoderType{
+	ECReedSolomon: {
+		numParams: 0,
+		newCoder: func(dataPieces, parityPieces int, _ []uint64) (modules.ErasureCoder, error) {
+			return NewRSCode(dataPieces, parityPieces)
+		},
+	},
+	ECReedSolomonSegmented: {
+		numParams: 1,
+		newCoder: func(dataPieces, parityPieces int, params []uint64) (modules.ErasureCoder, error) {
+			segmentSize := defaultSegmentSize
+			if params != nil {
+				segmentSize = int(params[0])
+			}
+			return newRSSegmentedCode(dataPieces, parityPieces, segmentSize)
+		},
+	},
+	ECReplication: {
+		numParams: 0,
+		newCoder: func(dataPieces, parityPieces int, _ []uint64) (modules.ErasureCoder, error) {
+			if dataPieces != 1 {
+				return nil, errReplicationDataPieces
+			}
+			return NewReplicationCode(parityPieces + 1)
+		},
+	},
+}
+
+// NewErasureCoder creates an erasure coder of the type identified by
+// specifier, using the default parameters of the type.
+func NewErasureCoder(specifier string, dataPieces, parityPieces int) (modules.ErasureCoder, error) {
+	ect, ok := erasureCoderTypes[specifier]
+	if !ok {
+		return nil, errUnknownErasureCoder
+	}
+	return ect.newCoder(dataPieces, parityPieces, nil)
+}
+
+// erasureCoderSpecifier returns the specifier of the type of ec, or an empty
+// string if the type is not registered.
+func erasureCoderSpecifier(ec modules.ErasureCoder) string {
+	if pc, ok := ec.(persistedCoder); ok {
+		return pc.specifier()
+	}
+	return ""
+}
+
+// sameErasureCoderType reports whether a and b are registered coders of the
+// same type with the same additional parameters. Such coders produce the same
+// pieces if they have the same number of data pieces.
+func sameErasureCoderType(a, b modules.ErasureCoder) bool {
+	pa, okA := a.(persistedCoder)
+	pb, okB := b.(persistedCoder)
+	if !okA || !okB || pa.specifier() != pb.specifier() {
+		return false
+	}
+	paramsA, paramsB := pa.params(), pb.params()
+	if len(paramsA) != len(paramsB) {
+		return false
+	}
+	for i := range paramsA {
+		if paramsA[i] != paramsB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// resizeErasureCoder returns a coder of the same type and with the same
+// additional parameters as ec, using the provided number of pieces.
+func resizeErasureCoder(ec modules.ErasureCoder, dataPieces, parityPieces int) (modules.ErasureCoder, error) {
+	pc, ok := ec.(persistedCoder)
+	if !ok {
+		return nil, errUnknownErasureCoder
+	}
+	ect, ok := erasureCoderTypes[pc.specifier()]
+	if !ok {
+		return nil, errUnknownErasureCoder
+	}
+	return ect.newCoder(dataPieces, parityPieces, pc.params())
+}
+
 // rsCode is a Reed-Solomon encoder/decoder. It implements the
 // modules.ErasureCoder interface.
 type rsCode struct {
@@ -24,6 +160,9 @@ func (rs *rsCode) NumPieces() int { return rs.numPieces }
 // recover the original data.
 func (rs *rsCode) MinPieces() int { return rs.dataPieces }
 
+func (rs *rsCode) specifier() string { return ECReedSolomon }
+func (rs *rsCode) params() []uint64  { return nil }
+
 // Encode splits data into equal-length pieces, some containing the original
 // data and some containing parity data.
 func (rs *rsCode) Encode(data []byte) ([][]byte, error) {
@@ -65,3 +204,187 @@ func NewRSCode(nData, nParity int) (modules.ErasureCoder, error) {
 		dataPieces: nData,
 	}, nil
 }
+
+// rsSegmentedCode is a Reed-Solomon encoder/decoder that encodes data as a
+// sequence of stripes. Each stripe covers segmentSize bytes of every piece,
+// so a range of the data can be recovered from the same range of the pieces.
+// It implements the modules.ErasureCoder interface.
+type rsSegmentedCode struct {
+	enc reedsolomon.Encoder
+
+	numPieces   int
+	dataPieces  int
+	segmentSize int
+}
+
+// NumPieces returns the number of pieces returned by Encode.
+func (rs *rsSegmentedCode) NumPieces() int { return rs.numPieces }
+
+// MinPieces return the minimum number of pieces that must be present to
+// recover the original data.
+func (rs *rsSegmentedCode) MinPieces() int { return rs.dataPieces }
+
+func (rs *rsSegmentedCode) specifier() string { return ECReedSolomonSegmented }
+func (rs *rsSegmentedCode) params() []uint64  { return []uint64{uint64(rs.segmentSize)} }
+
+// Encode splits data into equal-length pieces, some containing the original
+// data and some containing parity data. The pieces have the same length as
+// the pieces of a plain Reed-Solomon code; only the final stripe may cover
+// less than segmentSize bytes of each piece.
+func (rs *rsSegmentedCode) Encode(data []byte) ([][]byte, error) {
+	if len(data) == 0 {
+		return nil, reedsolomon.ErrShortData
+	}
+	pieceLen := (len(data) + rs.dataPieces - 1) / rs.dataPieces
+	pieces := make([][]byte, rs.numPieces)
+	for i := range pieces {
+		pieces[i] = make([]byte, pieceLen)
+	}
+
+	stripe := make([][]byte, rs.numPieces)
+	for off := 0; off < pieceLen; off += rs.segmentSize {
+		end := off + rs.segmentSize
+		if end > pieceLen {
+			end = pieceLen
+		}
+		// The stripe covers the data from off*dataPieces to end*dataPieces,
+		// split evenly between the data pieces.
+		segmentLen := end - off
+		stripeData := data[off*rs.dataPieces:]
+		for i := range stripe {
+			stripe[i] = pieces[i][off:end:end]
+			if i < rs.dataPieces && i*segmentLen < len(stripeData) {
+				copy(stripe[i], stripeData[i*segmentLen:])
+			}
+		}
+		if err := rs.enc.Encode(stripe); err != nil {
+			return nil, err
+		}
+	}
+	return pieces, nil
+}
+
+// Recover recovers the original data from pieces (including parity) and
+// writes it to w. pieces should be identical to the slice returned by
+// Encode (length and order must be preserved), but with missing elements
+// set to nil. Only the stripes that are needed to write n bytes are
+// reconstructed.
+func (rs *rsSegmentedCode) Recover(pieces [][]byte, n uint64, w io.Writer) error {
+	if len(pieces) != rs.numPieces {
+		return reedsolomon.ErrTooFewShards
+	}
+	pieceLen := -1
+	for _, piece := range pieces {
+		if piece == nil {
+			continue
+		} else if pieceLen == -1 {
+			pieceLen = len(piece)
+		} else if len(piece) != pieceLen {
+			return errMismatchedPieceLengths
+		}
+	}
+	if pieceLen == -1 {
+		return reedsolomon.ErrTooFewShards
+	}
+
+	stripe := make([][]byte, rs.numPieces)
+	for off := 0; off < pieceLen && n > 0; off += rs.segmentSize {
+		end := off + rs.segmentSize
+		if end > pieceLen {
+			end = pieceLen
+		}
+		for i, piece := range pieces {
+			stripe[i] = nil
+			if piece != nil {
+				stripe[i] = piece[off:end:end]
+			}
+		}
+		if err := rs.enc.Reconstruct(stripe); err != nil {
+			return err
+		}
+		size := uint64((end - off) * rs.dataPieces)
+		if size > n {
+			size = n
+		}
+		if err := rs.enc.Join(w, stripe, int(size)); err != nil {
+			return err
+		}
+		n -= size
+	}
+	if n > 0 {
+		return reedsolomon.ErrShortData
+	}
+	return nil
+}
+
+// newRSSegmentedCode creates a new segmented Reed-Solomon encoder/decoder
+// using the supplied parameters.
+func newRSSegmentedCode(nData, nParity, segmentSize int) (modules.ErasureCoder, error) {
+	if segmentSize <= 0 {
+		return nil, errBadSegmentSize
+	}
+	enc, err := reedsolomon.New(nData, nParity)
+	if err != nil {
+		return nil, err
+	}
+	return &rsSegmentedCode{
+		enc:         enc,
+		numPieces:   nData + nParity,
+		dataPieces:  nData,
+		segmentSize: segmentSize,
+	}, nil
+}
+
+// replicationCode is an encoder/decoder that stores a full copy of the data
+// in every piece. It implements the modules.ErasureCoder interface.
+type replicationCode struct {
+	numPieces int
+}
+
+// NumPieces returns the number of pieces returned by Encode.
+func (rc *replicationCode) NumPieces() int { return rc.numPieces }
+
+// MinPieces return the minimum number of pieces that must be present to
+// recover the original data, which is always one.
+func (rc *replicationCode) MinPieces() int { return 1 }
+
+func (rc *replicationCode) specifier() string { return ECReplication }
+func (rc *replicationCode) params() []uint64  { return nil }
+
+// Encode returns NumPieces copies of data. The copies share memory with data.
+func (rc *replicationCode) Encode(data []byte) ([][]byte, error) {
+	if len(data) == 0 {
+		return nil, reedsolomon.ErrShortData
+	}
+	pieces := make([][]byte, rc.numPieces)
+	for i := range pieces {
+		pieces[i] = data
+	}
+	return pieces, nil
+}
+
+// Recover writes the first n bytes of any of the pieces to w.
+func (rc *replicationCode) Recover(pieces [][]byte, n uint64, w io.Writer) error {
+	for _, piece := range pieces {
+		if piece == nil {
+			continue
+		}
+		if uint64(len(piece)) < n {
+			return reedsolomon.ErrShortData
+		}
+		_, err := w.Write(piece[:n])
+		return err
+	}
+	return reedsolomon.ErrTooFewShards
+}
+
+// NewReplicationCode creates a new replication encoder/decoder that stores
+// the supplied number of copies.
+func NewReplicationCode(copies int) (modules.ErasureCoder, error) {
+	if copies < 1 {
+		return nil, reedsolomon.ErrInvShardNum
+	}
+	return &replicationCode{
+		numPieces: copies,
+	}, nil
+}
@@ -57,6 +57,163 @@ func TestRSEncode(t *testing.T) {
 	}
 }
 
+// TestRSCodeParityPrefix checks that Reed-Solomon codes with the same number
+// of data pieces produce the same pieces, regardless of the number of parity
+// pieces. The redundancy of a file can only be changed without reuploading
+// it because of this property.
+func TestRSCodeParityPrefix(t *testing.T) {
+	small, err := NewRSCode(10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := NewRSCode(10, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := fastrand.Bytes(777)
+	smallPieces, err := small.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	largePieces, err := large.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range smallPieces {
+		if !bytes.Equal(smallPieces[i], largePieces[i]) {
+			t.Fatalf("piece %v differs between the codes", i)
+		}
+	}
+
+	// The smaller code should be able to recover the data from pieces
+	// created by the larger code.
+	pieces := largePieces[:small.NumPieces()]
+	for i := 0; i < small.NumPieces()-small.MinPieces(); i++ {
+		pieces[i] = nil
+	}
+	buf := new(bytes.Buffer)
+	if err := small.Recover(pieces, 777, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, buf.Bytes()) {
+		t.Fatal("recovered data does not match original")
+	}
+}
+
+// TestRSSegmentedCode tests the rsSegmentedCode type.
+func TestRSSegmentedCode(t *testing.T) {
+	if _, err := newRSSegmentedCode(10, 3, 0); err != errBadSegmentSize {
+		t.Fatal("expected errBadSegmentSize, got", err)
+	}
+	if _, err := newRSSegmentedCode(0, 3, 64); err == nil {
+		t.Fatal("expected bad parameter error, got nil")
+	}
+
+	// Use a segment size that does not divide the length of the pieces, so
+	// that the final stripe is shorter than the others.
+	sc, err := newRSSegmentedCode(10, 3, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsc, _ := NewRSCode(10, 3)
+	data := fastrand.Bytes(777)
+	pieces, err := sc.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsPieces, _ := rsc.Encode(data)
+	if len(pieces) != 13 || len(pieces[0]) != len(rsPieces[0]) {
+		t.Fatal("segmented pieces differ in size from plain Reed-Solomon pieces")
+	}
+	if _, err := sc.Encode(nil); err == nil {
+		t.Fatal("expected nil data error, got nil")
+	}
+
+	// Recover the data after losing as many pieces as possible.
+	pieces[0], pieces[5], pieces[12] = nil, nil, nil
+	buf := new(bytes.Buffer)
+	if err := sc.Recover(pieces, 777, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, buf.Bytes()) {
+		t.Fatal("recovered data does not match original")
+	}
+
+	// Recovering a prefix of the data only requires the first stripes.
+	buf.Reset()
+	if err := sc.Recover(pieces, 100, buf); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(data[:100], buf.Bytes()) {
+		t.Fatal("recovered prefix does not match original")
+	}
+
+	pieces[1] = nil
+	if err := sc.Recover(pieces, 777, ioutil.Discard); err == nil {
+		t.Fatal("expected too few pieces error, got nil")
+	}
+	if err := sc.Recover(nil, 777, ioutil.Discard); err == nil {
+		t.Fatal("expected nil pieces error, got nil")
+	}
+}
+
+// TestReplicationCode tests the replicationCode type.
+func TestReplicationCode(t *testing.T) {
+	if _, err := NewReplicationCode(0); err == nil {
+		t.Fatal("expected bad parameter error, got nil")
+	}
+	if _, err := NewErasureCoder(ECReplication, 2, 2); err != errReplicationDataPieces {
+		t.Fatal("expected errReplicationDataPieces, got", err)
+	}
+	rc, err := NewErasureCoder(ECReplication, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc.NumPieces() != 3 || rc.MinPieces() != 1 {
+		t.Fatal("wrong number of pieces:", rc.NumPieces(), rc.MinPieces())
+	}
+
+	data := fastrand.Bytes(777)
+	pieces, err := rc.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, piece := range pieces {
+		if !bytes.Equal(piece, data) {
+			t.Fatal("piece is not a copy of the data")
+		}
+	}
+
+	pieces[0], pieces[1] = nil, nil
+	buf := new(bytes.Buffer)
+	if err := rc.Recover(pieces, 777, buf); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(data, buf.Bytes()) {
+		t.Fatal("recovered data does not match original")
+	}
+	pieces[2] = nil
+	if err := rc.Recover(pieces, 777, ioutil.Discard); err == nil {
+		t.Fatal("expected too few pieces error, got nil")
+	}
+}
+
+// TestNewErasureCoder checks that coders are created for the registered types
+// only.
+func TestNewErasureCoder(t *testing.T) {
+	for specifier := range erasureCoderTypes {
+		ec, err := NewErasureCoder(specifier, 1, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if erasureCoderSpecifier(ec) != specifier {
+			t.Fatal("coder has the wrong specifier:", erasureCoderSpecifier(ec), specifier)
+		}
+	}
+	if _, err := NewErasureCoder("foo", 1, 2); err != errUnknownErasureCoder {
+		t.Fatal("expected errUnknownErasureCoder, got", err)
+	}
+}
+
 func BenchmarkRSEncode(b *testing.B) {
 	rsc, err := NewRSCode(80, 20)
 	if err != nil {
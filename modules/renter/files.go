@@ -16,6 +16,15 @@ var (
 	ErrEmptyFilename = errors.New("filename must be a nonempty string")
 	ErrUnknownPath   = errors.New("no file known with that path")
 	ErrPathOverload  = errors.New("a file already exists at that location")
+
+	// errDataPiecesChanged is returned when a change of redundancy would
+	// change the number of data pieces of a file, which would require the
+	// whole file to be uploaded again.
+	errDataPiecesChanged = errors.New("the number of data pieces of a file cannot be changed")
+
+	// errReadOnlyFile is returned when modifying a file that was loaded from
+	// a .sia file shared by another renter.
+	errReadOnlyFile = errors.New("file was loaded from a shared .sia file and is read-only")
 )
 
 // A file is a single file that has been uploaded to the network. Files are
@@ -23,15 +32,30 @@ var (
 // Each piece is separately encrypted, using a key derived from the file's
 // master key. The pieces are uploaded to hosts in groups, such that one file
 // contract covers many pieces.
+//
+// The erasure code of a file can be replaced to change the file's redundancy,
+// but only by a code with the same number of data pieces. MinPieces, and
+// therefore the chunk size, never change. Pieces with an index of NumPieces
+// or greater are left over from a higher redundancy and are ignored until
+// they are dropped by the repair loop.
 type file struct {
 	name        string
 	size        uint64 // Static - can be accessed without lock.
 	contracts   map[types.FileContractID]fileContract
 	masterKey   crypto.TwofishKey    // Static - can be accessed without lock.
-	erasureCode modules.ErasureCoder // Static - can be accessed without lock.
+	erasureCode modules.ErasureCoder // Only MinPieces can be accessed without lock.
 	pieceSize   uint64               // Static - can be accessed without lock.
 	mode        uint32               // actually an os.FileMode
 
+	// droppingPieces is set while the pieces left over from a higher
+	// redundancy are being deleted from the hosts.
+	droppingPieces bool
+
+	// readOnly is set for files that were loaded from a .sia file shared by
+	// another renter. Read-only files are never repaired and their
+	// redundancy cannot be changed. Static - can be accessed without lock.
+	readOnly bool
+
 	mu sync.RWMutex
 }
 
@@ -83,12 +107,15 @@ func (f *file) numChunks() uint64 {
 // available indicates whether the file is ready to be downloaded.
 func (f *file) available(isOffline func(types.FileContractID) bool) bool {
 	chunkPieces := make([]int, f.numChunks())
+	numPieces := uint64(f.erasureCode.NumPieces())
 	for _, fc := range f.contracts {
 		if isOffline(fc.ID) {
 			continue
 		}
 		for _, p := range fc.Pieces {
-			chunkPieces[p.Chunk]++
+			if p.Piece < numPieces {
+				chunkPieces[p.Chunk]++
+			}
 		}
 	}
 	for _, n := range chunkPieces {
@@ -104,8 +131,13 @@ func (f *file) available(isOffline func(types.FileContractID) bool) bool {
 // reaches 100%, and UploadProgress may report a value greater than 100%.
 func (f *file) uploadProgress() float64 {
 	var uploaded uint64
+	numPieces := uint64(f.erasureCode.NumPieces())
 	for _, fc := range f.contracts {
-		uploaded += uint64(len(fc.Pieces)) * f.pieceSize
+		for _, p := range fc.Pieces {
+			if p.Piece < numPieces {
+				uploaded += f.pieceSize
+			}
+		}
 	}
 	desired := f.pieceSize * uint64(f.erasureCode.NumPieces()) * f.numChunks()
 
@@ -128,13 +160,16 @@ func (f *file) redundancy(isOffline func(types.FileContractID) bool) float64 {
 		build.Critical("cannot get redundancy of a file with 0 chunks")
 		return -1
 	}
+	numPieces := uint64(f.erasureCode.NumPieces())
 	for _, fc := range f.contracts {
 		// do not count pieces from the contract if the contract is offline
 		if isOffline(fc.ID) {
 			continue
 		}
 		for _, p := range fc.Pieces {
-			piecesPerChunk[p.Chunk]++
+			if p.Piece < numPieces {
+				piecesPerChunk[p.Chunk]++
+			}
 		}
 	}
 	minPieces := piecesPerChunk[0]
@@ -146,6 +181,41 @@ func (f *file) redundancy(isOffline func(types.FileContractID) bool) float64 {
 	return float64(minPieces) / float64(f.erasureCode.MinPieces())
 }
 
+// health returns the number of additional pieces that the least healthy chunk
+// of the file can lose before the file becomes unrecoverable, i.e. the number
+// of unique pieces of the chunk that are stored on online hosts minus the
+// number of pieces needed to recover the chunk. A negative health means that
+// the file cannot currently be recovered. Files with size 0 cannot be lost,
+// and report the health of a fully uploaded file.
+func (f *file) health(isOffline func(types.FileContractID) bool) int {
+	minPieces := f.erasureCode.MinPieces()
+	if f.size == 0 {
+		return f.erasureCode.NumPieces() - minPieces
+	}
+	chunkPieces := make([]map[uint64]struct{}, f.numChunks())
+	for i := range chunkPieces {
+		chunkPieces[i] = make(map[uint64]struct{})
+	}
+	numPieces := uint64(f.erasureCode.NumPieces())
+	for _, fc := range f.contracts {
+		if isOffline(fc.ID) {
+			continue
+		}
+		for _, p := range fc.Pieces {
+			if p.Piece < numPieces {
+				chunkPieces[p.Chunk][p.Piece] = struct{}{}
+			}
+		}
+	}
+	health := len(chunkPieces[0]) - minPieces
+	for _, pieces := range chunkPieces {
+		if len(pieces)-minPieces < health {
+			health = len(pieces) - minPieces
+		}
+	}
+	return health
+}
+
 // expiration returns the lowest height at which any of the file's contracts
 // will expire.
 func (f *file) expiration() types.BlockHeight {
@@ -161,6 +231,23 @@ func (f *file) expiration() types.BlockHeight {
 	return lowest
 }
 
+// info returns the FileInfo of the file.
+func (f *file) info(isOffline func(types.FileContractID) bool) modules.FileInfo {
+	return modules.FileInfo{
+		SiaPath:          f.name,
+		Filesize:         f.size,
+		Renewing:         !f.readOnly,
+		Available:        f.available(isOffline),
+		Health:           f.health(isOffline),
+		Redundancy:       f.redundancy(isOffline),
+		TargetRedundancy: float64(f.erasureCode.NumPieces()) / float64(f.erasureCode.MinPieces()),
+		ErasureCoder:     erasureCoderSpecifier(f.erasureCode),
+		UploadProgress:   f.uploadProgress(),
+		Expiration:       f.expiration(),
+		ReadOnly:         f.readOnly,
+	}
+}
+
 // newFile creates a new file object.
 func newFile(name string, code modules.ErasureCoder, pieceSize, fileSize uint64) *file {
 	return &file{
@@ -185,8 +272,9 @@ func (r *Renter) DeleteFile(nickname string) error {
 		r.mu.Unlock(lockID)
 		return ErrUnknownPath
 	}
-	delete(r.files, nickname)
+	r.removeFile(nickname)
 	delete(r.tracking, nickname)
+	r.chunkCache.purge(f.masterKey)
 	err := os.RemoveAll(filepath.Join(r.persistDir, f.name+ShareExtension))
 	if err != nil {
 		r.log.Println("WARN: couldn't remove .sia file during delete:", err)
@@ -212,7 +300,21 @@ func (r *Renter) FileList() []modules.FileInfo {
 	}
 	r.mu.RUnlock(lockID)
 
-	isOffline := func(id types.FileContractID) bool {
+	isOffline := r.managedIsOfflineFunc()
+	var fileList []modules.FileInfo
+	for _, f := range files {
+		f.mu.RLock()
+		fileList = append(fileList, f.info(isOffline))
+		f.mu.RUnlock()
+	}
+	return fileList
+}
+
+// managedIsOfflineFunc returns a function that reports whether a file
+// contract should be considered unusable when computing the availability,
+// redundancy and health of a file.
+func (r *Renter) managedIsOfflineFunc() func(types.FileContractID) bool {
+	return func(id types.FileContractID) bool {
 		id = r.hostContractor.ResolveID(id)
 		offline := r.hostContractor.IsOffline(id)
 		contract, exists := r.hostContractor.ContractByID(id)
@@ -221,23 +323,56 @@ func (r *Renter) FileList() []modules.FileInfo {
 		}
 		return offline || !contract.GoodForRenew
 	}
+}
 
-	var fileList []modules.FileInfo
-	for _, f := range files {
-		f.mu.RLock()
-		renewing := true
-		fileList = append(fileList, modules.FileInfo{
-			SiaPath:        f.name,
-			Filesize:       f.size,
-			Renewing:       renewing,
-			Available:      f.available(isOffline),
-			Redundancy:     f.redundancy(isOffline),
-			UploadProgress: f.uploadProgress(),
-			Expiration:     f.expiration(),
-		})
-		f.mu.RUnlock()
+// SetFileRedundancy replaces the erasure code of a file with a code of the
+// same type that uses the provided number of pieces, changing its target
+// redundancy. The number of data pieces must stay the same. The repair loop
+// uploads the pieces that are missing under the new code, and pieces that
+// are no longer needed are deleted from the hosts.
+func (r *Renter) SetFileRedundancy(siaPath string, dataPieces, parityPieces int) error {
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+
+	f, exists := r.files[siaPath]
+	if !exists {
+		return ErrUnknownPath
 	}
-	return fileList
+	if f.readOnly {
+		return errReadOnlyFile
+	}
+
+	f.mu.Lock()
+	if dataPieces != f.erasureCode.MinPieces() {
+		f.mu.Unlock()
+		return errDataPiecesChanged
+	}
+	ec, err := resizeErasureCoder(f.erasureCode, dataPieces, parityPieces)
+	if err != nil {
+		f.mu.Unlock()
+		return err
+	}
+	dropPieces := ec.NumPieces() < f.erasureCode.NumPieces()
+	f.erasureCode = ec
+	err = r.saveFile(f)
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Update the file's pieces in the background.
+	if dropPieces {
+		go r.threadedDropExcessPieces(f)
+	}
+	if _, tracked := r.tracking[siaPath]; tracked {
+		go func() {
+			select {
+			case r.newRepairs <- f:
+			case <-r.tg.StopChan():
+			}
+		}()
+	}
+	return nil
 }
 
 // RenameFile takes an existing file and changes the nickname. The original
@@ -247,9 +382,9 @@ func (r *Renter) RenameFile(currentName, newName string) error {
 	lockID := r.mu.Lock()
 	defer r.mu.Unlock(lockID)
 
-	// Check that newName is nonempty.
-	if newName == "" {
-		return ErrEmptyFilename
+	// Check that newName is a valid siapath.
+	if err := validateSiapath(newName); err != nil {
+		return err
 	}
 
 	// Check that currentName exists and newName doesn't.
@@ -272,8 +407,8 @@ func (r *Renter) RenameFile(currentName, newName string) error {
 	}
 
 	// Update the entries in the renter.
-	delete(r.files, currentName)
-	r.files[newName] = file
+	r.removeFile(currentName)
+	r.addFile(file)
 	if t, ok := r.tracking[currentName]; ok {
 		delete(r.tracking, currentName)
 		r.tracking[newName] = t
@@ -386,3 +386,77 @@ func TestRenterRenameFile(t *testing.T) {
 		t.Error("renaming should have updated the entry in the tracking set")
 	}
 }
+
+// TestRenterSetFileRedundancy probes the SetFileRedundancy method of the
+// renter.
+func TestRenterSetFileRedundancy(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	rsc, _ := NewRSCode(2, 4)
+	if err := rt.renter.SetFileRedundancy("1", 2, 4); err != ErrUnknownPath {
+		t.Fatal("expected ErrUnknownPath, got", err)
+	}
+
+	// Create a file with every piece of its only chunk uploaded.
+	f := newFile("1", rsc, 100, 200)
+	fc := fileContract{ID: types.FileContractID{1}}
+	for i := uint64(0); i < 6; i++ {
+		fc.Pieces = append(fc.Pieces, pieceData{Chunk: 0, Piece: i})
+	}
+	f.contracts[fc.ID] = fc
+	rt.renter.files["1"] = f
+	neverOffline := func(types.FileContractID) bool { return false }
+	if info := f.info(neverOffline); info.Redundancy != 3 || info.TargetRedundancy != 3 {
+		t.Fatal("unexpected redundancy:", info.Redundancy, info.TargetRedundancy)
+	}
+
+	// The number of data pieces cannot change.
+	if err := rt.renter.SetFileRedundancy("1", 3, 3); err != errDataPiecesChanged {
+		t.Fatal("expected errDataPiecesChanged, got", err)
+	}
+
+	// Lowering the redundancy should cause the extra pieces to be ignored.
+	if err := rt.renter.SetFileRedundancy("1", 2, 2); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.RLock()
+	info := f.info(neverOffline)
+	f.mu.RUnlock()
+	if info.Redundancy != 2 || info.TargetRedundancy != 2 || info.UploadProgress != 100 {
+		t.Fatal("unexpected redundancy after lowering it:", info.Redundancy, info.TargetRedundancy, info.UploadProgress)
+	}
+
+	// Raising the redundancy should leave the file short of pieces.
+	if err := rt.renter.SetFileRedundancy("1", 2, 6); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.RLock()
+	info = f.info(neverOffline)
+	health := f.health(neverOffline)
+	f.mu.RUnlock()
+	if info.TargetRedundancy != 4 || info.UploadProgress >= 100 || health != 4 {
+		t.Fatal("unexpected redundancy after raising it:", info.TargetRedundancy, info.UploadProgress, health)
+	}
+
+	// The new code has the type and parameters of the file's current code.
+	segmented, _ := newRSSegmentedCode(2, 4, 1<<10)
+	g := newFile("2", segmented, 100, 200)
+	rt.renter.files["2"] = g
+	if err := rt.renter.SetFileRedundancy("2", 2, 2); err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := newRSSegmentedCode(2, 2, 1<<10)
+	g.mu.RLock()
+	ec := g.erasureCode
+	g.mu.RUnlock()
+	if !sameErasureCoderType(ec, expected) || ec.NumPieces() != 4 {
+		t.Fatal("erasure coder type was not kept:", ec)
+	}
+}
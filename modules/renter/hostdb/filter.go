@@ -0,0 +1,199 @@
+package hostdb
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errUnknownFilterMode = errors.New("filter mode must be 'disable', 'blacklist' or 'whitelist'")
+	errEmptyWhitelist    = errors.New("a whitelist must contain at least one host, subnet or country")
+)
+
+// A hostFilter excludes hosts from being selected for contracts. In blacklist
+// mode the hosts that match the filter are excluded, and in whitelist mode
+// every other host is excluded.
+//
+// countryRanges holds the ranges of addresses that the GeoIP database
+// assigns to the filtered countries. It is read from the database when the
+// filter is created, so that no lookups are needed when hosts are selected.
+type hostFilter struct {
+	mode          modules.HostDBFilterMode
+	keys          map[string]types.SiaPublicKey
+	subnets       []*net.IPNet
+	countries     map[string]struct{}
+	geoIPDatabase string
+	countryRanges []ipRange
+}
+
+// newHostFilter validates f and converts it into a hostFilter, reading the
+// ranges of its countries from the GeoIP database.
+func newHostFilter(f modules.HostDBFilter) (hostFilter, error) {
+	hf, err := parseHostFilter(f)
+	if err != nil {
+		return hostFilter{}, err
+	}
+	if err := hf.loadCountryRanges(); err != nil {
+		return hostFilter{}, err
+	}
+	return hf, nil
+}
+
+// parseHostFilter validates f and converts it into a hostFilter without
+// reading the GeoIP database.
+func parseHostFilter(f modules.HostDBFilter) (hostFilter, error) {
+	hf := hostFilter{
+		mode: f.Mode,
+		keys: make(map[string]types.SiaPublicKey),
+	}
+	switch hf.mode {
+	case "", modules.HostDBFilterDisabled:
+		// The lists of a disabled filter are discarded.
+		hf.mode = modules.HostDBFilterDisabled
+		return hf, nil
+	case modules.HostDBFilterBlacklist, modules.HostDBFilterWhitelist:
+	default:
+		return hostFilter{}, errUnknownFilterMode
+	}
+	for _, spk := range f.PublicKeys {
+		hf.keys[spk.String()] = spk
+	}
+	for _, subnet := range f.Subnets {
+		_, ipnet, err := net.ParseCIDR(subnet)
+		if err != nil {
+			return hostFilter{}, err
+		}
+		hf.subnets = append(hf.subnets, ipnet)
+	}
+	if len(f.Countries) > 0 {
+		hf.countries = make(map[string]struct{})
+		for _, country := range f.Countries {
+			code, err := parseCountryCode(country)
+			if err != nil {
+				return hostFilter{}, err
+			}
+			hf.countries[code] = struct{}{}
+		}
+		if f.GeoIPDatabase == "" {
+			return hostFilter{}, errNoGeoIPDatabase
+		}
+		if !filepath.IsAbs(f.GeoIPDatabase) {
+			return hostFilter{}, errRelGeoIPPath
+		}
+		hf.geoIPDatabase = f.GeoIPDatabase
+	}
+	if hf.mode == modules.HostDBFilterWhitelist && len(hf.keys) == 0 && len(hf.subnets) == 0 && len(hf.countries) == 0 {
+		return hostFilter{}, errEmptyWhitelist
+	}
+	return hf, nil
+}
+
+// loadCountryRanges reads the ranges of the filtered countries from the
+// GeoIP database.
+func (hf *hostFilter) loadCountryRanges() error {
+	if len(hf.countries) == 0 {
+		return nil
+	}
+	ranges, err := loadGeoIPRanges(hf.geoIPDatabase, hf.countries)
+	if err != nil {
+		return errors.New("unable to read the GeoIP database: " + err.Error())
+	}
+	hf.countryRanges = ranges
+	return nil
+}
+
+// persist returns the modules.HostDBFilter that describes hf.
+func (hf hostFilter) persist() modules.HostDBFilter {
+	f := modules.HostDBFilter{Mode: hf.mode}
+	if f.Mode == "" {
+		f.Mode = modules.HostDBFilterDisabled
+	}
+	for _, spk := range hf.keys {
+		f.PublicKeys = append(f.PublicKeys, spk)
+	}
+	sort.Slice(f.PublicKeys, func(i, j int) bool {
+		return f.PublicKeys[i].String() < f.PublicKeys[j].String()
+	})
+	for _, ipnet := range hf.subnets {
+		f.Subnets = append(f.Subnets, ipnet.String())
+	}
+	for country := range hf.countries {
+		f.Countries = append(f.Countries, country)
+	}
+	sort.Strings(f.Countries)
+	f.GeoIPDatabase = hf.geoIPDatabase
+	return f
+}
+
+// matches returns whether the host with the provided key and IP addresses is
+// matched by the filter. The addresses are the ones resolved by the host's
+// last scan, so that no lookups are needed when hosts are selected.
+func (hf hostFilter) matches(spk types.SiaPublicKey, ips []net.IP) bool {
+	if _, exists := hf.keys[spk.String()]; exists {
+		return true
+	}
+	for _, ip := range ips {
+		for _, ipnet := range hf.subnets {
+			if ipnet.Contains(ip) {
+				return true
+			}
+		}
+		if rangesContain(hf.countryRanges, ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludes returns whether the filter prevents the host with the provided key
+// and IP addresses from being selected.
+func (hf hostFilter) excludes(spk types.SiaPublicKey, ips []net.IP) bool {
+	switch hf.mode {
+	case modules.HostDBFilterBlacklist:
+		return hf.matches(spk, ips)
+	case modules.HostDBFilterWhitelist:
+		return !hf.matches(spk, ips)
+	default:
+		return false
+	}
+}
+
+// Filter returns the hostdb's filter.
+func (hdb *HostDB) Filter() modules.HostDBFilter {
+	hdb.mu.RLock()
+	defer hdb.mu.RUnlock()
+	return hdb.filter.persist()
+}
+
+// SetFilter replaces the hostdb's filter. The new filter applies to every
+// host selected afterwards.
+func (hdb *HostDB) SetFilter(f modules.HostDBFilter) error {
+	if err := hdb.tg.Add(); err != nil {
+		return err
+	}
+	defer hdb.tg.Done()
+
+	hf, err := newHostFilter(f)
+	if err != nil {
+		return err
+	}
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	hdb.filter = hf
+	return hdb.saveSync()
+}
+
+// IsFiltered returns whether the hostdb's filter prevents the host with the
+// provided public key from being selected.
+func (hdb *HostDB) IsFiltered(spk types.SiaPublicKey) bool {
+	hdb.mu.RLock()
+	filter := hdb.filter
+	hdb.mu.RUnlock()
+	ips, _ := hdb.hostTree.IPs(spk)
+	return filter.excludes(spk, ips)
+}
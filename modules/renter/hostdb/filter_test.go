@@ -0,0 +1,184 @@
+package hostdb
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestHostFilter checks that RandomHosts honours the blacklist and whitelist
+// modes of the hostdb's filter.
+func TestHostFilter(t *testing.T) {
+	hdb := bareHostDB()
+
+	// Add three hosts, two of which are in the same /16. The address of the
+	// second host is a host name, which is matched against the subnets
+	// through the IP it resolved to.
+	hosts := make([]modules.HostDBEntry, 3)
+	addrs := []modules.NetAddress{"10.0.1.1:9982", "host.example.com:9982", "192.168.1.1:9982"}
+	for i := range hosts {
+		hosts[i] = makeHostDBEntry()
+		hosts[i].NetAddress = addrs[i]
+		if err := hdb.hostTree.Insert(hosts[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := hdb.hostTree.SetIPs(hosts[1].PublicKey, []net.IP{net.ParseIP("10.0.2.1")}); err != nil {
+		t.Fatal(err)
+	}
+	selected := func() map[string]bool {
+		keys := make(map[string]bool)
+		for _, host := range hdb.RandomHosts(len(hosts), nil) {
+			keys[host.PublicKey.String()] = true
+		}
+		return keys
+	}
+	setFilter := func(f modules.HostDBFilter) {
+		hf, err := newHostFilter(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hdb.filter = hf
+	}
+
+	// Blacklisting a host by key excludes only that host.
+	setFilter(modules.HostDBFilter{
+		Mode:       modules.HostDBFilterBlacklist,
+		PublicKeys: []types.SiaPublicKey{hosts[0].PublicKey},
+	})
+	if keys := selected(); len(keys) != 2 || keys[hosts[0].PublicKey.String()] {
+		t.Fatal("blacklisted host was selected")
+	}
+	if !hdb.IsFiltered(hosts[0].PublicKey) || hdb.IsFiltered(hosts[1].PublicKey) {
+		t.Fatal("IsFiltered does not match the blacklist")
+	}
+
+	// Blacklisting a subnet excludes every host within it.
+	setFilter(modules.HostDBFilter{
+		Mode:    modules.HostDBFilterBlacklist,
+		Subnets: []string{"10.0.0.0/16"},
+	})
+	if keys := selected(); len(keys) != 1 || !keys[hosts[2].PublicKey.String()] {
+		t.Fatal("host in a blacklisted subnet was selected")
+	}
+
+	// In whitelist mode, only the listed hosts are selected.
+	setFilter(modules.HostDBFilter{
+		Mode:       modules.HostDBFilterWhitelist,
+		PublicKeys: []types.SiaPublicKey{hosts[2].PublicKey},
+		Subnets:    []string{"10.0.2.1/32"},
+	})
+	if keys := selected(); len(keys) != 2 || keys[hosts[0].PublicKey.String()] {
+		t.Fatal("host missing from the whitelist was selected")
+	}
+
+	// Blacklisting a country excludes every host with an address in it.
+	geoIPPath := filepath.Join(build.TempDir("hostdb", t.Name()), "geoip.csv")
+	if err := os.MkdirAll(filepath.Dir(geoIPPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	geoIPData := "10.0.0.0,10.0.1.255,NL\n10.0.2.0,10.0.255.255,DE\n192.168.0.0,192.168.255.255,NL\n"
+	if err := ioutil.WriteFile(geoIPPath, []byte(geoIPData), 0600); err != nil {
+		t.Fatal(err)
+	}
+	setFilter(modules.HostDBFilter{
+		Mode:          modules.HostDBFilterBlacklist,
+		Countries:     []string{"de"},
+		GeoIPDatabase: geoIPPath,
+	})
+	if keys := selected(); len(keys) != 2 || keys[hosts[1].PublicKey.String()] {
+		t.Fatal("host in a blacklisted country was selected")
+	}
+	setFilter(modules.HostDBFilter{
+		Mode:          modules.HostDBFilterWhitelist,
+		Countries:     []string{"NL"},
+		GeoIPDatabase: geoIPPath,
+	})
+	if keys := selected(); len(keys) != 2 || keys[hosts[1].PublicKey.String()] {
+		t.Fatal("host outside of the whitelisted countries was selected")
+	}
+
+	// Disabling the filter selects every host again.
+	setFilter(modules.HostDBFilter{Mode: modules.HostDBFilterDisabled})
+	if keys := selected(); len(keys) != 3 {
+		t.Fatal("disabled filter excluded hosts")
+	}
+
+	// Invalid filters are rejected.
+	invalid := []modules.HostDBFilter{
+		{Mode: "greylist"},
+		{Mode: modules.HostDBFilterWhitelist},
+		{Mode: modules.HostDBFilterBlacklist, Subnets: []string{"10.0.0.1"}},
+		{Mode: modules.HostDBFilterBlacklist, Countries: []string{"NL"}},
+		{Mode: modules.HostDBFilterBlacklist, Countries: []string{"NLD"}, GeoIPDatabase: geoIPPath},
+		{Mode: modules.HostDBFilterBlacklist, Countries: []string{"NL"}, GeoIPDatabase: "geoip.csv"},
+		{Mode: modules.HostDBFilterBlacklist, Countries: []string{"NL"}, GeoIPDatabase: geoIPPath + ".dne"},
+	}
+	for _, f := range invalid {
+		if _, err := newHostFilter(f); err == nil {
+			t.Fatal("invalid filter was accepted:", f)
+		}
+	}
+}
+
+// TestHostFilterPersist checks that the hostdb's filter survives a restart.
+func TestHostFilterPersist(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	hdbt, err := newHDBTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	geoIPPath := filepath.Join(hdbt.persistDir, "geoip.csv")
+	if err := ioutil.WriteFile(geoIPPath, []byte("10.0.0.0,10.255.255.255,NL\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	filter := modules.HostDBFilter{
+		Mode:          modules.HostDBFilterBlacklist,
+		PublicKeys:    []types.SiaPublicKey{{Key: []byte("foo")}},
+		Subnets:       []string{"10.0.0.0/8"},
+		Countries:     []string{"DE", "NL"},
+		GeoIPDatabase: geoIPPath,
+	}
+	if err := hdbt.hdb.SetFilter(filter); err != nil {
+		t.Fatal(err)
+	}
+	if err := hdbt.hdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	hdbt.hdb, err = newHostDB(hdbt.gateway, hdbt.cs, filepath.Join(hdbt.persistDir, modules.RenterDir), quitAfterLoadDeps{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := hdbt.hdb.Filter(); !reflect.DeepEqual(f, filter) {
+		t.Fatal("filter was not persisted:", f)
+	}
+	if len(hdbt.hdb.filter.countryRanges) != 1 {
+		t.Fatal("country ranges were not loaded:", hdbt.hdb.filter.countryRanges)
+	}
+
+	// The filter is kept if its GeoIP database has disappeared.
+	if err := hdbt.hdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(geoIPPath); err != nil {
+		t.Fatal(err)
+	}
+	hdbt.hdb, err = newHostDB(hdbt.gateway, hdbt.cs, filepath.Join(hdbt.persistDir, modules.RenterDir), quitAfterLoadDeps{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := hdbt.hdb.Filter(); !reflect.DeepEqual(f, filter) {
+		t.Fatal("filter was not kept:", f)
+	}
+}
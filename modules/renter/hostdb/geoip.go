@@ -0,0 +1,94 @@
+package hostdb
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+var (
+	errBadCountryCode  = errors.New("countries must be given as two-letter ISO 3166 codes")
+	errNoGeoIPDatabase = errors.New("filtering hosts by country requires a GeoIP database")
+	errRelGeoIPPath    = errors.New("the path of the GeoIP database must be absolute")
+)
+
+// An ipRange is a range of IP addresses, both ends included, that a GeoIP
+// database assigns to a country. The addresses are stored in their 16-byte
+// form, so that IPv4 and IPv6 addresses can be compared with each other.
+type ipRange struct {
+	first, last net.IP
+}
+
+// parseCountryCode normalizes a two-letter ISO 3166 country code.
+func parseCountryCode(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+		return "", errBadCountryCode
+	}
+	return code, nil
+}
+
+// loadGeoIPRanges reads the ranges of the provided countries from the GeoIP
+// database at path. The database is a CSV file in which each record holds
+// the first and last address of a range followed by the code of its country,
+// such as the "IP to Country Lite" database published by DB-IP. The returned
+// ranges are sorted by their first address.
+func loadGeoIPRanges(path string, countries map[string]struct{}) ([]ipRange, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+	var ranges []ipRange
+	for line := 1; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if len(record) < 3 {
+			return nil, fmt.Errorf("GeoIP database line %v: expected at least 3 fields, got %v", line, len(record))
+		}
+		if _, exists := countries[strings.ToUpper(strings.TrimSpace(record[2]))]; !exists {
+			continue
+		}
+		ipr := ipRange{
+			first: net.ParseIP(strings.TrimSpace(record[0])),
+			last:  net.ParseIP(strings.TrimSpace(record[1])),
+		}
+		if ipr.first == nil || ipr.last == nil || bytes.Compare(ipr.first.To16(), ipr.last.To16()) > 0 {
+			return nil, fmt.Errorf("GeoIP database line %v: invalid address range", line)
+		}
+		ipr.first, ipr.last = ipr.first.To16(), ipr.last.To16()
+		ranges = append(ranges, ipr)
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return bytes.Compare(ranges[i].first, ranges[j].first) < 0
+	})
+	return ranges, nil
+}
+
+// rangesContain reports whether ip lies within one of the sorted ranges.
+func rangesContain(ranges []ipRange, ip net.IP) bool {
+	ip = ip.To16()
+	if ip == nil {
+		return false
+	}
+	// Find the first range that starts after ip. Ranges do not overlap, so
+	// only the range before it can contain ip.
+	i := sort.Search(len(ranges), func(i int) bool {
+		return bytes.Compare(ranges[i].first, ip) > 0
+	})
+	return i > 0 && bytes.Compare(ip, ranges[i-1].last) <= 0
+}
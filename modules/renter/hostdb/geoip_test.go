@@ -0,0 +1,71 @@
+package hostdb
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+)
+
+// TestLoadGeoIPRanges probes the loading of GeoIP databases and the lookup
+// of addresses in the loaded ranges.
+func TestLoadGeoIPRanges(t *testing.T) {
+	dir := build.TempDir("hostdb", t.Name())
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeDB := func(data string) string {
+		path := filepath.Join(dir, "geoip.csv")
+		if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	// Only the ranges of the requested countries are loaded, in order.
+	path := writeDB(`"10.0.2.0","10.0.2.255","nl"
+10.0.0.0,10.0.0.255,NL,Netherlands
+10.0.1.0,10.0.1.255,DE
+2001:db8::,2001:db8::ffff,NL
+`)
+	ranges, err := loadGeoIPRanges(path, map[string]struct{}{"NL": {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 3 || !ranges[0].first.Equal(net.ParseIP("10.0.0.0")) {
+		t.Fatal("wrong ranges loaded:", ranges)
+	}
+	lookups := []struct {
+		ip       string
+		contains bool
+	}{
+		{"9.255.255.255", false},
+		{"10.0.0.0", true},
+		{"10.0.0.255", true},
+		{"10.0.1.1", false},
+		{"10.0.2.128", true},
+		{"10.0.3.0", false},
+		{"2001:db8::1", true},
+		{"2001:db8::1:0", false},
+	}
+	for _, l := range lookups {
+		if rangesContain(ranges, net.ParseIP(l.ip)) != l.contains {
+			t.Errorf("rangesContain(%v): expected %v", l.ip, l.contains)
+		}
+	}
+
+	// Malformed databases are rejected.
+	malformed := []string{
+		"10.0.0.0,10.0.0.255\n",
+		"10.0.0.0,notanip,NL\n",
+		"10.0.0.255,10.0.0.0,NL\n",
+	}
+	for _, data := range malformed {
+		if _, err := loadGeoIPRanges(writeDB(data), map[string]struct{}{"NL": {}}); err == nil {
+			t.Errorf("malformed database %q was accepted", data)
+		}
+	}
+}
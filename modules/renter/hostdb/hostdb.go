@@ -7,6 +7,7 @@ package hostdb
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"sync"
@@ -20,8 +21,9 @@ import (
 )
 
 var (
-	errNilCS      = errors.New("cannot create hostdb with nil consensus set")
-	errNilGateway = errors.New("cannot create hostdb with nil gateway")
+	errNilCS             = errors.New("cannot create hostdb with nil consensus set")
+	errNilGateway        = errors.New("cannot create hostdb with nil gateway")
+	errInvalidSubnetBits = errors.New("subnet prefix lengths must be between 0 and 32 for IPv4 and between 0 and 128 for IPv6")
 )
 
 // The HostDB is a database of potential hosts. It assigns a weight to each
@@ -42,6 +44,13 @@ type HostDB struct {
 	// random.
 	hostTree *hosttree.HostTree
 
+	// filter excludes hosts from being returned by RandomHosts.
+	filter hostFilter
+
+	// scoringProfile adjusts the weights of the hosts. Hosts that do not meet
+	// its requirements are not returned by RandomHosts.
+	scoringProfile modules.HostScoringProfile
+
 	// the scanPool is a set of hosts that need to be scanned. There are a
 	// handful of goroutines constantly waiting on the channel for hosts to
 	// scan. The scan map is used to prevent duplicates from entering the scan
@@ -80,7 +89,8 @@ func newHostDB(g modules.Gateway, cs modules.ConsensusSet, persistDir string, de
 		gateway:    g,
 		persistDir: persistDir,
 
-		scanMap: make(map[string]struct{}),
+		scanMap:        make(map[string]struct{}),
+		scoringProfile: modules.DefaultHostScoringProfile,
 	}
 
 	// Create the persist directory if it does not yet exist.
@@ -237,7 +247,59 @@ func (hdb *HostDB) Host(spk types.SiaPublicKey) (modules.HostDBEntry, bool) {
 
 // RandomHosts implements the HostDB interface's RandomHosts() method. It takes
 // a number of hosts to return, and a slice of netaddresses to ignore, and
-// returns a slice of entries.
+// returns a slice of entries. Hosts excluded by the hostdb's filter or that do
+// not meet the requirements of the scoring profile are never returned, and at
+// most one host is returned per IP subnet.
 func (hdb *HostDB) RandomHosts(n int, excludeKeys []types.SiaPublicKey) []modules.HostDBEntry {
-	return hdb.hostTree.SelectRandom(n, excludeKeys)
+	hdb.mu.RLock()
+	filter := hdb.filter
+	profile := hdb.scoringProfile
+	hdb.mu.RUnlock()
+	var filteredKeys []types.SiaPublicKey
+	filtering := filter.mode == modules.HostDBFilterBlacklist || filter.mode == modules.HostDBFilterWhitelist
+	if filtering || hasRequirements(profile) {
+		for _, host := range hdb.hostTree.All() {
+			if !meetsRequirements(profile, host) {
+				filteredKeys = append(filteredKeys, host.PublicKey)
+				continue
+			}
+			if filtering {
+				ips, _ := hdb.hostTree.IPs(host.PublicKey)
+				if filter.excludes(host.PublicKey, ips) {
+					filteredKeys = append(filteredKeys, host.PublicKey)
+				}
+			}
+		}
+	}
+	return hdb.hostTree.SelectRandomFiltered(n, excludeKeys, filteredKeys)
+}
+
+// SubnetViolations returns the hosts in spks that share an IP subnet with a
+// host that appears earlier in spks.
+func (hdb *HostDB) SubnetViolations(spks []types.SiaPublicKey) []types.SiaPublicKey {
+	return hdb.hostTree.SubnetViolations(spks)
+}
+
+// SubnetRanges returns the prefix lengths of the IPv4 and IPv6 subnets within
+// which at most one host is selected.
+func (hdb *HostDB) SubnetRanges() (ipv4Bits, ipv6Bits int) {
+	return hdb.hostTree.SubnetRanges()
+}
+
+// SetSubnetRanges sets the prefix lengths of the IPv4 and IPv6 subnets within
+// which at most one host is selected. A prefix length of zero disables the
+// check for that type of address.
+func (hdb *HostDB) SetSubnetRanges(ipv4Bits, ipv6Bits int) error {
+	if err := hdb.tg.Add(); err != nil {
+		return err
+	}
+	defer hdb.tg.Done()
+
+	if ipv4Bits < 0 || ipv4Bits > 8*net.IPv4len || ipv6Bits < 0 || ipv6Bits > 8*net.IPv6len {
+		return errInvalidSubnetBits
+	}
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	hdb.hostTree.SetSubnetRanges(ipv4Bits, ipv6Bits)
+	return hdb.saveSync()
 }
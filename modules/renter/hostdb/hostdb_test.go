@@ -39,7 +39,8 @@ type hdbTester struct {
 // dependencies or scanning threads. It is only intended for use in unit tests.
 func bareHostDB() *HostDB {
 	hdb := &HostDB{
-		log: persist.NewLogger(ioutil.Discard),
+		log:            persist.NewLogger(ioutil.Discard),
+		scoringProfile: modules.DefaultHostScoringProfile,
 	}
 	hdb.hostTree = hosttree.New(hdb.calculateHostWeight)
 	return hdb
@@ -2,7 +2,9 @@ package hosttree
 
 import (
 	"errors"
+	"net"
 	"sort"
+	"strconv"
 	"sync"
 
 	"github.com/NebulousLabs/Sia/build"
@@ -34,6 +36,16 @@ var (
 	errNoSuchHost = errors.New("no host with specified public key")
 )
 
+const (
+	// IPv4SubnetBits is the default prefix length of the IPv4 subnets within
+	// which at most one host is selected.
+	IPv4SubnetBits = 24
+
+	// IPv6SubnetBits is the default prefix length of the IPv6 subnets within
+	// which at most one host is selected.
+	IPv6SubnetBits = 54
+)
+
 type (
 	// WeightFunc is a function used to weight a given HostDBEntry in the tree.
 	WeightFunc func(modules.HostDBEntry) types.Currency
@@ -51,6 +63,12 @@ type (
 		// weightFn calculates the weight of a hostEntry
 		weightFn WeightFunc
 
+		// ipv4Bits and ipv6Bits are the prefix lengths of the subnets used to
+		// enforce IP diversity. A prefix length of zero disables the check for
+		// that type of address.
+		ipv4Bits int
+		ipv6Bits int
+
 		mu sync.Mutex
 	}
 
@@ -58,6 +76,9 @@ type (
 	hostEntry struct {
 		modules.HostDBEntry
 		weight types.Currency
+
+		// ips are the resolved IP addresses of the host's NetAddress.
+		ips []net.IP
 	}
 
 	// node is a node in the tree.
@@ -95,6 +116,56 @@ func New(wf WeightFunc) *HostTree {
 		},
 		weightFn: wf,
 		hosts:    make(map[string]*node),
+		ipv4Bits: IPv4SubnetBits,
+		ipv6Bits: IPv6SubnetBits,
+	}
+}
+
+// parseIPs returns the IP address of a NetAddress whose host is an IP
+// literal. Host names are resolved separately and supplied through SetIPs.
+func parseIPs(addr modules.NetAddress) []net.IP {
+	if ip := net.ParseIP(addr.Host()); ip != nil {
+		return []net.IP{ip}
+	}
+	return nil
+}
+
+// subnets returns the subnets that contain the IP addresses of the entry.
+// Loopback and unspecified addresses do not belong to any subnet, so that
+// hosts running on the same machine during testing can all be selected.
+func (ht *HostTree) subnets(entry *hostEntry) []string {
+	var subnets []string
+	for _, ip := range entry.ips {
+		if ip.IsLoopback() || ip.IsUnspecified() {
+			continue
+		}
+		bits, size := ht.ipv6Bits, 8*net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits, size = ip4, ht.ipv4Bits, 8*net.IPv4len
+		}
+		if bits == 0 {
+			continue
+		}
+		subnet := ip.Mask(net.CIDRMask(bits, size)).String() + "/" + strconv.Itoa(bits)
+		subnets = append(subnets, subnet)
+	}
+	return subnets
+}
+
+// inSubnets returns whether any of the entry's subnets is in used.
+func (ht *HostTree) inSubnets(entry *hostEntry, used map[string]struct{}) bool {
+	for _, subnet := range ht.subnets(entry) {
+		if _, exists := used[subnet]; exists {
+			return true
+		}
+	}
+	return false
+}
+
+// addSubnets adds the entry's subnets to used.
+func (ht *HostTree) addSubnets(entry *hostEntry, used map[string]struct{}) {
+	for _, subnet := range ht.subnets(entry) {
+		used[subnet] = struct{}{}
 	}
 }
 
@@ -212,6 +283,7 @@ func (ht *HostTree) Insert(hdbe modules.HostDBEntry) error {
 	entry := &hostEntry{
 		HostDBEntry: hdbe,
 		weight:      ht.weightFn(hdbe),
+		ips:         parseIPs(hdbe.NetAddress),
 	}
 
 	if _, exists := ht.hosts[string(entry.PublicKey.Key)]; exists {
@@ -252,9 +324,15 @@ func (ht *HostTree) Modify(hdbe modules.HostDBEntry) error {
 
 	node.remove()
 
+	// The resolved addresses are kept unless the host has a new address.
+	ips := node.entry.ips
+	if hdbe.NetAddress != node.entry.NetAddress {
+		ips = parseIPs(hdbe.NetAddress)
+	}
 	entry := &hostEntry{
 		HostDBEntry: hdbe,
 		weight:      ht.weightFn(hdbe),
+		ips:         ips,
 	}
 
 	_, node = ht.root.recursiveInsert(entry)
@@ -275,18 +353,144 @@ func (ht *HostTree) Select(spk types.SiaPublicKey) (modules.HostDBEntry, bool) {
 	return node.entry.HostDBEntry, true
 }
 
+// SetWeightFunction replaces the tree's weight function and recomputes the
+// weight of every host in the tree.
+func (ht *HostTree) SetWeightFunction(wf WeightFunc) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	ht.weightFn = wf
+	entries := make([]*hostEntry, 0, len(ht.hosts))
+	for _, node := range ht.hosts {
+		entries = append(entries, node.entry)
+	}
+	ht.root = &node{
+		count: 1,
+	}
+	ht.hosts = make(map[string]*node)
+	for _, entry := range entries {
+		entry.weight = wf(entry.HostDBEntry)
+		_, node := ht.root.recursiveInsert(entry)
+		ht.hosts[string(entry.PublicKey.Key)] = node
+	}
+}
+
+// IPs returns the resolved IP addresses of the host with the provided public
+// key. The addresses of hosts that have not been resolved yet are only known
+// if the host's NetAddress is an IP literal.
+func (ht *HostTree) IPs(spk types.SiaPublicKey) ([]net.IP, bool) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	node, exists := ht.hosts[string(spk.Key)]
+	if !exists {
+		return nil, false
+	}
+	return append([]net.IP(nil), node.entry.ips...), true
+}
+
+// SetIPs sets the resolved IP addresses of the host with the provided public
+// key. The addresses are used to avoid selecting multiple hosts from the same
+// subnet.
+func (ht *HostTree) SetIPs(spk types.SiaPublicKey, ips []net.IP) error {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	node, exists := ht.hosts[string(spk.Key)]
+	if !exists {
+		return errNoSuchHost
+	}
+	node.entry.ips = ips
+	return nil
+}
+
+// AllIPs returns the resolved IP addresses of every host in the tree whose
+// addresses are known, keyed by the string form of the host's public key.
+func (ht *HostTree) AllIPs() map[string][]net.IP {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	ips := make(map[string][]net.IP)
+	for _, node := range ht.hosts {
+		if len(node.entry.ips) > 0 {
+			ips[node.entry.PublicKey.String()] = append([]net.IP(nil), node.entry.ips...)
+		}
+	}
+	return ips
+}
+
+// SubnetRanges returns the prefix lengths of the IPv4 and IPv6 subnets within
+// which at most one host is selected.
+func (ht *HostTree) SubnetRanges() (ipv4Bits, ipv6Bits int) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+	return ht.ipv4Bits, ht.ipv6Bits
+}
+
+// SetSubnetRanges sets the prefix lengths of the IPv4 and IPv6 subnets within
+// which at most one host is selected. A prefix length of zero disables the
+// check for that type of address.
+func (ht *HostTree) SetSubnetRanges(ipv4Bits, ipv6Bits int) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+	ht.ipv4Bits = ipv4Bits
+	ht.ipv6Bits = ipv6Bits
+}
+
+// SubnetViolations returns the hosts in spks that share a subnet with a host
+// that appears earlier in spks. Hosts that are not in the tree are ignored.
+func (ht *HostTree) SubnetViolations(spks []types.SiaPublicKey) []types.SiaPublicKey {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	var violations []types.SiaPublicKey
+	used := make(map[string]struct{})
+	for _, spk := range spks {
+		node, exists := ht.hosts[string(spk.Key)]
+		if !exists {
+			continue
+		}
+		if ht.inSubnets(node.entry, used) {
+			violations = append(violations, spk)
+			continue
+		}
+		ht.addSubnets(node.entry, used)
+	}
+	return violations
+}
+
 // SelectRandom grabs a random n hosts from the tree. There will be no repeats, but
 // the length of the slice returned may be less than n, and may even be zero.
 // The hosts that are returned first have the higher priority. Hosts passed to
-// 'ignore' will not be considered; pass `nil` if no blacklist is desired.
+// 'ignore' will not be considered; pass `nil` if no blacklist is desired. At
+// most one host is returned per subnet, and no host is returned from the
+// subnet of an ignored host.
 func (ht *HostTree) SelectRandom(n int, ignore []types.SiaPublicKey) []modules.HostDBEntry {
+	return ht.SelectRandomFiltered(n, ignore, nil)
+}
+
+// SelectRandomFiltered is like SelectRandom, but it does not consider the
+// hosts passed to 'filtered' either. Unlike ignored hosts, filtered hosts do
+// not prevent other hosts in their subnet from being returned.
+func (ht *HostTree) SelectRandomFiltered(n int, ignore, filtered []types.SiaPublicKey) []modules.HostDBEntry {
 	ht.mu.Lock()
 	defer ht.mu.Unlock()
 
 	var hosts []modules.HostDBEntry
 	var removedEntries []*hostEntry
+	usedSubnets := make(map[string]struct{})
 
 	for _, pubkey := range ignore {
+		node, exists := ht.hosts[string(pubkey.Key)]
+		if !exists {
+			continue
+		}
+		ht.addSubnets(node.entry, usedSubnets)
+		node.remove()
+		delete(ht.hosts, string(pubkey.Key))
+		removedEntries = append(removedEntries, node.entry)
+	}
+	for _, pubkey := range filtered {
 		node, exists := ht.hosts[string(pubkey.Key)]
 		if !exists {
 			continue
@@ -302,10 +506,13 @@ func (ht *HostTree) SelectRandom(n int, ignore []types.SiaPublicKey) []modules.H
 
 		if node.entry.AcceptingContracts &&
 			len(node.entry.ScanHistory) > 0 &&
-			node.entry.ScanHistory[len(node.entry.ScanHistory)-1].Success {
-			// The host must be online and accepting contracts to be returned
-			// by the random function.
+			node.entry.ScanHistory[len(node.entry.ScanHistory)-1].Success &&
+			!ht.inSubnets(node.entry, usedSubnets) {
+			// The host must be online, accepting contracts, and in a subnet
+			// that has not been used yet to be returned by the random
+			// function.
 			hosts = append(hosts, node.entry.HostDBEntry)
+			ht.addSubnets(node.entry, usedSubnets)
 		}
 
 		removedEntries = append(removedEntries, node.entry)
@@ -3,6 +3,7 @@ package hosttree
 import (
 	"errors"
 	"fmt"
+	"net"
 	"strconv"
 	"sync"
 	"testing"
@@ -473,3 +474,81 @@ func TestRandomHosts(t *testing.T) {
 		t.Error("doubled up")
 	}
 }
+
+// TestSelectRandomSubnets checks that SelectRandom returns at most one host
+// per subnet, and that SubnetViolations reports hosts in used subnets.
+func TestSelectRandomSubnets(t *testing.T) {
+	tree := New(func(dbe modules.HostDBEntry) types.Currency {
+		return types.NewCurrency64(10)
+	})
+
+	// Insert two hosts in the same IPv4 /24, two hosts in the same IPv6 /54,
+	// a host whose address is resolved separately, and two loopback hosts.
+	addrs := []modules.NetAddress{
+		"55.1.2.3:9982",
+		"55.1.2.4:9982",
+		"[2001:db8::1]:9982",
+		"[2001:db8:0:1::1]:9982",
+		"host.example.com:9982",
+		"127.0.0.1:9982",
+		"127.0.0.1:9983",
+	}
+	entries := make([]modules.HostDBEntry, len(addrs))
+	for i, addr := range addrs {
+		entries[i] = makeHostDBEntry()
+		entries[i].NetAddress = addr
+		if err := tree.Insert(entries[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tree.SetIPs(entries[4].PublicKey, []net.IP{net.ParseIP("55.1.2.5")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// One host from each subnet plus both loopback hosts should be selected.
+	for i := 0; i < 10; i++ {
+		if hosts := tree.SelectRandom(len(entries), nil); len(hosts) != 4 {
+			t.Fatal("expected 4 hosts, got", len(hosts))
+		}
+	}
+
+	// Ignoring a host prevents the other hosts in its subnet from being
+	// selected, but filtering it does not.
+	hosts := tree.SelectRandom(len(entries), []types.SiaPublicKey{entries[0].PublicKey})
+	if len(hosts) != 3 {
+		t.Fatal("expected 3 hosts, got", len(hosts))
+	}
+	hosts = tree.SelectRandomFiltered(len(entries), nil, []types.SiaPublicKey{entries[0].PublicKey})
+	if len(hosts) != 4 {
+		t.Fatal("expected 4 hosts, got", len(hosts))
+	}
+
+	// Changing the subnet ranges changes which hosts share a subnet.
+	tree.SetSubnetRanges(32, 64)
+	if ipv4Bits, ipv6Bits := tree.SubnetRanges(); ipv4Bits != 32 || ipv6Bits != 64 {
+		t.Fatal("wrong subnet ranges:", ipv4Bits, ipv6Bits)
+	}
+	if hosts := tree.SelectRandom(len(entries), nil); len(hosts) != 7 {
+		t.Fatal("expected 7 hosts, got", len(hosts))
+	}
+	tree.SetSubnetRanges(IPv4SubnetBits, IPv6SubnetBits)
+
+	// Only the later hosts in a subnet are violations.
+	spks := []types.SiaPublicKey{entries[1].PublicKey, entries[0].PublicKey, entries[2].PublicKey, entries[4].PublicKey, entries[5].PublicKey, entries[6].PublicKey}
+	violations := tree.SubnetViolations(spks)
+	if len(violations) != 2 || violations[0].String() != entries[0].PublicKey.String() || violations[1].String() != entries[4].PublicKey.String() {
+		t.Fatal("wrong subnet violations:", violations)
+	}
+
+	// Modifying a host without changing its address keeps its resolved IPs.
+	entries[4].AcceptingContracts = true
+	if err := tree.Modify(entries[4]); err != nil {
+		t.Fatal(err)
+	}
+	if violations := tree.SubnetViolations([]types.SiaPublicKey{entries[0].PublicKey, entries[4].PublicKey}); len(violations) != 1 {
+		t.Fatal("resolved IPs were lost after modifying the host")
+	}
+	if ips := tree.AllIPs()[entries[4].PublicKey.String()]; len(ips) != 1 || !ips[0].Equal(net.ParseIP("55.1.2.5")) {
+		t.Fatal("resolved IPs are missing from AllIPs:", ips)
+	}
+}
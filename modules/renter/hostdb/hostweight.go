@@ -379,28 +379,39 @@ func (hdb *HostDB) uptimeAdjustments(entry modules.HostDBEntry) float64 {
 	return math.Pow(uptimeRatio, exp)
 }
 
-// calculateHostWeight returns the weight of a host according to the settings of
-// the host database entry.
-func (hdb *HostDB) calculateHostWeight(entry modules.HostDBEntry) types.Currency {
-	collateralReward := hdb.collateralAdjustments(entry)
-	interactionPenalty := hdb.interactionAdjustments(entry)
-	lifetimePenalty := hdb.lifetimeAdjustments(entry)
-	pricePenalty := hdb.priceAdjustments(entry)
-	storageRemainingPenalty := storageRemainingAdjustments(entry)
-	uptimePenalty := hdb.uptimeAdjustments(entry)
-	versionPenalty := versionAdjustments(entry)
+// scoreBreakdown returns the adjustments and the resulting score of a host,
+// weighted according to the hostdb's scoring profile.
+func (hdb *HostDB) scoreBreakdown(entry modules.HostDBEntry) modules.HostScoreBreakdown {
+	sb := modules.HostScoreBreakdown{
+		AgeAdjustment:              hdb.lifetimeAdjustments(entry),
+		BurnAdjustment:             1,
+		CollateralAdjustment:       hdb.collateralAdjustments(entry),
+		InteractionAdjustment:      hdb.interactionAdjustments(entry),
+		PriceAdjustment:            hdb.priceAdjustments(entry),
+		StorageRemainingAdjustment: storageRemainingAdjustments(entry),
+		UptimeAdjustment:           hdb.uptimeAdjustments(entry),
+		VersionAdjustment:          versionAdjustments(entry),
+	}
+	applyScoringProfile(hdb.scoringProfile, entry, &sb)
 
 	// Combine the adjustments.
-	fullPenalty := collateralReward * interactionPenalty * lifetimePenalty *
-		pricePenalty * storageRemainingPenalty * uptimePenalty * versionPenalty
+	fullPenalty := sb.AgeAdjustment * sb.BurnAdjustment * sb.CollateralAdjustment *
+		sb.InteractionAdjustment * sb.PriceAdjustment * sb.StorageRemainingAdjustment *
+		sb.UptimeAdjustment * sb.VersionAdjustment
 
-	// Return a types.Currency.
-	weight := baseWeight.MulFloat(fullPenalty)
-	if weight.IsZero() {
+	// Convert to a types.Currency.
+	sb.Score = baseWeight.MulFloat(fullPenalty)
+	if sb.Score.IsZero() {
 		// A weight of zero is problematic for for the host tree.
-		return types.NewCurrency64(1)
+		sb.Score = types.NewCurrency64(1)
 	}
-	return weight
+	return sb
+}
+
+// calculateHostWeight returns the weight of a host according to the settings of
+// the host database entry.
+func (hdb *HostDB) calculateHostWeight(entry modules.HostDBEntry) types.Currency {
+	return hdb.scoreBreakdown(entry).Score
 }
 
 // calculateConversionRate calculates the conversion rate of the provided
@@ -422,56 +433,46 @@ func (hdb *HostDB) calculateConversionRate(score types.Currency) float64 {
 }
 
 // EstimateHostScore takes a HostExternalSettings and returns the estimated
-// score of that host in the hostdb, assuming no penalties for age or uptime.
+// score of that host in the hostdb, assuming no penalties for age, uptime or
+// interactions.
 func (hdb *HostDB) EstimateHostScore(entry modules.HostDBEntry) modules.HostScoreBreakdown {
-	// Grab the adjustments. Age, and uptime penalties are set to '1', to
-	// assume best behavior from the host.
-	collateralReward := hdb.collateralAdjustments(entry)
-	pricePenalty := hdb.priceAdjustments(entry)
-	storageRemainingPenalty := storageRemainingAdjustments(entry)
-	versionPenalty := versionAdjustments(entry)
-
-	// Combine into a full penalty, then determine the resulting estimated
-	// score.
-	fullPenalty := collateralReward * pricePenalty * storageRemainingPenalty * versionPenalty
-	estimatedScore := baseWeight.MulFloat(fullPenalty)
-	if estimatedScore.IsZero() {
-		estimatedScore = types.NewCurrency64(1)
-	}
-
-	// Compile the estimates into a host score breakdown.
-	return modules.HostScoreBreakdown{
-		Score:          estimatedScore,
-		ConversionRate: hdb.calculateConversionRate(estimatedScore),
-
+	// Grab the adjustments. Age, uptime and interaction penalties are set to
+	// '1', to assume best behavior from the host.
+	sb := modules.HostScoreBreakdown{
 		AgeAdjustment:              1,
 		BurnAdjustment:             1,
-		CollateralAdjustment:       collateralReward,
-		PriceAdjustment:            pricePenalty,
-		StorageRemainingAdjustment: storageRemainingPenalty,
+		CollateralAdjustment:       hdb.collateralAdjustments(entry),
+		InteractionAdjustment:      1,
+		PriceAdjustment:            hdb.priceAdjustments(entry),
+		StorageRemainingAdjustment: storageRemainingAdjustments(entry),
 		UptimeAdjustment:           1,
-		VersionAdjustment:          versionPenalty,
+		VersionAdjustment:          versionAdjustments(entry),
+	}
+	hdb.mu.RLock()
+	profile := hdb.scoringProfile
+	hdb.mu.RUnlock()
+	profile.MinUptime = 0
+	applyScoringProfile(profile, entry, &sb)
+
+	// Combine into a full penalty, then determine the resulting estimated
+	// score.
+	fullPenalty := sb.CollateralAdjustment * sb.PriceAdjustment * sb.StorageRemainingAdjustment * sb.VersionAdjustment
+	sb.Score = baseWeight.MulFloat(fullPenalty)
+	if sb.Score.IsZero() {
+		sb.Score = types.NewCurrency64(1)
 	}
+	sb.ConversionRate = hdb.calculateConversionRate(sb.Score)
+	return sb
 }
 
 // ScoreBreakdown provdes a detailed set of scalars and bools indicating
-// elements of the host's overall score.
+// elements of the host's overall score. The breakdown is computed from the
+// current scoring profile every time it is requested.
 func (hdb *HostDB) ScoreBreakdown(entry modules.HostDBEntry) modules.HostScoreBreakdown {
 	hdb.mu.Lock()
 	defer hdb.mu.Unlock()
 
-	score := hdb.calculateHostWeight(entry)
-	return modules.HostScoreBreakdown{
-		Score:          score,
-		ConversionRate: hdb.calculateConversionRate(score),
-
-		AgeAdjustment:              hdb.lifetimeAdjustments(entry),
-		BurnAdjustment:             1,
-		CollateralAdjustment:       hdb.collateralAdjustments(entry),
-		InteractionAdjustment:      hdb.interactionAdjustments(entry),
-		PriceAdjustment:            hdb.priceAdjustments(entry),
-		StorageRemainingAdjustment: storageRemainingAdjustments(entry),
-		UptimeAdjustment:           hdb.uptimeAdjustments(entry),
-		VersionAdjustment:          versionAdjustments(entry),
-	}
+	sb := hdb.scoreBreakdown(entry)
+	sb.ConversionRate = hdb.calculateConversionRate(sb.Score)
+	return sb
 }
@@ -284,3 +284,76 @@ func TestHostWeightUptimeDifferences4(t *testing.T) {
 		t.Error("Been around longer should have more weight")
 	}
 }
+
+// TestScoringProfile checks that the scoring profile adjusts the score
+// breakdown, that hosts which do not meet its requirements are not selected,
+// and that invalid profiles are rejected.
+func TestScoringProfile(t *testing.T) {
+	hdb := bareHostDB()
+	hdb.deps = prodDependencies{}
+
+	cheap := makeHostDBEntry()
+	cheap.StoragePrice = types.NewCurrency64(1000)
+	cheap.Version = "1.3.1"
+	expensive := makeHostDBEntry()
+	expensive.StoragePrice = types.SiacoinPrecision
+	expensive.Version = "1.3.1"
+	for _, entry := range []modules.HostDBEntry{cheap, expensive} {
+		if err := hdb.hostTree.Insert(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defaultBreakdown := hdb.scoreBreakdown(expensive)
+
+	// Squaring the price adjustment should square it in the breakdown and
+	// update the weights in the host tree.
+	hdb.scoringProfile = modules.DefaultHostScoringProfile
+	hdb.scoringProfile.PriceExponent = 2
+	hdb.hostTree.SetWeightFunction(hdb.calculateHostWeight)
+	sb := hdb.scoreBreakdown(expensive)
+	if sb.PriceAdjustment != defaultBreakdown.PriceAdjustment*defaultBreakdown.PriceAdjustment {
+		t.Fatal("price exponent was not applied:", sb.PriceAdjustment, defaultBreakdown.PriceAdjustment)
+	}
+	if sb.Score.Cmp(defaultBreakdown.Score) >= 0 {
+		t.Fatal("squaring a price penalty did not lower the score")
+	}
+
+	// Ignoring every adjustment should give every host the same score.
+	hdb.scoringProfile = modules.HostScoringProfile{}
+	if hdb.calculateHostWeight(cheap).Cmp(hdb.calculateHostWeight(expensive)) != 0 {
+		t.Fatal("hosts have different scores when every adjustment is ignored")
+	}
+
+	// Hosts above a price ceiling or below the minimum version should not be
+	// selected.
+	hdb.scoringProfile = modules.DefaultHostScoringProfile
+	hdb.scoringProfile.MaxStoragePrice = types.NewCurrency64(1e6)
+	hosts := hdb.RandomHosts(2, nil)
+	if len(hosts) != 1 || hosts[0].PublicKey.String() != cheap.PublicKey.String() {
+		t.Fatal("host above the price ceiling was selected")
+	}
+	if hdb.scoreBreakdown(expensive).PriceAdjustment != 0 {
+		t.Fatal("price adjustment of a host above the price ceiling is not zero")
+	}
+	hdb.scoringProfile = modules.DefaultHostScoringProfile
+	hdb.scoringProfile.MinVersion = "1.3.2"
+	if hosts := hdb.RandomHosts(2, nil); len(hosts) != 0 {
+		t.Fatal("host below the minimum version was selected")
+	}
+
+	// Invalid profiles should be rejected.
+	invalid := []modules.HostScoringProfile{
+		{},
+		{PriceExponent: -1},
+		{MinUptime: 1.5},
+		{MinVersion: "latest"},
+	}
+	for _, p := range invalid {
+		if err := validateScoringProfile(p); err == nil {
+			t.Fatal("invalid profile was accepted:", p)
+		}
+	}
+	if err := validateScoringProfile(modules.HostScoringProfile{PriceExponent: 1}); err != nil {
+		t.Fatal("profile with a single nonzero exponent was rejected:", err)
+	}
+}
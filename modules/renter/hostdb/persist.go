@@ -1,10 +1,12 @@
 package hostdb
 
 import (
+	"net"
 	"path/filepath"
 	"time"
 
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter/hostdb/hosttree"
 	"github.com/NebulousLabs/Sia/persist"
 	"github.com/NebulousLabs/Sia/types"
 )
@@ -22,18 +24,28 @@ var (
 	}
 )
 
-// hdbPersist defines what HostDB data persists across sessions.
+// hdbPersist defines what HostDB data persists across sessions. HostIPs holds
+// the resolved IP addresses of the hosts, keyed by public key.
 type hdbPersist struct {
-	AllHosts    []modules.HostDBEntry
-	BlockHeight types.BlockHeight
-	LastChange  modules.ConsensusChangeID
+	AllHosts       []modules.HostDBEntry
+	BlockHeight    types.BlockHeight
+	Filter         modules.HostDBFilter
+	HostIPs        map[string][]net.IP
+	IPv4SubnetBits int
+	IPv6SubnetBits int
+	LastChange     modules.ConsensusChangeID
+	ScoringProfile modules.HostScoringProfile
 }
 
 // persistData returns the data in the hostdb that will be saved to disk.
 func (hdb *HostDB) persistData() (data hdbPersist) {
 	data.AllHosts = hdb.hostTree.All()
 	data.BlockHeight = hdb.blockHeight
+	data.Filter = hdb.filter.persist()
+	data.HostIPs = hdb.hostTree.AllIPs()
+	data.IPv4SubnetBits, data.IPv6SubnetBits = hdb.hostTree.SubnetRanges()
 	data.LastChange = hdb.lastChange
+	data.ScoringProfile = hdb.scoringProfile
 	return data
 }
 
@@ -44,8 +56,13 @@ func (hdb *HostDB) saveSync() error {
 
 // load loads the hostdb persistence data from disk.
 func (hdb *HostDB) load() error {
-	// Fetch the data from the file.
-	var data hdbPersist
+	// Fetch the data from the file. Older persist files do not contain a
+	// scoring profile or subnet ranges, in which case the defaults are kept.
+	data := hdbPersist{
+		IPv4SubnetBits: hosttree.IPv4SubnetBits,
+		IPv6SubnetBits: hosttree.IPv6SubnetBits,
+		ScoringProfile: modules.DefaultHostScoringProfile,
+	}
 	err := hdb.deps.loadFile(persistMetadata, &data, filepath.Join(hdb.persistDir, persistFilename))
 	if err != nil {
 		return err
@@ -54,6 +71,21 @@ func (hdb *HostDB) load() error {
 	// Set the hostdb internal values.
 	hdb.blockHeight = data.BlockHeight
 	hdb.lastChange = data.LastChange
+	hdb.filter, err = parseHostFilter(data.Filter)
+	if err != nil {
+		hdb.log.Println("WARN: could not load the host filter:", err)
+	}
+	// The filter is kept if its GeoIP database cannot be read, but no host
+	// is matched by country until the filter is set again.
+	if err := hdb.filter.loadCountryRanges(); err != nil {
+		hdb.log.Println("WARN: could not load the countries of the host filter:", err)
+	}
+	if err := validateScoringProfile(data.ScoringProfile); err != nil {
+		hdb.log.Println("WARN: could not load the scoring profile:", err)
+	} else {
+		hdb.scoringProfile = data.ScoringProfile
+	}
+	hdb.hostTree.SetSubnetRanges(data.IPv4SubnetBits, data.IPv6SubnetBits)
 
 	// Load each of the hosts into the host tree.
 	for _, host := range data.AllHosts {
@@ -70,6 +102,9 @@ func (hdb *HostDB) load() error {
 		if err != nil {
 			hdb.log.Debugln("ERROR: could not insert host while loading:", host.NetAddress)
 		}
+		if ips, exists := data.HostIPs[host.PublicKey.String()]; exists && err == nil {
+			hdb.hostTree.SetIPs(host.PublicKey, ips)
+		}
 
 		// Make sure that all hosts have gone through the initial scanning.
 		if len(host.ScanHistory) < 2 {
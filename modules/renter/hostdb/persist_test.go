@@ -1,6 +1,7 @@
 package hostdb
 
 import (
+	"net"
 	"path/filepath"
 	"testing"
 
@@ -115,3 +116,75 @@ func TestRescan(t *testing.T) {
 
 	t.Skip("create two consensus sets with blocks + announcements")
 }
+
+// TestScoringProfilePersist checks that the scoring profile survives a
+// restart.
+func TestScoringProfilePersist(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	hdbt, err := newHDBTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile := modules.DefaultHostScoringProfile
+	profile.PriceExponent = 3
+	profile.MinUptime = 0.9
+	profile.MinVersion = "1.3.0"
+	if err := hdbt.hdb.SetScoringProfile(profile); err != nil {
+		t.Fatal(err)
+	}
+	if err := hdbt.hdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	hdbt.hdb, err = newHostDB(hdbt.gateway, hdbt.cs, filepath.Join(hdbt.persistDir, modules.RenterDir), quitAfterLoadDeps{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := hdbt.hdb.ScoringProfile(); p.PriceExponent != 3 || p.MinUptime != 0.9 || p.MinVersion != "1.3.0" {
+		t.Fatal("scoring profile was not persisted:", p)
+	}
+}
+
+// TestSubnetPersist checks that the subnet ranges and the resolved IP
+// addresses of the hosts survive a restart.
+func TestSubnetPersist(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	hdbt, err := newHDBTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host := makeHostDBEntry()
+	host.NetAddress = "host.example.com:9982"
+	if err := hdbt.hdb.hostTree.Insert(host); err != nil {
+		t.Fatal(err)
+	}
+	if err := hdbt.hdb.hostTree.SetIPs(host.PublicKey, []net.IP{net.ParseIP("55.1.2.3")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := hdbt.hdb.SetSubnetRanges(16, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := hdbt.hdb.SetSubnetRanges(33, 0); err != errInvalidSubnetBits {
+		t.Fatal("expected errInvalidSubnetBits, got", err)
+	}
+	if err := hdbt.hdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	hdbt.hdb, err = newHostDB(hdbt.gateway, hdbt.cs, filepath.Join(hdbt.persistDir, modules.RenterDir), quitAfterLoadDeps{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ipv4Bits, ipv6Bits := hdbt.hdb.SubnetRanges(); ipv4Bits != 16 || ipv6Bits != 0 {
+		t.Fatal("subnet ranges were not persisted:", ipv4Bits, ipv6Bits)
+	}
+	if ips, _ := hdbt.hdb.hostTree.IPs(host.PublicKey); len(ips) != 1 || !ips[0].Equal(net.ParseIP("55.1.2.3")) {
+		t.Fatal("resolved IPs were not persisted:", ips)
+	}
+}
@@ -246,7 +246,8 @@ func (hdb *HostDB) managedScanHost(entry modules.HostDBEntry) {
 		}
 		var pubkey crypto.PublicKey
 		copy(pubkey[:], pubKey.Key)
-		return crypto.ReadSignedObject(conn, &settings, maxSettingsLen, pubkey)
+		// Older hosts do not send all of the fields of the settings.
+		return crypto.ReadSignedObject(conn, (*modules.CompatHostExternalSettings)(&settings), maxSettingsLen, pubkey)
 	}()
 	if err != nil {
 		hdb.log.Debugf("Scan of host at %v failed: %v", netAddr, err)
@@ -263,10 +264,20 @@ func (hdb *HostDB) managedScanHost(entry modules.HostDBEntry) {
 		entry.RecentSuccessfulInteractions++
 	}
 
+	// Resolve the host's address so that the host tree can keep track of the
+	// subnets that the host belongs to.
+	ips, lookupErr := hdb.deps.lookupIP(netAddr.Host())
+	if lookupErr != nil {
+		hdb.log.Debugf("Unable to resolve host address %v: %v", netAddr, lookupErr)
+	}
+
 	// Update the host tree to have a new entry, including the new error. Then
 	// delete the entry from the scan map as the scan has been successful.
 	hdb.mu.Lock()
 	hdb.updateEntry(entry, err)
+	if recentEntry, exists := hdb.hostTree.Select(pubKey); exists && recentEntry.NetAddress == netAddr && lookupErr == nil {
+		hdb.hostTree.SetIPs(pubKey, ips)
+	}
 	hdb.mu.Unlock()
 }
 
@@ -0,0 +1,152 @@
+package hostdb
+
+import (
+	"errors"
+	"math"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errInvalidExponent  = errors.New("scoring exponents must be non-negative numbers")
+	errInvalidMinUptime = errors.New("minimum uptime must be between 0 and 1")
+	errInvalidVersion   = errors.New("minimum version is not a valid version number")
+	errZeroExponents    = errors.New("at least one scoring exponent must be nonzero")
+)
+
+// validateScoringProfile checks that every field of the profile is in range.
+// A profile whose exponents are all zero is rejected, since it would give
+// every host the same score.
+func validateScoringProfile(p modules.HostScoringProfile) error {
+	exponents := []float64{
+		p.AgeExponent,
+		p.CollateralExponent,
+		p.InteractionExponent,
+		p.PriceExponent,
+		p.StorageRemainingExponent,
+		p.UptimeExponent,
+		p.VersionExponent,
+	}
+	allZero := true
+	for _, exp := range exponents {
+		if exp < 0 || math.IsNaN(exp) || math.IsInf(exp, 0) {
+			return errInvalidExponent
+		}
+		allZero = allZero && exp == 0
+	}
+	if allZero {
+		return errZeroExponents
+	}
+	if p.MinUptime < 0 || p.MinUptime > 1 || math.IsNaN(p.MinUptime) {
+		return errInvalidMinUptime
+	}
+	if p.MinVersion != "" && !build.IsVersion(p.MinVersion) {
+		return errInvalidVersion
+	}
+	return nil
+}
+
+// exceedsPriceCeilings returns whether any of the host's prices is above the
+// corresponding ceiling of the profile.
+func exceedsPriceCeilings(p modules.HostScoringProfile, entry modules.HostDBEntry) bool {
+	exceeds := func(price, ceiling types.Currency) bool {
+		return !ceiling.IsZero() && price.Cmp(ceiling) > 0
+	}
+	return exceeds(entry.ContractPrice, p.MaxContractPrice) ||
+		exceeds(entry.DownloadBandwidthPrice, p.MaxDownloadBandwidthPrice) ||
+		exceeds(entry.StoragePrice, p.MaxStoragePrice) ||
+		exceeds(entry.UploadBandwidthPrice, p.MaxUploadBandwidthPrice)
+}
+
+// uptimeRatio returns the fraction of time that the host has been online,
+// according to its historic uptime and its scan history. If nothing is known
+// about the host, the ratio is zero.
+func uptimeRatio(entry modules.HostDBEntry) float64 {
+	uptime := entry.HistoricUptime
+	downtime := entry.HistoricDowntime
+	for i := 1; i < len(entry.ScanHistory); i++ {
+		prev, cur := entry.ScanHistory[i-1], entry.ScanHistory[i]
+		if prev.Success {
+			uptime += cur.Timestamp.Sub(prev.Timestamp)
+		} else {
+			downtime += cur.Timestamp.Sub(prev.Timestamp)
+		}
+	}
+	if uptime+downtime <= 0 {
+		return 0
+	}
+	return float64(uptime) / float64(uptime+downtime)
+}
+
+// applyScoringProfile raises each adjustment of the breakdown to the power of
+// the corresponding exponent of the profile, and zeroes the adjustments for
+// which the host does not meet the profile's requirements.
+func applyScoringProfile(p modules.HostScoringProfile, entry modules.HostDBEntry, sb *modules.HostScoreBreakdown) {
+	sb.AgeAdjustment = math.Pow(sb.AgeAdjustment, p.AgeExponent)
+	sb.CollateralAdjustment = math.Pow(sb.CollateralAdjustment, p.CollateralExponent)
+	sb.InteractionAdjustment = math.Pow(sb.InteractionAdjustment, p.InteractionExponent)
+	sb.PriceAdjustment = math.Pow(sb.PriceAdjustment, p.PriceExponent)
+	sb.StorageRemainingAdjustment = math.Pow(sb.StorageRemainingAdjustment, p.StorageRemainingExponent)
+	sb.UptimeAdjustment = math.Pow(sb.UptimeAdjustment, p.UptimeExponent)
+	sb.VersionAdjustment = math.Pow(sb.VersionAdjustment, p.VersionExponent)
+
+	if exceedsPriceCeilings(p, entry) {
+		sb.PriceAdjustment = 0
+	}
+	if p.MinUptime > 0 && uptimeRatio(entry) < p.MinUptime {
+		sb.UptimeAdjustment = 0
+	}
+	if p.MinVersion != "" && build.VersionCmp(entry.Version, p.MinVersion) < 0 {
+		sb.VersionAdjustment = 0
+	}
+}
+
+// hasRequirements returns whether the profile has any price ceilings or
+// minimums.
+func hasRequirements(p modules.HostScoringProfile) bool {
+	return !p.MaxContractPrice.IsZero() || !p.MaxDownloadBandwidthPrice.IsZero() ||
+		!p.MaxStoragePrice.IsZero() || !p.MaxUploadBandwidthPrice.IsZero() ||
+		p.MinUptime > 0 || p.MinVersion != ""
+}
+
+// meetsRequirements returns whether the host is within the price ceilings
+// and above the minimum uptime and version of the profile.
+func meetsRequirements(p modules.HostScoringProfile, entry modules.HostDBEntry) bool {
+	if exceedsPriceCeilings(p, entry) {
+		return false
+	}
+	if p.MinUptime > 0 && uptimeRatio(entry) < p.MinUptime {
+		return false
+	}
+	if p.MinVersion != "" && build.VersionCmp(entry.Version, p.MinVersion) < 0 {
+		return false
+	}
+	return true
+}
+
+// ScoringProfile returns the profile that the hostdb uses to score hosts.
+func (hdb *HostDB) ScoringProfile() modules.HostScoringProfile {
+	hdb.mu.RLock()
+	defer hdb.mu.RUnlock()
+	return hdb.scoringProfile
+}
+
+// SetScoringProfile replaces the profile that the hostdb uses to score hosts,
+// and recomputes the score of every host.
+func (hdb *HostDB) SetScoringProfile(p modules.HostScoringProfile) error {
+	if err := hdb.tg.Add(); err != nil {
+		return err
+	}
+	defer hdb.tg.Done()
+
+	if err := validateScoringProfile(p); err != nil {
+		return err
+	}
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	hdb.scoringProfile = p
+	hdb.hostTree.SetWeightFunction(hdb.calculateHostWeight)
+	return hdb.saveSync()
+}
@@ -0,0 +1,204 @@
+package renter
+
+import (
+	"sync"
+
+	"github.com/NebulousLabs/Sia/build"
+)
+
+type (
+	// A memoryManager limits the amount of memory that the upload pipeline
+	// uses to hold chunk data. Requests are granted in the order in which they
+	// were made, so that large requests are not starved by small ones, except
+	// that pipeline requests may overtake queued requests to hold memory. A
+	// request that exceeds the limit is granted once no memory is in use, so
+	// that chunks larger than the limit can still be uploaded one at a time.
+	//
+	// held is the part of used that holds data waiting on the pipeline, such
+	// as the chunks of streamed uploads. Held memory is only released once
+	// the pipeline has processed the data, so it does not count as in use
+	// when deciding whether an oversized pipeline request can be granted;
+	// otherwise the pipeline could never get the memory to process the held
+	// data. Oversized requests to hold memory still wait until no memory is
+	// in use. For the same reason a request to hold memory never blocks the
+	// pipeline requests queued behind it: the held memory is only released
+	// once those requests have been granted.
+	memoryManager struct {
+		limit uint64
+		used  uint64
+		held  uint64
+
+		// queue contains the requests that are waiting for memory, oldest
+		// first.
+		queue []*memoryRequest
+
+		mu   sync.Mutex
+		stop <-chan struct{}
+	}
+
+	// memoryRequest is a request for memory that is waiting to be granted.
+	// granted is closed once the memory has been assigned to the request.
+	memoryRequest struct {
+		amount  uint64
+		hold    bool
+		granted chan struct{}
+	}
+)
+
+// newMemoryManager returns a memory manager with the provided limit. Pending
+// requests are abandoned when stop is closed.
+func newMemoryManager(limit uint64, stop <-chan struct{}) *memoryManager {
+	return &memoryManager{
+		limit: limit,
+		stop:  stop,
+	}
+}
+
+// fits reports whether amount can be granted right now. The caller must hold
+// the lock.
+func (mm *memoryManager) fits(amount uint64, hold bool) bool {
+	inUse := mm.used - mm.held
+	if hold {
+		inUse = mm.used
+	}
+	return inUse == 0 || mm.used+amount <= mm.limit
+}
+
+// reserve assigns amount bytes of memory. The caller must hold the lock.
+func (mm *memoryManager) reserve(amount uint64, hold bool) {
+	mm.used += amount
+	if hold {
+		mm.held += amount
+	}
+}
+
+// grantQueued grants as many of the queued requests as fit, in order. A
+// pipeline request that fits is granted even if requests to hold memory are
+// waiting in front of it. The caller must hold the lock.
+func (mm *memoryManager) grantQueued() {
+	var waiting []*memoryRequest
+	pipelineWaiting := false
+	for _, mr := range mm.queue {
+		blocked := len(waiting) > 0
+		if !mr.hold {
+			blocked = pipelineWaiting
+		}
+		if blocked || !mm.fits(mr.amount, mr.hold) {
+			waiting = append(waiting, mr)
+			pipelineWaiting = pipelineWaiting || !mr.hold
+			continue
+		}
+		mm.reserve(mr.amount, mr.hold)
+		close(mr.granted)
+	}
+	mm.queue = waiting
+}
+
+// request blocks until amount bytes of memory are available, and reserves
+// them. False is returned if the renter shut down before the memory could be
+// reserved.
+func (mm *memoryManager) request(amount uint64) bool {
+	return mm.managedRequest(amount, false)
+}
+
+// hold is like request, but reserves memory for data that waits on the
+// pipeline. The memory must be returned with releaseHeld.
+func (mm *memoryManager) hold(amount uint64) bool {
+	return mm.managedRequest(amount, true)
+}
+
+// managedRequest blocks until amount bytes of memory are available, and
+// reserves them as held memory if hold is set.
+func (mm *memoryManager) managedRequest(amount uint64, hold bool) bool {
+	mr := &memoryRequest{
+		amount:  amount,
+		hold:    hold,
+		granted: make(chan struct{}),
+	}
+	mm.mu.Lock()
+	mm.queue = append(mm.queue, mr)
+	mm.grantQueued()
+	mm.mu.Unlock()
+
+	select {
+	case <-mr.granted:
+		return true
+	case <-mm.stop:
+	}
+
+	// The request may have been granted while the renter was shutting down,
+	// in which case the memory is returned.
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	select {
+	case <-mr.granted:
+		mm.used -= amount
+		if hold {
+			mm.held -= amount
+		}
+		mm.grantQueued()
+		return false
+	default:
+	}
+	for i := range mm.queue {
+		if mm.queue[i] == mr {
+			mm.queue = append(mm.queue[:i], mm.queue[i+1:]...)
+			break
+		}
+	}
+	// The dropped request may have been blocking smaller requests behind it.
+	mm.grantQueued()
+	return false
+}
+
+// release returns memory that was reserved by request, and grants the
+// requests that are waiting for it.
+func (mm *memoryManager) release(amount uint64) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if amount > mm.used-mm.held {
+		build.Critical("memory manager released more memory than was requested")
+		amount = mm.used - mm.held
+	}
+	mm.used -= amount
+	mm.grantQueued()
+}
+
+// releaseHeld returns memory that was reserved by hold.
+func (mm *memoryManager) releaseHeld(amount uint64) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if amount > mm.held {
+		build.Critical("memory manager released more held memory than was requested")
+		amount = mm.held
+	}
+	mm.used -= amount
+	mm.held -= amount
+	mm.grantQueued()
+}
+
+// setLimit changes the amount of memory that can be reserved. Memory that is
+// already reserved is not reclaimed if the limit is lowered.
+func (mm *memoryManager) setLimit(limit uint64) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.limit = limit
+	mm.grantQueued()
+}
+
+// wait blocks until every pipeline request that was made before it has been
+// granted.
+// It is used to apply back-pressure to new uploads. False is returned if the
+// renter shut down while waiting.
+func (mm *memoryManager) wait() bool {
+	return mm.request(0)
+}
+
+// uploadMemoryLimit returns the memory limit of the upload pipeline for the
+// persisted setting, where 0 selects the default limit.
+func uploadMemoryLimit(setting uint64) uint64 {
+	if setting == 0 {
+		return defaultUploadMemory
+	}
+	return setting
+}
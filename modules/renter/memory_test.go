@@ -0,0 +1,147 @@
+package renter
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMemoryManager checks that the memory manager grants requests in order
+// and only once enough memory has been released.
+func TestMemoryManager(t *testing.T) {
+	stop := make(chan struct{})
+	mm := newMemoryManager(100, stop)
+
+	// A request larger than the limit is granted if no memory is in use.
+	if !mm.request(150) {
+		t.Fatal("request was not granted")
+	}
+	mm.release(150)
+	if !mm.request(60) {
+		t.Fatal("request was not granted")
+	}
+
+	// The next request does not fit, and the one after it has to wait its
+	// turn even though it would fit.
+	granted := make(chan uint64, 2)
+	go func() {
+		mm.request(50)
+		granted <- 50
+	}()
+	for !func() bool { mm.mu.Lock(); defer mm.mu.Unlock(); return len(mm.queue) == 1 }() {
+		time.Sleep(time.Millisecond)
+	}
+	go func() {
+		mm.wait()
+		granted <- 0
+	}()
+	select {
+	case amount := <-granted:
+		t.Fatal("request was granted before memory was released:", amount)
+	case <-time.After(50 * time.Millisecond):
+	}
+	mm.release(60)
+	if first, second := <-granted, <-granted; first+second != 50 {
+		t.Fatal("wrong requests were granted:", first, second)
+	}
+
+	// Waiting requests are abandoned on shutdown.
+	go func() {
+		granted <- 0
+		if mm.request(100) {
+			t.Error("request was granted after shutdown")
+		}
+		granted <- 100
+	}()
+	<-granted
+	close(stop)
+	<-granted
+	mm.release(50)
+	if mm.used != 0 || len(mm.queue) != 0 {
+		t.Fatal("memory manager was not cleaned up:", mm.used, len(mm.queue))
+	}
+}
+
+// TestMemoryManagerHold checks that held memory does not prevent the pipeline
+// from getting memory, while further requests to hold memory still wait.
+func TestMemoryManagerHold(t *testing.T) {
+	mm := newMemoryManager(100, make(chan struct{}))
+	if !mm.hold(80) {
+		t.Fatal("hold was not granted")
+	}
+
+	// Only held memory is in use, so an oversized pipeline request is
+	// granted.
+	if !mm.request(50) {
+		t.Fatal("request was not granted")
+	}
+
+	// Holding more memory has to wait until the limit is respected.
+	granted := make(chan struct{})
+	go func() {
+		mm.hold(40)
+		close(granted)
+	}()
+	select {
+	case <-granted:
+		t.Fatal("hold was granted before memory was released")
+	case <-time.After(50 * time.Millisecond):
+	}
+	mm.release(50)
+	select {
+	case <-granted:
+		t.Fatal("hold was granted before memory was released")
+	case <-time.After(50 * time.Millisecond):
+	}
+	mm.releaseHeld(80)
+	<-granted
+	mm.releaseHeld(40)
+	if mm.used != 0 || mm.held != 0 {
+		t.Fatal("memory manager was not cleaned up:", mm.used, mm.held)
+	}
+}
+
+// TestMemoryManagerHoldQueued checks that a queued request to hold memory
+// does not block the pipeline request that frees the memory already held,
+// even when fewer than maxStreamChunks chunks fit within the limit.
+func TestMemoryManagerHoldQueued(t *testing.T) {
+	const chunkSize = 40
+	mm := newMemoryManager(uint64(chunkSize*maxStreamChunks-1), make(chan struct{}))
+	for i := 0; i < maxStreamChunks-1; i++ {
+		if !mm.hold(chunkSize) {
+			t.Fatal("hold was not granted")
+		}
+	}
+
+	// The next chunk of the stream has to wait for memory.
+	held := make(chan struct{})
+	go func() {
+		mm.hold(chunkSize)
+		close(held)
+	}()
+	select {
+	case <-held:
+		t.Fatal("hold was granted before memory was released")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Encoding the first chunk must not wait behind the queued hold.
+	requested := make(chan struct{})
+	go func() {
+		mm.request(3 * chunkSize)
+		close(requested)
+	}()
+	select {
+	case <-requested:
+	case <-time.After(time.Second):
+		t.Fatal("pipeline request was blocked by a queued hold")
+	}
+	mm.release(3 * chunkSize)
+	mm.releaseHeld(chunkSize)
+	<-held
+	for i := 0; i < maxStreamChunks-1; i++ {
+		mm.releaseHeld(chunkSize)
+	}
+	if mm.used != 0 || mm.held != 0 || len(mm.queue) != 0 {
+		t.Fatal("memory manager was not cleaned up:", mm.used, mm.held, len(mm.queue))
+	}
+}
@@ -8,7 +8,10 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/encoding"
@@ -18,9 +21,10 @@ import (
 )
 
 const (
-	PersistFilename = "renter.json"
-	ShareExtension  = ".sia"
-	logFile         = modules.RenterDir + ".log"
+	PersistFilename   = "renter.json"
+	DownloadsFilename = "downloads.json"
+	ShareExtension    = ".sia"
+	logFile           = modules.RenterDir + ".log"
 )
 
 var (
@@ -36,8 +40,29 @@ var (
 		Header:  "Renter Persistence",
 		Version: "0.4",
 	}
+
+	downloadsMetadata = persist.Metadata{
+		Header:  "Renter Download History",
+		Version: "1.3.0",
+	}
 )
 
+// persistDownload is the persisted form of a download in the download queue.
+// Only the chunks listed in FinishedChunks need to be fetched again when the
+// download is resumed.
+type persistDownload struct {
+	ID             string
+	SiaPath        string
+	Destination    string
+	Offset         uint64
+	Length         uint64
+	FinishedChunks []uint64
+	Received       uint64
+	Status         modules.DownloadStatus
+	Error          string
+	StartTime      time.Time
+}
+
 // MarshalSia implements the encoding.SiaMarshaller interface, writing the
 // file data to w.
 func (f *file) MarshalSia(w io.Writer) error {
@@ -62,22 +87,26 @@ func (f *file) MarshalSia(w io.Writer) error {
 	}
 
 	// encode erasureCode
-	switch code := f.erasureCode.(type) {
-	case *rsCode:
-		err = enc.EncodeAll(
-			"Reed-Solomon",
-			uint64(code.dataPieces),
-			uint64(code.numPieces-code.dataPieces),
-		)
-		if err != nil {
-			return err
-		}
-	default:
+	code, ok := f.erasureCode.(persistedCoder)
+	if !ok {
 		if build.DEBUG {
 			panic("unknown erasure code")
 		}
 		return errors.New("unknown erasure code")
 	}
+	err = enc.EncodeAll(
+		code.specifier(),
+		uint64(code.MinPieces()),
+		uint64(code.NumPieces()-code.MinPieces()),
+	)
+	if err != nil {
+		return err
+	}
+	for _, param := range code.params() {
+		if err := enc.Encode(param); err != nil {
+			return err
+		}
+	}
 	// encode contracts
 	if err := enc.Encode(uint64(len(f.contracts))); err != nil {
 		return err
@@ -117,24 +146,29 @@ func (f *file) UnmarshalSia(r io.Reader) error {
 	if err := dec.Decode(&codeType); err != nil {
 		return err
 	}
-	switch codeType {
-	case "Reed-Solomon":
-		var nData, nParity uint64
-		err = dec.DecodeAll(
-			&nData,
-			&nParity,
-		)
-		if err != nil {
-			return err
-		}
-		rsc, err := NewRSCode(int(nData), int(nParity))
-		if err != nil {
+	ect, ok := erasureCoderTypes[codeType]
+	if !ok {
+		return errors.New("unrecognized erasure code type: " + codeType)
+	}
+	var nData, nParity uint64
+	err = dec.DecodeAll(
+		&nData,
+		&nParity,
+	)
+	if err != nil {
+		return err
+	}
+	params := make([]uint64, ect.numParams)
+	for i := range params {
+		if err := dec.Decode(&params[i]); err != nil {
 			return err
 		}
-		f.erasureCode = rsc
-	default:
-		return errors.New("unrecognized erasure code type: " + codeType)
 	}
+	ec, err := ect.newCoder(int(nData), int(nParity), params)
+	if err != nil {
+		return err
+	}
+	f.erasureCode = ec
 
 	// Decode contracts.
 	var nContracts uint64
@@ -181,12 +215,117 @@ func (r *Renter) saveFile(f *file) error {
 // saveSync stores the current renter data to disk and then syncs to disk.
 func (r *Renter) saveSync() error {
 	data := struct {
-		Tracking map[string]trackedFile
-	}{r.tracking}
+		Tracking         map[string]trackedFile
+		Directories      []string
+		ChunkCacheSize   uint64
+		MaxDownloadSpeed int64
+		MaxUploadSpeed   int64
+		MaxUploadMemory  uint64
+		ReadOnly         []string
+	}{r.tracking, r.dirNames(), r.chunkCacheSize, r.maxDownloadSpeed, r.maxUploadSpeed, r.maxUploadMemory, r.readOnlyNames()}
 
 	return persist.SaveJSON(saveMetadata, data, filepath.Join(r.persistDir, PersistFilename))
 }
 
+// downloadHistory returns the persisted form of the download history.
+func (r *Renter) downloadHistory() []persistDownload {
+	downloads := make([]persistDownload, 0, len(r.downloadQueue))
+	for _, d := range r.downloadQueue {
+		pd := persistDownload{
+			ID:          d.id,
+			SiaPath:     d.siapath,
+			Destination: d.destination.Destination(),
+			Offset:      d.offset,
+			Length:      d.length,
+			Received:    atomic.LoadUint64(&d.atomicDataReceived),
+			StartTime:   d.startTime,
+		}
+		d.mu.Lock()
+		for i, finished := range d.finishedChunks {
+			if finished {
+				pd.FinishedChunks = append(pd.FinishedChunks, i)
+			}
+		}
+		pd.Status = d.status()
+		if d.downloadErr != nil {
+			pd.Error = d.downloadErr.Error()
+		}
+		d.mu.Unlock()
+		downloads = append(downloads, pd)
+	}
+	return downloads
+}
+
+// managedSaveDownloads stores the download history to disk. The history is
+// written without holding the renter lock, and saves are serialized so that
+// an older snapshot never overwrites a newer one.
+func (r *Renter) managedSaveDownloads() error {
+	r.downloadsSaveMu.Lock()
+	defer r.downloadsSaveMu.Unlock()
+
+	id := r.mu.RLock()
+	downloads := r.downloadHistory()
+	r.mu.RUnlock(id)
+	return persist.SaveJSON(downloadsMetadata, downloads, filepath.Join(r.persistDir, DownloadsFilename))
+}
+
+// threadedSaveDownloads saves the download history in the background.
+func (r *Renter) threadedSaveDownloads() {
+	if err := r.tg.Add(); err != nil {
+		return
+	}
+	defer r.tg.Done()
+	if err := r.managedSaveDownloads(); err != nil {
+		r.log.Println("ERROR: unable to save download history:", err)
+	}
+}
+
+// loadDownloads restores the download history. Downloads that were in
+// progress are marked as interrupted so that they can be resumed once the
+// renter has started.
+func (r *Renter) loadDownloads() error {
+	var downloads []persistDownload
+	err := persist.LoadJSON(downloadsMetadata, &downloads, filepath.Join(r.persistDir, DownloadsFilename))
+	if err != nil {
+		return err
+	}
+	for _, pd := range downloads {
+		d := &download{
+			atomicDataReceived: pd.Received,
+			downloadComplete:   true,
+			finishedChunks:     make(map[uint64]bool),
+			offset:             pd.Offset,
+			length:             pd.Length,
+			startTime:          pd.StartTime,
+			destination:        historicDestination(pd.Destination),
+			id:                 pd.ID,
+			siapath:            pd.SiaPath,
+			downloadFinished:   make(chan struct{}),
+		}
+		close(d.downloadFinished)
+		for _, i := range pd.FinishedChunks {
+			d.finishedChunks[i] = true
+		}
+		switch pd.Status {
+		case modules.DownloadStatusActive:
+			if d.resumable() {
+				d.downloadErr = errDownloadInterrupted
+			} else {
+				d.downloadErr = errors.New("download was interrupted by shutdown and cannot be resumed")
+			}
+		case modules.DownloadStatusPaused:
+			d.downloadErr = errDownloadPaused
+		case modules.DownloadStatusCancelled:
+			d.downloadErr = errDownloadCancelled
+		case modules.DownloadStatusFailed:
+			d.downloadErr = errors.New(pd.Error)
+		}
+		r.downloadQueue = append(r.downloadQueue, d)
+	}
+	r.pruneDownloadHistory()
+	return nil
+}
+
 // load fetches the saved renter data from disk.
 func (r *Renter) load() error {
 	// Recursively load all files found in renter directory. Errors
@@ -213,11 +352,14 @@ func (r *Renter) load() error {
 		defer file.Close()
 
 		// Load the file contents into the renter.
-		_, err = r.loadSharedFiles(file)
+		files, err := readSharedFiles(file)
 		if err != nil {
 			r.log.Println("ERROR: could not load .sia file:", err)
 			return nil
 		}
+		for _, f := range files {
+			r.addFile(f)
+		}
 		return nil
 	})
 	if err != nil {
@@ -226,8 +368,14 @@ func (r *Renter) load() error {
 
 	// Load contracts, repair set, and entropy.
 	data := struct {
-		Tracking  map[string]trackedFile
-		Repairing map[string]string // COMPATv0.4.8
+		Tracking         map[string]trackedFile
+		Directories      []string
+		ChunkCacheSize   uint64
+		MaxDownloadSpeed int64
+		MaxUploadSpeed   int64
+		MaxUploadMemory  uint64
+		ReadOnly         []string
+		Repairing        map[string]string // COMPATv0.4.8
 	}{}
 	err = persist.LoadJSON(saveMetadata, &data, filepath.Join(r.persistDir, PersistFilename))
 	if err != nil {
@@ -236,6 +384,21 @@ func (r *Renter) load() error {
 	if data.Tracking != nil {
 		r.tracking = data.Tracking
 	}
+	// Directories containing files were created as the files were loaded;
+	// this restores any empty directories.
+	for _, name := range data.Directories {
+		r.createDir(name)
+	}
+	r.chunkCacheSize = data.ChunkCacheSize
+	r.maxDownloadSpeed = data.MaxDownloadSpeed
+	r.maxUploadSpeed = data.MaxUploadSpeed
+	r.hostContractor.SetRateLimits(data.MaxDownloadSpeed, data.MaxUploadSpeed)
+	r.maxUploadMemory = data.MaxUploadMemory
+	for _, name := range data.ReadOnly {
+		if f, exists := r.files[name]; exists {
+			f.readOnly = true
+		}
+	}
 
 	return nil
 }
@@ -268,6 +431,41 @@ func shareFiles(files []*file, w io.Writer) error {
 	return zip.Close()
 }
 
+// sharedFiles returns the files with the provided nicknames. Each file is
+// returned once, even if its nickname is repeated.
+func (r *Renter) sharedFiles(nicknames []string) ([]*file, error) {
+	if len(nicknames) == 0 {
+		return nil, ErrNoNicknames
+	}
+	files := make([]*file, 0, len(nicknames))
+	seen := make(map[string]struct{}, len(nicknames))
+	for _, name := range nicknames {
+		if _, dup := seen[name]; dup {
+			continue
+		}
+		seen[name] = struct{}{}
+		f, exists := r.files[name]
+		if !exists {
+			return nil, ErrUnknownPath
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// readOnlyNames returns the nicknames of the files that were loaded from a
+// .sia file shared by another renter.
+func (r *Renter) readOnlyNames() []string {
+	var names []string
+	for name, f := range r.files {
+		if f.readOnly {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 // ShareFile saves the specified files to shareDest.
 func (r *Renter) ShareFiles(nicknames []string, shareDest string) error {
 	lockID := r.mu.RLock()
@@ -278,21 +476,18 @@ func (r *Renter) ShareFiles(nicknames []string, shareDest string) error {
 		return ErrNonShareSuffix
 	}
 
-	handle, err := os.Create(shareDest)
+	// Load files from renter before creating the destination, so that an
+	// unknown nickname does not leave an empty .sia file behind.
+	files, err := r.sharedFiles(nicknames)
 	if err != nil {
 		return err
 	}
-	defer handle.Close()
 
-	// Load files from renter.
-	files := make([]*file, len(nicknames))
-	for i, name := range nicknames {
-		f, exists := r.files[name]
-		if !exists {
-			return ErrUnknownPath
-		}
-		files[i] = f
+	handle, err := os.Create(shareDest)
+	if err != nil {
+		return err
 	}
+	defer handle.Close()
 
 	err = shareFiles(files, handle)
 	if err != nil {
@@ -309,17 +504,13 @@ func (r *Renter) ShareFilesAscii(nicknames []string) (string, error) {
 	defer r.mu.RUnlock(lockID)
 
 	// Load files from renter.
-	files := make([]*file, len(nicknames))
-	for i, name := range nicknames {
-		f, exists := r.files[name]
-		if !exists {
-			return "", ErrUnknownPath
-		}
-		files[i] = f
+	files, err := r.sharedFiles(nicknames)
+	if err != nil {
+		return "", err
 	}
 
 	buf := new(bytes.Buffer)
-	err := shareFiles(files, base64.NewEncoder(base64.URLEncoding, buf))
+	err = shareFiles(files, base64.NewEncoder(base64.URLEncoding, buf))
 	if err != nil {
 		return "", err
 	}
@@ -327,9 +518,9 @@ func (r *Renter) ShareFilesAscii(nicknames []string) (string, error) {
 	return buf.String(), nil
 }
 
-// loadSharedFiles reads .sia data from reader and registers the contained
-// files in the renter. It returns the nicknames of the loaded files.
-func (r *Renter) loadSharedFiles(reader io.Reader) ([]string, error) {
+// readSharedFiles decodes the files in the .sia data read from reader and
+// checks that they are consistent.
+func readSharedFiles(reader io.Reader) ([]*file, error) {
 	// read header
 	var header [15]byte
 	var version string
@@ -354,40 +545,56 @@ func (r *Renter) loadSharedFiles(reader io.Reader) ([]string, error) {
 	}
 	dec := encoding.NewDecoder(unzip)
 
-	// Read each file.
-	files := make([]*file, numFiles)
-	for i := range files {
-		files[i] = new(file)
-		err := dec.Decode(files[i])
-		if err != nil {
+	// Read each file. numFiles is not trusted to preallocate the slice,
+	// since the .sia data may have been crafted.
+	var files []*file
+	for i := uint64(0); i < numFiles; i++ {
+		f := new(file)
+		if err := dec.Decode(f); err != nil {
 			return nil, err
 		}
-
-		// Make sure the file's name does not conflict with existing files.
-		dupCount := 0
-		origName := files[i].name
-		for {
-			_, exists := r.files[files[i].name]
-			if !exists {
-				break
-			}
-			dupCount++
-			files[i].name = origName + "_" + strconv.Itoa(dupCount)
+		if err := validateSharedFile(f); err != nil {
+			return nil, err
 		}
+		files = append(files, f)
 	}
+	return files, nil
+}
 
-	// Add files to renter.
-	names := make([]string, numFiles)
+// loadSharedFiles reads .sia data from reader and registers the contained
+// files in the renter as read-only files. Files whose nickname is already in
+// use, including by another file in the same .sia data, are renamed with a
+// numeric suffix. It returns the loaded files and their original nicknames.
+func (r *Renter) loadSharedFiles(reader io.Reader) ([]*file, []string, error) {
+	files, err := readSharedFiles(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	origNames := make([]string, len(files))
 	for i, f := range files {
-		r.files[f.name] = f
-		names[i] = f.name
+		// Make sure the file's name does not conflict with existing files
+		// or dirs.
+		origNames[i] = f.name
+		inUse := func(name string) bool {
+			_, fileExists := r.files[name]
+			_, dirExists := r.dirs[name]
+			return fileExists || dirExists
+		}
+		for dupCount := 1; inUse(f.name); dupCount++ {
+			f.name = origNames[i] + "_" + strconv.Itoa(dupCount)
+		}
+		f.readOnly = true
+		r.addFile(f)
 	}
+
 	// Save the files.
 	for _, f := range files {
-		r.saveFile(f)
+		if err := r.saveFile(f); err != nil {
+			return nil, nil, err
+		}
 	}
-
-	return names, nil
+	return files, origNames, r.saveSync()
 }
 
 // initPersist handles all of the persistence initialization, such as creating
@@ -410,29 +617,58 @@ func (r *Renter) initPersist() error {
 	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
-	return nil
+
+	// Load the download history.
+	err = r.loadDownloads()
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Create the memory manager of the upload pipeline.
+	r.memoryManager = newMemoryManager(uploadMemoryLimit(r.maxUploadMemory), r.tg.StopChan())
+
+	// Create the chunk cache.
+	r.chunkCache, err = newChunkCache(filepath.Join(r.persistDir, chunkCacheDir), r.chunkCacheSize)
+	return err
 }
 
-// LoadSharedFiles loads a .sia file into the renter. It returns the nicknames
-// of the loaded files.
-func (r *Renter) LoadSharedFiles(filename string) ([]string, error) {
-	lockID := r.mu.Lock()
-	defer r.mu.Unlock(lockID)
+// LoadSharedFiles loads a .sia file into the renter. It returns the
+// availability of the loaded files.
+func (r *Renter) LoadSharedFiles(filename string) ([]modules.SharedFileInfo, error) {
+	if err := r.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer r.tg.Done()
 
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
-	return r.loadSharedFiles(file)
+
+	lockID := r.mu.Lock()
+	files, origNames, err := r.loadSharedFiles(file)
+	r.mu.Unlock(lockID)
+	if err != nil {
+		return nil, err
+	}
+	return r.managedSharedFileInfo(files, origNames), nil
 }
 
 // LoadSharedFilesAscii loads an ASCII-encoded .sia file into the renter. It
-// returns the nicknames of the loaded files.
-func (r *Renter) LoadSharedFilesAscii(asciiSia string) ([]string, error) {
-	lockID := r.mu.Lock()
-	defer r.mu.Unlock(lockID)
+// returns the availability of the loaded files.
+func (r *Renter) LoadSharedFilesAscii(asciiSia string) ([]modules.SharedFileInfo, error) {
+	if err := r.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer r.tg.Done()
 
 	dec := base64.NewDecoder(base64.URLEncoding, bytes.NewBufferString(asciiSia))
-	return r.loadSharedFiles(dec)
+	lockID := r.mu.Lock()
+	files, origNames, err := r.loadSharedFiles(dec)
+	r.mu.Unlock(lockID)
+	if err != nil {
+		return nil, err
+	}
+	return r.managedSharedFileInfo(files, origNames), nil
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/fastrand"
 )
 
@@ -47,25 +48,35 @@ func equalFiles(f1, f2 *file) error {
 	if f1.pieceSize != f2.pieceSize {
 		return fmt.Errorf("pieceSizes do not match: %v %v", f1.pieceSize, f2.pieceSize)
 	}
+	if !sameErasureCoderType(f1.erasureCode, f2.erasureCode) || f1.erasureCode.NumPieces() != f2.erasureCode.NumPieces() || f1.erasureCode.MinPieces() != f2.erasureCode.MinPieces() {
+		return fmt.Errorf("erasure codes do not match: %v %v", f1.erasureCode, f2.erasureCode)
+	}
 	return nil
 }
 
 // TestFileMarshalling tests the MarshalSia and UnmarshalSia functions of the
-// file type.
+// file type, for every type of erasure coder.
 func TestFileMarshalling(t *testing.T) {
-	savedFile := newTestingFile()
-	buf := new(bytes.Buffer)
-	savedFile.MarshalSia(buf)
+	segmented, _ := newRSSegmentedCode(4, 2, 4096)
+	replication, _ := NewReplicationCode(3)
+	for _, ec := range []modules.ErasureCoder{nil, segmented, replication} {
+		savedFile := newTestingFile()
+		if ec != nil {
+			savedFile.erasureCode = ec
+		}
+		buf := new(bytes.Buffer)
+		savedFile.MarshalSia(buf)
 
-	loadedFile := new(file)
-	err := loadedFile.UnmarshalSia(buf)
-	if err != nil {
-		t.Fatal(err)
-	}
+		loadedFile := new(file)
+		err := loadedFile.UnmarshalSia(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
 
-	err = equalFiles(savedFile, loadedFile)
-	if err != nil {
-		t.Fatal(err)
+		err = equalFiles(savedFile, loadedFile)
+		if err != nil {
+			t.Fatal(err)
+		}
 	}
 }
 
@@ -101,7 +112,7 @@ func TestFileShareLoad(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(names) != 1 || names[0] != savedFile.name {
+	if len(names) != 1 || names[0].SiaPath != savedFile.name {
 		t.Fatal("nickname not loaded properly:", names)
 	}
 	err = equalFiles(rt.renter.files[savedFile.name], savedFile)
@@ -126,7 +137,7 @@ func TestFileShareLoad(t *testing.T) {
 	if err != nil {
 		t.Fatal(nil)
 	}
-	if len(names) != 2 || (names[0] != savedFile2.name && names[1] != savedFile2.name) {
+	if len(names) != 2 || (names[0].SiaPath != savedFile2.name && names[1].SiaPath != savedFile2.name) {
 		t.Fatal("nicknames not loaded properly:", names)
 	}
 	err = equalFiles(rt.renter.files[savedFile.name], savedFile)
@@ -139,6 +150,67 @@ func TestFileShareLoad(t *testing.T) {
 	}
 }
 
+// TestRenterBackupRestore tests that a backup restores the files of a renter
+// into another renter.
+func TestRenterBackupRestore(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	// Add a tracked file and an empty directory to the renter.
+	savedFile := newTestingFile()
+	id := rt.renter.mu.Lock()
+	rt.renter.addFile(savedFile)
+	rt.renter.tracking[savedFile.name] = trackedFile{RepairPath: "/foo"}
+	rt.renter.createDir("foo/bar")
+	rt.renter.mu.Unlock(id)
+
+	path := filepath.Join(build.SiaTestingDir, "renter", t.Name(), "test.backup")
+	if err := rt.renter.CreateBackup(path, "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restore the backup into a fresh renter.
+	rt2, err := newRenterTester(t.Name() + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt2.Close()
+	if err := rt2.renter.RestoreBackup(path, "wrong"); err != errBackupPassword {
+		t.Fatal("expected errBackupPassword, got", err)
+	}
+	sharePath := filepath.Join(build.SiaTestingDir, "renter", t.Name(), "test.sia")
+	if err := rt.renter.ShareFiles([]string{savedFile.name}, sharePath); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt2.renter.RestoreBackup(sharePath, "password"); err != ErrBadBackup {
+		t.Fatal("expected ErrBadBackup, got", err)
+	}
+	for i := 0; i < 2; i++ {
+		// Restoring twice should not duplicate the files.
+		if err := rt2.renter.RestoreBackup(path, "password"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(rt2.renter.files) != 1 {
+		t.Fatal("expected 1 file, got", len(rt2.renter.files))
+	}
+	if err := equalFiles(rt2.renter.files[savedFile.name], savedFile); err != nil {
+		t.Fatal(err)
+	}
+	if rt2.renter.tracking[savedFile.name].RepairPath != "/foo" {
+		t.Fatal("tracking data was not restored")
+	}
+	if _, exists := rt2.renter.dirs["foo/bar"]; !exists {
+		t.Fatal("empty directory was not restored")
+	}
+}
+
 // TestFileShareLoadASCII tests the ASCII sharing/loading functions.
 func TestFileShareLoadASCII(t *testing.T) {
 	if testing.Short() {
@@ -168,7 +240,7 @@ func TestFileShareLoadASCII(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(names) != 1 || names[0] != savedFile.name {
+	if len(names) != 1 || names[0].SiaPath != savedFile.name {
 		t.Fatal("nickname not loaded properly")
 	}
 
@@ -311,7 +383,7 @@ func TestSiafileCompatibility(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(names) != 1 || names[0] != "testfile-183" {
+	if len(names) != 1 || names[0].SiaPath != "testfile-183" {
 		t.Fatal("nickname not loaded properly:", names)
 	}
 }
@@ -137,8 +137,8 @@ func (hd *Downloader) Close() error {
 }
 
 // NewDownloader initiates the download request loop with a host, and returns a
-// Downloader.
-func NewDownloader(host modules.HostDBEntry, contract modules.RenterContract, hdb hostDB, cancel <-chan struct{}) (_ *Downloader, err error) {
+// Downloader. The bandwidth of the connection counts towards the limits of rl.
+func NewDownloader(host modules.HostDBEntry, contract modules.RenterContract, hdb hostDB, rl *modules.RateLimit, cancel <-chan struct{}) (_ *Downloader, err error) {
 	// check that contract has enough value to support a download
 	if len(contract.LastRevision.NewValidProofOutputs) != 2 {
 		return nil, errors.New("invalid contract")
@@ -166,6 +166,7 @@ func NewDownloader(host modules.HostDBEntry, contract modules.RenterContract, hd
 	if err != nil {
 		return nil, err
 	}
+	conn = rl.Conn(conn)
 
 	closeChan := make(chan struct{})
 	go func() {
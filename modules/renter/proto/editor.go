@@ -258,8 +258,8 @@ func (he *Editor) Modify(oldRoot, newRoot crypto.Hash, offset uint64, newData []
 }
 
 // NewEditor initiates the contract revision process with a host, and returns
-// an Editor.
-func NewEditor(host modules.HostDBEntry, contract modules.RenterContract, currentHeight types.BlockHeight, hdb hostDB, cancel <-chan struct{}) (_ *Editor, err error) {
+// an Editor. The bandwidth of the connection counts towards the limits of rl.
+func NewEditor(host modules.HostDBEntry, contract modules.RenterContract, currentHeight types.BlockHeight, hdb hostDB, rl *modules.RateLimit, cancel <-chan struct{}) (_ *Editor, err error) {
 	// check that contract has enough value to support an upload
 	if len(contract.LastRevision.NewValidProofOutputs) != 2 {
 		return nil, errors.New("invalid contract")
@@ -283,6 +283,7 @@ func NewEditor(host modules.HostDBEntry, contract modules.RenterContract, curren
 	if err != nil {
 		return nil, err
 	}
+	conn = rl.Conn(conn)
 
 	closeChan := make(chan struct{})
 	go func() {
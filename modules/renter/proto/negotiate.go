@@ -50,9 +50,9 @@ func verifySettings(conn net.Conn, host modules.HostDBEntry) (modules.HostDBEntr
 	var pk crypto.PublicKey
 	copy(pk[:], host.PublicKey.Key)
 
-	// read signed host settings
+	// read signed host settings. Older hosts do not send all of the fields.
 	var recvSettings modules.HostExternalSettings
-	if err := crypto.ReadSignedObject(conn, &recvSettings, modules.NegotiateMaxHostExternalSettingsLen, pk); err != nil {
+	if err := crypto.ReadSignedObject(conn, (*modules.CompatHostExternalSettings)(&recvSettings), modules.NegotiateMaxHostExternalSettingsLen, pk); err != nil {
 		return modules.HostDBEntry{}, errors.New("couldn't read host's settings: " + err.Error())
 	}
 	// TODO: check recvSettings against host.HostExternalSettings. If there is
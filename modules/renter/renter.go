@@ -9,6 +9,8 @@ package renter
 
 import (
 	"errors"
+	"io"
+	"reflect"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
@@ -55,6 +57,9 @@ type hostDB interface {
 	// Close closes the hostdb.
 	Close() error
 
+	// Filter returns the hostdb's filter.
+	Filter() modules.HostDBFilter
+
 	// Host returns the HostDBEntry for a given host.
 	Host(types.SiaPublicKey) (modules.HostDBEntry, bool)
 
@@ -67,6 +72,24 @@ type hostDB interface {
 	// of the host.
 	ScoreBreakdown(modules.HostDBEntry) modules.HostScoreBreakdown
 
+	// ScoringProfile returns the profile that adjusts the hosts' scores.
+	ScoringProfile() modules.HostScoringProfile
+
+	// SetFilter replaces the hostdb's filter, which excludes hosts from
+	// being returned by RandomHosts.
+	SetFilter(modules.HostDBFilter) error
+
+	// SetScoringProfile replaces the profile that adjusts the hosts' scores.
+	SetScoringProfile(modules.HostScoringProfile) error
+
+	// SetSubnetRanges sets the prefix lengths of the IPv4 and IPv6 subnets
+	// within which at most one host is selected.
+	SetSubnetRanges(ipv4Bits, ipv6Bits int) error
+
+	// SubnetRanges returns the prefix lengths of the IPv4 and IPv6 subnets
+	// within which at most one host is selected.
+	SubnetRanges() (ipv4Bits, ipv6Bits int)
+
 	// EstimateHostScore returns the estimated score breakdown of a host with the
 	// provided settings.
 	EstimateHostScore(modules.HostDBEntry) modules.HostScoreBreakdown
@@ -75,6 +98,9 @@ type hostDB interface {
 // A hostContractor negotiates, revises, renews, and provides access to file
 // contracts.
 type hostContractor interface {
+	// Backup writes the contractor's contracts, including their keys, to w.
+	Backup(io.Writer) error
+
 	// SetAllowance sets the amount of money the contractor is allowed to
 	// spend on contracts over a given time period, divided among the number
 	// of hosts specified. Note that contractor can start forming contracts as
@@ -84,6 +110,10 @@ type hostContractor interface {
 	// Allowance returns the current allowance
 	Allowance() modules.Allowance
 
+	// CancelContract cancels the specified contract, so that it is no longer
+	// used for uploading or renewed.
+	CancelContract(types.FileContractID) error
+
 	// Close closes the hostContractor.
 	Close() error
 
@@ -104,6 +134,14 @@ type hostContractor interface {
 	// insertion, deletion, and modification of sectors.
 	Editor(types.FileContractID, <-chan struct{}) (contractor.Editor, error)
 
+	// ExpiredContracts returns the contracts that have expired or that have
+	// been renewed.
+	ExpiredContracts() []modules.RenterContract
+
+	// FormContract forms a manual contract with the specified host, outside
+	// of the allowance.
+	FormContract(hostKey types.SiaPublicKey, funds types.Currency, endHeight types.BlockHeight) (modules.RenterContract, error)
+
 	// GoodForRenew indicates whether the contract line of the provided contract
 	// is actively being renewed.
 	GoodForRenew(types.FileContractID) bool
@@ -111,12 +149,32 @@ type hostContractor interface {
 	// IsOffline reports whether the specified host is considered offline.
 	IsOffline(types.FileContractID) bool
 
+	// MarkContractsUtility updates whether each contract is useful for
+	// uploading and renewing, and replaces the contracts that are not.
+	MarkContractsUtility()
+
 	// Downloader creates a Downloader from the specified contract ID,
 	// allowing the retrieval of sectors.
 	Downloader(types.FileContractID, <-chan struct{}) (contractor.Downloader, error)
 
+	// RenewContract renews the specified contract with the provided funds
+	// and end height.
+	RenewContract(id types.FileContractID, funds types.Currency, endHeight types.BlockHeight) (modules.RenterContract, error)
+
 	// ResolveID returns the most recent renewal of the specified ID.
 	ResolveID(types.FileContractID) types.FileContractID
+
+	// Restore adds the contracts of a backup created by Backup to the
+	// contractor.
+	Restore(io.Reader) error
+
+	// SetRateLimits sets the maximum bandwidth, in bytes per second, of the
+	// connections used by Editors and Downloaders.
+	SetRateLimits(downloadSpeed, uploadSpeed int64)
+
+	// Spending returns the spending of the current allowance period and of
+	// each past allowance period.
+	Spending() (modules.RenterSpending, []modules.RenterSpending)
 }
 
 // A trackedFile contains metadata about files being tracked by the Renter.
@@ -135,7 +193,11 @@ type Renter struct {
 	//
 	// tracking contains a list of files that the user intends to maintain. By
 	// default, files loaded through sharing are not maintained by the user.
+	//
+	// dirs indexes the directory tree by siapath. The root directory has the
+	// empty siapath and always exists.
 	files    map[string]*file
+	dirs     map[string]*dir
 	tracking map[string]trackedFile // map from nickname to metadata
 
 	// Work management.
@@ -145,12 +207,39 @@ type Renter struct {
 	// thread, which means it can be accessed and updated without locks.
 	//
 	// downloadQueue contains a complete history of work that has been
-	// submitted to the download loop.
-	chunkQueue    []*chunkDownload // Accessed without locks.
-	downloadQueue []*download
-	newDownloads  chan *download
-	newRepairs    chan *file
-	workerPool    map[types.FileContractID]*worker
+	// submitted to the download loop by the user. The history is persisted
+	// in downloads.json, and downloadsSaveMu serializes the writes of that
+	// file.
+	//
+	// newStreamChunks is used to hand the chunks of streamed uploads to the
+	// repair loop.
+	chunkQueue      []*chunkDownload // Accessed without locks.
+	downloadQueue   []*download
+	downloadsSaveMu sync.TryMutex
+	newDownloads    chan *download
+	newRepairs      chan *file
+	newStreamChunks chan *streamChunk
+	workerPool      map[types.FileContractID]*worker
+
+	// hostDownloadStats contains the download statistics of the hosts, keyed
+	// by host public key. The statistics are shared by the workers.
+	hostDownloadStats map[string]*hostDownloadStats
+
+	// chunkCache holds recently downloaded chunks, so that downloads of the
+	// same data do not need to fetch it from the hosts again. chunkCacheSize
+	// is the persisted budget of the cache.
+	chunkCache     *chunkCache
+	chunkCacheSize uint64
+
+	// maxDownloadSpeed and maxUploadSpeed are the persisted bandwidth limits
+	// of the renter, which are enforced by the hostContractor.
+	maxDownloadSpeed int64
+	maxUploadSpeed   int64
+
+	// memoryManager limits the memory used by the upload pipeline.
+	// maxUploadMemory is the persisted limit, where 0 selects the default.
+	memoryManager   *memoryManager
+	maxUploadMemory uint64
 
 	// Utilities.
 	cs             modules.ConsensusSet
@@ -194,13 +283,17 @@ func newRenter(cs modules.ConsensusSet, tpool modules.TransactionPool, hdb hostD
 	}
 
 	r := &Renter{
-		newRepairs: make(chan *file),
-		files:      make(map[string]*file),
-		tracking:   make(map[string]trackedFile),
+		newRepairs:      make(chan *file),
+		newStreamChunks: make(chan *streamChunk),
+		files:           make(map[string]*file),
+		dirs:            map[string]*dir{"": newDir("")},
+		tracking:        make(map[string]trackedFile),
 
 		newDownloads: make(chan *download),
 		workerPool:   make(map[types.FileContractID]*worker),
 
+		hostDownloadStats: make(map[string]*hostDownloadStats),
+
 		cs:             cs,
 		hostDB:         hdb,
 		hostContractor: hc,
@@ -219,6 +312,7 @@ func newRenter(cs modules.ConsensusSet, tpool modules.TransactionPool, hdb hostD
 	go r.threadedRepairLoop()
 	go r.threadedDownloadLoop()
 	go r.threadedQueueRepairs()
+	go r.threadedResumeInterruptedDownloads()
 
 	// Kill workers on shutdown.
 	r.tg.OnStop(func() {
@@ -298,16 +392,57 @@ func (r *Renter) PriceEstimation() modules.RenterPriceEstimation {
 
 // SetSettings will update the settings for the renter.
 func (r *Renter) SetSettings(s modules.RenterSettings) error {
-	err := r.hostContractor.SetAllowance(s.Allowance)
-	if err != nil {
-		return err
+	// Contracts with hosts that score differently under a new scoring profile
+	// need to be re-evaluated.
+	if !reflect.DeepEqual(s.ScoringProfile, r.hostDB.ScoringProfile()) {
+		if err := r.hostDB.SetScoringProfile(s.ScoringProfile); err != nil {
+			return err
+		}
+		r.hostContractor.MarkContractsUtility()
+	}
+
+	// Contracts whose hosts now share a subnet with another host, or no
+	// longer do, need to be re-evaluated as well.
+	if ipv4Bits, ipv6Bits := r.hostDB.SubnetRanges(); s.IPv4SubnetBits != ipv4Bits || s.IPv6SubnetBits != ipv6Bits {
+		if err := r.hostDB.SetSubnetRanges(s.IPv4SubnetBits, s.IPv6SubnetBits); err != nil {
+			return err
+		}
+		r.hostContractor.MarkContractsUtility()
+	}
+
+	// Setting an empty allowance cancels the allowance, which must not
+	// happen when only the other settings are changed.
+	if !emptyAllowance(s.Allowance) || !emptyAllowance(r.hostContractor.Allowance()) {
+		err := r.hostContractor.SetAllowance(s.Allowance)
+		if err != nil {
+			return err
+		}
 	}
 
 	contracts := r.hostContractor.Contracts()
 	id := r.mu.Lock()
 	r.updateWorkerPool(contracts)
+	r.chunkCacheSize = s.ChunkCacheSize
+	r.chunkCache.setBudget(s.ChunkCacheSize)
+	r.maxDownloadSpeed = s.MaxDownloadSpeed
+	r.maxUploadSpeed = s.MaxUploadSpeed
+	r.hostContractor.SetRateLimits(s.MaxDownloadSpeed, s.MaxUploadSpeed)
+	r.maxUploadMemory = s.MaxUploadMemory
+	r.memoryManager.setLimit(uploadMemoryLimit(s.MaxUploadMemory))
+	err := r.saveSync()
 	r.mu.Unlock(id)
-	return nil
+	return err
+}
+
+// emptyAllowance reports whether a is the empty allowance.
+func emptyAllowance(a modules.Allowance) bool {
+	return a.Funds.IsZero() && a.Hosts == 0 && a.Period == 0 && a.RenewWindow == 0
+}
+
+// ChunkCacheMetrics returns the hit and miss counts and the size of the
+// renter's cache of downloaded chunks.
+func (r *Renter) ChunkCacheMetrics() modules.RenterChunkCacheMetrics {
+	return r.chunkCache.metrics()
 }
 
 // hostdb passthroughs
@@ -320,13 +455,50 @@ func (r *Renter) ScoreBreakdown(e modules.HostDBEntry) modules.HostScoreBreakdow
 func (r *Renter) EstimateHostScore(e modules.HostDBEntry) modules.HostScoreBreakdown {
 	return r.hostDB.EstimateHostScore(e)
 }
+func (r *Renter) HostDBFilter() modules.HostDBFilter { return r.hostDB.Filter() }
+
+// SetHostDBFilter replaces the hostdb's filter and re-evaluates the renter's
+// contracts, so that contracts with hosts that are excluded by the new filter
+// are no longer used or renewed.
+func (r *Renter) SetHostDBFilter(f modules.HostDBFilter) error {
+	if err := r.hostDB.SetFilter(f); err != nil {
+		return err
+	}
+	r.hostContractor.MarkContractsUtility()
+	return nil
+}
 
 // contractor passthroughs
 func (r *Renter) Contracts() []modules.RenterContract { return r.hostContractor.Contracts() }
 func (r *Renter) CurrentPeriod() types.BlockHeight    { return r.hostContractor.CurrentPeriod() }
+func (r *Renter) ExpiredContracts() []modules.RenterContract {
+	return r.hostContractor.ExpiredContracts()
+}
+func (r *Renter) FormContract(hostKey types.SiaPublicKey, funds types.Currency, endHeight types.BlockHeight) (modules.RenterContract, error) {
+	return r.hostContractor.FormContract(hostKey, funds, endHeight)
+}
+func (r *Renter) RenewContract(id types.FileContractID, funds types.Currency, endHeight types.BlockHeight) (modules.RenterContract, error) {
+	return r.hostContractor.RenewContract(id, funds, endHeight)
+}
+func (r *Renter) CancelContract(id types.FileContractID) error {
+	return r.hostContractor.CancelContract(id)
+}
+func (r *Renter) Spending() (modules.RenterSpending, []modules.RenterSpending) {
+	return r.hostContractor.Spending()
+}
 func (r *Renter) Settings() modules.RenterSettings {
+	id := r.mu.RLock()
+	defer r.mu.RUnlock(id)
+	ipv4Bits, ipv6Bits := r.hostDB.SubnetRanges()
 	return modules.RenterSettings{
-		Allowance: r.hostContractor.Allowance(),
+		Allowance:        r.hostContractor.Allowance(),
+		ChunkCacheSize:   r.chunkCacheSize,
+		MaxDownloadSpeed: r.maxDownloadSpeed,
+		MaxUploadSpeed:   r.maxUploadSpeed,
+		MaxUploadMemory:  r.maxUploadMemory,
+		ScoringProfile:   r.hostDB.ScoringProfile(),
+		IPv4SubnetBits:   ipv4Bits,
+		IPv6SubnetBits:   ipv6Bits,
 	}
 }
 func (r *Renter) AllContracts() []modules.RenterContract {
@@ -6,9 +6,11 @@ package renter
 // ids are actually a part of the same file contract.
 
 import (
+	"container/heap"
 	"errors"
 	"io"
 	"os"
+	"sort"
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
@@ -29,18 +31,32 @@ type (
 		// contracts is the set of file contracts which are already storing
 		// pieces for the chunk.
 		//
+		// id identifies the chunk, and minPieces is the number of pieces
+		// needed to recover it.
+		//
 		// pieces contains the indices of the pieces that have already been
 		// uploaded for this chunk.
 		//
 		// recordedGaps indicates the value that this chunk has recorded in the
 		// gapCounts map.
+		//
+		// stream is set if the chunk belongs to a streamed upload, in which
+		// case the chunk's data is held by the stream rather than read from
+		// disk.
 		activePieces int
 		contracts    map[types.FileContractID]struct{}
+		id           chunkID
+		minPieces    int
 		pieces       map[uint64]struct{}
 		recordedGaps int
+		stream       *streamChunk
 		totalPieces  int
 	}
 
+	// chunkHeap is a heap of incomplete chunks, ordered so that the least
+	// healthy chunk is at the top of the heap.
+	chunkHeap []*chunkStatus
+
 	// chunkID can be used to uniquely identify a chunk within the repair
 	// matrix.
 	chunkID struct {
@@ -61,7 +77,8 @@ type (
 		//
 		// incompleteChunks tracks a set of chunks that don't yet have full
 		// redundancy, along with information about which pieces and contracts
-		// aren't being used.
+		// aren't being used. chunkHeap holds the same chunks, and determines
+		// the order in which they are repaired.
 		//
 		// downloadingChunks tracks the set of chunks that are currently being
 		// downloaded in order to be re-uploaded.
@@ -72,6 +89,7 @@ type (
 		// workerSet tracks the set of workers which can be used for uploading.
 		activeWorkers     map[types.FileContractID]*worker
 		availableWorkers  map[types.FileContractID]*worker
+		chunkHeap         chunkHeap
 		gapCounts         map[int]int
 		incompleteChunks  map[chunkID]*chunkStatus
 		downloadingChunks map[chunkID]*downloadingChunk
@@ -87,6 +105,34 @@ type (
 	}
 )
 
+// Implementation of heap.Interface for chunkHeap.
+func (ch chunkHeap) Len() int            { return len(ch) }
+func (ch chunkHeap) Less(i, j int) bool  { return ch[i].health() < ch[j].health() }
+func (ch chunkHeap) Swap(i, j int)       { ch[i], ch[j] = ch[j], ch[i] }
+func (ch *chunkHeap) Push(x interface{}) { *ch = append(*ch, x.(*chunkStatus)) }
+func (ch *chunkHeap) Pop() interface{} {
+	old := *ch
+	cs := old[len(old)-1]
+	*ch = old[:len(old)-1]
+	return cs
+}
+
+// health returns the number of additional pieces that the chunk can lose
+// before it becomes unrecoverable. Pieces that are currently being uploaded
+// are counted, so that a chunk which is already being repaired yields to
+// chunks that are not.
+func (cs *chunkStatus) health() int {
+	return len(cs.pieces) - cs.minPieces
+}
+
+// addIncompleteChunk adds a chunk to the set of incomplete chunks.
+func (rs *repairState) addIncompleteChunk(cs *chunkStatus) {
+	cs.recordedGaps = cs.numGaps(rs)
+	rs.incompleteChunks[cs.id] = cs
+	rs.gapCounts[cs.recordedGaps]++
+	heap.Push(&rs.chunkHeap, cs)
+}
+
 // numGaps returns the number of gaps that a chunk has.
 func (cs *chunkStatus) numGaps(rs *repairState) int {
 	incompatContracts := 0
@@ -117,7 +163,7 @@ func (r *Renter) managedAddFileToRepairState(rs *repairState, file *file) {
 	_, exists := r.tracking[file.name]
 	file.mu.RUnlock()
 	r.mu.RUnlock(id)
-	if !exists {
+	if !exists || file.readOnly {
 		return
 	}
 
@@ -142,11 +188,13 @@ func (r *Renter) managedAddFileToRepairState(rs *repairState, file *file) {
 
 	// Iterate through each contract and figure out which pieces are available.
 	file.mu.RLock()
+	numPieces := file.erasureCode.NumPieces()
 	var fileContracts []fileContract
 	for _, c := range file.contracts {
 		fileContracts = append(fileContracts, c)
 	}
 	file.mu.RUnlock()
+	var excessPieces bool
 	for _, contract := range fileContracts {
 		// Check whether this contract is offline. Even if the contract is
 		// offline, we want to record that the chunk has attempted to use this
@@ -154,8 +202,13 @@ func (r *Renter) managedAddFileToRepairState(rs *repairState, file *file) {
 		id := r.hostContractor.ResolveID(contract.ID)
 		stable := !r.hostContractor.IsOffline(id) && r.hostContractor.GoodForRenew(id)
 
-		// Scan all of the pieces of the contract.
+		// Scan all of the pieces of the contract. Pieces that are not part of
+		// the file's erasure code anymore are dropped instead.
 		for _, piece := range contract.Pieces {
+			if piece.Piece >= uint64(numPieces) {
+				excessPieces = true
+				continue
+			}
 			utilizedContracts[piece.Chunk][id] = struct{}{}
 
 			// Only mark the piece as complete if the piece can be recovered.
@@ -165,11 +218,15 @@ func (r *Renter) managedAddFileToRepairState(rs *repairState, file *file) {
 		}
 	}
 
+	if excessPieces {
+		go r.threadedDropExcessPieces(file)
+	}
+
 	// Create the chunkStatus object for each chunk and add it to the set of
 	// incomplete chunks.
 	for i := uint64(0); i < chunkCount; i++ {
 		// Skip this chunk if all pieces have been uploaded.
-		if len(availablePieces[i]) >= file.erasureCode.NumPieces() {
+		if len(availablePieces[i]) >= numPieces {
 			continue
 		}
 
@@ -182,14 +239,13 @@ func (r *Renter) managedAddFileToRepairState(rs *repairState, file *file) {
 
 		// Create the chunkStatus object and add it to the set of incomplete
 		// chunks.
-		cs := &chunkStatus{
+		rs.addIncompleteChunk(&chunkStatus{
 			contracts:   utilizedContracts[i],
+			id:          cid,
+			minPieces:   file.erasureCode.MinPieces(),
 			pieces:      availablePieces[i],
-			totalPieces: file.erasureCode.NumPieces(),
-		}
-		cs.recordedGaps = cs.numGaps(rs)
-		rs.incompleteChunks[cid] = cs
-		rs.gapCounts[cs.recordedGaps]++
+			totalPieces: numPieces,
+		})
 	}
 }
 
@@ -205,6 +261,9 @@ func (r *Renter) managedRepairIteration(rs *repairState) {
 		case file := <-r.newRepairs:
 			r.managedAddFileToRepairState(rs, file)
 			return
+		case sc := <-r.newStreamChunks:
+			r.addStreamChunkToRepairState(rs, sc)
+			return
 		}
 	}
 
@@ -213,6 +272,9 @@ func (r *Renter) managedRepairIteration(rs *repairState) {
 	case file := <-r.newRepairs:
 		r.managedAddFileToRepairState(rs, file)
 		return
+	case sc := <-r.newStreamChunks:
+		r.addStreamChunkToRepairState(rs, sc)
+		return
 	default:
 	}
 
@@ -264,9 +326,26 @@ func (r *Renter) managedRepairIteration(rs *repairState) {
 		delete(rs.cachedChunks, cid)
 	}
 
-	// Scan through the chunks until a candidate for uploads is found.
+	// Scan through the chunks until a candidate for uploads is found. The
+	// chunks are visited from least to most healthy, so that the chunks
+	// closest to being lost are the first to be given workers. The health of
+	// a chunk changes as pieces are uploaded, so the heap is rebuilt first.
+	heap.Init(&rs.chunkHeap)
+	visited := make([]*chunkStatus, 0, rs.chunkHeap.Len())
 	var chunksToDelete []chunkID
-	for chunkID, chunkStatus := range rs.incompleteChunks {
+	for rs.chunkHeap.Len() > 0 {
+		chunkStatus := heap.Pop(&rs.chunkHeap).(*chunkStatus)
+		chunkID := chunkStatus.id
+		visited = append(visited, chunkStatus)
+
+		// Drop the chunks of streamed uploads that have failed, as their
+		// file will never be committed.
+		if chunkStatus.stream != nil && chunkStatus.stream.cancelled() {
+			rs.gapCounts[chunkStatus.recordedGaps]--
+			chunksToDelete = append(chunksToDelete, chunkID)
+			continue
+		}
+
 		// check if the chunk is currently being downloaded for recovery
 		dc, downloading := rs.downloadingChunks[chunkID]
 		if downloading {
@@ -306,8 +385,9 @@ func (r *Renter) managedRepairIteration(rs *repairState) {
 			continue
 		}
 
-		// Skip this chunk if it does not have enough gaps.
-		if maxGaps >= minPiecesRepair && numGaps < minPiecesRepair {
+		// Skip this chunk if it does not have enough gaps. Chunks of streamed
+		// uploads are never batched, as the uploader is waiting on them.
+		if chunkStatus.stream == nil && maxGaps >= minPiecesRepair && numGaps < minPiecesRepair {
 			continue
 		}
 
@@ -320,16 +400,19 @@ func (r *Renter) managedRepairIteration(rs *repairState) {
 				usefulWorkers = append(usefulWorkers, workerID)
 			}
 		}
+		if len(usefulWorkers) == 0 {
+			continue
+		}
 
 		// Skip this chunk if the set of useful workers does not meet the
 		// minimum pieces requirement.
-		if maxGaps >= minPiecesRepair && len(usefulWorkers) < minPiecesRepair {
+		if chunkStatus.stream == nil && maxGaps >= minPiecesRepair && len(usefulWorkers) < minPiecesRepair {
 			continue
 		}
 
 		// Skip this chunk if the set of useful workers is not complete, and
 		// the maxGaps value is less than the minPiecesRepair value.
-		if maxGaps < minPiecesRepair && len(usefulWorkers) < numGaps {
+		if chunkStatus.stream == nil && maxGaps < minPiecesRepair && len(usefulWorkers) < numGaps {
 			continue
 		}
 
@@ -342,8 +425,20 @@ func (r *Renter) managedRepairIteration(rs *repairState) {
 		}
 	}
 	for _, cid := range chunksToDelete {
+		// A streamed chunk that is removed before reaching the minimum
+		// redundancy cannot be retried, as the uploader is waiting on it.
+		// The chunk's data is no longer needed once it is removed.
+		if cs, ok := rs.incompleteChunks[cid]; ok && cs.stream != nil {
+			cs.stream.finish(errInsufficientStreamHosts)
+			r.memoryManager.releaseHeld(cs.stream.memory)
+		}
 		delete(rs.incompleteChunks, cid)
 	}
+	for _, cs := range visited {
+		if _, ok := rs.incompleteChunks[cs.id]; ok {
+			rs.chunkHeap = append(rs.chunkHeap, cs)
+		}
+	}
 
 	// Block until some of the workers return.
 	r.managedWaitOnRepairWork(rs)
@@ -393,65 +488,72 @@ func (r *Renter) managedDownloadChunkData(rs *repairState, file *file, offset ui
 	return nil, nil
 }
 
-// managedGetChunkData grabs the requested `chunkID` from the file, in order to
-// repair the file. If the `trackedFile` can be found on disk, grab the chunk
-// from the file, otherwise attempt to queue a new download for only that chunk
-// and return the downloaded chunk.
-func (r *Renter) managedGetChunkData(rs *repairState, file *file, trackedFile trackedFile, chunkID chunkID) ([]byte, error) {
-	chunkIndex := chunkID.index
-	offset := chunkIndex * file.chunkSize()
-
-	// try to read the chunk from disk
-	f, err := os.Open(trackedFile.RepairPath)
+// readChunk reads the data of a chunk from the source file of a file into buf.
+// The last chunk of a file may be shorter than buf, in which case the rest of
+// buf is left zeroed.
+func readChunk(path string, f *file, index uint64, buf []byte) error {
+	src, err := os.Open(path)
 	if err != nil {
-		// if that fails, try to download the chunk
-		return r.managedDownloadChunkData(rs, file, offset, chunkIndex, chunkID)
+		return err
 	}
-	defer f.Close()
+	defer src.Close()
 
-	chunkData := make([]byte, file.chunkSize())
-	_, err = f.ReadAt(chunkData, int64(offset))
+	_, err = src.ReadAt(buf, int64(index*f.chunkSize()))
 	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 		// TODO: We should be doing better error handling here - shouldn't be
 		// running into ErrUnexpectedEOF intentionally because if it happens
 		// unintentionally we will believe that the chunk was read from memory
 		// correctly.
-
-		return nil, err
+		return err
 	}
-
-	return chunkData, nil
+	return nil
 }
 
 // managedScheduleChunkRepair takes a chunk and schedules some repair on that
-// chunk using the chunk state and a list of workers.
+// chunk using the chunk state and a list of workers. The workers are reserved
+// for the chunk immediately, while reading, erasure coding and encrypting the
+// chunk happens in a separate thread.
 func (r *Renter) managedScheduleChunkRepair(rs *repairState, chunkID chunkID, chunkStatus *chunkStatus, usefulWorkers []types.FileContractID) error {
-	// Check that the file is still in the renter.
-	id := r.mu.RLock()
+	// The data of streamed chunks is held in memory by the stream.
 	var file *file
-	for _, f := range r.files {
-		if f.masterKey == chunkID.masterkey {
-			file = f
-			break
-		}
+	var chunkData []byte
+	if chunkStatus.stream != nil {
+		file = chunkStatus.stream.file
+		chunkData = chunkStatus.stream.data
 	}
+
+	// Check that the file is still in the renter.
 	var meta trackedFile
-	var exists bool
-	if file != nil {
-		meta, exists = r.tracking[file.name]
-	}
-	r.mu.RUnlock(id)
-	if !exists {
-		return errFileDeleted
+	if file == nil {
+		id := r.mu.RLock()
+		for _, f := range r.files {
+			if f.masterKey == chunkID.masterkey {
+				file = f
+				break
+			}
+		}
+		var exists bool
+		if file != nil {
+			meta, exists = r.tracking[file.name]
+		}
+		r.mu.RUnlock(id)
+		if !exists {
+			return errFileDeleted
+		}
 	}
 
-	// read the chunk into memory
-	// check the cache first
-	var chunkData []byte
-	if cachedData, exists := rs.cachedChunks[chunkID]; exists {
+	// Locate the data of the chunk. Chunks that had to be downloaded from the
+	// hosts are cached, while chunks of files that are still on disk are read
+	// by the encoding thread.
+	var sourcePath string
+	if chunkData != nil {
+		// The chunk belongs to a streamed upload.
+	} else if cachedData, exists := rs.cachedChunks[chunkID]; exists {
 		chunkData = cachedData
+	} else if _, err := os.Stat(meta.RepairPath); err == nil {
+		sourcePath = meta.RepairPath
 	} else {
-		data, err := r.managedGetChunkData(rs, file, meta, chunkID)
+		data, err := r.managedDownloadChunkData(rs, file, chunkID.index*file.chunkSize(), chunkID.index, chunkID)
 		if err != nil {
 			return build.ExtendErr("unable to get repair chunk:", err)
 		}
@@ -464,15 +566,12 @@ func (r *Renter) managedScheduleChunkRepair(rs *repairState, chunkID chunkID, ch
 		rs.cachedChunks[chunkID] = data
 	}
 
-	// Erasure code the pieces.
-	pieces, err := file.erasureCode.Encode(chunkData)
-	if err != nil {
-		return build.ExtendErr("unable to erasure code chunk data", err)
-	}
-
 	// Get the set of pieces that are missing from the chunk.
+	file.mu.RLock()
+	ec := file.erasureCode
+	file.mu.RUnlock()
 	var missingPieces []uint64
-	for i := uint64(0); i < uint64(file.erasureCode.NumPieces()); i++ {
+	for i := uint64(0); i < uint64(ec.NumPieces()); i++ {
 		_, exists := chunkStatus.pieces[i]
 		if !exists {
 			missingPieces = append(missingPieces, i)
@@ -484,30 +583,26 @@ func (r *Renter) managedScheduleChunkRepair(rs *repairState, chunkID chunkID, ch
 		missingPieces = missingPieces[:len(usefulWorkers)]
 	}
 
-	// Encrypt the missing pieces.
-	for _, missingPiece := range missingPieces {
-		key := deriveKey(file.masterKey, chunkID.index, uint64(missingPiece))
-		pieces[missingPiece] = key.EncryptBytes(pieces[missingPiece])
+	// Reserve a worker from the set of useful workers for each piece.
+	job := &encodingJob{
+		chunkID:    chunkID,
+		data:       chunkData,
+		ec:         ec,
+		file:       file,
+		pieces:     missingPieces,
+		sourcePath: sourcePath,
+		resultChan: rs.resultChan,
 	}
-
-	// Give each piece to a worker in the set of useful workers.
-	for len(usefulWorkers) > 0 && len(missingPieces) > 0 {
-		uw := uploadWork{
-			chunkID:    chunkID,
-			data:       pieces[missingPieces[0]],
-			file:       file,
-			pieceIndex: missingPieces[0],
-
-			resultChan: rs.resultChan,
-		}
+	for _, missingPiece := range missingPieces {
 		// Grab the worker, and update the worker tracking in the repair state.
 		worker := rs.availableWorkers[usefulWorkers[0]]
 		rs.activeWorkers[usefulWorkers[0]] = worker
 		delete(rs.availableWorkers, usefulWorkers[0])
+		job.workers = append(job.workers, worker)
 
 		chunkStatus.activePieces++
 		chunkStatus.contracts[usefulWorkers[0]] = struct{}{}
-		chunkStatus.pieces[missingPieces[0]] = struct{}{}
+		chunkStatus.pieces[missingPiece] = struct{}{}
 
 		// Update the number of gaps for this chunk.
 		numGaps := chunkStatus.numGaps(rs)
@@ -515,18 +610,10 @@ func (r *Renter) managedScheduleChunkRepair(rs *repairState, chunkID chunkID, ch
 		rs.gapCounts[numGaps]++
 		chunkStatus.recordedGaps = numGaps
 
-		// Update the set of useful workers and the set of missing pieces.
-		missingPieces = missingPieces[1:]
+		// Update the set of useful workers.
 		usefulWorkers = usefulWorkers[1:]
-
-		// Deliver the payload to the worker.
-		select {
-		case worker.uploadChan <- uw:
-		default:
-			r.log.Critical("Worker is supposed to be available, but upload work channel is full")
-			worker.uploadChan <- uw
-		}
 	}
+	go r.threadedEncodeChunk(job)
 	return nil
 }
 
@@ -545,6 +632,9 @@ func (r *Renter) managedWaitOnRepairWork(rs *repairState) {
 	case file := <-r.newRepairs:
 		r.managedAddFileToRepairState(rs, file)
 		return
+	case sc := <-r.newStreamChunks:
+		r.addStreamChunkToRepairState(rs, sc)
+		return
 	case <-r.tg.StopChan():
 		return
 	}
@@ -563,6 +653,12 @@ func (r *Renter) managedWaitOnRepairWork(rs *repairState) {
 	// If there was no error, add the worker back to the set of
 	// available workers and wait for the next worker.
 	if finishedUpload.err == nil {
+		if sc := rs.incompleteChunks[finishedUpload.chunkID].stream; sc != nil {
+			sc.uploadedPieces++
+			if sc.uploadedPieces >= sc.file.erasureCode.MinPieces() {
+				sc.finish(nil)
+			}
+		}
 		rs.availableWorkers[finishedUpload.workerID] = rs.activeWorkers[finishedUpload.workerID]
 		delete(rs.activeWorkers, finishedUpload.workerID)
 		return
@@ -573,8 +669,80 @@ func (r *Renter) managedWaitOnRepairWork(rs *repairState) {
 	delete(rs.activeWorkers, finishedUpload.workerID)
 
 	// Indicate in the set of incomplete chunks that this piece was not
-	// completed.
-	rs.incompleteChunks[finishedUpload.chunkID].pieces[finishedUpload.pieceIndex] = struct{}{}
+	// completed, so that it can be given to another worker.
+	delete(rs.incompleteChunks[finishedUpload.chunkID].pieces, finishedUpload.pieceIndex)
+}
+
+// threadedDropExcessPieces deletes the pieces of a file that are not part of
+// its erasure code anymore, which is the case after the redundancy of the file
+// has been lowered. The pieces are removed from the file once they have been
+// deleted from the host, so that pieces on hosts that cannot be reached are
+// tried again during a later repair.
+func (r *Renter) threadedDropExcessPieces(f *file) {
+	if err := r.tg.Add(); err != nil {
+		return
+	}
+	defer r.tg.Done()
+
+	// Only one thread drops the pieces of a file at a time.
+	f.mu.Lock()
+	if f.droppingPieces {
+		f.mu.Unlock()
+		return
+	}
+	f.droppingPieces = true
+	numPieces := uint64(f.erasureCode.NumPieces())
+	excess := make(map[types.FileContractID][]crypto.Hash)
+	for id, fc := range f.contracts {
+		for _, p := range fc.Pieces {
+			if p.Piece >= numPieces {
+				excess[id] = append(excess[id], p.MerkleRoot)
+			}
+		}
+	}
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.droppingPieces = false
+		f.mu.Unlock()
+	}()
+
+	for id, roots := range excess {
+		e, err := r.hostContractor.Editor(r.hostContractor.ResolveID(id), r.tg.StopChan())
+		if err != nil {
+			r.log.Debugln("Unable to drop excess pieces from contract", id, "::", err)
+			continue
+		}
+		deleted := make(map[crypto.Hash]struct{})
+		for _, root := range roots {
+			if err := e.Delete(root); err != nil {
+				r.log.Debugln("Unable to drop excess piece from contract", id, "::", err)
+				break
+			}
+			deleted[root] = struct{}{}
+		}
+		e.Close()
+
+		// Remove the deleted pieces from the file.
+		lockID := r.mu.Lock()
+		f.mu.Lock()
+		if fc, exists := f.contracts[id]; exists {
+			var pieces []pieceData
+			for _, p := range fc.Pieces {
+				if _, ok := deleted[p.MerkleRoot]; !ok {
+					pieces = append(pieces, p)
+				}
+			}
+			fc.Pieces = pieces
+			f.contracts[id] = fc
+		}
+		if cur, exists := r.files[f.name]; exists && cur == f {
+			r.saveFile(f)
+			r.dirChanged(parentSiapath(f.name))
+		}
+		f.mu.Unlock()
+		r.mu.Unlock(lockID)
+	}
 }
 
 // threadedQueueRepairs is a goroutine that runs in the background and
@@ -590,13 +758,26 @@ func (r *Renter) threadedQueueRepairs() {
 		id := r.mu.RLock()
 		var files []*file
 		for _, file := range r.files {
-			if _, ok := r.tracking[file.name]; ok {
-				// Only repair files that are being tracked.
+			if _, ok := r.tracking[file.name]; ok && !file.readOnly {
+				// Only repair files that are being tracked, and that were
+				// not shared by another renter.
 				files = append(files, file)
 			}
 		}
 		r.mu.RUnlock(id)
 
+		// Queue the least healthy files first.
+		isOffline := r.managedIsOfflineFunc()
+		health := make(map[*file]int, len(files))
+		for _, file := range files {
+			file.mu.RLock()
+			health[file] = file.health(isOffline)
+			file.mu.RUnlock()
+		}
+		sort.Slice(files, func(i, j int) bool {
+			return health[files[i]] < health[files[j]]
+		})
+
 		// Add files.
 		for _, file := range files {
 			// Send the file down the repair channel.
@@ -0,0 +1,49 @@
+package renter
+
+import (
+	"container/heap"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestChunkHeap checks that the repair state hands out the least healthy
+// chunks first.
+func TestChunkHeap(t *testing.T) {
+	rs := &repairState{
+		activeWorkers:    make(map[types.FileContractID]*worker),
+		availableWorkers: make(map[types.FileContractID]*worker),
+		gapCounts:        make(map[int]int),
+		incompleteChunks: make(map[chunkID]*chunkStatus),
+	}
+	for i, numPieces := range []int{7, 2, 10, 4, 0} {
+		pieces := make(map[uint64]struct{})
+		for j := 0; j < numPieces; j++ {
+			pieces[uint64(j)] = struct{}{}
+		}
+		rs.addIncompleteChunk(&chunkStatus{
+			contracts:   make(map[types.FileContractID]struct{}),
+			id:          chunkID{index: uint64(i)},
+			minPieces:   3,
+			pieces:      pieces,
+			totalPieces: 12,
+		})
+	}
+	if len(rs.incompleteChunks) != 5 {
+		t.Fatal("chunks were not added to the set of incomplete chunks")
+	}
+
+	// Uploading pieces changes the health of a chunk, which is picked up once
+	// the heap is rebuilt.
+	rs.incompleteChunks[chunkID{index: 1}].pieces[11] = struct{}{}
+	rs.incompleteChunks[chunkID{index: 1}].pieces[10] = struct{}{}
+	heap.Init(&rs.chunkHeap)
+
+	expHealth := []int{-3, 1, 1, 4, 7}
+	for _, exp := range expHealth {
+		cs := heap.Pop(&rs.chunkHeap).(*chunkStatus)
+		if cs.health() != exp {
+			t.Fatalf("expected chunk with health %v, got %v", exp, cs.health())
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package renter
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errSharedPieceSize  = errors.New("shared file has a piece size of 0")
+	errSharedPieceIndex = errors.New("shared file refers to a chunk that is out of range")
+)
+
+// validateSharedFile checks that a file decoded from a .sia file is
+// consistent, so that a malformed .sia file cannot corrupt the renter.
+func validateSharedFile(f *file) error {
+	if err := validateSiapath(f.name); err != nil {
+		return err
+	}
+	if f.pieceSize == 0 {
+		return errSharedPieceSize
+	}
+	// Pieces beyond the erasure code's number of pieces are allowed, since
+	// they remain in the contracts until excess pieces are dropped.
+	numChunks := f.numChunks()
+	for _, fc := range f.contracts {
+		for _, p := range fc.Pieces {
+			if p.Chunk >= numChunks {
+				return errSharedPieceIndex
+			}
+		}
+	}
+	return nil
+}
+
+// managedReachableHosts reports which of the hosts in addrs are reachable,
+// according to the scans of the hostdb. The hosts are never contacted
+// directly, since the addresses in a .sia file cannot be trusted. Hosts that
+// are unknown to the hostdb, or whose most recent scan failed, are considered
+// unreachable.
+func (r *Renter) managedReachableHosts(addrs map[modules.NetAddress]struct{}) map[modules.NetAddress]bool {
+	reachable := make(map[modules.NetAddress]bool, len(addrs))
+	if r.hostDB == nil {
+		return reachable
+	}
+	for _, host := range r.hostDB.AllHosts() {
+		if _, ok := addrs[host.NetAddress]; !ok {
+			continue
+		}
+		numScans := len(host.ScanHistory)
+		if numScans > 0 && host.ScanHistory[numScans-1].Success {
+			reachable[host.NetAddress] = true
+		}
+	}
+	return reachable
+}
+
+// managedSharedFileInfo reports the availability of files that were loaded
+// from a .sia file. Since the contracts of shared files usually belong to
+// another renter, the hosts are looked up in the hostdb instead of relying on
+// the contractor. origNames holds the name of each file in the .sia file.
+func (r *Renter) managedSharedFileInfo(files []*file, origNames []string) []modules.SharedFileInfo {
+	addrs := make(map[modules.NetAddress]struct{})
+	for _, f := range files {
+		f.mu.RLock()
+		for _, fc := range f.contracts {
+			addrs[fc.IP] = struct{}{}
+		}
+		f.mu.RUnlock()
+	}
+	reachable := r.managedReachableHosts(addrs)
+
+	infos := make([]modules.SharedFileInfo, len(files))
+	for i, f := range files {
+		f.mu.RLock()
+		isOffline := func(id types.FileContractID) bool {
+			fc, ok := f.contracts[id]
+			return !ok || !reachable[fc.IP]
+		}
+		infos[i] = modules.SharedFileInfo{
+			SiaPath:         f.name,
+			OriginalSiaPath: origNames[i],
+			Available:       f.available(isOffline),
+			Redundancy:      f.redundancy(isOffline),
+		}
+		hosts := make(map[modules.NetAddress]struct{})
+		for _, fc := range f.contracts {
+			hosts[fc.IP] = struct{}{}
+		}
+		f.mu.RUnlock()
+		for addr := range hosts {
+			if reachable[addr] {
+				infos[i].ReachableHosts++
+			} else {
+				infos[i].UnreachableHosts++
+			}
+		}
+	}
+	return infos
+}
@@ -0,0 +1,149 @@
+package renter
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// knownHostsDB is a hostDB that returns a fixed set of hosts from AllHosts.
+type knownHostsDB struct {
+	hostDB
+	hosts []modules.HostDBEntry
+}
+
+func (db *knownHostsDB) AllHosts() []modules.HostDBEntry { return db.hosts }
+
+// TestLoadSharedFilesReadOnly checks that shared files are loaded read-only,
+// that conflicting nicknames are renamed, and that the availability of the
+// loaded files reflects which hosts the hostdb considers reachable.
+func TestLoadSharedFilesReadOnly(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+
+	// The hostdb knows one host whose last scan succeeded and one whose last
+	// scan failed. A third host is unknown to the hostdb.
+	online := modules.NetAddress("online.com:9982")
+	offline := modules.NetAddress("offline.com:9982")
+	r.hostDB = &knownHostsDB{
+		hostDB: r.hostDB,
+		hosts: []modules.HostDBEntry{
+			{HostExternalSettings: modules.HostExternalSettings{NetAddress: online}, ScanHistory: modules.HostDBScans{{Success: false}, {Success: true}}},
+			{HostExternalSettings: modules.HostExternalSettings{NetAddress: offline}, ScanHistory: modules.HostDBScans{{Success: true}, {Success: false}}},
+		},
+	}
+
+	// The file has a single chunk whose data piece is stored on the
+	// reachable host and whose parity piece is stored on the unreachable
+	// and the unknown host.
+	rsc, _ := NewRSCode(1, 1)
+	f := newFile("foo/bar", rsc, 10, 5)
+	f.contracts[types.FileContractID{1}] = fileContract{
+		ID:     types.FileContractID{1},
+		IP:     online,
+		Pieces: []pieceData{{Chunk: 0, Piece: 0}},
+	}
+	f.contracts[types.FileContractID{2}] = fileContract{
+		ID:     types.FileContractID{2},
+		IP:     offline,
+		Pieces: []pieceData{{Chunk: 0, Piece: 1}},
+	}
+	f.contracts[types.FileContractID{4}] = fileContract{
+		ID:     types.FileContractID{4},
+		IP:     "unknown.com:9982",
+		Pieces: []pieceData{{Chunk: 0, Piece: 1}},
+	}
+	id := r.mu.Lock()
+	r.addFile(f)
+	err = r.saveFile(f)
+	r.mu.Unlock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Loading the file while the original still exists should rename it.
+	ascii, err := r.ShareFilesAscii([]string{f.name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	infos, err := r.LoadSharedFilesAscii(ascii)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := modules.SharedFileInfo{
+		SiaPath:          "foo/bar_1",
+		OriginalSiaPath:  "foo/bar",
+		Available:        true,
+		Redundancy:       1,
+		ReachableHosts:   1,
+		UnreachableHosts: 2,
+	}
+	if len(infos) != 1 || infos[0] != exp {
+		t.Fatal("wrong shared file info:", infos)
+	}
+	if err := r.SetFileRedundancy("foo/bar_1", 1, 2); err != errReadOnlyFile {
+		t.Fatal("expected errReadOnlyFile, got", err)
+	}
+	for _, fi := range r.FileList() {
+		if fi.ReadOnly != (fi.SiaPath == "foo/bar_1") {
+			t.Fatal("wrong read-only status for", fi.SiaPath)
+		}
+	}
+
+	// Files with the same nickname in a single .sia file should not
+	// overwrite each other.
+	buf := new(bytes.Buffer)
+	err = shareFiles([]*file{f, f}, base64.NewEncoder(base64.URLEncoding, buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	infos, err = r.LoadSharedFilesAscii(buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].SiaPath != "foo/bar_2" || infos[1].SiaPath != "foo/bar_3" {
+		t.Fatal("conflicting nicknames were not renamed:", infos)
+	}
+
+	// Malformed files are rejected without loading anything.
+	bad := newFile("foo/bad", rsc, 10, 5)
+	bad.contracts[types.FileContractID{3}] = fileContract{
+		ID:     types.FileContractID{3},
+		Pieces: []pieceData{{Chunk: 1, Piece: 0}},
+	}
+	buf.Reset()
+	err = shareFiles([]*file{f, bad}, base64.NewEncoder(base64.URLEncoding, buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.LoadSharedFilesAscii(buf.String()); err != errSharedPieceIndex {
+		t.Fatal("expected errSharedPieceIndex, got", err)
+	}
+	if len(r.FileList()) != 4 {
+		t.Fatal("malformed .sia file was partially loaded")
+	}
+
+	// The read-only status should persist.
+	id = r.mu.Lock()
+	r.files = make(map[string]*file)
+	err = r.load()
+	r.mu.Unlock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range r.FileList() {
+		if fi.ReadOnly != (fi.SiaPath != "foo/bar") {
+			t.Fatal("wrong read-only status after loading", fi.SiaPath)
+		}
+	}
+}
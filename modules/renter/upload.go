@@ -13,8 +13,25 @@ import (
 
 var (
 	errInsufficientContracts = errors.New("not enough contracts to upload file")
+	errReservedSiapath       = errors.New("siapath is reserved for renter metadata")
 	errUploadDirectory       = errors.New("cannot upload directory")
 
+	// reservedSiapaths are the names of the files and directories that the
+	// renter, the contractor and the hostdb keep next to the .sia files in the
+	// renter's persist directory. Siapaths may not begin with them, so that the
+	// siapath tree can never overlap with persisted state.
+	reservedSiapaths = map[string]struct{}{
+		PersistFilename:      {},
+		DownloadsFilename:    {},
+		logFile:              {},
+		chunkCacheDir:        {},
+		"contractor.json":    {},
+		"contractor.journal": {},
+		"contractor.log":     {},
+		"hostdb.json":        {},
+		"hostdb.log":         {},
+	}
+
 	// Erasure-coded piece size
 	pieceSize = modules.SectorSize - crypto.TwofishOverhead
 
@@ -46,26 +63,33 @@ var (
 	}()
 )
 
-// validateSiapath checks that a Siapath is a legal filename.
-// ../ is disallowed to prevent directory traversal,
-// and paths must not begin with / or be empty.
+// validateSiapath checks that a Siapath is a legal filename. Siapaths are
+// made up of elements separated by /, and since each element other than the
+// last names a directory, every element must be non-empty. "." and ".." are
+// disallowed to prevent directory traversal, and paths must not begin with /
+// or be empty. The first element must not name a file that holds persisted
+// metadata, or a temporary version of such a file.
 func validateSiapath(siapath string) error {
-	if strings.HasPrefix(siapath, "/") || strings.HasPrefix(siapath, "./") {
-		return errors.New("nicknames cannot begin with /")
-	}
-
 	if siapath == "" {
 		return ErrEmptyFilename
 	}
-
-	if strings.Contains(siapath, "../") {
-		return errors.New("directory traversal is not allowed")
+	if strings.HasPrefix(siapath, "/") {
+		return errors.New("nicknames cannot begin with /")
 	}
-
-	if strings.Contains(siapath, "./") {
-		return errors.New("siapath contains invalid characters")
+	first := strings.SplitN(siapath, "/", 2)[0]
+	first = strings.TrimSuffix(strings.TrimSuffix(first, "_temp"), "_tmp")
+	if _, reserved := reservedSiapaths[first]; reserved {
+		return errReservedSiapath
 	}
 
+	for _, elem := range strings.Split(siapath, "/") {
+		switch elem {
+		case "":
+			return errors.New("siapath contains an empty directory name")
+		case ".", "..":
+			return errors.New("directory traversal is not allowed")
+		}
+	}
 	return nil
 }
 
@@ -83,6 +107,18 @@ func validateSource(sourcePath string) error {
 	return nil
 }
 
+// checkUploadContracts checks that the renter has enough contracts to upload a
+// file using the provided erasure code. We need at least (data + parity/2)
+// contracts; since NumPieces = data + parity, we arrive at the expression
+// below.
+func (r *Renter) checkUploadContracts(ec modules.ErasureCoder) error {
+	needed := (ec.NumPieces() + ec.MinPieces()) / 2
+	if nContracts := len(r.hostContractor.Contracts()); nContracts < needed && build.Release != "testing" {
+		return fmt.Errorf("not enough contracts to upload file: got %v, needed %v", nContracts, needed)
+	}
+	return nil
+}
+
 // Upload instructs the renter to start tracking a file. The renter will
 // automatically upload and repair tracked files using a background loop.
 func (r *Renter) Upload(up modules.FileUploadParams) error {
@@ -113,11 +149,14 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 		up.ErasureCode, _ = NewRSCode(defaultDataPieces, defaultParityPieces)
 	}
 
-	// Check that we have contracts to upload to. We need at least (data +
-	// parity/2) contracts; since NumPieces = data + parity, we arrive at the
-	// expression below.
-	if nContracts := len(r.hostContractor.Contracts()); nContracts < (up.ErasureCode.NumPieces()+up.ErasureCode.MinPieces())/2 && build.Release != "testing" {
-		return fmt.Errorf("not enough contracts to upload file: got %v, needed %v", nContracts, (up.ErasureCode.NumPieces()+up.ErasureCode.MinPieces())/2)
+	if err := r.checkUploadContracts(up.ErasureCode); err != nil {
+		return err
+	}
+
+	// Wait for the upload pipeline to have memory available before adding
+	// another file.
+	if !r.memoryManager.wait() {
+		return errRenterShutdown
 	}
 
 	// Create file object.
@@ -126,7 +165,7 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 
 	// Add file to renter.
 	lockID = r.mu.Lock()
-	r.files[up.SiaPath] = f
+	r.addFile(f)
 	r.tracking[up.SiaPath] = trackedFile{
 		RepairPath: up.Source,
 	}
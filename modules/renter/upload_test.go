@@ -24,7 +24,18 @@ func TestRenterSiapathValidate(t *testing.T) {
 		{"test/path", true},
 		{"/leading/slash", false},
 		{"foo/./bar", false},
+		{"trailing/slash/", false},
+		{"double//slash", false},
+		{"..", false},
+		{"trailing/..", false},
 		{"", false},
+		{"renter.json", false},
+		{"contractor.journal/foo", false},
+		{"hostdb.json_temp", false},
+		{"chunkcache/foo", false},
+		{"contractor.journal_tmp", false},
+		{"foo/contractor.journal", true},
+		{"renter.json.bak", true},
 	}
 	for _, pathtest := range pathtests {
 		err := validateSiapath(pathtest.in)
@@ -0,0 +1,241 @@
+package renter
+
+import (
+	"errors"
+	"io"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// errEmptyStream is returned when a streamed upload is attempted with a
+	// reader that does not contain any data.
+	errEmptyStream = errors.New("cannot upload an empty stream")
+
+	// errInsufficientStreamHosts is returned when a streamed chunk could not
+	// be uploaded to enough hosts to be recoverable.
+	errInsufficientStreamHosts = errors.New("unable to upload chunk to enough hosts to make it recoverable")
+
+	// errRenterShutdown is returned when an upload is interrupted because the
+	// renter is shutting down.
+	errRenterShutdown = errors.New("renter is shutting down")
+)
+
+// A streamChunk is a chunk of a streamed upload. Unlike the chunks of other
+// files, the data of a streamChunk is held in memory, because there is no
+// source file on disk that the repair loop can read it from.
+//
+// streamChunks are only accessed by the repair loop after they have been
+// submitted. cancel is closed by the uploader if the upload fails, after
+// which the repair loop drops the chunk instead of uploading more of its
+// pieces.
+//
+// The memory that holds data is reserved from the renter's memory manager by
+// the uploader, and released by the repair loop once it drops the chunk.
+type streamChunk struct {
+	data   []byte
+	file   *file
+	index  uint64
+	cancel <-chan struct{}
+	memory uint64
+
+	// uploadedPieces counts the pieces that have been successfully uploaded.
+	// Once uploadedPieces reaches the number of pieces required to recover
+	// the chunk, the result is sent down done.
+	uploadedPieces int
+	finished       bool
+	done           chan error
+}
+
+// finish reports the result of the chunk's upload to the uploader. Only the
+// first call has any effect.
+func (sc *streamChunk) finish(err error) {
+	if sc.finished {
+		return
+	}
+	sc.finished = true
+	sc.done <- err
+}
+
+// cancelled reports whether the upload that the chunk belongs to has failed.
+func (sc *streamChunk) cancelled() bool {
+	select {
+	case <-sc.cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// addStreamChunkToRepairState adds a streamed chunk to the set of incomplete
+// chunks of the repair state.
+func (r *Renter) addStreamChunkToRepairState(rs *repairState, sc *streamChunk) {
+	cid := chunkID{sc.index, sc.file.masterKey}
+	if _, exists := rs.incompleteChunks[cid]; exists {
+		build.Critical("streamed chunk is already in the repair state")
+		sc.finish(errors.New("chunk is already being uploaded"))
+		r.memoryManager.releaseHeld(sc.memory)
+		return
+	}
+	rs.addIncompleteChunk(&chunkStatus{
+		contracts:   make(map[types.FileContractID]struct{}),
+		id:          cid,
+		minPieces:   sc.file.erasureCode.MinPieces(),
+		pieces:      make(map[uint64]struct{}),
+		stream:      sc,
+		totalPieces: sc.file.erasureCode.NumPieces(),
+	})
+}
+
+// managedSubmitStreamChunk hands a chunk of a streamed upload to the repair
+// loop.
+func (r *Renter) managedSubmitStreamChunk(sc *streamChunk) error {
+	select {
+	case r.newStreamChunks <- sc:
+		return nil
+	case <-r.tg.StopChan():
+		return errRenterShutdown
+	}
+}
+
+// managedWaitStreamChunk blocks until the upload of a streamed chunk has
+// either reached the minimum redundancy or failed.
+func (r *Renter) managedWaitStreamChunk(sc *streamChunk) error {
+	select {
+	case err := <-sc.done:
+		return err
+	case <-r.tg.StopChan():
+		return errRenterShutdown
+	}
+}
+
+// UploadStreamFromReader uploads a file to the network using data read from
+// reader rather than from a file on disk. The data is read and uploaded one
+// chunk at a time, with at most maxStreamChunks chunks held in memory. The
+// file is added to the renter only after every chunk has been uploaded to
+// enough hosts to be recoverable, and is then tracked and repaired like any
+// other file. The call blocks until the file is added or the upload fails.
+//
+// up.Source is ignored.
+func (r *Renter) UploadStreamFromReader(up modules.FileUploadParams, reader io.Reader) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+
+	// Enforce nickname rules.
+	if err := validateSiapath(up.SiaPath); err != nil {
+		return err
+	}
+
+	// Check for a nickname conflict.
+	lockID := r.mu.RLock()
+	_, exists := r.files[up.SiaPath]
+	r.mu.RUnlock(lockID)
+	if exists {
+		return ErrPathOverload
+	}
+
+	// Fill in any missing upload params with sensible defaults.
+	if up.ErasureCode == nil {
+		up.ErasureCode, _ = NewRSCode(defaultDataPieces, defaultParityPieces)
+	}
+	if err := r.checkUploadContracts(up.ErasureCode); err != nil {
+		return err
+	}
+
+	// Create the file object. The size of the file is not known until the
+	// reader is exhausted, and is updated as chunks are read. The file is
+	// shared with the repair loop as soon as its first chunk is submitted, so
+	// the size is only updated while holding the file's lock.
+	f := newFile(up.SiaPath, up.ErasureCode, pieceSize, 0)
+
+	// Read and submit chunks, waiting on the oldest chunk whenever the
+	// maximum number of chunks are in flight. If the upload fails, cancel is
+	// closed so that the repair loop drops the chunks that were submitted.
+	var inFlight []*streamChunk
+	var uploadErr error
+	cancel := make(chan struct{})
+	for index := uint64(0); ; index++ {
+		if len(inFlight) >= maxStreamChunks {
+			if uploadErr = r.managedWaitStreamChunk(inFlight[0]); uploadErr != nil {
+				break
+			}
+			inFlight = inFlight[1:]
+		}
+
+		// Reserve the memory of the chunk from the upload pipeline before
+		// reading it. The memory is released once the repair loop drops the
+		// chunk.
+		memory := f.chunkSize()
+		if !r.memoryManager.hold(memory) {
+			uploadErr = errRenterShutdown
+			break
+		}
+		data := make([]byte, memory)
+		n, err := io.ReadFull(reader, data)
+		if err == io.EOF {
+			// The reader ended on a chunk boundary. An empty reader would
+			// otherwise be uploaded as a chunk of zeros.
+			r.memoryManager.releaseHeld(memory)
+			if index == 0 {
+				uploadErr = errEmptyStream
+			}
+			break
+		} else if err != nil && err != io.ErrUnexpectedEOF {
+			r.memoryManager.releaseHeld(memory)
+			uploadErr = err
+			break
+		}
+		f.mu.Lock()
+		f.size += uint64(n)
+		f.mu.Unlock()
+
+		sc := &streamChunk{
+			data:   data,
+			file:   f,
+			index:  index,
+			cancel: cancel,
+			memory: memory,
+			done:   make(chan error, 1),
+		}
+		if uploadErr = r.managedSubmitStreamChunk(sc); uploadErr != nil {
+			r.memoryManager.releaseHeld(memory)
+			break
+		}
+		inFlight = append(inFlight, sc)
+		if err != nil {
+			// The final chunk has been read.
+			break
+		}
+	}
+	for _, sc := range inFlight {
+		if uploadErr != nil {
+			break
+		}
+		uploadErr = r.managedWaitStreamChunk(sc)
+	}
+	if uploadErr != nil {
+		close(cancel)
+		return build.ExtendErr("streamed upload failed", uploadErr)
+	}
+
+	// Commit the file to the renter. The file has no repair path, so the
+	// repair loop will download any chunks that need to be repaired.
+	lockID = r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	if _, exists := r.files[up.SiaPath]; exists {
+		return ErrPathOverload
+	}
+	r.addFile(f)
+	r.tracking[up.SiaPath] = trackedFile{}
+	f.mu.RLock()
+	err := r.saveFile(f)
+	f.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return r.saveSync()
+}
@@ -5,6 +5,7 @@ package renter
 // will be different.
 
 import (
+	"sync"
 	"time"
 
 	"github.com/NebulousLabs/Sia/crypto"
@@ -50,10 +51,12 @@ type (
 	// uploadWork contains instructions to upload a piece to a host, and a
 	// channel for returning the results.
 	uploadWork struct {
-		// data is the payload of the upload.
+		// data is the payload of the upload, and memory is the amount of
+		// upload memory that is released once the upload has finished.
 		chunkID    chunkID
 		data       []byte
 		file       *file
+		memory     uint64
 		pieceIndex uint64
 
 		// resultChan is a channel that the worker will use to return the
@@ -61,6 +64,14 @@ type (
 		resultChan chan finishedUpload
 	}
 
+	// hostDownloadStats contains the rolling download statistics of a host.
+	// The statistics are kept per host rather than per contract, so that they
+	// survive contract renewals.
+	hostDownloadStats struct {
+		stats modules.HostDownloadStats
+		mu    sync.Mutex
+	}
+
 	// A worker listens for work on a certain host.
 	worker struct {
 		// contractID specifies which contract the worker specifically works
@@ -88,6 +99,11 @@ type (
 		// has failed.
 		recentDownloadFailure time.Time // Only modified by the primary download loop.
 
+		// downloadStats contains the rolling latency and throughput of the
+		// worker's host. The statistics are updated by the worker after every
+		// piece download, and read by the download loop.
+		downloadStats *hostDownloadStats
+
 		// Utilities.
 		renter *Renter
 	}
@@ -95,8 +111,10 @@ type (
 
 // download will perform some download work.
 func (w *worker) download(dw downloadWork) {
+	start := time.Now()
 	d, err := w.renter.hostContractor.Downloader(w.contractID, w.renter.tg.StopChan())
 	if err != nil {
+		w.recordFailure()
 		go func() {
 			select {
 			case dw.resultChan <- finishedDownload{dw.chunkDownload, nil, err, dw.pieceIndex, w.contractID}:
@@ -107,7 +125,13 @@ func (w *worker) download(dw downloadWork) {
 	}
 	defer d.Close()
 
+	connected := time.Now()
 	data, err := d.Sector(dw.dataRoot)
+	if err == nil {
+		w.recordDownload(connected.Sub(start), time.Since(connected), len(data))
+	} else {
+		w.recordFailure()
+	}
 	go func() {
 		select {
 		case dw.resultChan <- finishedDownload{dw.chunkDownload, data, err, dw.pieceIndex, w.contractID}:
@@ -116,8 +140,64 @@ func (w *worker) download(dw downloadWork) {
 	}()
 }
 
+// recordDownload adds a successful piece download to the rolling download
+// statistics of the worker's host.
+func (w *worker) recordDownload(latency, transfer time.Duration, size int) {
+	w.downloadStats.mu.Lock()
+	defer w.downloadStats.mu.Unlock()
+
+	var throughput uint64
+	if transfer > 0 {
+		throughput = uint64(float64(size) / transfer.Seconds())
+	}
+	stats := &w.downloadStats.stats
+	if stats.Samples == 0 {
+		stats.Latency = latency
+		stats.Throughput = throughput
+	} else {
+		stats.Latency = time.Duration(downloadStatsDecay*float64(stats.Latency) + (1-downloadStatsDecay)*float64(latency))
+		stats.Throughput = uint64(downloadStatsDecay*float64(stats.Throughput) + (1-downloadStatsDecay)*float64(throughput))
+	}
+	stats.Samples++
+}
+
+// recordFailure counts a piece download of the worker that failed.
+func (w *worker) recordFailure() {
+	w.downloadStats.mu.Lock()
+	w.downloadStats.stats.Failures++
+	w.downloadStats.mu.Unlock()
+}
+
+// recordStall counts a piece download of the worker that stalled.
+func (w *worker) recordStall() {
+	w.downloadStats.mu.Lock()
+	w.downloadStats.stats.Stalls++
+	w.downloadStats.mu.Unlock()
+}
+
+// estimatedDownloadTime returns the time the worker is expected to need to
+// download a piece, based on the statistics of its host. Zero is returned for
+// workers that have not downloaded anything yet. unreliable is true if the
+// host has never delivered a piece, but has failed or stalled.
+func (w *worker) estimatedDownloadTime() (estimate time.Duration, unreliable bool) {
+	w.downloadStats.mu.Lock()
+	defer w.downloadStats.mu.Unlock()
+
+	stats := w.downloadStats.stats
+	if stats.Samples == 0 {
+		return 0, stats.Failures > 0 || stats.Stalls > 0
+	}
+	estimate = stats.Latency
+	if stats.Throughput > 0 {
+		estimate += time.Duration(float64(modules.SectorSize) / float64(stats.Throughput) * float64(time.Second))
+	}
+	return estimate, false
+}
+
 // upload will perform some upload work.
 func (w *worker) upload(uw uploadWork) {
+	defer w.renter.memoryManager.release(uw.memory)
+
 	e, err := w.renter.hostContractor.Editor(w.contractID, w.renter.tg.StopChan())
 	if err != nil {
 		w.recentUploadFailure = time.Now()
@@ -167,7 +247,12 @@ func (w *worker) upload(uw uploadWork) {
 		MerkleRoot: root,
 	})
 	uw.file.contracts[w.contractID] = contract
-	w.renter.saveFile(uw.file)
+	// Only save files that are part of the renter. Streamed uploads are saved
+	// once they are committed, and deleted files should stay deleted.
+	if f, exists := w.renter.files[uw.file.name]; exists && f == uw.file {
+		w.renter.saveFile(uw.file)
+		w.renter.dirChanged(parentSiapath(uw.file.name))
+	}
 	uw.file.mu.Unlock()
 	w.renter.mu.Unlock(id)
 
@@ -251,9 +336,17 @@ func (r *Renter) updateWorkerPool(contracts []modules.RenterContract) {
 	for id, contract := range newContracts {
 		_, exists := r.workerPool[id]
 		if !exists {
+			// Workers of the same host share its download statistics.
+			host := contract.HostPublicKey.String()
+			stats, ok := r.hostDownloadStats[host]
+			if !ok {
+				stats = new(hostDownloadStats)
+				r.hostDownloadStats[host] = stats
+			}
 			worker := &worker{
-				contract:   contract,
-				contractID: id,
+				contract:      contract,
+				contractID:    id,
+				downloadStats: stats,
 
 				downloadChan:         make(chan downloadWork, 1),
 				killChan:             make(chan struct{}),
@@ -273,6 +366,29 @@ func (r *Renter) updateWorkerPool(contracts []modules.RenterContract) {
 		if !exists {
 			delete(r.workerPool, id)
 			close(worker.killChan)
+
+			// Release the memory of any upload that the worker did not pick
+			// up before it was killed.
+			select {
+			case uw := <-worker.uploadChan:
+				r.memoryManager.release(uw.memory)
+			default:
+			}
 		}
 	}
 }
+
+// DownloadStats returns the download statistics of the hosts that the renter
+// has downloaded from, keyed by host public key.
+func (r *Renter) DownloadStats() map[string]modules.HostDownloadStats {
+	id := r.mu.RLock()
+	defer r.mu.RUnlock(id)
+
+	stats := make(map[string]modules.HostDownloadStats, len(r.hostDownloadStats))
+	for host, hds := range r.hostDownloadStats {
+		hds.mu.Lock()
+		stats[host] = hds.stats
+		hds.mu.Unlock()
+	}
+	return stats
+}
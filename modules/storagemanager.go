@@ -2,6 +2,7 @@ package modules
 
 import (
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/types"
 )
 
 const (
@@ -40,6 +41,18 @@ type (
 		// folder. Progress is always reported in bytes.
 		ProgressNumerator   uint64
 		ProgressDenominator uint64
+
+		// The background scrubber periodically reads every sector in the
+		// storage folder and checks that it still matches its Merkle root.
+		// ScrubProgress and ScrubTotal count the sectors that have been
+		// checked by the current or most recent scrub, and LastScrub is the
+		// time at which the most recent scrub finished. LastScrub is saved
+		// across restarts. CorruptSectors is the number of sectors that the
+		// current or most recent scrub found to be corrupt.
+		CorruptSectors uint64          `json:"corruptsectors"`
+		LastScrub      types.Timestamp `json:"lastscrub"`
+		ScrubProgress  uint64          `json:"scrubprogress"` // sectors
+		ScrubTotal     uint64          `json:"scrubtotal"`    // sectors
 	}
 
 	// A StorageManager is responsible for managing storage folders and
@@ -45,16 +45,49 @@ Available settings:
      minstorageprice:           currency / TB / Month
      minuploadbandwidthprice:   currency / TB
 
+     autopricing:                   boolean
+     autopricingtargetutilization:  fraction of storage, e.g. 0.8
+     contractpricefloor:            currency
+     contractpriceceiling:          currency
+     downloadbandwidthpricefloor:   currency / TB
+     downloadbandwidthpriceceiling: currency / TB
+     storagepricefloor:             currency / TB / Month
+     storagepriceceiling:           currency / TB / Month
+     uploadbandwidthpricefloor:     currency / TB
+     uploadbandwidthpriceceiling:   currency / TB
+
+     maxdownloadspeed:    bytes per second
+     maxuploadspeed:      bytes per second
+     maxconnections:      number of connections
+     maxconnectionsperip: number of connections
+
+     maintenancemode:          boolean
+     maintenancerefuseuploads: boolean
+
 Currency units can be specified, e.g. 10SC; run 'siac help wallet' for details.
 
 Durations (maxduration and windowsize) must be specified in either blocks (b),
 hours (h), days (d), or weeks (w). A block is approximately 10 minutes, so one
 hour is six blocks, a day is 144 blocks, and a week is 1008 blocks.
 
+Speeds (maxdownloadspeed and maxuploadspeed) are given in bytes per second,
+with a unit such as KB/s, MB/s or MiB/s. A speed or connection limit of 0
+removes the limit.
+
 For a description of each parameter, see doc/API.md.
 
 To configure the host to accept new contracts, set acceptingcontracts to true:
 	siac host config acceptingcontracts true
+
+When autopricing is enabled, the host periodically moves its minimum prices
+toward the target utilization of its storage, keeping each price between its
+floor and ceiling. Prices without a ceiling are not adjusted.
+
+In maintenance mode, the host refuses new contracts and renewals but keeps
+serving downloads and submitting storage proofs, so that it can be retired
+without losing collateral once its last obligation has expired. Set
+maintenancerefuseuploads to also refuse uploads to existing contracts:
+	siac host config maintenancemode true
 `,
 		Run: wrap(hostconfigcmd),
 	}
@@ -72,6 +105,15 @@ Doing so will override the standard connectivity checks.`,
 		Run: hostannouncecmd,
 	}
 
+	hostContractsCmd = &cobra.Command{
+		Use:   "contracts",
+		Short: "View the host's contracts",
+		Long: `View the storage obligations of the host, ordered by the height at which
+their storage proofs are due. Use --status to only show unresolved, succeeded,
+failed, or rejected contracts.`,
+		Run: wrap(hostcontractscmd),
+	}
+
 	hostFolderCmd = &cobra.Command{
 		Use:   "folder",
 		Short: "Add, remove, or resize a storage folder",
@@ -197,6 +239,25 @@ Host Internal Settings:
 	minstorageprice:           %v / TB / Month
 	minuploadbandwidthprice:   %v / TB
 
+	autopricing:                   %v
+	autopricingtargetutilization:  %.2f%%
+	contractpricefloor:            %v
+	contractpriceceiling:          %v
+	downloadbandwidthpricefloor:   %v / TB
+	downloadbandwidthpriceceiling: %v / TB
+	storagepricefloor:             %v / TB / Month
+	storagepriceceiling:           %v / TB / Month
+	uploadbandwidthpricefloor:     %v / TB
+	uploadbandwidthpriceceiling:   %v / TB
+
+	maxdownloadspeed:    %v
+	maxuploadspeed:      %v
+	maxconnections:      %v
+	maxconnectionsperip: %v
+
+	maintenancemode:          %v
+	maintenancerefuseuploads: %v
+
 Host Financials:
 	Contract Count:               %v
 	Transaction Fee Compensation: %v
@@ -223,6 +284,10 @@ RPC Stats:
 	Revise Calls:       %v
 	Settings Calls:     %v
 	FormContract Calls: %v
+
+Connections:
+	Active Connections:   %v
+	Rejected Connections: %v
 `,
 			connectabilityString,
 
@@ -240,6 +305,22 @@ RPC Stats:
 			currencyUnits(is.MinStoragePrice.Mul(modules.BlockBytesPerMonthTerabyte)),
 			currencyUnits(is.MinUploadBandwidthPrice.Mul(modules.BytesPerTerabyte)),
 
+			yesNo(is.AutoPricing), is.AutoPricingTargetUtilization*100,
+			currencyUnits(is.ContractPriceFloor),
+			currencyUnits(is.ContractPriceCeiling),
+			currencyUnits(is.DownloadBandwidthPriceFloor.Mul(modules.BytesPerTerabyte)),
+			currencyUnits(is.DownloadBandwidthPriceCeiling.Mul(modules.BytesPerTerabyte)),
+			currencyUnits(is.StoragePriceFloor.Mul(modules.BlockBytesPerMonthTerabyte)),
+			currencyUnits(is.StoragePriceCeiling.Mul(modules.BlockBytesPerMonthTerabyte)),
+			currencyUnits(is.UploadBandwidthPriceFloor.Mul(modules.BytesPerTerabyte)),
+			currencyUnits(is.UploadBandwidthPriceCeiling.Mul(modules.BytesPerTerabyte)),
+
+			speedUnits(is.MaxDownloadSpeed), speedUnits(is.MaxUploadSpeed),
+			connectionLimit(is.MaxConnections), connectionLimit(is.MaxConnectionsPerIP),
+
+			maintenanceStatus(is, hg.LastObligationExpiration),
+			yesNo(is.MaintenanceRefuseUploads),
+
 			fm.ContractCount, currencyUnits(fm.ContractCompensation),
 			currencyUnits(fm.PotentialContractCompensation),
 			currencyUnits(fm.TransactionFeeExpenses),
@@ -258,7 +339,9 @@ RPC Stats:
 
 			nm.ErrorCalls, nm.UnrecognizedCalls, nm.DownloadCalls,
 			nm.RenewCalls, nm.ReviseCalls, nm.SettingsCalls,
-			nm.FormContractCalls)
+			nm.FormContractCalls,
+
+			nm.ActiveConnections, nm.RejectedConnections)
 	} else {
 		fmt.Printf(`Host info:
 	Connectability Status: %v
@@ -268,6 +351,7 @@ RPC Stats:
 	Max Duration: %v Weeks
 
 	Accepting Contracts:  %v
+	Maintenance Mode:     %v
 	Anticipated Revenue:  %v
 	Locked Collateral:    %v
 	Revenue:              %v
@@ -278,7 +362,9 @@ RPC Stats:
 			filesizeUnits(int64(totalstorage-storageremaining)), price,
 			periodUnits(is.MaxDuration),
 
-			yesNo(is.AcceptingContracts), currencyUnits(totalPotentialRevenue),
+			yesNo(is.AcceptingContracts),
+			maintenanceStatus(is, hg.LastObligationExpiration),
+			currencyUnits(totalPotentialRevenue),
 			currencyUnits(fm.LockedStorageCollateral),
 			currencyUnits(totalRevenue))
 	}
@@ -294,29 +380,45 @@ RPC Stats:
 		return
 	}
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
-	fmt.Fprintf(w, "\tUsed\tCapacity\t%% Used\tPath\n")
+	fmt.Fprintf(w, "\tUsed\tCapacity\t%% Used\tScrubbed\tCorrupt Sectors\tPath\n")
 	for _, folder := range sg.Folders {
 		curSize := int64(folder.Capacity - folder.CapacityRemaining)
 		pctUsed := 100 * (float64(curSize) / float64(folder.Capacity))
-		fmt.Fprintf(w, "\t%s\t%s\t%.2f\t%s\n", filesizeUnits(curSize), filesizeUnits(int64(folder.Capacity)), pctUsed, folder.Path)
+		scrubbed := fmt.Sprintf("%v/%v", folder.ScrubProgress, folder.ScrubTotal)
+		fmt.Fprintf(w, "\t%s\t%s\t%.2f\t%s\t%v\t%s\n", filesizeUnits(curSize), filesizeUnits(int64(folder.Capacity)), pctUsed, scrubbed, folder.CorruptSectors, folder.Path)
 	}
 	w.Flush()
 }
 
+// maintenanceStatus describes whether the host is in maintenance mode, and if
+// so, the height at which its last storage obligation expires.
+func maintenanceStatus(is modules.HostInternalSettings, lastExpiration types.BlockHeight) string {
+	if !is.MaintenanceMode {
+		return "No"
+	}
+	if lastExpiration == 0 {
+		return "Yes (no remaining obligations)"
+	}
+	return fmt.Sprintf("Yes (last obligation expires at block %v)", lastExpiration)
+}
+
 // hostconfigcmd is the handler for the command `siac host config [setting] [value]`.
 // Modifies host settings.
 func hostconfigcmd(param, value string) {
 	var err error
 	switch param {
 	// currency (convert to hastings)
-	case "collateralbudget", "maxcollateral", "mincontractprice":
+	case "collateralbudget", "maxcollateral", "mincontractprice",
+		"contractpricefloor", "contractpriceceiling":
 		value, err = parseCurrency(value)
 		if err != nil {
 			die("Could not parse "+param+":", err)
 		}
 
 	// currency/TB (convert to hastings/byte)
-	case "mindownloadbandwidthprice", "minuploadbandwidthprice":
+	case "mindownloadbandwidthprice", "minuploadbandwidthprice",
+		"downloadbandwidthpricefloor", "downloadbandwidthpriceceiling",
+		"uploadbandwidthpricefloor", "uploadbandwidthpriceceiling":
 		hastings, err := parseCurrency(value)
 		if err != nil {
 			die("Could not parse "+param+":", err)
@@ -326,7 +428,7 @@ func hostconfigcmd(param, value string) {
 		value = c.String()
 
 	// currency/TB/month (convert to hastings/byte/block)
-	case "collateral", "minstorageprice":
+	case "collateral", "minstorageprice", "storagepricefloor", "storagepriceceiling":
 		hastings, err := parseCurrency(value)
 		if err != nil {
 			die("Could not parse "+param+":", err)
@@ -336,7 +438,7 @@ func hostconfigcmd(param, value string) {
 		value = c.String()
 
 	// bool (allow "yes" and "no")
-	case "acceptingcontracts":
+	case "acceptingcontracts", "autopricing", "maintenancemode", "maintenancerefuseuploads":
 		switch strings.ToLower(value) {
 		case "yes":
 			value = "true"
@@ -344,6 +446,13 @@ func hostconfigcmd(param, value string) {
 			value = "false"
 		}
 
+	// speed (convert to bytes per second)
+	case "maxdownloadspeed", "maxuploadspeed":
+		value, err = parseSpeed(value)
+		if err != nil {
+			die("Could not parse "+param+":", err)
+		}
+
 	// duration (convert to blocks)
 	case "maxduration", "windowsize":
 		value, err = parsePeriod(value)
@@ -352,7 +461,8 @@ func hostconfigcmd(param, value string) {
 		}
 
 	// other valid settings
-	case "maxdownloadbatchsize", "maxrevisebatchsize", "netaddress":
+	case "autopricingtargetutilization", "maxconnections", "maxconnectionsperip",
+		"maxdownloadbatchsize", "maxrevisebatchsize", "netaddress":
 
 	// invalid settings
 	default:
@@ -402,6 +512,39 @@ To revert this, run:
 `)
 }
 
+// hostcontractscmd is the handler for the command `siac host contracts`.
+// Lists the storage obligations of the host.
+func hostcontractscmd() {
+	var hc api.HostContractsGET
+	err := getAPI("/host/contracts?status="+hostContractsStatus, &hc)
+	if err != nil {
+		die("Could not fetch host contracts:", err)
+	}
+	if len(hc.Contracts) == 0 {
+		fmt.Println("No contracts.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tStatus\tSize\tSectors\tProof Window\tLocked Collateral\tRisked Collateral\tContract Cost\tStorage Revenue\tUpload Revenue\tDownload Revenue")
+	for _, so := range hc.Contracts {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v - %v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			so.ObligationID,
+			so.Status,
+			filesizeUnits(int64(so.FileSize)),
+			so.SectorCount,
+			so.ExpirationHeight,
+			so.ProofDeadline,
+			currencyUnits(so.LockedCollateral),
+			currencyUnits(so.RiskedCollateral),
+			currencyUnits(so.ContractCost),
+			currencyUnits(so.PotentialStorageRevenue),
+			currencyUnits(so.PotentialUploadRevenue),
+			currencyUnits(so.PotentialDownloadRevenue))
+	}
+	w.Flush()
+}
+
 // hostfolderaddcmd adds a folder to the host.
 func hostfolderaddcmd(path, size string) {
 	size, err := parseFilesize(size)
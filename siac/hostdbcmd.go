@@ -3,7 +3,10 @@ package main
 import (
 	"fmt"
 	"math/big"
+	"net"
+	"net/url"
 	"os"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
@@ -16,6 +19,7 @@ const scanHistoryLen = 30
 
 var (
 	hostdbNumHosts int
+	hostdbGeoIP    string
 	hostdbVerbose  bool
 )
 
@@ -27,6 +31,22 @@ var (
 		Run:   wrap(hostdbcmd),
 	}
 
+	hostdbFilterCmd = &cobra.Command{
+		Use:   "filter [disable|blacklist|whitelist] [pubkey|subnet|country]...",
+		Short: "View or change the hostdb's filter.",
+		Long: `View or change the filter that excludes hosts from being selected for contracts.
+Without arguments, the current filter is printed.
+
+In blacklist mode, the listed hosts are never selected. In whitelist mode, only
+the listed hosts are selected. Hosts are listed by public key, such as
+ed25519:1234..., by subnet in CIDR notation, such as 203.0.113.0/24, or by
+country as a two-letter code, such as NL. Countries are looked up in the GeoIP
+database given with --geoip, a CSV file of address ranges such as the
+"IP to Country Lite" database of DB-IP.
+Contracts with hosts that the new filter excludes are not renewed.`,
+		Run: hostdbfiltercmd,
+	}
+
 	hostdbViewCmd = &cobra.Command{
 		Use:   "view [pubkey]",
 		Short: "View the full information for a host.",
@@ -329,3 +349,55 @@ func hostdbviewcmd(pubkey string) {
 
 	fmt.Println()
 }
+
+// hostdbfiltercmd is the handler for the command `siac hostdb filter`. It
+// prints the hostdb's filter, or replaces it if a mode is given.
+func hostdbfiltercmd(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		var hfm api.HostdbFilterModeGET
+		err := getAPI("/hostdb/filtermode", &hfm)
+		if err != nil {
+			die("Could not fetch the hostdb filter:", err)
+		}
+		fmt.Println("Filter mode:", hfm.FilterMode)
+		for _, host := range hfm.Hosts {
+			fmt.Println("  Host:  ", host)
+		}
+		for _, subnet := range hfm.Subnets {
+			fmt.Println("  Subnet:", subnet)
+		}
+		for _, country := range hfm.Countries {
+			fmt.Println("  Country:", country)
+		}
+		if hfm.GeoIPDatabase != "" {
+			fmt.Println("GeoIP database:", hfm.GeoIPDatabase)
+		}
+		return
+	}
+
+	var hosts, subnets, countries []string
+	for _, arg := range args[1:] {
+		if _, _, err := net.ParseCIDR(arg); err == nil {
+			subnets = append(subnets, arg)
+		} else if len(arg) == 2 {
+			countries = append(countries, arg)
+		} else {
+			hosts = append(hosts, arg)
+		}
+	}
+	geoIPPath := hostdbGeoIP
+	if geoIPPath != "" {
+		geoIPPath = abs(geoIPPath)
+	}
+	values := url.Values{}
+	values.Set("filtermode", args[0])
+	values.Set("hosts", strings.Join(hosts, ","))
+	values.Set("subnets", strings.Join(subnets, ","))
+	values.Set("countries", strings.Join(countries, ","))
+	values.Set("geoipdatabase", geoIPPath)
+	err := post("/hostdb/filtermode", values.Encode())
+	if err != nil {
+		die("Could not set the hostdb filter:", err)
+	}
+	fmt.Println("Hostdb filter updated.")
+}
@@ -18,12 +18,14 @@ import (
 
 var (
 	// Flags.
-	addr              string // override default API address
-	initPassword      bool   // supply a custom password when creating a wallet
-	initForce         bool   // destroy and reencrypt the wallet on init if it already exists
-	hostVerbose       bool   // display additional host info
-	renterShowHistory bool   // Show download history in addition to download queue.
-	renterListVerbose bool   // Show additional info about uploaded files.
+	addr                string // override default API address
+	initPassword        bool   // supply a custom password when creating a wallet
+	initForce           bool   // destroy and reencrypt the wallet on init if it already exists
+	hostVerbose         bool   // display additional host info
+	hostContractsStatus string // only show host contracts with this status
+	renterShowHistory   bool   // Show download history in addition to download queue.
+	renterListVerbose   bool   // Show additional info about uploaded files.
+	renterShowExpired   bool   // Show expired contracts in addition to active contracts.
 
 	// Globals.
 	rootCmd *cobra.Command // Root command cobra object, used by bash completion cmd.
@@ -249,14 +251,16 @@ func main() {
 	updateCmd.AddCommand(updateCheckCmd)
 
 	root.AddCommand(hostCmd)
-	hostCmd.AddCommand(hostConfigCmd, hostAnnounceCmd, hostFolderCmd, hostSectorCmd)
+	hostCmd.AddCommand(hostConfigCmd, hostAnnounceCmd, hostContractsCmd, hostFolderCmd, hostSectorCmd)
 	hostFolderCmd.AddCommand(hostFolderAddCmd, hostFolderRemoveCmd, hostFolderResizeCmd)
 	hostSectorCmd.AddCommand(hostSectorDeleteCmd)
 	hostCmd.Flags().BoolVarP(&hostVerbose, "verbose", "v", false, "Display detailed host info")
+	hostContractsCmd.Flags().StringVarP(&hostContractsStatus, "status", "s", "", "Only show contracts with this status (unresolved, succeeded, failed, or rejected)")
 
 	root.AddCommand(hostdbCmd)
-	hostdbCmd.AddCommand(hostdbViewCmd)
+	hostdbCmd.AddCommand(hostdbViewCmd, hostdbFilterCmd)
 	hostdbCmd.Flags().IntVarP(&hostdbNumHosts, "numhosts", "n", 0, "Number of hosts to display from the hostdb")
+	hostdbFilterCmd.Flags().StringVarP(&hostdbGeoIP, "geoip", "", "", "Path of the GeoIP database used to filter hosts by country")
 	hostdbCmd.Flags().BoolVarP(&hostdbVerbose, "verbose", "v", false, "Display full hostdb information")
 
 	root.AddCommand(minerCmd)
@@ -275,17 +279,20 @@ func main() {
 
 	root.AddCommand(renterCmd)
 	renterCmd.AddCommand(renterFilesDeleteCmd, renterFilesDownloadCmd,
-		renterDownloadsCmd, renterAllowanceCmd, renterSetAllowanceCmd,
-		renterContractsCmd, renterFilesListCmd, renterFilesRenameCmd,
+		renterDownloadsCmd, renterAllowanceCmd, renterSetAllowanceCmd, renterSetCacheSizeCmd,
+		renterSetUploadMemoryCmd, renterSetRateLimitCmd, renterContractsCmd, renterDirListCmd, renterFilesListCmd, renterFilesRedundancyCmd, renterFilesRenameCmd,
 		renterFilesUploadCmd, renterUploadsCmd, renterExportCmd,
-		renterPricesCmd)
+		renterPricesCmd, renterSpendingCmd, renterBackupCmd, renterRestoreCmd)
 
-	renterContractsCmd.AddCommand(renterContractsViewCmd)
+	renterContractsCmd.AddCommand(renterContractsCancelCmd, renterContractsFormCmd, renterContractsRenewCmd, renterContractsViewCmd)
 	renterAllowanceCmd.AddCommand(renterAllowanceCancelCmd)
+	renterDownloadsCmd.AddCommand(renterDownloadsCancelCmd, renterDownloadsPauseCmd, renterDownloadsResumeCmd)
 
 	renterCmd.Flags().BoolVarP(&renterListVerbose, "verbose", "v", false, "Show additional file info such as redundancy")
+	renterContractsCmd.Flags().BoolVarP(&renterShowExpired, "expired", "e", false, "Show expired contracts in addition to active contracts")
 	renterDownloadsCmd.Flags().BoolVarP(&renterShowHistory, "history", "H", false, "Show download history in addition to the download queue")
 	renterFilesListCmd.Flags().BoolVarP(&renterListVerbose, "verbose", "v", false, "Show additional file info such as redundancy")
+	renterDirListCmd.Flags().BoolVarP(&renterListVerbose, "verbose", "v", false, "Show additional directory info such as health and redundancy")
 	renterExportCmd.AddCommand(renterExportContractTxnsCmd)
 
 	root.AddCommand(gatewayCmd)
@@ -22,6 +22,15 @@ func filesizeUnits(size int64) string {
 	return fmt.Sprintf("%.*f %s", i, float64(size)/math.Pow10(3*i), sizes[i])
 }
 
+// speedUnits converts a speed in bytes per second to a human-readable string.
+// A speed of 0 is not limited.
+func speedUnits(bps int64) string {
+	if bps == 0 {
+		return "unlimited"
+	}
+	return filesizeUnits(bps) + "/s"
+}
+
 // parseFilesize converts strings of form 10GB to a size in bytes. Fractional
 // sizes are truncated at the byte size.
 func parseFilesize(strSize string) (string, error) {
@@ -59,6 +68,21 @@ func parseFilesize(strSize string) (string, error) {
 	return "", errUnableToParseSize
 }
 
+// parseSpeed converts strings of form 10MB/s or 10MBps to a speed in bytes
+// per second. A speed of 0 needs no unit.
+func parseSpeed(strSpeed string) (string, error) {
+	if strSpeed == "0" {
+		return "0", nil
+	}
+	strSpeed = strings.ToLower(strSpeed)
+	for _, suffix := range []string{"/s", "ps"} {
+		if strings.HasSuffix(strSpeed, suffix) {
+			return parseFilesize(strings.TrimSuffix(strSpeed, suffix))
+		}
+	}
+	return parseFilesize(strSpeed)
+}
+
 // periodUnits turns a period in terms of blocks to a number of weeks.
 func periodUnits(blocks types.BlockHeight) string {
 	return fmt.Sprint(blocks / 1008) // 1008 blocks per week
@@ -166,3 +190,12 @@ func yesNo(b bool) string {
 	}
 	return "No"
 }
+
+// connectionLimit returns a human-readable connection limit, where a limit of
+// 0 means that the number of connections is not limited.
+func connectionLimit(limit uint64) string {
+	if limit == 0 {
+		return "unlimited"
+	}
+	return fmt.Sprint(limit)
+}
@@ -43,6 +43,28 @@ func TestParseFilesize(t *testing.T) {
 	}
 }
 
+func TestParseSpeed(t *testing.T) {
+	tests := []struct {
+		in, out string
+		err     error
+	}{
+		{"0", "0", nil},
+		{"1KB", "1000", nil},
+		{"1KB/s", "1000", nil},
+		{"1MiBps", "1048576", nil},
+		{"2.5MB/S", "2500000", nil},
+		{"123", "", errUnableToParseSize},
+		{"/s", "", errUnableToParseSize},
+		{"1KB/h", "", errUnableToParseSize},
+	}
+	for _, test := range tests {
+		res, err := parseSpeed(test.in)
+		if res != test.out || err != test.err {
+			t.Errorf("parseSpeed(%v): expected %v %v, got %v %v", test.in, test.out, test.err, res, err)
+		}
+	}
+}
+
 func TestParsePeriod(t *testing.T) {
 	tests := []struct {
 		in, out string
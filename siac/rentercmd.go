@@ -2,12 +2,15 @@ package main
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"text/tabwriter"
 	"time"
 
+	"github.com/bgentry/speakeasy"
 	"github.com/spf13/cobra"
 
 	"github.com/NebulousLabs/Sia/api"
@@ -36,6 +39,27 @@ var (
 		Run:   wrap(renterdownloadscmd),
 	}
 
+	renterDownloadsCancelCmd = &cobra.Command{
+		Use:   "cancel [id]",
+		Short: "Cancel a download",
+		Long:  "Cancel a download in the download queue. Paused downloads can be cancelled as well.",
+		Run:   wrap(renterdownloadscancelcmd),
+	}
+
+	renterDownloadsPauseCmd = &cobra.Command{
+		Use:   "pause [id]",
+		Short: "Pause a download",
+		Long:  "Pause a download in the download queue. Only downloads to a file can be paused.",
+		Run:   wrap(renterdownloadspausecmd),
+	}
+
+	renterDownloadsResumeCmd = &cobra.Command{
+		Use:   "resume [id]",
+		Short: "Resume a paused download",
+		Long:  "Resume a paused download from its last completed chunk.",
+		Run:   wrap(renterdownloadsresumecmd),
+	}
+
 	renterAllowanceCmd = &cobra.Command{
 		Use:   "allowance",
 		Short: "View the current allowance",
@@ -67,6 +91,39 @@ have a reasonable number (>30) of hosts in your hostdb.`,
 		Run: wrap(rentersetallowancecmd),
 	}
 
+	renterSetCacheSizeCmd = &cobra.Command{
+		Use:   "setcachesize [size]",
+		Short: "Set the size of the download cache",
+		Long: `Set the amount of disk space used to cache recently downloaded chunks, so
+that downloading the same data again does not use any bandwidth. The cache
+holds decrypted data. A size of 0 disables the cache.
+
+size is given in bytes (B), or with a unit such as MB, GB, MiB or GiB.`,
+		Run: wrap(rentersetcachesizecmd),
+	}
+
+	renterSetUploadMemoryCmd = &cobra.Command{
+		Use:   "setuploadmemory [size]",
+		Short: "Limit the memory used for uploading",
+		Long: `Limit the amount of memory used to erasure code, encrypt and upload chunks.
+New uploads wait for memory to become available once the limit is reached. A
+size of 0 selects the default limit.
+
+size is given in bytes (B), or with a unit such as MB, GB, MiB or GiB.`,
+		Run: wrap(rentersetuploadmemorycmd),
+	}
+
+	renterSetRateLimitCmd = &cobra.Command{
+		Use:   "setratelimit [maxdownloadspeed] [maxuploadspeed]",
+		Short: "Limit the renter's bandwidth",
+		Long: `Limit the combined bandwidth of all uploads and downloads to and from hosts.
+The limits apply to transfers that are already in progress. A speed of 0
+removes the limit.
+
+Speeds are given in bytes per second, with a unit such as KB/s, MB/s or MiB/s.`,
+		Run: wrap(rentersetratelimitcmd),
+	}
+
 	renterContractsCmd = &cobra.Command{
 		Use:   "contracts",
 		Short: "View the Renter's contracts",
@@ -74,6 +131,36 @@ have a reasonable number (>30) of hosts in your hostdb.`,
 		Run:   wrap(rentercontractscmd),
 	}
 
+	renterContractsCancelCmd = &cobra.Command{
+		Use:   "cancel [contract-id]",
+		Short: "Cancel a contract",
+		Long: `Cancel a contract, so that it is no longer used for uploading and is not
+renewed. The data stored with the host can be downloaded until the contract
+expires.`,
+		Run: wrap(rentercontractscancelcmd),
+	}
+
+	renterContractsFormCmd = &cobra.Command{
+		Use:   "form [host-public-key] [amount] [endheight]",
+		Short: "Form a contract with a host",
+		Long: `Form a manual contract with the specified host, outside of the allowance.
+Manual contracts are used for uploading, but they are never renewed or replaced
+by the renter; use 'siac renter contracts renew' to extend them.
+
+amount is given in currency units (SC, KS, etc.)`,
+		Run: wrap(rentercontractsformcmd),
+	}
+
+	renterContractsRenewCmd = &cobra.Command{
+		Use:   "renew [contract-id] [amount] [endheight]",
+		Short: "Renew a contract",
+		Long: `Renew the specified contract, adding the provided funds and extending it to
+the provided end height.
+
+amount is given in currency units (SC, KS, etc.)`,
+		Run: wrap(rentercontractsrenewcmd),
+	}
+
 	renterContractsViewCmd = &cobra.Command{
 		Use:   "view [contract-id]",
 		Short: "View details of the specified contract",
@@ -96,12 +183,30 @@ have a reasonable number (>30) of hosts in your hostdb.`,
 		Run:   wrap(renterfilesdownloadcmd),
 	}
 
+	renterDirListCmd = &cobra.Command{
+		Use:   "ls [path]",
+		Short: "List the contents of a directory",
+		Long: `List the subdirectories and files of a directory on the Sia network,
+along with the size, health and redundancy of each subdirectory. Lists the root
+directory if no path is given.`,
+		Run: renterdirlistcmd,
+	}
+
 	renterFilesListCmd = &cobra.Command{
-		Use:     "list",
-		Aliases: []string{"ls"},
-		Short:   "List the status of all files",
-		Long:    "List the status of all files known to the renter on the Sia network.",
-		Run:     wrap(renterfileslistcmd),
+		Use:   "list",
+		Short: "List the status of all files",
+		Long:  "List the status of all files known to the renter on the Sia network.",
+		Run:   wrap(renterfileslistcmd),
+	}
+
+	renterFilesRedundancyCmd = &cobra.Command{
+		Use:   "redundancy [path] [datapieces] [paritypieces]",
+		Short: "Change the redundancy of a file",
+		Long: `Change the erasure coding parameters, and therefore the target redundancy, of
+a file. The number of data pieces must match the one the file was uploaded
+with. Pieces are uploaded or deleted in the background to reach the new
+redundancy.`,
+		Run: wrap(renterfilesredundancycmd),
 	}
 
 	renterFilesRenameCmd = &cobra.Command{
@@ -125,6 +230,33 @@ have a reasonable number (>30) of hosts in your hostdb.`,
 		Long:  "Display the estimated prices of storing files, retrieving files, and creating a set of contracts",
 		Run:   wrap(renterpricescmd),
 	}
+
+	renterSpendingCmd = &cobra.Command{
+		Use:   "spending",
+		Short: "Display the renter's spending",
+		Long: `Display a breakdown of the funds spent on contracts in the current allowance
+period and in each past allowance period, including fees, storage, uploads,
+downloads, and the funds that are unspent or returned by the contracts.`,
+		Run: wrap(renterspendingcmd),
+	}
+
+	renterBackupCmd = &cobra.Command{
+		Use:   "backup [destination]",
+		Short: "Back up the renter's files and contracts",
+		Long: `Write an encrypted archive of the renter's files and contracts to destination.
+If the renter's directory is lost, restoring the archive allows the files to
+be downloaded again. You will be asked for the password that encrypts the
+archive.`,
+		Run: wrap(renterbackupcmd),
+	}
+
+	renterRestoreCmd = &cobra.Command{
+		Use:   "restore [source]",
+		Short: "Restore the renter's files and contracts from a backup",
+		Long: `Restore the files and contracts in an archive created by 'siac renter backup'.
+Files and contracts that the renter already has are left untouched.`,
+		Run: wrap(renterrestorecmd),
+	}
 )
 
 // abs returns the absolute representation of a path.
@@ -158,6 +290,23 @@ func rentercmd() {
 `, currencyUnits(fm.StorageSpending), currencyUnits(fm.UploadSpending),
 		currencyUnits(fm.DownloadSpending), currencyUnits(unspent),
 		currencyUnits(fm.ContractSpending))
+	if rg.Settings.MaxDownloadSpeed > 0 || rg.Settings.MaxUploadSpeed > 0 {
+		fmt.Printf(`Rate limits:
+	Download: %v
+	Upload:   %v
+
+`, speedUnits(rg.Settings.MaxDownloadSpeed), speedUnits(rg.Settings.MaxUploadSpeed))
+	}
+	if rg.Settings.ChunkCacheSize > 0 {
+		cc := rg.ChunkCache
+		fmt.Printf(`Download cache:
+	Size:   %v of %v (%v chunks)
+	Hits:   %v
+	Misses: %v
+
+`, filesizeUnits(int64(cc.Size)), filesizeUnits(int64(rg.Settings.ChunkCacheSize)),
+			cc.Chunks, cc.Hits, cc.Misses)
+	}
 
 	// also list files
 	renterfileslistcmd()
@@ -195,18 +344,18 @@ func renteruploadscmd() {
 }
 
 // renterdownloadscmd is the handler for the command `siac renter downloads`.
-// Lists files currently downloading, and optionally previously downloaded
-// files if the -H or --history flag is specified.
+// Lists files currently downloading or paused, and optionally previously
+// downloaded files if the -H or --history flag is specified.
 func renterdownloadscmd() {
 	var queue api.RenterDownloadQueue
 	err := getAPI("/renter/downloads", &queue)
 	if err != nil {
 		die("Could not get download queue:", err)
 	}
-	// Filter out files that have been downloaded.
+	// Filter out downloads that have stopped.
 	var downloading []api.DownloadInfo
 	for _, file := range queue.Downloads {
-		if file.Received != file.Filesize {
+		if file.Status == modules.DownloadStatusActive || file.Status == modules.DownloadStatusPaused {
 			downloading = append(downloading, file)
 		}
 	}
@@ -215,17 +364,21 @@ func renterdownloadscmd() {
 	} else {
 		fmt.Println("Downloading", len(downloading), "files:")
 		for _, file := range downloading {
-			fmt.Printf("%s: %5.1f%% %s -> %s\n", file.StartTime.Format("Jan 02 03:04 PM"), 100*float64(file.Received)/float64(file.Filesize), file.SiaPath, file.Destination)
+			fmt.Printf("%s: %s %5.1f%% %s -> %s", file.StartTime.Format("Jan 02 03:04 PM"), file.ID, 100*float64(file.Received)/float64(file.Filesize), file.SiaPath, file.Destination)
+			if file.Status == modules.DownloadStatusPaused {
+				fmt.Print(" (paused)")
+			}
+			fmt.Println()
 		}
 	}
 	if !renterShowHistory {
 		return
 	}
 	fmt.Println()
-	// Filter out files that are downloading.
+	// Filter out downloads that are still in progress.
 	var downloaded []api.DownloadInfo
 	for _, file := range queue.Downloads {
-		if file.Received == file.Filesize {
+		if file.Status != modules.DownloadStatusActive && file.Status != modules.DownloadStatusPaused {
 			downloaded = append(downloaded, file)
 		}
 	}
@@ -234,11 +387,41 @@ func renterdownloadscmd() {
 	} else {
 		fmt.Println("Downloaded", len(downloaded), "files:")
 		for _, file := range downloaded {
-			fmt.Printf("%s: %s -> %s\n", file.StartTime.Format("Jan 02 03:04 PM"), file.SiaPath, file.Destination)
+			fmt.Printf("%s: %s %s -> %s (%s)\n", file.StartTime.Format("Jan 02 03:04 PM"), file.ID, file.SiaPath, file.Destination, file.Status)
 		}
 	}
 }
 
+// renterdownloadscancelcmd is the handler for the command `siac renter
+// downloads cancel [id]`.
+func renterdownloadscancelcmd(id string) {
+	err := post("/renter/download/cancel/"+id, "")
+	if err != nil {
+		die("Could not cancel download:", err)
+	}
+	fmt.Println("Cancelled download", id)
+}
+
+// renterdownloadspausecmd is the handler for the command `siac renter
+// downloads pause [id]`.
+func renterdownloadspausecmd(id string) {
+	err := post("/renter/download/pause/"+id, "")
+	if err != nil {
+		die("Could not pause download:", err)
+	}
+	fmt.Println("Paused download", id)
+}
+
+// renterdownloadsresumecmd is the handler for the command `siac renter
+// downloads resume [id]`.
+func renterdownloadsresumecmd(id string) {
+	err := post("/renter/download/resume/"+id, "")
+	if err != nil {
+		die("Could not resume download:", err)
+	}
+	fmt.Println("Resumed download", id)
+}
+
 // renterallowancecmd displays the current allowance.
 func renterallowancecmd() {
 	var rg api.RenterGET
@@ -281,6 +464,57 @@ func rentersetallowancecmd(amount, period string) {
 	fmt.Println("Allowance updated.")
 }
 
+// rentersetcachesizecmd sets the size of the renter's download cache.
+func rentersetcachesizecmd(size string) {
+	bytes := "0"
+	if size != "0" {
+		var err error
+		bytes, err = parseFilesize(size)
+		if err != nil {
+			die("Could not parse size:", err)
+		}
+	}
+	err := post("/renter", "chunkcachesize="+bytes)
+	if err != nil {
+		die("Could not set cache size:", err)
+	}
+	fmt.Println("Download cache size updated.")
+}
+
+// rentersetuploadmemorycmd sets the memory limit of the renter's uploads.
+func rentersetuploadmemorycmd(size string) {
+	bytes := "0"
+	if size != "0" {
+		var err error
+		bytes, err = parseFilesize(size)
+		if err != nil {
+			die("Could not parse size:", err)
+		}
+	}
+	err := post("/renter", "maxuploadmemory="+bytes)
+	if err != nil {
+		die("Could not set upload memory:", err)
+	}
+	fmt.Println("Upload memory limit updated.")
+}
+
+// rentersetratelimitcmd sets the renter's bandwidth limits.
+func rentersetratelimitcmd(maxDownloadSpeed, maxUploadSpeed string) {
+	download, err := parseSpeed(maxDownloadSpeed)
+	if err != nil {
+		die("Could not parse maxdownloadspeed:", err)
+	}
+	upload, err := parseSpeed(maxUploadSpeed)
+	if err != nil {
+		die("Could not parse maxuploadspeed:", err)
+	}
+	err = post("/renter", fmt.Sprintf("maxdownloadspeed=%s&maxuploadspeed=%s", download, upload))
+	if err != nil {
+		die("Could not set rate limits:", err)
+	}
+	fmt.Println("Rate limits updated.")
+}
+
 // byValue sorts contracts by their value in siacoins, high to low. If two
 // contracts have the same value, they are sorted by their host's address.
 type byValue []api.RenterContract
@@ -299,29 +533,91 @@ func (s byValue) Less(i, j int) bool {
 // It lists the Renter's contracts.
 func rentercontractscmd() {
 	var rc api.RenterContracts
-	err := getAPI("/renter/contracts", &rc)
+	call := "/renter/contracts"
+	if renterShowExpired {
+		call += "?expired=true"
+	}
+	err := getAPI(call, &rc)
 	if err != nil {
 		die("Could not get contracts:", err)
 	}
-	if len(rc.Contracts) == 0 {
+	if len(rc.Contracts) == 0 && len(rc.ExpiredContracts) == 0 {
 		fmt.Println("No contracts have been formed.")
 		return
 	}
-	sort.Sort(byValue(rc.Contracts))
-	fmt.Println("Contracts:")
-	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "Host\tRemaining Funds\tSpent Funds\tSpent Fees\tData\tEnd Height\tID")
-	for _, c := range rc.Contracts {
-		fmt.Fprintf(w, "%v\t%8s\t%8s\t%8s\t%v\t%v\t%v\n",
-			c.NetAddress,
-			currencyUnits(c.RenterFunds),
-			currencyUnits(c.TotalCost.Sub(c.RenterFunds).Sub(c.Fees)),
-			currencyUnits(c.Fees),
-			filesizeUnits(int64(c.Size)),
-			c.EndHeight,
-			c.ID)
+	if len(rc.Contracts) != 0 {
+		sort.Sort(byValue(rc.Contracts))
+		fmt.Println("Contracts:")
+		w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Host\tRemaining Funds\tSpent Funds\tSpent Fees\tData\tEnd Height\tID")
+		for _, c := range rc.Contracts {
+			fmt.Fprintf(w, "%v\t%8s\t%8s\t%8s\t%v\t%v\t%v\n",
+				c.NetAddress,
+				currencyUnits(c.RenterFunds),
+				currencyUnits(c.TotalCost.Sub(c.RenterFunds).Sub(c.Fees)),
+				currencyUnits(c.Fees),
+				filesizeUnits(int64(c.Size)),
+				c.EndHeight,
+				c.ID)
+		}
+		w.Flush()
+	}
+	if len(rc.ExpiredContracts) != 0 {
+		fmt.Println("Expired Contracts:")
+		w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Host\tTotal Cost\tSpent Fees\tRenter Payout\tProof\tEnd Height\tID")
+		for _, c := range rc.ExpiredContracts {
+			fmt.Fprintf(w, "%v\t%8s\t%8s\t%8s\t%v\t%v\t%v\n",
+				c.NetAddress,
+				currencyUnits(c.TotalCost),
+				currencyUnits(c.Fees),
+				currencyUnits(c.RenterPayout),
+				c.ProofStatus,
+				c.EndHeight,
+				c.ID)
+		}
+		w.Flush()
 	}
-	w.Flush()
+}
+
+// rentercontractsformcmd is the handler for the command `siac renter contracts
+// form [host-public-key] [amount] [endheight]`.
+func rentercontractsformcmd(hostKey, amount, endHeight string) {
+	hastings, err := parseCurrency(amount)
+	if err != nil {
+		die("Could not parse amount:", err)
+	}
+	var rc api.RenterContract
+	err = postResp("/renter/contracts/form", fmt.Sprintf("host=%s&funds=%s&endheight=%s", hostKey, hastings, endHeight), &rc)
+	if err != nil {
+		die("Could not form contract:", err)
+	}
+	fmt.Println("Formed contract", rc.ID)
+}
+
+// rentercontractsrenewcmd is the handler for the command `siac renter
+// contracts renew [contract-id] [amount] [endheight]`.
+func rentercontractsrenewcmd(cid, amount, endHeight string) {
+	hastings, err := parseCurrency(amount)
+	if err != nil {
+		die("Could not parse amount:", err)
+	}
+	var rc api.RenterContract
+	err = postResp("/renter/contracts/"+cid+"/renew", fmt.Sprintf("funds=%s&endheight=%s", hastings, endHeight), &rc)
+	if err != nil {
+		die("Could not renew contract:", err)
+	}
+	fmt.Println("Renewed contract", cid, "as", rc.ID)
+}
+
+// rentercontractscancelcmd is the handler for the command `siac renter
+// contracts cancel [contract-id]`.
+func rentercontractscancelcmd(cid string) {
+	err := post("/renter/contracts/"+cid+"/cancel", "")
+	if err != nil {
+		die("Could not cancel contract:", err)
+	}
+	fmt.Println("Canceled contract", cid)
 }
 
 // rentercontractsviewcmd is the handler for the command `siac renter contracts <id>`.
@@ -346,6 +642,8 @@ Contract %v
 
   Start Height: %v
   End Height:   %v
+  Manual:       %v
+  Canceled:     %v
 
   Total cost:        %v (Fees: %v)
   Funds Allocated:   %v
@@ -355,7 +653,11 @@ Contract %v
   Remaining Funds:   %v
 
   File Size: %v
-`, rc.ID, rc.NetAddress, rc.HostPublicKey.String(), rc.StartHeight, rc.EndHeight,
+
+  Download Latency:    %v ms
+  Download Throughput: %v/s
+  Download Stalls:     %v
+`, rc.ID, rc.NetAddress, rc.HostPublicKey.String(), rc.StartHeight, rc.EndHeight, rc.Manual, rc.Canceled,
 				currencyUnits(rc.TotalCost),
 				currencyUnits(rc.Fees),
 				currencyUnits(rc.TotalCost.Sub(rc.Fees)),
@@ -363,7 +665,10 @@ Contract %v
 				currencyUnits(rc.StorageSpending),
 				currencyUnits(rc.DownloadSpending),
 				currencyUnits(rc.RenterFunds),
-				filesizeUnits(int64(rc.Size)))
+				filesizeUnits(int64(rc.Size)),
+				rc.DownloadLatency,
+				filesizeUnits(int64(rc.DownloadThroughput)),
+				rc.DownloadStalls)
 
 			printScoreBreakdown(&hostInfo)
 			return
@@ -454,7 +759,7 @@ func renterfileslistcmd() {
 	fmt.Println("Tracking", len(rf.Files), "files:")
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	if renterListVerbose {
-		fmt.Fprintln(w, "File size\tAvailable\tProgress\tRedundancy\tRenewing\tSia path")
+		fmt.Fprintln(w, "File size\tAvailable\tProgress\tHealth\tRedundancy\tRenewing\tSia path")
 	}
 	sort.Sort(bySiaPath(rf.Files))
 	for _, file := range rf.Files {
@@ -462,15 +767,15 @@ func renterfileslistcmd() {
 		if renterListVerbose {
 			availableStr := yesNo(file.Available)
 			renewingStr := yesNo(file.Renewing)
-			redundancyStr := fmt.Sprintf("%.2f", file.Redundancy)
+			redundancyStr := fmt.Sprintf("%.2f/%.2f", file.Redundancy, file.TargetRedundancy)
 			if file.Redundancy == -1 {
-				redundancyStr = "-"
+				redundancyStr = fmt.Sprintf("-/%.2f", file.TargetRedundancy)
 			}
 			uploadProgressStr := fmt.Sprintf("%.2f%%", file.UploadProgress)
 			if file.UploadProgress == -1 {
 				uploadProgressStr = "-"
 			}
-			fmt.Fprintf(w, "\t%s\t%8s\t%10s\t%s", availableStr, uploadProgressStr, redundancyStr, renewingStr)
+			fmt.Fprintf(w, "\t%s\t%8s\t%6v\t%10s\t%s", availableStr, uploadProgressStr, file.Health, redundancyStr, renewingStr)
 		}
 		fmt.Fprintf(w, "\t%s", file.SiaPath)
 		if !renterListVerbose && !file.Available {
@@ -481,6 +786,68 @@ func renterfileslistcmd() {
 	w.Flush()
 }
 
+// renterdirlistcmd is the handler for the command `siac renter ls [path]`.
+// Lists the contents of a renter directory.
+func renterdirlistcmd(cmd *cobra.Command, args []string) {
+	var path string
+	switch len(args) {
+	case 0:
+	case 1:
+		path = strings.Trim(args[0], "/")
+	default:
+		cmd.UsageFunc()(cmd)
+		os.Exit(exitCodeUsage)
+	}
+	var rd api.RenterDirectory
+	err := getAPI("/renter/dir/"+path, &rd)
+	if err != nil {
+		die("Could not list directory:", err)
+	}
+	if len(rd.Directories) == 0 {
+		die("Could not list directory: no directory info returned")
+	}
+	dir := rd.Directories[0]
+	fmt.Printf("%v files, %v subdirectories, %s total:\n", dir.NumFiles, dir.NumSubDirs, filesizeUnits(int64(dir.AggregateSize)))
+	if len(rd.Directories) == 1 && len(rd.Files) == 0 {
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if renterListVerbose {
+		fmt.Fprintln(w, "Size\tFiles\tHealth\tRedundancy\tSia path")
+	}
+	for _, sd := range rd.Directories[1:] {
+		fmt.Fprintf(w, "%9s", filesizeUnits(int64(sd.AggregateSize)))
+		if renterListVerbose {
+			healthStr, redundancyStr := "-", "-"
+			if sd.NumFiles > 0 {
+				healthStr = fmt.Sprint(sd.Health)
+			}
+			if sd.MinRedundancy != -1 {
+				redundancyStr = fmt.Sprintf("%.2f", sd.MinRedundancy)
+			}
+			fmt.Fprintf(w, "\t%v\t%s\t%s", sd.NumFiles, healthStr, redundancyStr)
+		}
+		fmt.Fprintf(w, "\t%s/\n", sd.SiaPath)
+	}
+	for _, file := range rd.Files {
+		fmt.Fprintf(w, "%9s", filesizeUnits(int64(file.Filesize)))
+		if renterListVerbose {
+			redundancyStr := fmt.Sprintf("%.2f", file.Redundancy)
+			if file.Redundancy == -1 {
+				redundancyStr = "-"
+			}
+			fmt.Fprintf(w, "\t-\t-\t%s", redundancyStr)
+		}
+		fmt.Fprintf(w, "\t%s", file.SiaPath)
+		if !file.Available {
+			fmt.Fprintf(w, " (uploading, %0.2f%%)", file.UploadProgress)
+		}
+		fmt.Fprintln(w, "")
+	}
+	w.Flush()
+}
+
 // renterfilesrenamecmd is the handler for the command `siac renter rename [path] [newpath]`.
 // Renames a file on the Sia network.
 func renterfilesrenamecmd(path, newpath string) {
@@ -491,6 +858,16 @@ func renterfilesrenamecmd(path, newpath string) {
 	fmt.Printf("Renamed %s to %s\n", path, newpath)
 }
 
+// renterfilesredundancycmd is the handler for the command `siac renter
+// redundancy [path] [datapieces] [paritypieces]`.
+func renterfilesredundancycmd(path, dataPieces, parityPieces string) {
+	err := post("/renter/redundancy/"+path, "datapieces="+dataPieces+"&paritypieces="+parityPieces)
+	if err != nil {
+		die("Could not change redundancy:", err)
+	}
+	fmt.Printf("Changed the erasure coding of %s to %s data pieces and %s parity pieces\n", path, dataPieces, parityPieces)
+}
+
 // renterfilesuploadcmd is the handler for the command `siac renter upload
 // [source] [path]`. Uploads the [source] file to [path] on the Sia network.
 // If [source] is a directory, all files inside it will be uploaded and named
@@ -557,3 +934,74 @@ func renterpricescmd() {
 	fmt.Fprintln(w, "\tUpload 1 TB:\t", currencyUnits(rpg.UploadTerabyte))
 	w.Flush()
 }
+
+// printSpending prints the spending of a single allowance period.
+func printSpending(title string, rs modules.RenterSpending) {
+	end := fmt.Sprint(rs.EndHeight)
+	if rs.EndHeight == 0 {
+		end = "-"
+	}
+	fmt.Printf("%v (blocks %v to %v):\n", title, rs.StartHeight, end)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tAllowance:\t", currencyUnits(rs.Allowance))
+	fmt.Fprintln(w, "\tContract Fees:\t", currencyUnits(rs.ContractFees))
+	fmt.Fprintln(w, "\tTransaction Fees:\t", currencyUnits(rs.TransactionFees))
+	fmt.Fprintln(w, "\tSiafund Fees:\t", currencyUnits(rs.SiafundFees))
+	fmt.Fprintln(w, "\tStorage:\t", currencyUnits(rs.StorageSpending))
+	fmt.Fprintln(w, "\tUpload:\t", currencyUnits(rs.UploadSpending))
+	fmt.Fprintln(w, "\tDownload:\t", currencyUnits(rs.DownloadSpending))
+	fmt.Fprintln(w, "\tUnspent:\t", currencyUnits(rs.Unspent))
+	fmt.Fprintln(w, "\tRefundable:\t", currencyUnits(rs.Refundable))
+	w.Flush()
+}
+
+// renterspendingcmd is the handler for the command `siac renter spending`.
+// It prints the spending of the current and past allowance periods, most
+// recent first.
+func renterspendingcmd() {
+	var rsg api.RenterSpendingGET
+	err := getAPI("/renter/spending", &rsg)
+	if err != nil {
+		die("Could not get the renter's spending:", err)
+	}
+	printSpending("Current Period", rsg.Current)
+	for i := len(rsg.Past) - 1; i >= 0; i-- {
+		fmt.Println()
+		printSpending("Past Period", rsg.Past[i])
+	}
+}
+
+// renterbackupcmd writes an encrypted backup of the renter's files and
+// contracts to destination.
+func renterbackupcmd(destination string) {
+	password, err := speakeasy.Ask("Backup password: ")
+	if err != nil {
+		die("Reading password failed:", err)
+	}
+	confirm, err := speakeasy.Ask("Confirm: ")
+	if err != nil {
+		die("Reading password failed:", err)
+	} else if password != confirm {
+		die("Passwords do not match")
+	}
+	qs := url.Values{"destination": {abs(destination)}, "password": {password}}
+	err = post("/renter/backup", qs.Encode())
+	if err != nil {
+		die("Could not create backup:", err)
+	}
+	fmt.Println("Backup written to", abs(destination))
+}
+
+// renterrestorecmd restores the renter's files and contracts from a backup.
+func renterrestorecmd(source string) {
+	password, err := speakeasy.Ask("Backup password: ")
+	if err != nil {
+		die("Reading password failed:", err)
+	}
+	qs := url.Values{"source": {abs(source)}, "password": {password}}
+	err = post("/renter/restore", qs.Encode())
+	if err != nil {
+		die("Could not restore backup:", err)
+	}
+	fmt.Println("Backup restored.")
+}